@@ -0,0 +1,165 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/Aleksey170999/go-shortener/internal/model"
+)
+
+// defaultBatchSize keeps a batch request well below the default body limit
+// of the server for batch routes.
+const defaultBatchSize = 500
+
+// shortenBatch returns the short URLs of originals, in the same order, with
+// a single request to the batch API.
+func (c *client) shortenBatch(ctx context.Context, originals []string) ([]string, error) {
+	req := make([]model.RequestURLItem, len(originals))
+	for i, original := range originals {
+		req[i] = model.RequestURLItem{СorrelationID: strconv.Itoa(i), OriginalURL: original}
+	}
+	var resp []model.ResponseURLItem
+	if err := c.post(ctx, "/api/shorten/batch", req, &resp); err != nil {
+		return nil, err
+	}
+	shorts := make([]string, len(originals))
+	for _, item := range resp {
+		i, err := strconv.Atoi(item.CorrelationID)
+		if err != nil || i < 0 || i >= len(shorts) {
+			return nil, fmt.Errorf("invalid response: unknown correlation_id %q", item.CorrelationID)
+		}
+		shorts[i] = item.ShortURL
+	}
+	for i, short := range shorts {
+		if short == "" {
+			return nil, fmt.Errorf("invalid response: no short URL for %s", originals[i])
+		}
+	}
+	return shorts, nil
+}
+
+// readURLs reads the URLs to shorten from r. A CSV file has the URLs in the
+// column named "url" or "original_url" if its first row is such a header,
+// and in its first column otherwise. Any other file has one URL per line.
+// Blank lines are skipped.
+func readURLs(r io.Reader, isCSV bool) ([]string, error) {
+	var urls []string
+	if !isCSV {
+		scanner := bufio.NewScanner(r)
+		for scanner.Scan() {
+			if line := strings.TrimSpace(scanner.Text()); line != "" {
+				urls = append(urls, line)
+			}
+		}
+		return urls, scanner.Err()
+	}
+
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+	column := 0
+	for row := 0; ; row++ {
+		record, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			return urls, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if row == 0 {
+			if i := headerColumn(record); i >= 0 {
+				column = i
+				continue
+			}
+		}
+		if column < len(record) {
+			if u := strings.TrimSpace(record[column]); u != "" {
+				urls = append(urls, u)
+			}
+		}
+	}
+}
+
+// headerColumn returns the index of the URL column in the CSV header
+// record, or -1 if record is not a header.
+func headerColumn(record []string) int {
+	for i, name := range record {
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "url", "original_url":
+			return i
+		}
+	}
+	return -1
+}
+
+// isCSVFile reports whether path names a CSV file.
+func isCSVFile(path string) bool {
+	return strings.EqualFold(filepath.Ext(path), ".csv")
+}
+
+// migrate shortens urls in batches of batchSize and writes the mapping of
+// each original URL to its short URL as CSV to out, with a header row. A
+// batch the server rejects as a whole because of an invalid URL is retried
+// URL by URL, so that only the invalid URLs are left out of the mapping;
+// they are reported on stderr. Other failures stop the migration, after the
+// mapping of the batches done so far has been written.
+func migrate(ctx context.Context, c *client, urls []string, batchSize int, out, stderr io.Writer) int {
+	w := csv.NewWriter(out)
+	defer w.Flush()
+	if err := w.Write([]string{"original_url", "short_url"}); err != nil {
+		fmt.Fprintf(stderr, "failed to write mapping: %v\n", err)
+		return 1
+	}
+
+	status, done, written := 0, 0, 0
+	for start := 0; start < len(urls); start += batchSize {
+		batch := urls[start:min(start+batchSize, len(urls))]
+		shorts, err := c.shortenBatch(ctx, batch)
+		var statusErr *errStatus
+		if errors.As(err, &statusErr) && statusErr.code == http.StatusBadRequest {
+			shorts, err = make([]string, len(batch)), nil
+			for i, original := range batch {
+				short, err := c.shorten(ctx, original)
+				if err != nil {
+					fmt.Fprintf(stderr, "%s: %v\n", original, err)
+					status = 1
+					if ctx.Err() != nil {
+						return status
+					}
+					continue
+				}
+				shorts[i] = short
+			}
+		}
+		if err != nil {
+			fmt.Fprintf(stderr, "stopped after %d of %d URLs: %v\n", done, len(urls), err)
+			return 1
+		}
+		for i, short := range shorts {
+			if short == "" {
+				continue
+			}
+			if err := w.Write([]string{batch[i], short}); err != nil {
+				fmt.Fprintf(stderr, "failed to write mapping: %v\n", err)
+				return 1
+			}
+			written++
+		}
+		done += len(batch)
+		w.Flush()
+		if err := w.Error(); err != nil {
+			fmt.Fprintf(stderr, "failed to write mapping: %v\n", err)
+			return 1
+		}
+	}
+	fmt.Fprintf(stderr, "shortened %d of %d URLs\n", written, len(urls))
+	return status
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/Aleksey170999/go-shortener/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadURLs(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		isCSV   bool
+		want    []string
+		wantErr bool
+	}{
+		{
+			name:  "lines",
+			input: "https://example.com/a\n\n  https://example.com/b\n",
+			want:  []string{"https://example.com/a", "https://example.com/b"},
+		},
+		{
+			name:  "csv with url header",
+			input: "id,url\n1,https://example.com/a\n2,https://example.com/b\n",
+			isCSV: true,
+			want:  []string{"https://example.com/a", "https://example.com/b"},
+		},
+		{
+			name:  "csv with original_url header",
+			input: "Original_URL, note\nhttps://example.com/a, first\n",
+			isCSV: true,
+			want:  []string{"https://example.com/a"},
+		},
+		{
+			name:  "csv without header",
+			input: "https://example.com/a,first\nhttps://example.com/b\n",
+			isCSV: true,
+			want:  []string{"https://example.com/a", "https://example.com/b"},
+		},
+		{
+			name:  "csv with short and blank rows",
+			input: "id,url\n1\n2,\n3,https://example.com/c\n",
+			isCSV: true,
+			want:  []string{"https://example.com/c"},
+		},
+		{
+			name:    "malformed csv",
+			input:   "url\n\"https://example.com/a\n",
+			isCSV:   true,
+			wantErr: true,
+		},
+		{
+			name:    "stray quote",
+			input:   "https://example.com/a\"b\n",
+			isCSV:   true,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			urls, err := readURLs(strings.NewReader(tt.input), tt.isCSV)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, urls)
+		})
+	}
+}
+
+func TestShortenBatch(t *testing.T) {
+	srv := newFakeServer(t)
+	c := newClient(srv.URL)
+	originals := []string{"https://example.com/a", "https://example.com/b", "https://example.com/c"}
+
+	shorts, err := c.shortenBatch(context.Background(), originals)
+	require.NoError(t, err)
+	assert.Equal(t, []string{srv.URL + "/a", srv.URL + "/b", srv.URL + "/c"}, shorts,
+		"the reversed response is mapped back by correlation ID")
+}
+
+func TestShortenBatch_InvalidResponse(t *testing.T) {
+	tests := []struct {
+		name    string
+		resp    func(items []model.RequestURLItem) []model.ResponseURLItem
+		wantErr string
+	}{
+		{
+			name: "unknown correlation ID",
+			resp: func(items []model.RequestURLItem) []model.ResponseURLItem {
+				return []model.ResponseURLItem{{CorrelationID: strconv.Itoa(len(items)), ShortURL: "x"}}
+			},
+			wantErr: "unknown correlation_id",
+		},
+		{
+			name: "non-numeric correlation ID",
+			resp: func([]model.RequestURLItem) []model.ResponseURLItem {
+				return []model.ResponseURLItem{{CorrelationID: "a", ShortURL: "x"}}
+			},
+			wantErr: "unknown correlation_id",
+		},
+		{
+			name: "missing item",
+			resp: func(items []model.RequestURLItem) []model.ResponseURLItem {
+				return []model.ResponseURLItem{{CorrelationID: items[0].СorrelationID, ShortURL: "x"}}
+			},
+			wantErr: "no short URL for https://example.com/b",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newFakeServer(t)
+			srv.batchResponse = func(_ int, items []model.RequestURLItem) []model.ResponseURLItem {
+				return tt.resp(items)
+			}
+			_, err := newClient(srv.URL).shortenBatch(context.Background(), []string{"https://example.com/a", "https://example.com/b"})
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	srv := newFakeServer(t)
+	urls := []string{"https://example.com/a", "https://example.com/b", "https://example.com/c", "https://example.com/d", "https://example.com/e"}
+
+	var out, stderr bytes.Buffer
+	status := migrate(context.Background(), newClient(srv.URL), urls, 2, &out, &stderr)
+	assert.Equal(t, 0, status, stderr.String())
+	assert.Equal(t, 3, srv.batchCount())
+
+	var want strings.Builder
+	want.WriteString("original_url,short_url\n")
+	for _, u := range urls {
+		want.WriteString(u + "," + srv.URL + "/" + u[len(u)-1:] + "\n")
+	}
+	assert.Equal(t, want.String(), out.String())
+	assert.Equal(t, "shortened 5 of 5 URLs\n", stderr.String())
+}
+
+func TestMigrate_PartialFailure(t *testing.T) {
+	srv := newFakeServer(t)
+	urls := []string{"https://example.com/a", "example.com/b", "https://example.com/c", "https://example.com/d"}
+
+	var out, stderr bytes.Buffer
+	status := migrate(context.Background(), newClient(srv.URL), urls, 2, &out, &stderr)
+	assert.Equal(t, 1, status)
+	assert.Equal(t, 2, srv.batchCount(), "the rejected batch is not retried as a batch")
+	assert.Equal(t, "original_url,short_url\n"+
+		"https://example.com/a,"+srv.URL+"/a\n"+
+		"https://example.com/c,"+srv.URL+"/c\n"+
+		"https://example.com/d,"+srv.URL+"/d\n", out.String())
+	assert.Contains(t, stderr.String(), "example.com/b: server responded with 400 Bad Request")
+	assert.Contains(t, stderr.String(), "shortened 3 of 4 URLs")
+}
+
+func TestMigrate_Stopped(t *testing.T) {
+	srv := newFakeServer(t)
+	srv.batchResponse = func(n int, items []model.RequestURLItem) []model.ResponseURLItem {
+		if n > 1 {
+			return nil
+		}
+		resp := make([]model.ResponseURLItem, len(items))
+		for i, item := range items {
+			resp[i] = model.ResponseURLItem{CorrelationID: item.СorrelationID, ShortURL: srv.short(item.OriginalURL)}
+		}
+		return resp
+	}
+	urls := []string{"https://example.com/a", "https://example.com/b", "https://example.com/c"}
+
+	var out, stderr bytes.Buffer
+	status := migrate(context.Background(), newClient(srv.URL), urls, 2, &out, &stderr)
+	assert.Equal(t, 1, status)
+	assert.Equal(t, "original_url,short_url\n"+
+		"https://example.com/a,"+srv.URL+"/a\n"+
+		"https://example.com/b,"+srv.URL+"/b\n", out.String(), "the mapping of the finished batches is kept")
+	assert.Contains(t, stderr.String(), "stopped after 2 of 3 URLs")
+}
+
+func TestRun_File(t *testing.T) {
+	srv := newFakeServer(t)
+	dir := t.TempDir()
+	in := filepath.Join(dir, "links.csv")
+	out := filepath.Join(dir, "mapping.csv")
+	require.NoError(t, os.WriteFile(in, []byte("id,url\n1,https://example.com/a\n2,https://example.com/b\n"), 0o600))
+
+	var stdout, stderr bytes.Buffer
+	status := run([]string{"-server", srv.URL, "-file", in, "-out", out}, tempFile(t, ""), &stdout, &stderr)
+	require.Equal(t, 0, status, stderr.String())
+	assert.Empty(t, stdout.String())
+
+	mapping, err := os.ReadFile(out)
+	require.NoError(t, err)
+	assert.Equal(t, "original_url,short_url\n"+
+		"https://example.com/a,"+srv.URL+"/a\n"+
+		"https://example.com/b,"+srv.URL+"/b\n", string(mapping))
+
+	status = run([]string{"-server", srv.URL, "-file", filepath.Join(dir, "missing.txt")}, tempFile(t, ""), &stdout, &stderr)
+	assert.Equal(t, 1, status)
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"strings"
+	"time"
+
+	"github.com/Aleksey170999/go-shortener/internal/model"
+)
+
+// requestTimeout bounds a single request to the server.
+const requestTimeout = 30 * time.Second
+
+// client calls the API of a shortener server. Its cookie jar keeps the
+// user cookie the server issues, so that all links of a run belong to the
+// same user.
+type client struct {
+	server     string // Base URL of the server, without a trailing slash
+	httpClient *http.Client
+}
+
+// newClient returns a client for the server at the base URL server.
+func newClient(server string) *client {
+	jar, _ := cookiejar.New(nil)
+	return &client{
+		server:     strings.TrimSuffix(server, "/"),
+		httpClient: &http.Client{Timeout: requestTimeout, Jar: jar},
+	}
+}
+
+// shorten returns the short URL of original. A URL the server already
+// shortened is not an error; its existing short URL is returned.
+func (c *client) shorten(ctx context.Context, original string) (string, error) {
+	var resp model.ShortenJSONResponse
+	if err := c.post(ctx, "/api/shorten", model.ShortenJSONRequest{URL: original}, &resp); err != nil {
+		return "", err
+	}
+	return resp.Result, nil
+}
+
+// errStatus is returned by post for a response with an unexpected status code.
+type errStatus struct {
+	code int
+	msg  string
+}
+
+func (e *errStatus) Error() string { return e.msg }
+
+// post sends req as JSON to path and decodes the JSON response into resp.
+// Responses other than 201 Created and 409 Conflict are errors.
+func (c *client) post(ctx context.Context, path string, req, resp any) error {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.server+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpResp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return err
+	}
+	defer httpResp.Body.Close()
+	if httpResp.StatusCode != http.StatusCreated && httpResp.StatusCode != http.StatusConflict {
+		msg, _ := io.ReadAll(io.LimitReader(httpResp.Body, 512))
+		return &errStatus{
+			code: httpResp.StatusCode,
+			msg:  fmt.Sprintf("server responded with %s: %s", httpResp.Status, bytes.TrimSpace(msg)),
+		}
+	}
+	if err := json.NewDecoder(httpResp.Body).Decode(resp); err != nil {
+		return fmt.Errorf("invalid response: %w", err)
+	}
+	return nil
+}
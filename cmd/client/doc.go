@@ -1,17 +1,37 @@
 // Command client provides a simple command-line interface for the URL shortener service.
 // It allows users to interact with the shortener service by sending HTTP requests.
-// The client sends a POST request to the shortener service with a long URL and receives a shortened version.
+// The client sends the long URLs to the JSON API of the service and prints their shortened versions.
 //
-// Usage:
-//  1. Run the client
-//  2. Enter a long URL when prompted
-//  3. The client will display the server's response, which includes the shortened URL
+// Flags:
+//   - -server: Base URL of the service (default: http://localhost:8080)
+//   - -url: URL to shorten; without it the URLs are read from stdin, one per line
+//   - -file: File of URLs to shorten with the batch API, one per line or CSV if named *.csv
+//   - -out: File the mapping of -file is written to (default: stdout)
+//   - -batch-size: URLs per batch request (default: 500)
+//
+// Run in a terminal without -url, the client prompts for a single URL. Otherwise
+// it prints the short URL of every input URL on its own line, in input order, so
+// that it can be used in scripts; failures are reported on stderr, and the exit
+// status is 1 if any URL could not be shortened. All URLs of a run belong to the
+// same user.
+//
+// With -file the client migrates many links at once: it sends the URLs in batches
+// and writes a CSV mapping with the columns original_url and short_url. A CSV
+// input has the URLs in its "url" or "original_url" column if it has such a
+// header, and in its first column otherwise. A batch the server rejects because
+// of an invalid URL is retried URL by URL, and only the invalid URLs are left out
+// of the mapping.
 //
 // Example:
 //
-//	$ go run cmd/client/main.go
+//	$ go run ./cmd/client
 //	Введите длинный URL
 //	https://example.com/very/long/url
-//	Статус-код 201
 //	http://localhost:8080/abc123
+//
+//	$ echo https://example.com/very/long/url | client -server https://sho.rt
+//	https://sho.rt/abc123
+//
+//	$ client -server https://sho.rt -file links.csv -out mapping.csv
+//	shortened 2500 of 2500 URLs
 package main
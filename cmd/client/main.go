@@ -2,41 +2,143 @@ package main
 
 import (
 	"bufio"
+	"context"
+	"flag"
 	"fmt"
 	"io"
-	"net/http"
-	"net/url"
 	"os"
+	"os/signal"
 	"strings"
+	"syscall"
 )
 
-const defaultEndpoint = "http://localhost:8080/"
+const defaultServer = "http://localhost:8080"
 
 func main() {
-	data := url.Values{}
-	fmt.Println("Введите длинный URL")
-	reader := bufio.NewReader(os.Stdin)
-	long, err := reader.ReadString('\n')
-	if err != nil {
-		panic(err)
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// run runs the client with the command-line arguments args and returns the
+// exit status: 0 if every URL was shortened, 1 if any failed and 2 for
+// invalid arguments.
+func run(args []string, stdin *os.File, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("client", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	server := fs.String("server", defaultServer, "Базовый адрес сервера сокращения ссылок")
+	long := fs.String("url", "", "URL для сокращения; без него URL читаются из stdin, по одному в строке")
+	file := fs.String("file", "", "Файл с URL для пакетного сокращения: по одному в строке или CSV (*.csv)")
+	out := fs.String("out", "", "Файл для соответствия исходных и коротких URL в формате CSV (по умолчанию stdout)")
+	batchSize := fs.Int("batch-size", defaultBatchSize, "Количество URL в одном пакетном запросе")
+	if err := fs.Parse(args); err != nil {
+		return 2
 	}
-	long = strings.TrimSuffix(long, "\n")
-	data.Set("url", long)
-	client := &http.Client{}
-	request, err := http.NewRequest(http.MethodPost, defaultEndpoint, strings.NewReader(data.Encode()))
-	if err != nil {
-		panic(err)
+	if fs.NArg() > 0 {
+		fmt.Fprintf(stderr, "unexpected arguments %q\n", fs.Args())
+		return 2
+	}
+	if *file != "" && *long != "" {
+		fmt.Fprintln(stderr, "-file and -url are mutually exclusive")
+		return 2
+	}
+	if *out != "" && *file == "" {
+		fmt.Fprintln(stderr, "-out requires -file")
+		return 2
+	}
+	if *batchSize < 1 {
+		fmt.Fprintln(stderr, "-batch-size must be positive")
+		return 2
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	c := newClient(*server)
+
+	if *file != "" {
+		return runBatch(ctx, c, *file, *out, *batchSize, stdout, stderr)
+	}
+	if *long != "" {
+		return shortenAll(ctx, c, []string{*long}, stdout, stderr)
+	}
+	if interactive(stdin) {
+		fmt.Fprintln(stderr, "Введите длинный URL")
+		line, err := bufio.NewReader(stdin).ReadString('\n')
+		if err != nil && line == "" {
+			fmt.Fprintln(stderr, err)
+			return 1
+		}
+		return shortenAll(ctx, c, []string{strings.TrimSpace(line)}, stdout, stderr)
 	}
-	request.Header.Add("Content-Type", "application/x-www-form-urlencoded")
-	response, err := client.Do(request)
+
+	var urls []string
+	scanner := bufio.NewScanner(stdin)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			urls = append(urls, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		fmt.Fprintf(stderr, "failed to read stdin: %v\n", err)
+		return 1
+	}
+	return shortenAll(ctx, c, urls, stdout, stderr)
+}
+
+// runBatch shortens the URLs of the file path with the batch API and
+// writes their mapping to the file out, or to stdout if out is empty.
+func runBatch(ctx context.Context, c *client, path, out string, batchSize int, stdout, stderr io.Writer) (status int) {
+	f, err := os.Open(path)
 	if err != nil {
-		panic(err)
+		fmt.Fprintln(stderr, err)
+		return 1
 	}
-	fmt.Println("Статус-код ", response.Status)
-	defer response.Body.Close()
-	body, err := io.ReadAll(response.Body)
+	urls, err := readURLs(f, isCSVFile(path))
+	f.Close()
 	if err != nil {
-		panic(err)
+		fmt.Fprintf(stderr, "failed to read %s: %v\n", path, err)
+		return 1
+	}
+
+	w := stdout
+	if out != "" {
+		f, err := os.Create(out)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return 1
+		}
+		defer func() {
+			if err := f.Close(); err != nil {
+				fmt.Fprintf(stderr, "failed to write mapping: %v\n", err)
+				status = 1
+			}
+		}()
+		w = f
+	}
+	return migrate(ctx, c, urls, batchSize, w, stderr)
+}
+
+// interactive reports whether f is a terminal, where the user is prompted
+// for a URL, rather than a pipe or a file.
+func interactive(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// shortenAll shortens urls one by one and writes their short URLs to
+// stdout, one per line in input order. A URL that fails is reported on
+// stderr and leaves its line out; the others are still shortened.
+func shortenAll(ctx context.Context, c *client, urls []string, stdout, stderr io.Writer) int {
+	status := 0
+	for _, long := range urls {
+		short, err := c.shorten(ctx, long)
+		if err != nil {
+			fmt.Fprintf(stderr, "%s: %v\n", long, err)
+			status = 1
+			if ctx.Err() != nil {
+				return status
+			}
+			continue
+		}
+		fmt.Fprintln(stdout, short)
 	}
-	fmt.Println(string(body))
+	return status
 }
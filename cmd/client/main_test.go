@@ -0,0 +1,231 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/Aleksey170999/go-shortener/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeServer serves the shorten APIs of the shortener. The short code of a
+// URL is its last path element; URLs not starting with https:// are
+// rejected with 400 Bad Request, as is a batch containing any of them.
+type fakeServer struct {
+	*httptest.Server
+
+	mu        sync.Mutex
+	anonymous int // Requests without the user cookie
+	batches   int // Batch requests received
+	// batchResponse, if set, replaces the response to the valid batch
+	// request with the number n, counting from 1.
+	batchResponse func(n int, items []model.RequestURLItem) []model.ResponseURLItem
+}
+
+func newFakeServer(t *testing.T) *fakeServer {
+	f := &fakeServer{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/shorten", func(w http.ResponseWriter, r *http.Request) {
+		f.identify(w, r)
+		var req model.ShortenJSONRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || !valid(req.URL) {
+			http.Error(w, "invalid url", http.StatusBadRequest)
+			return
+		}
+		status := http.StatusCreated
+		if strings.Contains(req.URL, "existing") {
+			status = http.StatusConflict
+		}
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(model.ShortenJSONResponse{Result: f.short(req.URL)})
+	})
+	mux.HandleFunc("POST /api/shorten/batch", func(w http.ResponseWriter, r *http.Request) {
+		f.identify(w, r)
+		var items []model.RequestURLItem
+		if err := json.NewDecoder(r.Body).Decode(&items); err != nil {
+			http.Error(w, "invalid json", http.StatusBadRequest)
+			return
+		}
+		f.mu.Lock()
+		f.batches++
+		n := f.batches
+		f.mu.Unlock()
+		for _, item := range items {
+			if !valid(item.OriginalURL) {
+				http.Error(w, "invalid url", http.StatusBadRequest)
+				return
+			}
+		}
+		var resp []model.ResponseURLItem
+		if f.batchResponse != nil {
+			resp = f.batchResponse(n, items)
+		} else {
+			// Respond in reverse order; the client maps by correlation ID.
+			for i := len(items) - 1; i >= 0; i-- {
+				resp = append(resp, model.ResponseURLItem{CorrelationID: items[i].СorrelationID, ShortURL: f.short(items[i].OriginalURL)})
+			}
+		}
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(resp)
+	})
+	f.Server = httptest.NewServer(mux)
+	t.Cleanup(f.Close)
+	return f
+}
+
+// identify counts requests without the user cookie and issues one to them.
+func (f *fakeServer) identify(w http.ResponseWriter, r *http.Request) {
+	if _, err := r.Cookie("user_id"); err == nil {
+		return
+	}
+	f.mu.Lock()
+	f.anonymous++
+	f.mu.Unlock()
+	http.SetCookie(w, &http.Cookie{Name: "user_id", Value: "user1", Path: "/"})
+}
+
+// batchCount returns the number of batch requests received.
+func (f *fakeServer) batchCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.batches
+}
+
+func (f *fakeServer) short(original string) string {
+	return f.URL + "/" + original[strings.LastIndex(original, "/")+1:]
+}
+
+func valid(original string) bool {
+	return strings.HasPrefix(original, "https://")
+}
+
+// tempFile returns a file with content, opened for reading.
+func tempFile(t *testing.T, content string) *os.File {
+	path := filepath.Join(t.TempDir(), "input")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	t.Cleanup(func() { f.Close() })
+	return f
+}
+
+func TestRun(t *testing.T) {
+	srv := newFakeServer(t)
+	tests := []struct {
+		name       string
+		args       []string
+		stdin      string
+		wantStatus int
+		wantStdout string
+		wantStderr string
+	}{
+		{
+			name:       "url flag",
+			args:       []string{"-url", "https://example.com/a"},
+			wantStdout: srv.URL + "/a\n",
+		},
+		{
+			name:       "server with trailing slash",
+			args:       []string{"-server", srv.URL + "/", "-url", "https://example.com/a"},
+			wantStdout: srv.URL + "/a\n",
+		},
+		{
+			name:       "existing url",
+			args:       []string{"-url", "https://example.com/existing"},
+			wantStdout: srv.URL + "/existing\n",
+		},
+		{
+			name:       "stdin",
+			stdin:      "https://example.com/a\n\n  https://example.com/b  \nhttps://example.com/c",
+			wantStdout: srv.URL + "/a\n" + srv.URL + "/b\n" + srv.URL + "/c\n",
+		},
+		{
+			name:       "empty stdin",
+			stdin:      "",
+			wantStdout: "",
+		},
+		{
+			name:       "invalid url on stdin",
+			stdin:      "https://example.com/a\nexample.com/b\nhttps://example.com/c\n",
+			wantStatus: 1,
+			wantStdout: srv.URL + "/a\n" + srv.URL + "/c\n",
+			wantStderr: "example.com/b: server responded with 400 Bad Request: invalid url",
+		},
+		{
+			name:       "invalid url flag",
+			args:       []string{"-url", "example.com"},
+			wantStatus: 1,
+			wantStderr: "400 Bad Request",
+		},
+		{
+			name:       "unexpected argument",
+			args:       []string{"https://example.com/a"},
+			wantStatus: 2,
+			wantStderr: "unexpected arguments",
+		},
+		{
+			name:       "unknown flag",
+			args:       []string{"-verbose"},
+			wantStatus: 2,
+			wantStderr: "flag provided but not defined",
+		},
+		{
+			name:       "file and url",
+			args:       []string{"-file", "urls.txt", "-url", "https://example.com/a"},
+			wantStatus: 2,
+			wantStderr: "-file and -url are mutually exclusive",
+		},
+		{
+			name:       "out without file",
+			args:       []string{"-out", "mapping.csv"},
+			wantStatus: 2,
+			wantStderr: "-out requires -file",
+		},
+		{
+			name:       "non-positive batch size",
+			args:       []string{"-file", "urls.txt", "-batch-size", "0"},
+			wantStatus: 2,
+			wantStderr: "-batch-size must be positive",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := append([]string{"-server", srv.URL}, tt.args...)
+			var stdout, stderr bytes.Buffer
+			status := run(args, tempFile(t, tt.stdin), &stdout, &stderr)
+			assert.Equal(t, tt.wantStatus, status, stderr.String())
+			assert.Equal(t, tt.wantStdout, stdout.String())
+			if tt.wantStderr != "" {
+				assert.Contains(t, stderr.String(), tt.wantStderr)
+			}
+		})
+	}
+}
+
+func TestRun_SameUser(t *testing.T) {
+	srv := newFakeServer(t)
+	var stdout, stderr bytes.Buffer
+	stdin := tempFile(t, "https://example.com/a\nhttps://example.com/b\nhttps://example.com/c\n")
+	require.Equal(t, 0, run([]string{"-server", srv.URL}, stdin, &stdout, &stderr), stderr.String())
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	assert.Equal(t, 1, srv.anonymous, "later requests carry the cookie of the first")
+}
+
+func TestRun_ServerUnavailable(t *testing.T) {
+	srv := newFakeServer(t)
+	srv.Close()
+	var stdout, stderr bytes.Buffer
+	status := run([]string{"-server", srv.URL, "-url", "https://example.com/a"}, tempFile(t, ""), &stdout, &stderr)
+	assert.Equal(t, 1, status)
+	assert.Empty(t, stdout.String())
+	assert.Contains(t, stderr.String(), "https://example.com/a:")
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/Aleksey170999/go-shortener/internal/config"
+	"github.com/Aleksey170999/go-shortener/internal/storage"
+)
+
+// compact removes duplicate and expired entries from the storage file, and
+// the entries of links deleted longer than DELETED_RETENTION ago. Links
+// purged by a server's cleanup are only known to that server, which removes
+// them with COMPACT_INTERVAL; compact fails while a server uses the file.
+// With -cleanup-dry-run the file is left alone and only the counts are
+// printed.
+func compact(cfg *config.Config, args []string) int {
+	if len(args) > 0 {
+		fmt.Fprintf(os.Stderr, "compact: unexpected arguments %q\n", args)
+		return 2
+	}
+	if cfg.DatabaseDSN != "" || cfg.StorageFilePath == "" {
+		fmt.Fprintln(os.Stderr, "compact: FILE_STORAGE_PATH is not set or DATABASE_DSN is used instead")
+		return 2
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	lock, err := storage.LockFile(cfg.StorageFilePath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "compact: %v\n", err)
+		return 1
+	}
+	defer lock.Close()
+
+	st, err := newStorage(cfg, cfg.StorageFilePath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "compact: %v\n", err)
+		return 1
+	}
+	now := time.Now()
+	stats, err := st.Compact(ctx, storage.CompactOptions{
+		Now:           now,
+		DeletedBefore: now.Add(-cfg.DeletedRetention),
+		DryRun:        cfg.CleanupDryRun,
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "compact: %v\n", err)
+		return 1
+	}
+	verb := "removed"
+	if cfg.CleanupDryRun {
+		verb = "would remove"
+	}
+	fmt.Printf("%s %d duplicate, %d expired and %d deleted URLs, %d kept\n", verb, stats.Duplicates, stats.Expired, stats.Removed, stats.Kept)
+	return 0
+}
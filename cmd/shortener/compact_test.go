@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Aleksey170999/go-shortener/internal/config"
+	"github.com/Aleksey170999/go-shortener/internal/model"
+	"github.com/Aleksey170999/go-shortener/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompact(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "storage.json")
+	expired := time.Now().Add(-time.Hour)
+	deletedAt := time.Now()
+	longDeleted := time.Now().Add(-48 * time.Hour)
+	require.NoError(t, storage.NewStorage(path).WriteAll(context.Background(), []model.URL{
+		{ID: "1", Short: "aaa", Original: "https://example.com/1"},
+		{ID: "2", Short: "bbb", Original: "https://example.com/2", ExpiresAt: &expired},
+		{ID: "1", Short: "aaa", Original: "https://example.com/1", IsDeleted: true, DeletedAt: &deletedAt},
+		{ID: "3", Short: "ccc", Original: "https://example.com/3", IsDeleted: true, DeletedAt: &longDeleted},
+	}))
+	before, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	cfg := config.NewConfig(config.WithStorageFile(path))
+	cfg.DeletedRetention = 24 * time.Hour
+	cfg.CleanupDryRun = true
+	require.Equal(t, 0, compact(cfg, nil))
+	after, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, before, after, "a dry run leaves the file alone")
+
+	cfg.CleanupDryRun = false
+	require.Equal(t, 0, compact(cfg, nil))
+	urls := loadURLs(t, path)
+	require.Len(t, urls, 1)
+	assert.Equal(t, "aaa", urls[0].Short)
+	assert.True(t, urls[0].IsDeleted, "the last record of a URL is kept, the long-deleted URL is removed")
+}
+
+func TestCompact_Arguments(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "storage.json")
+	assert.Equal(t, 2, compact(config.NewConfig(config.WithStorageFile(path)), []string{"storage.json"}))
+	assert.Equal(t, 2, compact(config.NewConfig(config.WithStorageFile("")), nil))
+	assert.Equal(t, 2, compact(config.NewConfig(config.WithStorageFile(path), config.WithDatabaseDSN("postgres://localhost/shortener")), nil))
+}
@@ -11,19 +11,62 @@
 //   - Audit logging to file or remote service
 //
 // Configuration:
-// The service can be configured using environment variables or command-line flags.
+// The service can be configured using environment variables, command-line flags
+// or a JSON or YAML configuration file given with -c/-config or CONFIG; environment
+// variables override flags, and flags override the file.
 // Key configuration options include:
 //   - SERVER_ADDRESS: Server address (default: localhost:8080)
 //   - BASE_URL: Base URL for shortened links (default: http://localhost:8080)
-//   - FILE_STORAGE_PATH: Path to file storage (optional)
+//   - FILE_STORAGE_PATH: Path to file storage, gzip-compressed if it ends with ".gz" (optional)
 //   - DATABASE_DSN: PostgreSQL connection string (optional)
+//   - SNAPSHOT_URL: S3-compatible object snapshots are written to and restored from instead of the storage file (optional)
+//   - STORAGE_KEY, STORAGE_KEY_FILE: Base64-encoded AES-256 key the storage file and snapshots are encrypted with (optional)
 //   - ENABLE_HTTPS: Enable HTTPS (default: false)
+//   - TLS_CERT_FILE, TLS_KEY_FILE: PEM certificate and key served when HTTPS is enabled
+//   - AUTOCERT_DOMAINS: Domains to obtain Let's Encrypt certificates for, enables HTTPS (optional)
+//   - CONFIG: Path to the JSON or YAML configuration file (optional)
+//   - ENV_FILE: Dotenv file with per-machine variables (default: .env, optional)
 //
 // Example usage:
 //
 //	$ go run cmd/shortener/main.go
 //	$ SERVER_ADDRESS=:8080 BASE_URL=http://localhost:8080 go run cmd/shortener/main.go
 //
+// To see which value each setting ends up with after env, flags and the
+// config file are merged, print the effective configuration (secrets
+// redacted) instead of starting the server:
+//
+//	$ go run cmd/shortener/main.go -c config.yaml --print-config
+//
+// Commands:
+// The first argument selects what to do; without one, or when the arguments
+// start with a flag, the server is run. Every command takes the flags,
+// environment and config file of the server, given after the command name.
+//   - serve: Run the server
+//   - migrate [up|down|status|version|redo]: Manage the database schema, "up" by default
+//   - export [file]: Write the stored URLs as JSON to file or stdout
+//   - import [file]: Store the URLs of a JSON file or stdin
+//   - compact: Remove duplicate, expired and long-deleted URLs from the storage file
+//   - check-config: Validate the configuration, and print it with -print-config
+//
+// export writes the format of the storage file, so that its output can be
+// given to import or used as FILE_STORAGE_PATH, e.g.
+//
+//	$ shortener export -d postgres://localhost/shortener urls.json
+//	$ shortener import -f /data/storage.json urls.json
+//
+// Together they migrate between the storage file and the database in either
+// direction, keeping the creation time, expiration and deletion of every
+// URL. Stop the servers using the source first. From the file to the
+// database:
+//
+//	$ shortener migrate -d postgres://localhost/shortener
+//	$ shortener import -d postgres://localhost/shortener /data/storage.json
+//
+// and from the database back to the file:
+//
+//	$ shortener export -d postgres://localhost/shortener /data/storage.json
+//
 // API Endpoints:
 //   - POST / - Create a new short URL
 //   - GET /{id} - Redirect to the original URL
@@ -1,64 +1,93 @@
 package main
 
 import (
-	"net/http"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
 
-	"github.com/Aleksey170999/go-shortener/internal/audit"
 	"github.com/Aleksey170999/go-shortener/internal/config"
-	"github.com/Aleksey170999/go-shortener/internal/handler"
-	"github.com/Aleksey170999/go-shortener/internal/middlewares"
-	"github.com/Aleksey170999/go-shortener/internal/repository"
-	"github.com/Aleksey170999/go-shortener/internal/service"
-	"github.com/Aleksey170999/go-shortener/internal/storage"
-	"github.com/go-chi/chi/v5"
-	"github.com/go-chi/chi/v5/middleware"
+	"github.com/Aleksey170999/go-shortener/internal/model"
 )
 
-func main() {
-	cfg := config.NewConfig()
-
-	auditManager := audit.NewAuditManager()
+// Build metadata, set with
+//
+//	go build -ldflags "-X main.buildVersion=v1.0.0 -X 'main.buildDate=$(date)' -X main.buildCommit=$(git rev-parse --short HEAD)"
+//
+// Unset values are reported as "N/A".
+var (
+	buildVersion string
+	buildDate    string
+	buildCommit  string
+)
 
-	if cfg.AuditFile != "" {
-		fileAudit := audit.NewFileAudit(cfg.AuditFile)
-		auditManager.RegisterWriter(fileAudit)
+// buildInfo returns the build metadata of the binary.
+func buildInfo() model.BuildInfo {
+	orNA := func(s string) string {
+		if s == "" {
+			return "N/A"
+		}
+		return s
 	}
+	return model.BuildInfo{Version: orNA(buildVersion), Date: orNA(buildDate), Commit: orNA(buildCommit)}
+}
 
-	if cfg.AuditURL != "" {
-		remoteAudit := audit.NewRemoteAudit(cfg.AuditURL)
-		auditManager.RegisterWriter(remoteAudit)
-	}
+// command is a subcommand of shortener. All subcommands take the flags of
+// the server configuration, so that they find the database and the storage
+// file the same way the server does.
+type command struct {
+	name string
+	args string // Positional arguments, shown in the usage
+	help string
+	run  func(cfg *config.Config, args []string) int // Returns the exit status
+}
+
+// commands lists the subcommands in the order the usage shows them.
+var commands = []command{
+	{name: "serve", help: "Run the server; the default when no command is given", run: serve},
+	{name: "migrate", args: "[up|down|status|version|redo] [version]", help: "Manage the database schema, applying pending migrations by default", run: migrate},
+	{name: "export", args: "[file]", help: "Write the stored URLs as JSON in the storage file format to file or stdout", run: export},
+	{name: "import", args: "[file]", help: "Store the URLs of a JSON file in the storage file format, read from file or stdin", run: importURLs},
+	{name: "compact", help: "Remove duplicate, expired and long-deleted URLs from the storage file", run: compact},
+	{name: "check-config", help: "Validate the configuration, and print it with -print-config", run: checkConfig},
+}
 
-	storage := storage.NewStorage(cfg.StorageFilePath)
-	var repo repository.URLRepository
-	if cfg.DatabaseDSN != "" {
-		repo = repository.NewDataBaseURLRepository(cfg)
-	} else {
-		repo = repository.NewMemoryURLRepository()
-		storage.LoadFromStorage(repo)
+// usage describes the subcommands on w.
+func usage(w io.Writer) {
+	fmt.Fprintf(w, "Usage: shortener [command] [flags] [args]\n\nCommands:\n")
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %-14s %s\n", cmd.name, cmd.help)
 	}
+	fmt.Fprintf(w, "\nRun \"shortener <command> -h\" for the flags.\n")
+}
 
-	urlService := service.NewURLService(repo)
-	logger := cfg.Logger
-	h := handler.NewHandler(urlService, cfg, storage, auditManager)
-	r := chi.NewRouter()
-	r.Use(middlewares.WithLogging(&logger))
-	r.Use(middlewares.GzipMiddleware)
-	r.Use(middleware.StripSlashes)
-	r.Use(middlewares.AuthMiddleware)
+// main runs the subcommand named by the first argument. Without one, i.e.
+// when the arguments are empty or start with a flag, the server is run, as
+// before there were subcommands.
+func main() {
+	name, args := "serve", os.Args[1:]
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		name, args = args[0], args[1:]
+	}
+	if name == "help" {
+		usage(os.Stdout)
+		return
+	}
+	i := slices.IndexFunc(commands, func(cmd command) bool { return cmd.name == name })
+	if i < 0 {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n", name)
+		usage(os.Stderr)
+		os.Exit(2)
+	}
+	cmd := commands[i]
 
-	r.Route("/", func(r chi.Router) {
-		r.Get("/ping", h.PingDBHandler)
-		r.Post("/api/shorten/batch", h.ShortenJSONURLBatchHandler)
-		r.Post("/api/shorten", h.ShortenJSONURLHandler)
-		r.Post("/", h.ShortenURLHandler)
-		r.Get("/{id}", h.RedirectHandler)
-		r.Get("/api/user/urls", h.GetUserURLsHandler)
-		r.Delete("/api/user/urls", h.BatchDeleteUserURLsHandler)
-	})
-	logger.Sugar().Infoln(
-		"msg", "Server starting",
-		"url", cfg.RunAddr,
-	)
-	http.ListenAndServe(cfg.RunAddr, r)
+	fs := flag.NewFlagSet("shortener "+cmd.name, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "%s\n\n%s.\n\nFlags:\n", strings.TrimSpace("Usage: "+fs.Name()+" [flags] "+cmd.args), cmd.help)
+		fs.PrintDefaults()
+	}
+	cfg := config.ParseArgs(fs, args)
+	os.Exit(cmd.run(cfg, fs.Args()))
 }
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/Aleksey170999/go-shortener/internal/config"
+	"github.com/Aleksey170999/go-shortener/internal/config/db"
+)
+
+// migrate runs a goose command, "up" by default, against DATABASE_DSN. The
+// server applies pending migrations when it starts; migrate is for applying
+// them ahead of a deploy, inspecting the schema version and rolling back.
+func migrate(cfg *config.Config, args []string) int {
+	if cfg.DatabaseDSN == "" {
+		fmt.Fprintln(os.Stderr, "migrate: DATABASE_DSN is not set")
+		return 2
+	}
+	command := "up"
+	if len(args) > 0 {
+		command, args = args[0], args[1:]
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	conn, err := sql.Open("postgres", cfg.DatabaseDSN)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "migrate: failed to open database: %v\n", err)
+		return 1
+	}
+	defer conn.Close()
+
+	if err := db.Migrate(ctx, conn, command, args...); err != nil {
+		fmt.Fprintf(os.Stderr, "migrate: %v\n", err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,17 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/Aleksey170999/go-shortener/internal/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMigrate_Arguments(t *testing.T) {
+	assert.Equal(t, 2, migrate(config.NewConfig(), nil), "DATABASE_DSN is required")
+}
+
+func TestMigrate_DatabaseUnavailable(t *testing.T) {
+	cfg := config.NewConfig(config.WithDatabaseDSN("postgres://user@127.0.0.1:1/shortener?sslmode=disable&connect_timeout=1"))
+	assert.Equal(t, 1, migrate(cfg, []string{"status"}))
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/Aleksey170999/go-shortener/internal/app"
+	"github.com/Aleksey170999/go-shortener/internal/config"
+	"github.com/Aleksey170999/go-shortener/internal/logger"
+	"go.uber.org/zap"
+)
+
+// serve runs the server until SIGINT or SIGTERM. With -print-config it
+// prints the effective configuration instead.
+func serve(cfg *config.Config, args []string) int {
+	if len(args) > 0 {
+		fmt.Fprintf(os.Stderr, "serve: unexpected arguments %q\n", args)
+		return 2
+	}
+	if cfg.PrintConfig {
+		if err := cfg.WriteJSON(os.Stdout); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to print configuration: %v\n", err)
+			return 1
+		}
+		return 0
+	}
+	build := buildInfo()
+	fmt.Printf("Build version: %s\nBuild date: %s\nBuild commit: %s\n", build.Version, build.Date, build.Commit)
+
+	// The logger is built from the configuration, so problems with it are
+	// reported on stderr.
+	if err := cfg.Validate(); err != nil {
+		fmt.Fprintf(os.Stderr, "invalid configuration:\n%v\n", err)
+		return 2
+	}
+	// Validate has checked the level. It is shared with the logger, so that
+	// changing it through /api/internal/log-level takes effect immediately.
+	level, _ := zap.ParseAtomicLevel(cfg.LogLevel)
+	logger, err := logger.New(level, cfg.LogOutput...)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to open log output: %v\n", err)
+		return 1
+	}
+	defer logger.Sync()
+
+	a, err := app.New(cfg, app.WithLogger(logger, level), app.WithBuildInfo(build))
+	if err != nil {
+		logger.Fatal("failed to start", zap.Error(err))
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		// Restore the default signal handling, so that a second SIGINT or
+		// SIGTERM kills the process instead of waiting for the grace period.
+		stop()
+	}()
+
+	if maintenanceSignal != nil {
+		toggle := make(chan os.Signal, 1)
+		signal.Notify(toggle, maintenanceSignal)
+		go func() {
+			for range toggle {
+				logger.Sugar().Infow("maintenance mode toggled", "enabled", a.ToggleMaintenance())
+			}
+		}()
+	}
+
+	if cfg.BlocklistFile != "" {
+		reloadSignal := make(chan os.Signal, 1)
+		signal.Notify(reloadSignal, syscall.SIGHUP)
+		go func() {
+			for range reloadSignal {
+				list, err := a.ReloadBlocklist()
+				if err != nil {
+					logger.Sugar().Errorw("blocklist reload failed", "error", err)
+					continue
+				}
+				logger.Sugar().Infow("blocklist reloaded", "ips", len(list.IPs), "users", len(list.Users))
+			}
+		}()
+	}
+
+	if err := a.Run(ctx); err != nil {
+		logger.Fatal("server failed", zap.Error(err))
+	}
+	logger.Sugar().Infow("Server stopped")
+	return 0
+}
+
+// checkConfig validates the configuration without starting anything, e.g.
+// before a deploy. With -print-config the effective configuration is
+// printed as well.
+func checkConfig(cfg *config.Config, args []string) int {
+	if len(args) > 0 {
+		fmt.Fprintf(os.Stderr, "check-config: unexpected arguments %q\n", args)
+		return 2
+	}
+	if err := cfg.Validate(); err != nil {
+		fmt.Fprintf(os.Stderr, "invalid configuration:\n%v\n", err)
+		return 2
+	}
+	if cfg.PrintConfig {
+		if err := cfg.WriteJSON(os.Stdout); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to print configuration: %v\n", err)
+			return 1
+		}
+		return 0
+	}
+	fmt.Println("configuration is valid")
+	return 0
+}
@@ -0,0 +1,9 @@
+//go:build !unix
+
+package main
+
+import "os"
+
+// maintenanceSignal is nil: the platform has no SIGUSR2, so maintenance
+// mode is only toggled by the admin endpoint.
+var maintenanceSignal os.Signal
@@ -0,0 +1,280 @@
+package main
+
+import (
+	"context"
+	"crypto/cipher"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"syscall"
+
+	"github.com/Aleksey170999/go-shortener/internal/config"
+	"github.com/Aleksey170999/go-shortener/internal/model"
+	"github.com/Aleksey170999/go-shortener/internal/repository"
+	"github.com/Aleksey170999/go-shortener/internal/storage"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// openRepository opens the repository the server would use: the database
+// if DATABASE_DSN is set, the in-memory one restored from the snapshot
+// object if SNAPSHOT_URL is, and the in-memory one loaded from the storage
+// file otherwise, which persists new URLs to the file. closeRepo releases
+// the repository; for the snapshot object it uploads a new snapshot if
+// URLs were stored.
+func openRepository(ctx context.Context, cfg *config.Config) (repo repository.URLRepository, closeRepo func() error, err error) {
+	if cfg.DatabaseDSN != "" {
+		dbRepo, err := repository.NewDataBaseURLRepository(cfg)
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := dbRepo.DB.PingContext(ctx); err != nil {
+			dbRepo.Close()
+			return nil, nil, fmt.Errorf("failed to connect to database: %w", err)
+		}
+		return dbRepo, dbRepo.Close, nil
+	}
+	if cfg.SnapshotURL != "" {
+		key, err := storage.LoadKey(cfg.StorageKey, cfg.StorageKeyFile)
+		if err != nil {
+			return nil, nil, err
+		}
+		object, err := storage.NewObjectStorage(storage.ObjectConfig{
+			URL:       cfg.SnapshotURL,
+			Region:    cfg.SnapshotRegion,
+			AccessKey: cfg.SnapshotAccessKey,
+			SecretKey: cfg.SnapshotSecretKey,
+			Key:       key,
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+		memRepo := repository.NewMemoryURLRepository()
+		if err := object.LoadFromStorage(ctx, memRepo); err != nil {
+			return nil, nil, fmt.Errorf("failed to restore snapshot: %w", err)
+		}
+		snap := storage.NewSnapshotter(object, memRepo, 0, 0, zap.NewNop())
+		return snap.Track(memRepo), func() error { return snap.Snapshot(ctx) }, nil
+	}
+	if cfg.StorageFilePath == "" {
+		return nil, nil, errors.New("neither DATABASE_DSN nor FILE_STORAGE_PATH is set")
+	}
+	memRepo := repository.NewMemoryURLRepository()
+	st, err := newStorage(cfg, cfg.StorageFilePath)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := st.LoadFromStorage(ctx, memRepo); err != nil {
+		return nil, nil, fmt.Errorf("failed to load storage file: %w", err)
+	}
+	return storage.Persist(memRepo, st), func() error { return nil }, nil
+}
+
+// newStorage returns the storage file at path, encrypted with the key of
+// STORAGE_KEY or STORAGE_KEY_FILE if one is configured.
+func newStorage(cfg *config.Config, path string) (*storage.Storage, error) {
+	st := storage.NewStorage(path)
+	key, err := storage.LoadKey(cfg.StorageKey, cfg.StorageKeyFile)
+	if err != nil || key == nil {
+		return st, err
+	}
+	return st, st.SetKey(key)
+}
+
+// export writes the URLs, soft-deleted ones included with their deletion,
+// to the file named by the argument, or to stdout without one or with "-".
+// The output is sorted by short code and has the format of the storage
+// file, so that it can be used as one or given to import. The file is
+// replaced atomically, like the storage file, compressed if its name ends
+// with ".gz" and encrypted if a storage key is configured; stdout is not.
+func export(cfg *config.Config, args []string) int {
+	if len(args) > 1 {
+		fmt.Fprintf(os.Stderr, "export: unexpected arguments %q\n", args[1:])
+		return 2
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	repo, closeRepo, err := openRepository(ctx, cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "export: %v\n", err)
+		return 1
+	}
+	defer closeRepo()
+
+	lister, ok := repo.(repository.Lister)
+	if !ok {
+		fmt.Fprintln(os.Stderr, "export: the repository cannot list its URLs")
+		return 1
+	}
+	urls, err := lister.List(ctx)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "export: %v\n", err)
+		return 1
+	}
+	slices.SortFunc(urls, func(a, b model.URL) int { return strings.Compare(a.Short, b.Short) })
+	if len(args) == 0 || args[0] == "-" {
+		var data []byte
+		if data, err = storage.Encode(urls, false); err == nil {
+			_, err = os.Stdout.Write(append(data, '\n'))
+		}
+	} else {
+		var st *storage.Storage
+		if st, err = newStorage(cfg, args[0]); err == nil {
+			err = st.WriteAll(ctx, urls)
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "export: %v\n", err)
+		return 1
+	}
+	fmt.Fprintf(os.Stderr, "exported %d URLs\n", len(urls))
+	return 0
+}
+
+// importURLs stores the URLs of the file named by the argument, or of stdin
+// without one or with "-", in the repository, e.g. to restore an export or
+// to seed a new instance. URLs already stored under the same ID are skipped,
+// and URLs whose short code is taken by another one are reported as
+// conflicts; neither makes the import fail. Deleted URLs are imported as
+// deleted. Entries without a uuid get a new one. The input may be
+// gzip-compressed, or encrypted with the configured storage key. The
+// storage file cannot be imported into while a server uses it; URLs
+// imported into the snapshot object while a server runs are overwritten by
+// its next snapshot.
+func importURLs(cfg *config.Config, args []string) (status int) {
+	if len(args) > 1 {
+		fmt.Fprintf(os.Stderr, "import: unexpected arguments %q\n", args[1:])
+		return 2
+	}
+	var data []byte
+	var err error
+	if len(args) == 0 || args[0] == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(args[0])
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "import: %v\n", err)
+		return 1
+	}
+	key, err := storage.LoadKey(cfg.StorageKey, cfg.StorageKeyFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "import: %v\n", err)
+		return 1
+	}
+	var aead cipher.AEAD
+	if key != nil {
+		if aead, err = storage.NewCipher(key); err != nil {
+			fmt.Fprintf(os.Stderr, "import: %v\n", err)
+			return 1
+		}
+	}
+	if data, err = storage.Decrypt(data, aead); err != nil {
+		fmt.Fprintf(os.Stderr, "import: %v\n", err)
+		return 1
+	}
+	urls, err := storage.Decode(data)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "import: invalid input: %v\n", err)
+		return 1
+	}
+	for i, url := range urls {
+		if url.Short == "" || url.Original == "" {
+			fmt.Fprintf(os.Stderr, "import: entry %d: short_url and original_url are required\n", i)
+			return 1
+		}
+		if url.ID == "" {
+			urls[i].ID = uuid.NewString()
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if cfg.DatabaseDSN == "" && cfg.SnapshotURL == "" && cfg.StorageFilePath != "" {
+		// A running server would overwrite the imported URLs.
+		lock, err := storage.LockFile(cfg.StorageFilePath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "import: %v\n", err)
+			return 1
+		}
+		defer lock.Close()
+	}
+	repo, closeRepo, err := openRepository(ctx, cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "import: %v\n", err)
+		return 1
+	}
+	defer func() {
+		if err := closeRepo(); err != nil && status == 0 {
+			fmt.Fprintf(os.Stderr, "import: %v\n", err)
+			status = 1
+		}
+	}()
+
+	// Of several records of a URL, as in a storage file, the last one is
+	// imported.
+	latest := make([]model.URL, 0, len(urls))
+	index := make(map[string]int, len(urls))
+	var existing, conflicts int
+	for _, url := range urls {
+		i, seen := index[url.Short]
+		switch {
+		case !seen:
+			index[url.Short] = len(latest)
+			latest = append(latest, url)
+		case latest[i].ID == url.ID:
+			latest[i] = url
+		default:
+			conflicts++
+			fmt.Fprintf(os.Stderr, "import: short code %s is used by several URLs\n", url.Short)
+		}
+	}
+
+	var fresh []*model.URL
+	for i := range latest {
+		url := &latest[i]
+		found, err := repo.GetByShortURL(ctx, url.Short)
+		switch {
+		case err == nil && found.ID == url.ID:
+			existing++
+		case err == nil:
+			conflicts++
+			fmt.Fprintf(os.Stderr, "import: short code %s is taken by another URL\n", url.Short)
+		case errors.Is(err, repository.ErrNotFound):
+			fresh = append(fresh, url)
+		default:
+			fmt.Fprintf(os.Stderr, "import: %v\n", err)
+			return 1
+		}
+	}
+
+	save := repo.SaveBatch
+	if importer, ok := repo.(repository.Importer); ok {
+		save = importer.Import
+	}
+	errs, err := save(ctx, fresh)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "import: %v\n", err)
+		return 1
+	}
+	var saved int
+	for i, err := range errs {
+		switch {
+		case err == nil:
+			saved++
+		case errors.Is(err, model.ErrURLAlreadyExists):
+			existing++
+		default:
+			conflicts++
+			fmt.Fprintf(os.Stderr, "import: %s: %v\n", fresh[i].Short, err)
+		}
+	}
+	fmt.Fprintf(os.Stderr, "imported %d URLs, %d already stored, %d conflicting\n", saved, existing, conflicts)
+	return 0
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Aleksey170999/go-shortener/internal/config"
+	"github.com/Aleksey170999/go-shortener/internal/model"
+	"github.com/Aleksey170999/go-shortener/internal/repository"
+	"github.com/Aleksey170999/go-shortener/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// loadURLs returns the URLs of the storage file at path, sorted by short code.
+func loadURLs(t *testing.T, path string) []model.URL {
+	repo := repository.NewMemoryURLRepository()
+	require.NoError(t, storage.NewStorage(path).LoadFromStorage(context.Background(), repo))
+	urls, err := repo.List(context.Background())
+	require.NoError(t, err)
+	slices.SortFunc(urls, func(a, b model.URL) int { return strings.Compare(a.Short, b.Short) })
+	return urls
+}
+
+func TestExportImport(t *testing.T) {
+	dir := t.TempDir()
+	created := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	expires := created.Add(365 * 24 * time.Hour)
+	deletedAt := created.Add(time.Hour)
+	urls := []model.URL{
+		{ID: "1", Short: "aaa", Original: "https://example.com/1", UserID: "user1", CreatedAt: created},
+		{ID: "2", Short: "bbb", Original: "https://example.com/2", CreatedAt: created, ExpiresAt: &expires},
+		{ID: "3", Short: "ccc", Original: "https://example.com/3", UserID: "user1", CreatedAt: created, IsDeleted: true, DeletedAt: &deletedAt},
+	}
+	source := filepath.Join(dir, "source.json")
+	require.NoError(t, storage.NewStorage(source).WriteAll(context.Background(), urls))
+
+	exported := filepath.Join(dir, "export.json.gz")
+	require.Equal(t, 0, export(config.NewConfig(config.WithStorageFile(source)), []string{exported}))
+
+	target := filepath.Join(dir, "target.json")
+	cfg := config.NewConfig(config.WithStorageFile(target))
+	require.Equal(t, 0, importURLs(cfg, []string{exported}))
+	assert.Equal(t, urls, loadURLs(t, target))
+
+	// Importing again stores nothing twice.
+	require.Equal(t, 0, importURLs(cfg, []string{exported}))
+	assert.Equal(t, urls, loadURLs(t, target))
+}
+
+func TestImport_InvalidInput(t *testing.T) {
+	dir := t.TempDir()
+	cfg := config.NewConfig(config.WithStorageFile(filepath.Join(dir, "storage.json")))
+	for name, content := range map[string]string{
+		"malformed":        `[{"uuid": "1",`,
+		"missing short":    `[{"uuid": "1", "original_url": "https://example.com"}]`,
+		"missing original": `[{"uuid": "1", "short_url": "aaa"}]`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			input := filepath.Join(dir, "input.json")
+			require.NoError(t, os.WriteFile(input, []byte(content), 0o600))
+			assert.Equal(t, 1, importURLs(cfg, []string{input}))
+		})
+	}
+	assert.Equal(t, 1, importURLs(cfg, []string{filepath.Join(dir, "missing.json")}))
+}
+
+func TestExportImport_Arguments(t *testing.T) {
+	cfg := config.NewConfig(config.WithStorageFile(filepath.Join(t.TempDir(), "storage.json")))
+	assert.Equal(t, 2, export(cfg, []string{"a.json", "b.json"}))
+	assert.Equal(t, 2, importURLs(cfg, []string{"a.json", "b.json"}))
+
+	noStorage := config.NewConfig(config.WithStorageFile(""))
+	assert.Equal(t, 1, export(noStorage, []string{filepath.Join(t.TempDir(), "export.json")}))
+}
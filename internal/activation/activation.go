@@ -0,0 +1,29 @@
+// Package activation implements systemd socket activation: the service
+// manager binds the listening sockets and passes them to the process, so it
+// can start the service on the first connection and restart it without
+// refusing connections in between.
+//
+// See sd_listen_fds(3) for the protocol.
+package activation
+
+import (
+	"net"
+	"os"
+)
+
+// listenFDsStart is the first file descriptor passed by systemd.
+const listenFDsStart = 3
+
+// Listeners returns the listening sockets passed to the process by systemd,
+// in the order of the ListenStream= lines of the socket unit, or nil if the
+// process was not socket activated. The LISTEN_* variables are unset so that
+// child processes do not take the sockets for theirs. Socket activation is
+// only supported on Unix; elsewhere Listeners always returns nil.
+func Listeners() ([]net.Listener, error) {
+	defer func() {
+		os.Unsetenv("LISTEN_PID")
+		os.Unsetenv("LISTEN_FDS")
+		os.Unsetenv("LISTEN_FDNAMES")
+	}()
+	return listeners(os.LookupEnv, os.Getpid(), listenFDsStart)
+}
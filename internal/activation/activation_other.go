@@ -0,0 +1,10 @@
+//go:build !unix
+
+package activation
+
+import "net"
+
+// listeners reports no inherited listeners: systemd only runs on Unix.
+func listeners(func(string) (string, bool), int, int) ([]net.Listener, error) {
+	return nil, nil
+}
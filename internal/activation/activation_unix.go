@@ -0,0 +1,42 @@
+//go:build unix
+
+package activation
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"syscall"
+)
+
+func listeners(lookupEnv func(string) (string, bool), pid, start int) ([]net.Listener, error) {
+	// LISTEN_PID guards against inheriting the variables of a parent that
+	// was socket activated itself.
+	listenPID, ok := lookupEnv("LISTEN_PID")
+	if !ok || listenPID != strconv.Itoa(pid) {
+		return nil, nil
+	}
+	value, _ := lookupEnv("LISTEN_FDS")
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		return nil, fmt.Errorf("invalid LISTEN_FDS %q", value)
+	}
+
+	lns := make([]net.Listener, 0, n)
+	for fd := start; fd < start+n; fd++ {
+		syscall.CloseOnExec(fd)
+		f := os.NewFile(uintptr(fd), "LISTEN_FD_"+strconv.Itoa(fd))
+		ln, err := net.FileListener(f)
+		// FileListener duplicates the descriptor.
+		f.Close()
+		if err != nil {
+			for _, l := range lns {
+				l.Close()
+			}
+			return nil, fmt.Errorf("socket %d is not a listening socket: %w", fd-start, err)
+		}
+		lns = append(lns, ln)
+	}
+	return lns, nil
+}
@@ -0,0 +1,75 @@
+//go:build unix
+
+package activation
+
+import (
+	"net"
+	"os"
+	"strconv"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// dup returns a copy of the descriptor of f, which listeners takes over.
+func dup(t *testing.T, f *os.File) int {
+	fd, err := syscall.Dup(int(f.Fd()))
+	require.NoError(t, err)
+	return fd
+}
+
+// env returns a lookup function serving the given variables.
+func env(vars map[string]string) func(string) (string, bool) {
+	return func(name string) (string, bool) {
+		v, ok := vars[name]
+		return v, ok
+	}
+}
+
+func TestListeners(t *testing.T) {
+	tcp, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer tcp.Close()
+	f, err := tcp.(*net.TCPListener).File()
+	require.NoError(t, err)
+	defer f.Close()
+	fd := dup(t, f)
+
+	lns, err := listeners(env(map[string]string{"LISTEN_PID": "1", "LISTEN_FDS": "1"}), 2, listenFDsStart)
+	require.NoError(t, err)
+	assert.Nil(t, lns, "the sockets of another process")
+
+	lns, err = listeners(env(map[string]string{"LISTEN_PID": strconv.Itoa(os.Getpid()), "LISTEN_FDS": "1"}), os.Getpid(), fd)
+	require.NoError(t, err)
+	require.Len(t, lns, 1)
+	defer lns[0].Close()
+	assert.Equal(t, tcp.Addr().String(), lns[0].Addr().String())
+
+	go func() {
+		if conn, err := net.Dial("tcp", tcp.Addr().String()); err == nil {
+			conn.Close()
+		}
+	}()
+	conn, err := lns[0].Accept()
+	require.NoError(t, err)
+	conn.Close()
+}
+
+func TestListeners_Invalid(t *testing.T) {
+	_, err := listeners(env(map[string]string{"LISTEN_PID": "7", "LISTEN_FDS": "many"}), 7, listenFDsStart)
+	assert.ErrorContains(t, err, `invalid LISTEN_FDS "many"`)
+
+	f, err := os.CreateTemp(t.TempDir(), "regular")
+	require.NoError(t, err)
+	defer f.Close()
+	_, err = listeners(env(map[string]string{"LISTEN_PID": "7", "LISTEN_FDS": "1"}), 7, dup(t, f))
+	assert.ErrorContains(t, err, "socket 0 is not a listening socket")
+}
+
+func TestListeners_NotActivated(t *testing.T) {
+	lns, err := listeners(env(nil), 7, listenFDsStart)
+	assert.NoError(t, err)
+	assert.Nil(t, lns)
+}
@@ -0,0 +1,693 @@
+// Package app wires the URL shortener together: it builds the repository,
+// the URL service, the audit pipeline, the HTTP handlers and the servers
+// from a config.Config, runs them and shuts them down in order.
+//
+// The main package only parses the configuration, sets up logging and
+// translates signals; integration tests and other binaries can construct
+// the whole server with New and serve App.Handlers or call Run.
+package app
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/Aleksey170999/go-shortener/internal/activation"
+	"github.com/Aleksey170999/go-shortener/internal/audit"
+	"github.com/Aleksey170999/go-shortener/internal/config"
+	"github.com/Aleksey170999/go-shortener/internal/handler"
+	"github.com/Aleksey170999/go-shortener/internal/jobs"
+	"github.com/Aleksey170999/go-shortener/internal/metrics"
+	"github.com/Aleksey170999/go-shortener/internal/middlewares"
+	"github.com/Aleksey170999/go-shortener/internal/model"
+	"github.com/Aleksey170999/go-shortener/internal/profiling"
+	"github.com/Aleksey170999/go-shortener/internal/reporting"
+	"github.com/Aleksey170999/go-shortener/internal/repository"
+	"github.com/Aleksey170999/go-shortener/internal/service"
+	"github.com/Aleksey170999/go-shortener/internal/storage"
+	"github.com/Aleksey170999/go-shortener/internal/tracing"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+)
+
+// tracingServiceName is the service.name reported with exported spans.
+const tracingServiceName = "go-shortener"
+
+// App is a fully wired URL shortener server.
+type App struct {
+	Config   *config.Config
+	Logger   *zap.Logger
+	Repo     repository.URLRepository
+	Service  *service.URLService
+	Handlers http.Handler // Router serving every endpoint of the main server
+	Server   *http.Server // Main server, on SERVER_ADDRESS
+	Jobs     *jobs.Runner // Periodic background jobs, started by Run
+
+	level           zap.AtomicLevel
+	build           model.BuildInfo
+	registry        *prometheus.Registry
+	vars            *metrics.Vars
+	audit           *audit.AuditManager
+	dbAudit         *audit.DBAudit
+	storage         *storage.Storage
+	maintenance     *middlewares.Maintenance
+	blocklist       *middlewares.Blocklist
+	watchdog        *profiling.Watchdog  // Nil unless PROFILE_DIR is set
+	snapshotter     *storage.Snapshotter // Nil unless SNAPSHOT_INTERVAL is set
+	storageLock     *storage.FileLock    // Lock of the storage file, nil if it is not used
+	https           bool
+	plainSrv        *http.Server // Plain HTTP next to HTTPS, on HTTP_ADDRESS
+	challengeSrv    *http.Server // ACME HTTP-01 challenges, on AUTOCERT_HTTP_ADDRESS
+	mtlsSrv         *http.Server // HTTPS requiring client certificates, on MTLS_ADDRESS
+	shutdownTracing func(context.Context) error
+	flushReporting  func(context.Context) error
+}
+
+// Option configures an App.
+type Option func(*App)
+
+// WithLogger sets the logger of the App and the level it is created with,
+// which the /api/internal/log-level endpoint reads and changes. Without it
+// nothing is logged.
+func WithLogger(logger *zap.Logger, level zap.AtomicLevel) Option {
+	return func(a *App) {
+		a.Logger = logger
+		a.level = level
+	}
+}
+
+// WithBuildInfo sets the build metadata served at /version.
+func WithBuildInfo(info model.BuildInfo) Option {
+	return func(a *App) {
+		a.build = info
+	}
+}
+
+// WithRepository makes the App store links in repo instead of the
+// repository selected by the configuration. The App closes repo on shutdown
+// if it implements io.Closer.
+func WithRepository(repo repository.URLRepository) Option {
+	return func(a *App) {
+		a.Repo = repo
+	}
+}
+
+// New builds the App described by cfg, which should have passed
+// config.Validate. Background work, such as the delete workers and the
+// audit writers, starts right away; the servers and the periodic jobs, such
+// as the cleanup and retention runners, start with Run.
+//
+// If New fails, everything it has built so far is shut down again.
+func New(cfg *config.Config, opts ...Option) (_ *App, err error) {
+	a := &App{
+		Config:          cfg,
+		Logger:          zap.NewNop(),
+		level:           zap.NewAtomicLevel(),
+		build:           model.BuildInfo{Version: "N/A", Date: "N/A", Commit: "N/A"},
+		registry:        metrics.NewRegistry(),
+		vars:            metrics.NewVars(),
+		shutdownTracing: func(context.Context) error { return nil },
+		flushReporting:  func(context.Context) error { return nil },
+	}
+	for _, opt := range opts {
+		opt(a)
+	}
+	defer func() {
+		if err != nil {
+			a.shutdown(context.Background())
+		}
+	}()
+
+	if err := a.newReporting(); err != nil {
+		return nil, err
+	}
+	if err := a.newAudit(); err != nil {
+		return nil, err
+	}
+	if err := a.newRepo(); err != nil {
+		return nil, err
+	}
+	if err := a.newService(); err != nil {
+		return nil, err
+	}
+	if err := a.newServers(); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// newReporting sets up error reporting to SENTRY_DSN, if it is set. It
+// comes first, so that failures of everything started later are reported.
+func (a *App) newReporting() error {
+	if a.Config.SentryDSN == "" {
+		return nil
+	}
+	release := a.build.Version
+	if release == "N/A" {
+		release = "" // Sentry falls back to SENTRY_RELEASE or the VCS revision
+	}
+	flush, err := reporting.Setup(reporting.Config{
+		DSN:         a.Config.SentryDSN,
+		Environment: a.Config.SentryEnvironment,
+		Release:     release,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to set up error reporting: %w", err)
+	}
+	a.flushReporting = flush
+	return nil
+}
+
+// newAudit creates the audit manager with the writers enabled by the
+// configuration, except the database writer, which needs the repository.
+func (a *App) newAudit() error {
+	a.audit = audit.NewAuditManager(
+		audit.WithQueueSize(a.Config.AuditQueueSize),
+		audit.WithWorkers(a.Config.AuditWorkers),
+		audit.WithErrorHandler(func(writer string, events int, err error) {
+			a.Logger.Warn("failed to deliver audit events",
+				zap.String("writer", writer), zap.Int("events", events), zap.Error(err))
+			reporting.Error("audit:"+writer, err)
+		}),
+	)
+	a.registry.MustRegister(audit.NewCollector(a.audit))
+
+	if !a.Config.EnableAudit {
+		return nil
+	}
+	writerConfigs := auditWriterConfigs(a.Config)
+	if a.Config.AuditConfig != "" {
+		auditCfg, err := audit.LoadConfig(a.Config.AuditConfig)
+		if err != nil {
+			return fmt.Errorf("failed to load audit config: %w", err)
+		}
+		writerConfigs = append(writerConfigs, auditCfg.Writers...)
+	}
+	for _, wc := range writerConfigs {
+		writer, err := audit.NewWriter(context.Background(), wc)
+		if err != nil {
+			return fmt.Errorf("failed to create %s audit writer: %w", wc.Type, err)
+		}
+		a.audit.RegisterWriter(writer)
+	}
+	return nil
+}
+
+// newRepo opens the PostgreSQL repository if DATABASE_DSN is set, and the
+// in-memory one loaded from the storage file otherwise, unless a repository
+// was given with WithRepository. The in-memory repository persists its
+// links to the file itself, see storage.Persist; the other repositories do
+// not use the file. With SNAPSHOT_INTERVAL the in-memory repository is
+// written to the file by a snapshot job instead of on every write, or to
+// the object of SNAPSHOT_URL, from which it is restored instead, and
+// with STORAGE_QUEUE_SIZE new and deleted links are appended by a writer
+// goroutine. With STORAGE_KEY or STORAGE_KEY_FILE the file or the object
+// is encrypted. The storage file is locked against other processes until
+// shutdown.
+func (a *App) newRepo() error {
+	a.storage = storage.NewStorage("")
+	if a.Repo != nil {
+		if a.Config.EnableAudit && a.Config.AuditDB {
+			a.Logger.Warn("database audit requires DATABASE_DSN, audit events are not stored in the database")
+		}
+	} else if a.Config.DatabaseDSN != "" {
+		dbRepo, err := repository.NewDataBaseURLRepository(a.Config)
+		if err != nil {
+			return err
+		}
+		a.Repo = dbRepo
+		if a.Config.EnableAudit && a.Config.AuditDB {
+			a.dbAudit = audit.NewDBAudit(dbRepo.DB, a.Config.AuditDBRetention)
+			a.audit.RegisterWriter(a.dbAudit)
+		}
+	} else if a.Config.SnapshotURL != "" {
+		key, err := storage.LoadKey(a.Config.StorageKey, a.Config.StorageKeyFile)
+		if err != nil {
+			return err
+		}
+		object, err := storage.NewObjectStorage(storage.ObjectConfig{
+			URL:       a.Config.SnapshotURL,
+			Region:    a.Config.SnapshotRegion,
+			AccessKey: a.Config.SnapshotAccessKey,
+			SecretKey: a.Config.SnapshotSecretKey,
+			Key:       key,
+		})
+		if err != nil {
+			return err
+		}
+		repo := repository.NewMemoryURLRepository()
+		if err := object.LoadFromStorage(context.Background(), repo); err != nil {
+			return fmt.Errorf("failed to restore snapshot: %w", err)
+		}
+		a.snapshotter = storage.NewSnapshotter(object, repo, a.Config.SnapshotInterval, a.Config.SnapshotChanges, a.Logger)
+		a.Repo = a.snapshotter.Track(repo)
+		if a.Config.EnableAudit && a.Config.AuditDB {
+			a.Logger.Warn("database audit requires DATABASE_DSN, audit events are not stored in the database")
+		}
+	} else {
+		if a.Config.StorageFilePath != "" {
+			lock, err := storage.LockFile(a.Config.StorageFilePath)
+			if err != nil {
+				return err
+			}
+			a.storageLock = lock
+		}
+		a.storage = storage.NewStorage(a.Config.StorageFilePath)
+		key, err := storage.LoadKey(a.Config.StorageKey, a.Config.StorageKeyFile)
+		if err != nil {
+			return err
+		}
+		if key != nil {
+			if err := a.storage.SetKey(key); err != nil {
+				return err
+			}
+		}
+		repo := repository.NewMemoryURLRepository()
+		if err := a.storage.LoadFromStorage(context.Background(), repo); err != nil {
+			return fmt.Errorf("failed to load storage file: %w", err)
+		}
+		if a.Config.SnapshotInterval > 0 {
+			a.snapshotter = storage.NewSnapshotter(a.storage, repo, a.Config.SnapshotInterval, a.Config.SnapshotChanges, a.Logger)
+			a.Repo = a.snapshotter.Track(repo)
+		} else {
+			a.Repo = storage.Persist(repo, a.storage)
+		}
+		if a.Config.EnableAudit && a.Config.AuditDB {
+			a.Logger.Warn("database audit requires DATABASE_DSN, audit events are not stored in the database")
+		}
+	}
+	if a.Config.StorageQueueSize > 0 {
+		a.storage.StartWriter(a.Config.StorageQueueSize, func(err error) {
+			a.Logger.Error("storage write failed", zap.Error(err))
+			reporting.Error("storage", err)
+		})
+	}
+	a.Repo = metrics.InstrumentRepository(a.Repo, a.registry)
+	return nil
+}
+
+// compactStorage removes duplicates and links that expired, were purged or
+// were deleted past DELETED_RETENTION from the storage file, so that they
+// are not loaded again on the next start. CLEANUP_DRY_RUN only logs what
+// would be removed.
+func (a *App) compactStorage(ctx context.Context) error {
+	now := time.Now()
+	deletedBefore := now.Add(-a.Config.DeletedRetention)
+	stats, err := a.storage.Compact(ctx, storage.CompactOptions{
+		Now:           now,
+		DeletedBefore: deletedBefore,
+		Keep:          storage.InRepository(a.Repo, deletedBefore),
+		DryRun:        a.Config.CleanupDryRun,
+	})
+	if err != nil {
+		return err
+	}
+	if stats.Duplicates+stats.Expired+stats.Removed > 0 {
+		a.Logger.Sugar().Infow("storage file compacted", "dry_run", a.Config.CleanupDryRun, "kept", stats.Kept,
+			"duplicates", stats.Duplicates, "expired", stats.Expired, "removed", stats.Removed)
+	}
+	return nil
+}
+
+// newService creates the URL service and sets up tracing, whose hooks it
+// reports to.
+func (a *App) newService() error {
+	cfg := a.Config
+	aliasPolicy := service.DefaultAliasPolicy()
+	aliasPolicy.Reserved = append(aliasPolicy.Reserved, cfg.AliasReserved...)
+	aliasPolicy.Blocked = cfg.AliasBlocked
+
+	serviceOpts := []service.Option{
+		service.WithAliasPolicy(aliasPolicy),
+		service.WithQueryTimeout(cfg.DBQueryTimeout),
+		service.WithDeleteBatchSize(cfg.DeleteBatchSize),
+		service.WithDeleteFlushInterval(cfg.DeleteFlushInterval),
+		service.WithDeleteQueueSize(cfg.DeleteQueueSize),
+		service.WithDeleteWorkers(cfg.DeleteWorkers),
+		service.WithResolveCache(cfg.ResolveCacheSize, cfg.ResolveCacheTTL),
+		service.WithHooks(metrics.NewHooks(a.registry), a.vars),
+		service.WithCleanup(service.CleanupConfig{
+			Interval:  cfg.CleanupInterval,
+			Retention: cfg.DeletedRetention,
+			DryRun:    cfg.CleanupDryRun,
+		}),
+		service.WithRateLimit(cfg.UserRateLimit, cfg.UserRateBurst),
+		service.WithRetention(service.RetentionConfig{
+			Interval: cfg.RetentionInterval,
+			Rules: []service.RetentionRule{
+				{Name: "anonymous_max_age", Anonymous: true, MaxAge: cfg.AnonymousMaxAge},
+				{Name: "deleted", DeletedFor: cfg.DeletedRetention},
+			},
+			DryRun: cfg.RetentionDryRun,
+			OnRemove: func(ctx context.Context, rule service.RetentionRule, url model.URL) {
+				a.audit.LogEvent(ctx, "retention_"+rule.Name, url.UserID, url.Original)
+			},
+		}),
+		service.WithDeleteJobDone(func(ctx context.Context, job service.DeleteJob) {
+			event := audit.AuditEvent{
+				Action:  "delete_completed",
+				UserID:  job.UserID,
+				Count:   job.Completed,
+				Outcome: audit.OutcomeSuccess,
+			}
+			if job.Failed > 0 {
+				event.Outcome = audit.OutcomeFailure
+				reporting.Error("delete", fmt.Errorf("delete job %s: %d of %d urls failed", job.ID, job.Failed, job.Total))
+			}
+			a.audit.Log(ctx, event)
+		}),
+	}
+	if cfg.DatabaseDSN != "" {
+		serviceOpts = append(serviceOpts, service.WithCircuitBreaker(cfg.DBBreakerThreshold, cfg.DBBreakerCooldown))
+	}
+	if cfg.EnableStats {
+		serviceOpts = append(serviceOpts, service.WithStats(service.StatsConfig{
+			Bucket:        cfg.StatsBucket,
+			FlushInterval: cfg.StatsFlushInterval,
+		}))
+	}
+	if cfg.HashCodes {
+		serviceOpts = append(serviceOpts, service.WithHashCodes())
+	}
+	if cfg.TracingEndpoint != "" {
+		shutdown, err := tracing.Setup(context.Background(), tracing.Config{
+			Endpoint:    cfg.TracingEndpoint,
+			Insecure:    cfg.TracingInsecure,
+			ServiceName: tracingServiceName,
+			SampleRatio: cfg.TracingSampleRatio,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to set up tracing: %w", err)
+		}
+		a.shutdownTracing = shutdown
+		serviceOpts = append(serviceOpts, service.WithHooks(tracing.Hooks{}))
+	}
+	a.Service = service.NewURLService(a.Repo, serviceOpts...)
+	a.registry.MustRegister(service.NewCollector(a.Service))
+	a.vars.TrackService(a.Service)
+	a.Jobs = jobs.NewRunner(a.Logger, jobs.WithErrorHandler(func(job string, err error) {
+		reporting.Error("job:"+job, err)
+	}))
+	a.Jobs.Add(a.Service.Jobs()...)
+	if a.snapshotter != nil {
+		a.Jobs.Add(a.snapshotter.Job())
+	}
+	if cfg.CompactInterval > 0 {
+		a.Jobs.Add(jobs.Job{Name: "compaction", Schedule: jobs.Every(cfg.CompactInterval), Run: a.compactStorage})
+	}
+	if cfg.ProfileDir != "" {
+		a.watchdog = profiling.NewWatchdog(profiling.Config{
+			Dir:         cfg.ProfileDir,
+			LatencyP99:  cfg.ProfileLatencyP99,
+			Goroutines:  cfg.ProfileGoroutines,
+			Sustain:     cfg.ProfileSustain,
+			Cooldown:    cfg.ProfileCooldown,
+			CPUDuration: cfg.ProfileCPUDuration,
+		}, a.Logger)
+		a.Jobs.Add(a.watchdog.Job())
+	}
+	return nil
+}
+
+// newServers creates the router and the servers: the main one, and the
+// plain HTTP, ACME challenge and mTLS servers if they are configured.
+func (a *App) newServers() error {
+	cfg := a.Config
+	router, err := a.routes()
+	if err != nil {
+		return err
+	}
+	a.Handlers = router
+	a.Server = &http.Server{
+		Addr:              cfg.RunAddr,
+		Handler:           router,
+		ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+		ReadTimeout:       cfg.ReadTimeout,
+		WriteTimeout:      cfg.WriteTimeout,
+		IdleTimeout:       cfg.IdleTimeout,
+		MaxHeaderBytes:    cfg.MaxHeaderBytes,
+		Protocols:         protocols(cfg),
+	}
+	// With Let's Encrypt certificates, a plain HTTP server answers the
+	// HTTP-01 challenges and redirects all other requests to HTTPS.
+	certs := certManager(cfg)
+	a.https = cfg.EnableHTTPS || certs != nil
+	var tlsCfg *tls.Config
+	if a.https || cfg.MTLSAddr != "" {
+		if tlsCfg, err = tlsConfig(cfg, certs); err != nil {
+			return err
+		}
+	}
+	if a.https {
+		a.Server.TLSConfig = tlsCfg
+	}
+	// Client certificates are required on MTLS_ADDRESS if it is set, and
+	// on the main server otherwise.
+	if cfg.TLSClientCAFile != "" {
+		mtlsCfg, err := mtlsConfig(cfg, tlsCfg)
+		if err != nil {
+			return err
+		}
+		if cfg.MTLSAddr == "" {
+			a.Server.TLSConfig = mtlsCfg
+		} else {
+			a.mtlsSrv = &http.Server{
+				Addr:              cfg.MTLSAddr,
+				Handler:           router,
+				TLSConfig:         mtlsCfg,
+				ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+				ReadTimeout:       cfg.ReadTimeout,
+				WriteTimeout:      cfg.WriteTimeout,
+				IdleTimeout:       cfg.IdleTimeout,
+				MaxHeaderBytes:    cfg.MaxHeaderBytes,
+				Protocols:         protocols(cfg),
+			}
+		}
+	}
+	if certs != nil {
+		a.challengeSrv = &http.Server{
+			Addr:              cfg.AutocertHTTPAddr,
+			Handler:           certs.HTTPHandler(nil),
+			ReadHeaderTimeout: 10 * time.Second,
+		}
+	}
+	// Next to HTTPS, HTTP_ADDRESS serves plain HTTP as well, redirecting to
+	// HTTPS unless HTTP_REDIRECT is disabled.
+	if a.https && cfg.HTTPAddr != "" {
+		plainHandler := router
+		if cfg.HTTPRedirect {
+			_, port, _ := net.SplitHostPort(cfg.RunAddr) // checked by Validate
+			plainHandler = handler.NewHTTPSRedirectHandler(port)
+		}
+		a.plainSrv = &http.Server{
+			Addr:              cfg.HTTPAddr,
+			Handler:           plainHandler,
+			ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+			ReadTimeout:       cfg.ReadTimeout,
+			WriteTimeout:      cfg.WriteTimeout,
+			IdleTimeout:       cfg.IdleTimeout,
+			MaxHeaderBytes:    cfg.MaxHeaderBytes,
+			Protocols:         protocols(cfg),
+		}
+	}
+	return nil
+}
+
+// Run serves requests until ctx is done or a server fails, then shuts the
+// App down within SHUTDOWN_TIMEOUT: the servers stop accepting requests and
+// finish the in-flight ones, queued deletions are flushed, the periodic jobs
+// stop, audit events are flushed, and the database is closed. It returns the error of the failed server, if
+// any. An App cannot be run again.
+func (a *App) Run(ctx context.Context) error {
+	lns, err := a.listen()
+	if err != nil {
+		a.shutdown(context.Background())
+		return err
+	}
+
+	a.Jobs.Start(context.Background())
+
+	failed := make(chan error, len(lns))
+	serve := func(name string, srv *http.Server, ln net.Listener, https bool) {
+		var err error
+		if https {
+			err = srv.ServeTLS(ln, "", "")
+		} else {
+			err = srv.Serve(ln)
+		}
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			failed <- fmt.Errorf("%s failed: %w", name, err)
+		}
+	}
+	a.Logger.Sugar().Infoln(
+		"msg", "Server starting",
+		"url", lns[0].Addr().String(),
+		"https", a.https,
+	)
+	go serve("server", a.Server, lns[0], a.https)
+	lns = lns[1:]
+	if a.plainSrv != nil {
+		a.Logger.Sugar().Infow("HTTP server starting", "url", lns[0].Addr().String(), "redirect", a.Config.HTTPRedirect)
+		go serve("HTTP server", a.plainSrv, lns[0], false)
+		lns = lns[1:]
+	}
+	if a.challengeSrv != nil {
+		a.Logger.Sugar().Infow("ACME challenge server starting", "url", lns[0].Addr().String(), "domains", a.Config.AutocertDomains)
+		go serve("ACME challenge server", a.challengeSrv, lns[0], false)
+		lns = lns[1:]
+	}
+	if a.mtlsSrv != nil {
+		a.Logger.Sugar().Infow("mTLS server starting", "url", lns[0].Addr().String())
+		go serve("mTLS server", a.mtlsSrv, lns[0], true)
+	}
+
+	select {
+	case <-ctx.Done():
+	case err = <-failed:
+	}
+	a.Logger.Sugar().Infow("Server shutting down", "grace_period", a.Config.ShutdownTimeout)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), a.Config.ShutdownTimeout)
+	defer cancel()
+	a.shutdown(shutdownCtx) // failures are logged
+	return err
+}
+
+// listen returns the listeners of the main server, the plain HTTP server,
+// the ACME challenge server and the mTLS server, in this order, for those
+// that are configured. Under systemd socket activation, the passed sockets are used
+// in that order; servers left without a socket bind their own address, with
+// SO_REUSEPORT if REUSE_PORT is set.
+func (a *App) listen() (lns []net.Listener, err error) {
+	sockets, err := activation.Listeners()
+	if err != nil {
+		return nil, fmt.Errorf("socket activation failed: %w", err)
+	}
+	if sockets != nil {
+		a.Logger.Sugar().Infow("socket activated", "sockets", len(sockets))
+	}
+	defer func() {
+		if err != nil {
+			for _, ln := range append(lns, sockets...) {
+				ln.Close()
+			}
+		}
+	}()
+
+	for _, srv := range []*http.Server{a.Server, a.plainSrv, a.challengeSrv, a.mtlsSrv} {
+		if srv == nil {
+			continue
+		}
+		if len(sockets) > 0 {
+			lns = append(lns, sockets[0])
+			sockets = sockets[1:]
+			continue
+		}
+		ln, err := listenConfig(a.Config).Listen(context.Background(), "tcp", srv.Addr)
+		if err != nil {
+			return lns, fmt.Errorf("failed to listen on %s: %w", srv.Addr, err)
+		}
+		lns = append(lns, ln)
+	}
+	for _, unused := range sockets {
+		a.Logger.Sugar().Warnw("unused activated socket closed", "address", unused.Addr().String())
+		unused.Close()
+	}
+	return lns, nil
+}
+
+// shutdown stops whatever parts of the App exist. The jobs stop after the
+// service, so that the final stats flush includes the clicks of the last
+// requests. The database is closed after them, as queued deletions, the
+// jobs and the database audit writer use it until they are done. Pending
+// error reports are sent at the very end, so that they include the
+// failures of the shutdown itself. Failures are logged, and returned
+// joined.
+func (a *App) shutdown(ctx context.Context) error {
+	var errs []error
+	fail := func(msg string, err error) {
+		a.Logger.Sugar().Errorw(msg, "error", err)
+		errs = append(errs, fmt.Errorf("%s: %w", msg, err))
+	}
+	servers := []struct {
+		name string
+		srv  *http.Server
+	}{
+		{"server", a.Server},
+		{"HTTP server", a.plainSrv},
+		{"ACME challenge server", a.challengeSrv},
+		{"mTLS server", a.mtlsSrv},
+	}
+	for _, s := range servers {
+		if s.srv == nil {
+			continue
+		}
+		if err := s.srv.Shutdown(ctx); err != nil {
+			fail(s.name+" shutdown failed", err)
+		}
+	}
+	if a.storage != nil {
+		if err := a.storage.Close(ctx); err != nil {
+			fail("storage queue drain failed", err)
+		}
+	}
+	if a.Service != nil {
+		if err := a.Service.Shutdown(ctx); err != nil {
+			fail("delete queue drain failed", err)
+		}
+	}
+	if a.Jobs != nil {
+		if err := a.Jobs.Stop(ctx); err != nil {
+			fail("background jobs stop failed", err)
+		}
+	}
+	if a.audit != nil {
+		if err := a.audit.Close(ctx); err != nil {
+			fail("audit flush failed", err)
+		}
+	}
+	if closer, ok := a.Repo.(io.Closer); ok {
+		if err := closer.Close(); err != nil {
+			fail("database close failed", err)
+		}
+	}
+	if a.storageLock != nil {
+		if err := a.storageLock.Close(); err != nil {
+			fail("storage file unlock failed", err)
+		}
+	}
+	if err := a.shutdownTracing(ctx); err != nil {
+		fail("tracing shutdown failed", err)
+	}
+	if err := a.flushReporting(ctx); err != nil {
+		fail("error reporting flush failed", err)
+	}
+	return errors.Join(errs...)
+}
+
+// Close shuts down an App that is not Run, e.g. one whose Handlers are
+// served by another server: queued deletions and audit events are flushed,
+// the periodic jobs stop, and the database is closed. It returns the joined
+// failures.
+func (a *App) Close(ctx context.Context) error {
+	return a.shutdown(ctx)
+}
+
+// ToggleMaintenance switches maintenance mode and reports whether it is now
+// enabled.
+func (a *App) ToggleMaintenance() bool {
+	return a.maintenance.Toggle()
+}
+
+// ReloadBlocklist reloads the blocklist from BLOCKLIST_FILE and returns it.
+// On failure the current blocklist is kept.
+func (a *App) ReloadBlocklist() (model.Blocklist, error) {
+	if err := a.blocklist.LoadFile(a.Config.BlocklistFile); err != nil {
+		return model.Blocklist{}, err
+	}
+	return a.blocklist.List(), nil
+}
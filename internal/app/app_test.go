@@ -0,0 +1,207 @@
+package app
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Aleksey170999/go-shortener/internal/config"
+	"github.com/Aleksey170999/go-shortener/internal/model"
+	"github.com/Aleksey170999/go-shortener/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newConfig returns the default configuration with the storage file in a
+// temporary directory.
+func newConfig(t *testing.T) *config.Config {
+	return config.NewConfig(
+		config.WithAddress("127.0.0.1:0"),
+		config.WithStorageFile(filepath.Join(t.TempDir(), "storage.json")),
+	)
+}
+
+func TestNew(t *testing.T) {
+	cfg := newConfig(t)
+	a, err := New(cfg, WithBuildInfo(model.BuildInfo{Version: "v1.0.0"}))
+	require.NoError(t, err)
+	srv := httptest.NewServer(a.Handlers)
+	defer srv.Close()
+	client := srv.Client()
+	client.CheckRedirect = func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }
+
+	resp, err := client.Post(srv.URL+"/", "text/plain", strings.NewReader("https://example.com"))
+	require.NoError(t, err)
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+	short := strings.TrimPrefix(string(body), cfg.ReturnPrefix)
+
+	resp, err = client.Get(srv.URL + short)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusTemporaryRedirect, resp.StatusCode)
+	assert.Equal(t, "https://example.com", resp.Header.Get("Location"))
+
+	resp, err = client.Get(srv.URL + "/version")
+	require.NoError(t, err)
+	body, _ = io.ReadAll(resp.Body)
+	resp.Body.Close()
+	assert.Contains(t, string(body), `"version":"v1.0.0"`)
+}
+
+func TestNew_Error(t *testing.T) {
+	cfg := newConfig(t)
+	cfg.CookieSameSite = "none"
+	cfg.CookieSecure = "false"
+	_, err := New(cfg)
+	assert.ErrorContains(t, err, "cookie SameSite=None requires the Secure attribute")
+}
+
+func TestCookieOptions_Secure(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(cfg *config.Config)
+		want   bool
+	}{
+		{name: "plain HTTP", modify: func(*config.Config) {}},
+		{name: "HTTPS", modify: func(cfg *config.Config) { cfg.EnableHTTPS = true }, want: true},
+		{name: "autocert", modify: func(cfg *config.Config) { cfg.AutocertDomains = []string{"sho.rt"} }, want: true},
+		{name: "HTTPS base URL", modify: func(cfg *config.Config) { cfg.ReturnPrefix = "https://sho.rt" }, want: true},
+		{name: "disabled explicitly", modify: func(cfg *config.Config) {
+			cfg.EnableHTTPS, cfg.CookieSecure = true, "false"
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := newConfig(t)
+			tt.modify(cfg)
+			opts, err := cookieOptions(cfg)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, opts.Secure)
+		})
+	}
+}
+
+func TestNew_DatabaseUnavailable(t *testing.T) {
+	cfg := newConfig(t)
+	cfg.DatabaseDSN = "postgres://user@127.0.0.1:1/shortener?sslmode=disable&connect_timeout=1"
+	_, err := New(cfg)
+	assert.ErrorContains(t, err, "failed to apply migrations")
+}
+
+func TestNew_StorageLocked(t *testing.T) {
+	cfg := newConfig(t)
+	a, err := New(cfg)
+	require.NoError(t, err)
+
+	_, err = New(cfg)
+	assert.ErrorIs(t, err, storage.ErrLocked, "a second instance on the same storage file fails")
+
+	require.NoError(t, a.Close(context.Background()))
+	b, err := New(cfg)
+	require.NoError(t, err, "the lock is released on shutdown")
+	require.NoError(t, b.Close(context.Background()))
+}
+
+func TestApp_Run(t *testing.T) {
+	a, err := New(newConfig(t))
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- a.Run(ctx) }()
+
+	cancel()
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run did not return after the context was canceled")
+	}
+}
+
+func TestApp_Run_ListenError(t *testing.T) {
+	cfg := newConfig(t)
+	cfg.RunAddr = "127.0.0.1:-1"
+	a, err := New(cfg)
+	require.NoError(t, err)
+	assert.ErrorContains(t, a.Run(context.Background()), "failed to listen on 127.0.0.1:-1")
+}
+
+// newCert returns a certificate for 127.0.0.1 signed by parent, or a
+// self-signed CA certificate if parent is nil.
+func newCert(t *testing.T, name string, parent *tls.Certificate) tls.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	signer, signerKey := tmpl, any(key)
+	if parent == nil {
+		tmpl.IsCA, tmpl.BasicConstraintsValid = true, true
+		tmpl.KeyUsage = x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature
+	} else {
+		signer, signerKey = parent.Leaf, parent.PrivateKey
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, signer, key.Public(), signerKey)
+	require.NoError(t, err)
+	leaf, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}
+}
+
+func TestMTLSConfig(t *testing.T) {
+	ca := newCert(t, "ca", nil)
+	other := newCert(t, "other ca", nil)
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	require.NoError(t, os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.Leaf.Raw}), 0o600))
+
+	cfg := newConfig(t)
+	cfg.TLSClientCAFile = caFile
+	mtlsCfg, err := mtlsConfig(cfg, &tls.Config{Certificates: []tls.Certificate{newCert(t, "server", &ca)}})
+	require.NoError(t, err)
+
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	srv.TLS = mtlsCfg
+	srv.StartTLS()
+	defer srv.Close()
+
+	get := func(clientCerts ...tls.Certificate) error {
+		roots := x509.NewCertPool()
+		roots.AddCert(ca.Leaf)
+		client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots, Certificates: clientCerts}}}
+		resp, err := client.Get(srv.URL)
+		if err == nil {
+			resp.Body.Close()
+		}
+		return err
+	}
+	assert.NoError(t, get(newCert(t, "client", &ca)))
+	assert.Error(t, get(), "a client without a certificate is rejected")
+	assert.Error(t, get(newCert(t, "stranger", &other)), "a certificate of another CA is rejected")
+
+	cfg.TLSClientCAFile = filepath.Join(t.TempDir(), "empty.pem")
+	require.NoError(t, os.WriteFile(cfg.TLSClientCAFile, nil, 0o600))
+	_, err = mtlsConfig(cfg, &tls.Config{})
+	assert.ErrorContains(t, err, "no certificates found")
+}
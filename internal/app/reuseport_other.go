@@ -0,0 +1,15 @@
+//go:build !(aix || darwin || dragonfly || freebsd || linux || netbsd || openbsd)
+
+package app
+
+import (
+	"errors"
+	"syscall"
+)
+
+// reusePort fails: the platform has no SO_REUSEPORT. config.Validate
+// rejects REUSE_PORT here, so it is only reached with an unvalidated
+// configuration.
+func reusePort(string, string, syscall.RawConn) error {
+	return errors.New("SO_REUSEPORT is not supported on this platform")
+}
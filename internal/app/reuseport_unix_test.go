@@ -0,0 +1,28 @@
+//go:build aix || darwin || dragonfly || freebsd || linux || netbsd || openbsd
+
+package app
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestListenConfig_ReusePort(t *testing.T) {
+	cfg := newConfig(t)
+	ctx := context.Background()
+	first, err := listenConfig(cfg).Listen(ctx, "tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer first.Close()
+	_, err = listenConfig(cfg).Listen(ctx, "tcp", first.Addr().String())
+	require.Error(t, err, "without REUSE_PORT the address is in use")
+
+	cfg.ReusePort = true
+	first, err = listenConfig(cfg).Listen(ctx, "tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer first.Close()
+	second, err := listenConfig(cfg).Listen(ctx, "tcp", first.Addr().String())
+	require.NoError(t, err, "a second instance binds the same port")
+	second.Close()
+}
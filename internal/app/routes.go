@@ -0,0 +1,156 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/pprof"
+	"strings"
+
+	"github.com/Aleksey170999/go-shortener/internal/handler"
+	"github.com/Aleksey170999/go-shortener/internal/middlewares"
+	"github.com/Aleksey170999/go-shortener/internal/oidc"
+	"github.com/Aleksey170999/go-shortener/internal/tracing"
+	"github.com/getsentry/sentry-go"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.opentelemetry.io/otel"
+)
+
+// routes returns the router of the main server.
+func (a *App) routes() (http.Handler, error) {
+	cfg := a.Config
+	secret, err := authSecret(cfg, a.Logger)
+	if err != nil {
+		return nil, err
+	}
+	cookie, err := cookieOptions(cfg)
+	if err != nil {
+		return nil, err
+	}
+	trustedProxies, err := middlewares.ParseNetworks(cfg.TrustedProxies)
+	if err != nil {
+		return nil, fmt.Errorf("invalid trusted proxies: %w", err)
+	}
+	h := handler.NewHandler(a.Service, cfg, a.Logger)
+
+	r := chi.NewRouter()
+	r.Use(middlewares.RequestID)
+	r.Use(middlewares.RealIP(trustedProxies))
+	if cfg.TracingEndpoint != "" {
+		r.Use(middlewares.Tracing(tracing.Tracer(), otel.GetTextMapPropagator()))
+	}
+	r.Use(middlewares.WithLogging(a.Logger))
+	r.Use(middlewares.Metrics(a.registry))
+	if a.watchdog != nil {
+		r.Use(a.watchdog.Middleware)
+	}
+	r.Use(a.vars.Middleware)
+	r.Use(middlewares.MaxInFlight(cfg.MaxInFlight))
+	r.Use(middlewares.GzipMiddleware)
+	r.Use(middlewares.Recoverer(a.Logger))
+	if cfg.SentryDSN != "" {
+		r.Use(middlewares.ErrorReporting(sentry.CurrentHub()))
+	}
+	r.Use(middleware.StripSlashes)
+	r.Use(middlewares.AuthMiddleware(secret, cookie))
+	if cfg.EnableAudit {
+		r.Use(middlewares.Audit(a.audit))
+	}
+
+	if cfg.OIDCIssuer != "" {
+		redirectURL := cfg.OIDCRedirectURL
+		if redirectURL == "" {
+			redirectURL = strings.TrimSuffix(cfg.ReturnPrefix, "/") + "/auth/callback"
+		}
+		provider, err := oidc.Discover(context.Background(), oidc.Config{
+			Issuer:       cfg.OIDCIssuer,
+			ClientID:     cfg.OIDCClientID,
+			ClientSecret: cfg.OIDCClientSecret,
+			RedirectURL:  redirectURL,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to discover oidc provider: %w", err)
+		}
+		oh := handler.NewOIDCHandler(provider, secret, cookie, a.Logger)
+		r.Get("/auth/login", oh.LoginHandler)
+		r.Get("/auth/callback", oh.CallbackHandler)
+	}
+
+	r.With(middlewares.TrustedSubnet(cfg.TrustedSubnet)).Handle("/metrics", promhttp.HandlerFor(a.registry, promhttp.HandlerOpts{}))
+	r.With(middlewares.TrustedSubnet(cfg.TrustedSubnet)).Handle("/debug/vars", a.vars.Handler("shortener"))
+	r.Get("/version", handler.NewVersionHandler(a.build).GetHandler)
+	r.With(middlewares.TrustedSubnet(cfg.TrustedSubnet)).Get("/health", handler.NewHealthHandler(a.audit).GetHandler)
+
+	password, err := adminPassword(cfg)
+	if err != nil {
+		return nil, err
+	}
+	adminAuth := middlewares.BasicAuth(adminRealm, cfg.AdminUser, password)
+
+	a.maintenance = middlewares.NewMaintenance(cfg.MaintenanceRetryAfter)
+	mh := handler.NewMaintenanceHandler(a.maintenance)
+	a.blocklist = middlewares.NewBlocklist()
+	if cfg.BlocklistFile != "" {
+		if err := a.blocklist.LoadFile(cfg.BlocklistFile); err != nil {
+			return nil, fmt.Errorf("failed to load blocklist: %w", err)
+		}
+	}
+	bh := handler.NewBlocklistHandler(a.blocklist)
+	r.Route("/api/internal", func(r chi.Router) {
+		r.Use(middlewares.TrustedSubnet(cfg.TrustedSubnet), adminAuth)
+		r.Get("/maintenance", mh.GetHandler)
+		r.Put("/maintenance", mh.SetHandler)
+		r.Get("/blocklist", bh.GetHandler)
+		r.Put("/blocklist", bh.SetHandler)
+		r.Method(http.MethodGet, "/log-level", a.level)
+		r.Method(http.MethodPut, "/log-level", a.level)
+	})
+	if a.dbAudit != nil {
+		ah := handler.NewAuditHandler(a.dbAudit)
+		r.Route("/api/admin", func(r chi.Router) {
+			r.Use(middlewares.TrustedSubnet(cfg.TrustedSubnet), adminAuth)
+			r.Get("/audit", ah.ListHandler)
+		})
+	}
+	if cfg.EnablePprof {
+		r.Route("/debug/pprof", func(r chi.Router) {
+			r.Use(middlewares.TrustedSubnet(cfg.TrustedSubnet), adminAuth)
+			r.Get("/", pprof.Index)
+			r.Get("/cmdline", pprof.Cmdline)
+			r.Get("/profile", pprof.Profile)
+			r.Get("/symbol", pprof.Symbol)
+			r.Get("/trace", pprof.Trace)
+			r.Get("/{profile}", pprof.Index)
+		})
+	}
+
+	shortenLimit := middlewares.IPRateLimit(cfg.ShortenRateLimit, cfg.ShortenRateBurst)
+	redirectLimit := middlewares.IPRateLimit(cfg.RedirectRateLimit, cfg.RedirectRateBurst)
+	redirectBlock := func(next http.Handler) http.Handler { return next }
+	if cfg.BlockRedirects {
+		redirectBlock = a.blocklist.Middleware
+	}
+
+	r.Route("/", func(r chi.Router) {
+		r.Group(func(r chi.Router) {
+			r.Use(middlewares.Timeout(cfg.RequestTimeout))
+			r.Get("/ping", h.PingDBHandler)
+			r.Get("/api/user/urls", h.GetUserURLsHandler)
+			r.Get("/api/user/urls/delete-jobs/{id}", h.GetDeleteJobHandler)
+			r.Group(func(r chi.Router) {
+				r.Use(a.maintenance.Middleware, a.blocklist.Middleware, shortenLimit, middlewares.MaxBodySize(cfg.ShortenBodyLimit))
+				r.Post("/api/shorten", h.ShortenJSONURLHandler)
+				r.Post("/", h.ShortenURLHandler)
+			})
+		})
+		r.Group(func(r chi.Router) {
+			r.Use(a.maintenance.Middleware, a.blocklist.Middleware, middlewares.Timeout(cfg.BatchTimeout), middlewares.MaxBodySize(cfg.BatchBodyLimit))
+			r.With(shortenLimit).Post("/api/shorten/batch", h.ShortenJSONURLBatchHandler)
+			r.Delete("/api/user/urls", h.BatchDeleteUserURLsHandler)
+		})
+		r.With(middlewares.Timeout(cfg.RedirectTimeout), redirectBlock, redirectLimit).Get("/{id}", h.RedirectHandler)
+	})
+	return r, nil
+}
@@ -0,0 +1,228 @@
+package app
+
+import (
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/Aleksey170999/go-shortener/internal/audit"
+	"github.com/Aleksey170999/go-shortener/internal/config"
+	"github.com/Aleksey170999/go-shortener/internal/middlewares"
+	"go.uber.org/zap"
+	"golang.org/x/crypto/acme"
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// authSecretSize is the length of the cookie signing key generated when none is configured.
+const authSecretSize = 32
+
+// authSecret returns the configured cookie signing key, or a random one if none
+// is configured. A random key invalidates all user cookies on restart and is
+// not shared between instances.
+func authSecret(cfg *config.Config, logger *zap.Logger) ([]byte, error) {
+	if cfg.AuthSecret != "" {
+		return []byte(cfg.AuthSecret), nil
+	}
+	logger.Warn("auth secret is not configured, user cookies will not survive a restart")
+	secret := make([]byte, authSecretSize)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, fmt.Errorf("failed to generate auth secret: %w", err)
+	}
+	return secret, nil
+}
+
+// cookieOptions returns the configured attributes of the user ID cookie.
+// Unless configured explicitly, Secure is set when clients reach the service
+// over HTTPS: when the server serves HTTPS itself, with its own or Let's
+// Encrypt certificates, or when BASE_URL is an HTTPS URL, as behind a proxy
+// terminating TLS. SameSite=None requires
+// Secure, so browsers would reject such a cookie without it.
+func cookieOptions(cfg *config.Config) (middlewares.CookieOptions, error) {
+	sameSite, err := middlewares.ParseSameSite(cfg.CookieSameSite)
+	if err != nil {
+		return middlewares.CookieOptions{}, fmt.Errorf("invalid cookie SameSite mode: %w", err)
+	}
+	secure := cfg.EnableHTTPS || len(cfg.AutocertDomains) > 0 ||
+		strings.HasPrefix(strings.ToLower(cfg.ReturnPrefix), "https://")
+	if cfg.CookieSecure != "" {
+		if secure, err = strconv.ParseBool(cfg.CookieSecure); err != nil {
+			return middlewares.CookieOptions{}, fmt.Errorf("invalid cookie Secure attribute: %w", err)
+		}
+	}
+	if sameSite == http.SameSiteNoneMode && !secure {
+		return middlewares.CookieOptions{}, errors.New("cookie SameSite=None requires the Secure attribute")
+	}
+	return middlewares.CookieOptions{
+		Secure:   secure,
+		SameSite: sameSite,
+		Domain:   cfg.CookieDomain,
+		MaxAge:   cfg.CookieMaxAge,
+	}, nil
+}
+
+// tlsConfig returns the TLS settings of the HTTPS server. Certificates are
+// obtained from Let's Encrypt by certManager if it is not nil, and are read
+// from TLS_CERT_FILE and TLS_KEY_FILE otherwise; Config.Validate ensures
+// they are set. The files are loaded here rather than by ListenAndServeTLS
+// so that an unreadable or invalid key pair is reported before the server
+// starts. Only TLS 1.2 and later with forward-secret AEAD cipher suites are
+// offered; TLS 1.3 suites are not configurable and are all secure.
+func tlsConfig(cfg *config.Config, certManager *autocert.Manager) (*tls.Config, error) {
+	tlsCfg := &tls.Config{
+		MinVersion:       tls.VersionTLS12,
+		CurvePreferences: []tls.CurveID{tls.X25519, tls.CurveP256},
+		CipherSuites: []uint16{
+			tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+			tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+			tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+			tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+			tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256,
+			tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256,
+		},
+	}
+	if certManager != nil {
+		tlsCfg.GetCertificate = certManager.GetCertificate
+		// acme-tls/1 lets Let's Encrypt validate over TLS-ALPN-01 as well;
+		// the server adds h2 and http/1.1 as configured.
+		tlsCfg.NextProtos = []string{acme.ALPNProto}
+		return tlsCfg, nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(cfg.TLSCertFile, cfg.TLSKeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load TLS certificate: %w", err)
+	}
+	tlsCfg.Certificates = []tls.Certificate{cert}
+	return tlsCfg, nil
+}
+
+// mtlsConfig returns the TLS settings of a server that requires client
+// certificates signed by a CA of TLS_CLIENT_CA_FILE, based on the server
+// settings tlsCfg. The handlers do not look at the certificate; every
+// client the CAs vouch for is trusted alike.
+func mtlsConfig(cfg *config.Config, tlsCfg *tls.Config) (*tls.Config, error) {
+	data, err := os.ReadFile(cfg.TLSClientCAFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read client CA file: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("no certificates found in client CA file %s", cfg.TLSClientCAFile)
+	}
+	mtlsCfg := tlsCfg.Clone()
+	mtlsCfg.ClientCAs = pool
+	mtlsCfg.ClientAuth = tls.RequireAndVerifyClientCert
+	return mtlsCfg, nil
+}
+
+// protocols returns the HTTP versions the servers accept. HTTP/2 is
+// negotiated with TLS clients over ALPN. h2c, unencrypted HTTP/2 with prior
+// knowledge, is for plain HTTP behind a trusted load balancer that speaks
+// HTTP/2 to its backends; the Upgrade-based h2c handshake is not supported.
+func protocols(cfg *config.Config) *http.Protocols {
+	p := new(http.Protocols)
+	p.SetHTTP1(true)
+	p.SetHTTP2(cfg.EnableHTTP2)
+	p.SetUnencryptedHTTP2(cfg.EnableH2C)
+	return p
+}
+
+// listenConfig returns how the servers bind their addresses. With REUSE_PORT
+// the sockets are opened with SO_REUSEPORT, so that during a rolling restart
+// the new instance binds the same ports while the old one is still serving;
+// the kernel spreads new connections over both until the old one closes its
+// sockets on shutdown. Both instances must run as the same user. Connections
+// still waiting in the backlog of a closed socket are reset, which the
+// clients see as a failed connection attempt.
+func listenConfig(cfg *config.Config) *net.ListenConfig {
+	lc := &net.ListenConfig{}
+	if cfg.ReusePort {
+		lc.Control = reusePort
+	}
+	return lc
+}
+
+// certManager returns the manager obtaining and renewing Let's Encrypt
+// certificates for AUTOCERT_DOMAINS, or nil if none are configured.
+// Certificates are only requested for the listed domains, so that arbitrary
+// SNI names cannot exhaust the Let's Encrypt rate limits.
+func certManager(cfg *config.Config) *autocert.Manager {
+	if len(cfg.AutocertDomains) == 0 {
+		return nil
+	}
+	return &autocert.Manager{
+		Prompt:     autocert.AcceptTOS,
+		HostPolicy: autocert.HostWhitelist(cfg.AutocertDomains...),
+		Cache:      autocert.DirCache(cfg.AutocertCacheDir),
+		Email:      cfg.AutocertEmail,
+	}
+}
+
+// adminRealm is the Basic auth realm of the admin and debug routes.
+const adminRealm = "go-shortener admin"
+
+// adminPassword returns the Basic auth password of the admin routes, read
+// from the configured secret file if there is one.
+func adminPassword(cfg *config.Config) (string, error) {
+	if cfg.AdminPasswordFile == "" {
+		return cfg.AdminPassword, nil
+	}
+	data, err := os.ReadFile(cfg.AdminPasswordFile)
+	if err != nil {
+		return "", fmt.Errorf("failed to read admin password file: %w", err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// auditWriterConfigs describes the audit writers enabled by individual
+// flags and environment variables; writers from the audit config file are
+// registered in addition to them.
+func auditWriterConfigs(cfg *config.Config) []audit.WriterConfig {
+	var writers []audit.WriterConfig
+	if cfg.AuditFile != "" {
+		writers = append(writers, audit.WriterConfig{Type: audit.WriterFile, Path: cfg.AuditFile})
+	}
+	if cfg.AuditURL != "" {
+		writers = append(writers, audit.WriterConfig{
+			Type:     audit.WriterRemote,
+			URL:      cfg.AuditURL,
+			Secret:   cfg.AuditURLSecret,
+			Encoding: cfg.AuditURLEncoding,
+		})
+	}
+	if cfg.AuditStdout != "" {
+		writers = append(writers, audit.WriterConfig{Type: cfg.AuditStdout})
+	}
+	if cfg.AuditNATSURL != "" {
+		writers = append(writers, audit.WriterConfig{
+			Type:     audit.WriterNATS,
+			URL:      cfg.AuditNATSURL,
+			Subject:  cfg.AuditNATSSubject,
+			Stream:   cfg.AuditNATSStream,
+			Encoding: cfg.AuditNATSEncoding,
+		})
+	}
+	if cfg.AuditSyslog != "" {
+		writers = append(writers, audit.WriterConfig{Type: audit.WriterSyslog, URL: cfg.AuditSyslog})
+	}
+	if cfg.AuditGELF != "" {
+		writers = append(writers, audit.WriterConfig{Type: audit.WriterGELF, URL: cfg.AuditGELF})
+	}
+	if cfg.AuditClickHouseURL != "" {
+		writers = append(writers, audit.WriterConfig{
+			Type:          audit.WriterClickHouse,
+			URL:           cfg.AuditClickHouseURL,
+			Table:         cfg.AuditClickHouseTable,
+			BatchSize:     cfg.AuditClickHouseBatchSize,
+			FlushInterval: cfg.AuditClickHouseFlushInterval.String(),
+		})
+	}
+	return writers
+}
@@ -2,26 +2,70 @@ package audit
 
 import (
 	"context"
+	"database/sql"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"net"
 	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
 	"sync/atomic"
 	"testing"
 	"time"
 
+	_ "github.com/lib/pq"
+	natstest "github.com/nats-io/nats-server/v2/test"
+	"github.com/nats-io/nats.go"
+	"github.com/pressly/goose/v3"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protowire"
 )
 
-// MockAuditWriter is a mock implementation of AuditWriter for testing
+// MockAuditWriter is a mock implementation of AuditWriter for testing.
+// It is safe for the concurrent writes of the delivery workers.
 type MockAuditWriter struct {
-	events []AuditEvent
+	mu        sync.Mutex
+	events    []AuditEvent
+	delivered chan struct{} // Signaled after every write if not nil; must be buffered
 }
 
-func (m *MockAuditWriter) Write(_ context.Context, e AuditEvent) {
+func (m *MockAuditWriter) Write(_ context.Context, e AuditEvent) error {
+	m.mu.Lock()
 	m.events = append(m.events, e)
+	m.mu.Unlock()
+	if m.delivered != nil {
+		m.delivered <- struct{}{}
+	}
+	return nil
+}
+
+// Events returns a copy of the events written so far.
+func (m *MockAuditWriter) Events() []AuditEvent {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]AuditEvent(nil), m.events...)
+}
+
+// waitDelivered waits until delivered has been signaled n times.
+func waitDelivered(t *testing.T, delivered <-chan struct{}, n int) {
+	t.Helper()
+	timeout := time.After(5 * time.Second)
+	for i := 0; i < n; i++ {
+		select {
+		case <-delivered:
+		case <-timeout:
+			t.Fatalf("only %d of %d events were delivered", i, n)
+		}
+	}
 }
 
 func TestAuditManager_LogEvent(t *testing.T) {
@@ -29,7 +73,7 @@ func TestAuditManager_LogEvent(t *testing.T) {
 	ctx := context.Background()
 
 	// Create a mock writer
-	mockWriter := &MockAuditWriter{}
+	mockWriter := &MockAuditWriter{delivered: make(chan struct{}, 1)}
 
 	// Create audit manager and register the mock writer
 	manager := NewAuditManager()
@@ -45,17 +89,19 @@ func TestAuditManager_LogEvent(t *testing.T) {
 	manager.LogEvent(ctx, action, userID, url)
 	afterLog := time.Now().Unix()
 
-	// Wait a bit for the async write to complete
-	time.Sleep(100 * time.Millisecond)
+	// Wait for the async write to complete
+	waitDelivered(t, mockWriter.delivered, 1)
 
 	// Verify the event was logged
-	require.Len(t, mockWriter.events, 1)
-	event := mockWriter.events[0]
+	events := mockWriter.Events()
+	require.Len(t, events, 1)
+	event := events[0]
 	assert.Equal(t, action, event.Action)
 	assert.Equal(t, userID, event.UserID)
 	assert.Equal(t, url, event.URL)
-	assert.GreaterOrEqual(t, event.TimeStamp, int(beforeLog))
-	assert.LessOrEqual(t, event.TimeStamp, int(afterLog))
+	assert.GreaterOrEqual(t, event.TimeStamp, beforeLog)
+	assert.LessOrEqual(t, event.TimeStamp, afterLog)
+	assert.Equal(t, CurrentSchemaVersion, event.SchemaVersion)
 }
 
 func TestAuditManager_ConcurrentWrites(t *testing.T) {
@@ -64,10 +110,11 @@ func TestAuditManager_ConcurrentWrites(t *testing.T) {
 
 	// Use an atomic counter to track the number of events written
 	var eventCount int32
+	delivered := make(chan struct{}, 500)
 
 	// Create multiple writers
 	for i := 0; i < 5; i++ {
-		writer := &countingWriter{count: &eventCount}
+		writer := &countingWriter{count: &eventCount, delivered: delivered}
 		manager.RegisterWriter(writer)
 	}
 
@@ -77,19 +124,22 @@ func TestAuditManager_ConcurrentWrites(t *testing.T) {
 	}
 
 	// Wait for all events to be processed
-	time.Sleep(500 * time.Millisecond)
+	waitDelivered(t, delivered, 500) // 100 events * 5 writers
 
-	// Verify all events were processed
-	assert.Equal(t, int32(500), eventCount) // 100 events * 5 writers
+	// Verify no event was written twice
+	assert.Equal(t, int32(500), atomic.LoadInt32(&eventCount))
 }
 
 // countingWriter is a test implementation of AuditWriter that counts events
 type countingWriter struct {
-	count *int32
+	count     *int32
+	delivered chan<- struct{} // Signaled after every write
 }
 
-func (w *countingWriter) Write(ctx context.Context, e AuditEvent) {
+func (w *countingWriter) Write(ctx context.Context, e AuditEvent) error {
 	atomic.AddInt32(w.count, 1)
+	w.delivered <- struct{}{}
+	return nil
 }
 
 func TestFileAudit_Write(t *testing.T) {
@@ -103,7 +153,7 @@ func TestFileAudit_Write(t *testing.T) {
 	// Test data
 	ctx := context.Background()
 	event := AuditEvent{
-		TimeStamp: int(time.Now().Unix()),
+		TimeStamp: time.Now().Unix(),
 		Action:    "test_action",
 		UserID:    "test_user",
 		URL:       "http://example.com",
@@ -133,6 +183,142 @@ func TestFileAudit_WriteError(t *testing.T) {
 	fileAudit.Write(context.Background(), AuditEvent{})
 }
 
+func TestStreamAudit_WriteBatch(t *testing.T) {
+	var out strings.Builder
+	streamAudit := NewStreamAudit(&out)
+
+	events := []AuditEvent{
+		{TimeStamp: 1, Action: "shorten", UserID: "user1", URL: "https://example.com"},
+		{TimeStamp: 2, Action: "follow", URL: "https://example.com"},
+	}
+	require.NoError(t, streamAudit.Write(context.Background(), events[0]))
+	require.NoError(t, streamAudit.WriteBatch(context.Background(), events[1:]))
+
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	require.Len(t, lines, 2)
+	for i, line := range lines {
+		var got AuditEvent
+		require.NoError(t, json.Unmarshal([]byte(line), &got))
+		assert.Equal(t, events[i], got)
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{
+		"server_address": "localhost:8080",
+		"audit": {"writers": [
+			{"type": "file", "path": "/var/log/audit.log"},
+			{"type": "remote", "url": "https://a.example.com", "secret": "s1"},
+			{"type": "remote", "url": "https://b.example.com"}
+		]}
+	}`), 0644))
+
+	cfg, err := LoadConfig(path)
+	require.NoError(t, err)
+	assert.Equal(t, []WriterConfig{
+		{Type: WriterFile, Path: "/var/log/audit.log"},
+		{Type: WriterRemote, URL: "https://a.example.com", Secret: "s1"},
+		{Type: WriterRemote, URL: "https://b.example.com"},
+	}, cfg.Writers)
+
+	require.NoError(t, os.WriteFile(path, []byte(`{"audit": {"writers": {}}}`), 0644))
+	_, err = LoadConfig(path)
+	assert.Error(t, err)
+
+	yamlPath := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(yamlPath, []byte(`
+server:
+  address: localhost:8080
+audit:
+  writers:
+    - type: clickhouse
+      url: http://clickhouse:8123
+      batch_size: 500
+      flush_interval: 10s
+`), 0644))
+	cfg, err = LoadConfig(yamlPath)
+	require.NoError(t, err)
+	assert.Equal(t, []WriterConfig{
+		{Type: WriterClickHouse, URL: "http://clickhouse:8123", BatchSize: 500, FlushInterval: "10s"},
+	}, cfg.Writers)
+}
+
+func TestNewWriter(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name    string
+		cfg     WriterConfig
+		want    AuditWriter
+		wantErr bool
+	}{
+		{name: "file", cfg: WriterConfig{Type: WriterFile, Path: "audit.log"}, want: &FileAudit{}},
+		{name: "stdout", cfg: WriterConfig{Type: WriterStdout}, want: &StreamAudit{}},
+		{name: "remote", cfg: WriterConfig{Type: WriterRemote, URL: "http://localhost"}, want: &RemoteAudit{}},
+		{name: "file without path", cfg: WriterConfig{Type: WriterFile}, wantErr: true},
+		{name: "remote without url", cfg: WriterConfig{Type: WriterRemote}, wantErr: true},
+		{name: "bad flush interval", cfg: WriterConfig{Type: WriterClickHouse, URL: "http://localhost", FlushInterval: "soon"}, wantErr: true},
+		{name: "unknown encoding", cfg: WriterConfig{Type: WriterRemote, URL: "http://localhost", Encoding: "avro"}, wantErr: true},
+		{name: "unknown type", cfg: WriterConfig{Type: "kafka", URL: "kafka://localhost"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			writer, err := NewWriter(ctx, tt.cfg)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.IsType(t, tt.want, writer)
+		})
+	}
+}
+
+func TestMarshalProto(t *testing.T) {
+	event := AuditEvent{
+		SchemaVersion: CurrentSchemaVersion,
+		TimeStamp:     1700000000,
+		Action:        "delete_batch",
+		UserID:        "user1",
+		IP:            "203.0.113.7",
+		Method:        http.MethodDelete,
+		Route:         "/api/user/urls",
+		Status:        http.StatusAccepted,
+		Outcome:       OutcomeSuccess,
+		Count:         3,
+	}
+	got, err := UnmarshalProto(MarshalProto(event))
+	require.NoError(t, err)
+	assert.Equal(t, event, got)
+
+	// Unknown fields, e.g. from a newer schema, are skipped.
+	data := protowire.AppendTag(MarshalProto(event), 99, protowire.BytesType)
+	data = protowire.AppendString(data, "future")
+	got, err = UnmarshalProto(data)
+	require.NoError(t, err)
+	assert.Equal(t, event, got)
+
+	_, err = UnmarshalProto([]byte{0x1a, 0x05, 'a'})
+	assert.Error(t, err)
+}
+
+func TestRemoteAudit_WriteProtobuf(t *testing.T) {
+	received := make(chan AuditEvent, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "application/x-protobuf", r.Header.Get("Content-Type"))
+		body, _ := io.ReadAll(r.Body)
+		e, err := UnmarshalProto(body)
+		assert.NoError(t, err)
+		received <- e
+	}))
+	defer server.Close()
+
+	remoteAudit := NewRemoteAudit(server.URL, WithEncoding(EncodingProtobuf))
+	event := AuditEvent{SchemaVersion: CurrentSchemaVersion, TimeStamp: 1700000000, Action: "shorten", URL: "https://example.com"}
+	require.NoError(t, remoteAudit.Write(context.Background(), event))
+	assert.Equal(t, event, <-received)
+}
+
 func TestRemoteAudit_Write(t *testing.T) {
 	// Start a test HTTP server
 	server := startTestHTTPServer(t)
@@ -144,7 +330,7 @@ func TestRemoteAudit_Write(t *testing.T) {
 	// Test data
 	ctx := context.Background()
 	event := AuditEvent{
-		TimeStamp: int(time.Now().Unix()),
+		TimeStamp: time.Now().Unix(),
 		Action:    "test_action",
 		UserID:    "test_user",
 		URL:       "http://example.com",
@@ -223,3 +409,562 @@ func (s *testHTTPServer) Close() {
 		s.server.Close()
 	}
 }
+
+func TestNATSAudit_Write(t *testing.T) {
+	opts := natstest.DefaultTestOptions
+	opts.Port = -1
+	opts.JetStream = true
+	opts.StoreDir = t.TempDir()
+	srv := natstest.RunServer(&opts)
+	defer srv.Shutdown()
+
+	ctx := context.Background()
+	natsAudit, err := NewNATSAudit(ctx, NATSConfig{
+		URL:     srv.ClientURL(),
+		Subject: "audit.events",
+		Stream:  "AUDIT",
+	})
+	require.NoError(t, err)
+	defer natsAudit.Close(ctx)
+
+	event := AuditEvent{
+		TimeStamp: time.Now().Unix(),
+		Action:    "test_action",
+		UserID:    "test_user",
+		URL:       "http://example.com",
+	}
+	natsAudit.Write(ctx, event)
+
+	stream, err := natsAudit.js.Stream(ctx, "AUDIT")
+	require.NoError(t, err)
+	msg, err := stream.GetLastMsgForSubject(ctx, "audit.events")
+	require.NoError(t, err)
+	assert.NotEmpty(t, msg.Header.Get(nats.MsgIdHdr))
+
+	var published AuditEvent
+	require.NoError(t, json.Unmarshal(msg.Data, &published))
+	assert.Equal(t, event, published)
+}
+
+func TestSyslogAudit_Write(t *testing.T) {
+	event := AuditEvent{
+		TimeStamp: 1700000000,
+		Action:    "shorten",
+		UserID:    "test_user",
+		URL:       `http://example.com/"quoted"]`,
+	}
+	wantPrefix := "<110>1 2023-11-14T22:13:20Z "
+	wantSD := `[audit@32473 action="shorten" user_id="test_user" url="http://example.com/\"quoted\"\]"]`
+
+	t.Run("udp", func(t *testing.T) {
+		conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+		require.NoError(t, err)
+		defer conn.Close()
+
+		syslogAudit, err := NewSyslogAudit("udp://" + conn.LocalAddr().String())
+		require.NoError(t, err)
+		defer syslogAudit.Close(context.Background())
+		syslogAudit.Write(context.Background(), event)
+
+		buf := make([]byte, 4096)
+		require.NoError(t, conn.SetReadDeadline(time.Now().Add(time.Second)))
+		n, _, err := conn.ReadFrom(buf)
+		require.NoError(t, err)
+		msg := string(buf[:n])
+		assert.True(t, strings.HasPrefix(msg, wantPrefix), msg)
+		assert.Contains(t, msg, " go-shortener ")
+		assert.Contains(t, msg, " audit "+wantSD+" {")
+	})
+
+	t.Run("tcp", func(t *testing.T) {
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		defer ln.Close()
+		received := make(chan string, 1)
+		go func() {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+			data, _ := io.ReadAll(conn)
+			received <- string(data)
+		}()
+
+		syslogAudit, err := NewSyslogAudit("tcp://" + ln.Addr().String())
+		require.NoError(t, err)
+		syslogAudit.Write(context.Background(), event)
+		require.NoError(t, syslogAudit.Close(context.Background()))
+
+		data := <-received
+		length, msg, ok := strings.Cut(data, " ")
+		require.True(t, ok)
+		assert.Equal(t, strconv.Itoa(len(msg)), length)
+		assert.True(t, strings.HasPrefix(msg, wantPrefix), msg)
+	})
+
+	_, err := NewSyslogAudit("http://example.com")
+	assert.Error(t, err)
+}
+
+func TestDBAudit(t *testing.T) {
+	// Skip if test database is not configured
+	if os.Getenv("TEST_DATABASE_DSN") == "" {
+		t.Skip("Skipping test as TEST_DATABASE_DSN is not set")
+	}
+
+	db, err := sql.Open("postgres", os.Getenv("TEST_DATABASE_DSN"))
+	require.NoError(t, err)
+	defer db.Close()
+	require.NoError(t, goose.SetDialect("postgres"))
+	require.NoError(t, goose.Up(db, "../../migrations"))
+	_, err = db.Exec(`TRUNCATE audit_events`)
+	require.NoError(t, err)
+
+	dbAudit := NewDBAudit(db, 0)
+	defer dbAudit.Close(context.Background())
+
+	ctx := context.Background()
+	now := time.Now()
+	dbAudit.Write(ctx, AuditEvent{TimeStamp: now.Add(-48 * time.Hour).Unix(), Action: "old", UserID: "test_user"})
+	dbAudit.Write(ctx, AuditEvent{TimeStamp: now.Unix(), Action: "shorten", UserID: "test_user", URL: "http://example.com", Status: 201})
+
+	var count int
+	require.NoError(t, db.QueryRow(`SELECT COUNT(*) FROM audit_events WHERE user_id = $1`, "test_user").Scan(&count))
+	assert.Equal(t, 2, count)
+
+	removed, err := dbAudit.DeleteBefore(ctx, now.Add(-24*time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), removed)
+
+	events, err := dbAudit.Search(ctx, Query{UserID: "test_user", From: now.Add(-time.Hour), Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, "shorten", events[0].Action)
+	assert.Equal(t, "http://example.com", events[0].URL)
+	assert.Equal(t, 201, events[0].Status)
+}
+
+func TestGELFAudit_Write(t *testing.T) {
+	event := AuditEvent{
+		SchemaVersion: CurrentSchemaVersion,
+		TimeStamp:     1700000000,
+		Action:        "shorten",
+		UserID:        "test_user",
+		URL:           "http://example.com",
+		Status:        http.StatusCreated,
+	}
+	checkMessage := func(t *testing.T, data []byte) map[string]any {
+		t.Helper()
+		var msg map[string]any
+		require.NoError(t, json.Unmarshal(data, &msg))
+		assert.Equal(t, "1.1", msg["version"])
+		assert.Equal(t, "audit: shorten", msg["short_message"])
+		assert.EqualValues(t, 1700000000, msg["timestamp"])
+		assert.Equal(t, "test_user", msg["_user_id"])
+		assert.EqualValues(t, http.StatusCreated, msg["_status"])
+		assert.NotContains(t, msg, "_ip")
+		return msg
+	}
+
+	t.Run("udp", func(t *testing.T) {
+		conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+		require.NoError(t, err)
+		defer conn.Close()
+
+		gelfAudit, err := NewGELFAudit("udp://" + conn.LocalAddr().String())
+		require.NoError(t, err)
+		defer gelfAudit.Close(context.Background())
+		require.NoError(t, gelfAudit.Write(context.Background(), event))
+
+		buf := make([]byte, 65536)
+		require.NoError(t, conn.SetReadDeadline(time.Now().Add(time.Second)))
+		n, _, err := conn.ReadFrom(buf)
+		require.NoError(t, err)
+		checkMessage(t, buf[:n])
+	})
+
+	t.Run("udp chunked", func(t *testing.T) {
+		conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+		require.NoError(t, err)
+		defer conn.Close()
+
+		gelfAudit, err := NewGELFAudit("udp://" + conn.LocalAddr().String())
+		require.NoError(t, err)
+		defer gelfAudit.Close(context.Background())
+		long := event
+		long.URL = "http://example.com/" + strings.Repeat("a", 3*gelfChunkSize)
+		require.NoError(t, gelfAudit.Write(context.Background(), long))
+
+		chunks := make(map[byte][]byte)
+		var id []byte
+		buf := make([]byte, 65536)
+		for count := 1; len(chunks) < count; {
+			require.NoError(t, conn.SetReadDeadline(time.Now().Add(time.Second)))
+			n, _, err := conn.ReadFrom(buf)
+			require.NoError(t, err)
+			chunk := append([]byte(nil), buf[:n]...)
+			require.LessOrEqual(t, len(chunk), gelfChunkSize)
+			require.Equal(t, gelfChunkMagic[:], chunk[:2])
+			if id == nil {
+				id = chunk[2:10]
+			}
+			assert.Equal(t, id, chunk[2:10])
+			chunks[chunk[10]] = chunk[gelfChunkHeaderSize:]
+			count = int(chunk[11])
+		}
+		require.Len(t, chunks, 4)
+		var data []byte
+		for seq := byte(0); seq < byte(len(chunks)); seq++ {
+			data = append(data, chunks[seq]...)
+		}
+		assert.Equal(t, long.URL, checkMessage(t, data)["_url"])
+	})
+
+	t.Run("tcp", func(t *testing.T) {
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		defer ln.Close()
+		received := make(chan []byte, 1)
+		go func() {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+			data, _ := io.ReadAll(conn)
+			received <- data
+		}()
+
+		gelfAudit, err := NewGELFAudit("tcp://" + ln.Addr().String())
+		require.NoError(t, err)
+		require.NoError(t, gelfAudit.Write(context.Background(), event))
+		require.NoError(t, gelfAudit.Close(context.Background()))
+
+		data := <-received
+		require.NotEmpty(t, data)
+		assert.Equal(t, byte(0), data[len(data)-1], "messages must be null-terminated")
+		checkMessage(t, data[:len(data)-1])
+	})
+
+	t.Run("unsupported scheme", func(t *testing.T) {
+		_, err := NewGELFAudit("http://graylog:12201")
+		assert.Error(t, err)
+	})
+}
+
+func TestClickHouseAudit_Write(t *testing.T) {
+	batches := make(chan []AuditEvent, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "INSERT INTO audit.events FORMAT JSONEachRow", r.URL.Query().Get("query"))
+		assert.Equal(t, "1", r.URL.Query().Get("async_insert"))
+		user, password, ok := r.BasicAuth()
+		assert.True(t, ok)
+		assert.Equal(t, "writer", user)
+		assert.Equal(t, "s3cret", password)
+
+		var batch []AuditEvent
+		dec := json.NewDecoder(r.Body)
+		for dec.More() {
+			var e AuditEvent
+			assert.NoError(t, dec.Decode(&e))
+			batch = append(batch, e)
+		}
+		batches <- batch
+	}))
+	defer server.Close()
+
+	clickHouseAudit, err := NewClickHouseAudit(ClickHouseConfig{
+		URL:           strings.Replace(server.URL, "http://", "http://writer:s3cret@", 1),
+		Table:         "audit.events",
+		BatchSize:     2,
+		FlushInterval: time.Hour,
+	})
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	for _, action := range []string{"a1", "a2", "a3"} {
+		clickHouseAudit.Write(ctx, AuditEvent{TimeStamp: time.Now().Unix(), Action: action, UserID: "test_user"})
+	}
+
+	select {
+	case batch := <-batches:
+		require.Len(t, batch, 2)
+		assert.Equal(t, "a1", batch[0].Action)
+		assert.Equal(t, "a2", batch[1].Action)
+	case <-time.After(time.Second):
+		require.FailNow(t, "full batch was not flushed")
+	}
+
+	// The incomplete batch is flushed on Close.
+	require.NoError(t, clickHouseAudit.Close(ctx))
+	batch := <-batches
+	require.Len(t, batch, 1)
+	assert.Equal(t, "a3", batch[0].Action)
+}
+
+func TestAuditManager_ClickHouseStats(t *testing.T) {
+	var failing atomic.Bool
+	failing.Store(true)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if failing.Load() {
+			http.Error(w, "too many parts", http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	clickHouseAudit, err := NewClickHouseAudit(ClickHouseConfig{URL: server.URL, Table: "audit_events", BatchSize: 2, FlushInterval: time.Hour})
+	require.NoError(t, err)
+	manager := NewAuditManager()
+	manager.RegisterWriter(clickHouseAudit)
+
+	ctx := context.Background()
+	manager.LogEvent(ctx, "a1", "user1", "")
+	manager.LogEvent(ctx, "a2", "user1", "")
+	require.Eventually(t, func() bool { return manager.Stats()[0].LastError != "" }, 5*time.Second, 10*time.Millisecond,
+		"the failed insert of the full batch is reported")
+	stats := manager.Stats()[0]
+	assert.Zero(t, stats.Delivered, "buffered events do not count as delivered")
+	assert.Zero(t, stats.Failed, "the events are kept for a retry")
+	assert.False(t, stats.Healthy())
+
+	failing.Store(false)
+	require.NoError(t, manager.Close(ctx))
+	stats = manager.Stats()[0]
+	assert.Equal(t, uint64(2), stats.Delivered, "the retried insert is reported")
+	assert.Zero(t, stats.Failed)
+	assert.True(t, stats.Healthy())
+}
+
+func TestAuditManager_ClickHouseLostOnClose(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	clickHouseAudit, err := NewClickHouseAudit(ClickHouseConfig{URL: server.URL, Table: "audit_events", BatchSize: 10, FlushInterval: time.Hour})
+	require.NoError(t, err)
+	manager := NewAuditManager()
+	manager.RegisterWriter(clickHouseAudit)
+
+	ctx := context.Background()
+	manager.LogEvent(ctx, "a1", "user1", "")
+	manager.LogEvent(ctx, "a2", "user1", "")
+	assert.Error(t, manager.Close(ctx))
+	stats := manager.Stats()[0]
+	assert.Zero(t, stats.Delivered)
+	assert.Equal(t, uint64(2), stats.Failed, "events that cannot be inserted on Close are lost")
+	assert.Contains(t, stats.LastError, "503 Service Unavailable")
+}
+
+// blockingWriter reports received events and blocks until released.
+type blockingWriter struct {
+	received chan AuditEvent
+	release  chan struct{}
+}
+
+func (w *blockingWriter) Write(_ context.Context, e AuditEvent) error {
+	w.received <- e
+	<-w.release
+	return nil
+}
+
+func TestAuditManager_DropsWhenQueueFull(t *testing.T) {
+	writer := &blockingWriter{received: make(chan AuditEvent, 10), release: make(chan struct{})}
+	manager := NewAuditManager(WithQueueSize(1), WithWorkers(1))
+	manager.RegisterWriter(writer)
+	ctx := context.Background()
+
+	manager.LogEvent(ctx, "first", "user1", "")
+	<-writer.received // The only worker is now busy
+	manager.LogEvent(ctx, "queued", "user1", "")
+	manager.LogEvent(ctx, "dropped", "user1", "")
+	assert.Equal(t, uint64(1), manager.Dropped())
+
+	close(writer.release)
+	assert.Equal(t, "queued", (<-writer.received).Action)
+}
+
+// batchRecorder is a BatchWriter sending each batch to a channel. Its first
+// WriteBatch call blocks until release is closed.
+type batchRecorder struct {
+	batches chan []AuditEvent
+	release chan struct{}
+	calls   int
+}
+
+func (b *batchRecorder) Write(ctx context.Context, e AuditEvent) error {
+	return b.WriteBatch(ctx, []AuditEvent{e})
+}
+
+func (b *batchRecorder) WriteBatch(_ context.Context, events []AuditEvent) error {
+	b.batches <- append([]AuditEvent(nil), events...)
+	if b.calls++; b.calls == 1 {
+		<-b.release
+	}
+	return nil
+}
+
+func TestAuditManager_BatchWriter(t *testing.T) {
+	writer := &batchRecorder{batches: make(chan []AuditEvent, 10), release: make(chan struct{})}
+	manager := NewAuditManager(WithWorkers(1), WithBatchSize(3))
+	manager.RegisterWriter(writer)
+
+	// Hold the worker in its first delivery so that later events queue up.
+	manager.LogEvent(context.Background(), "e0", "user1", "")
+	require.Len(t, <-writer.batches, 1)
+	for i := 1; i <= 4; i++ {
+		manager.LogEvent(context.Background(), fmt.Sprintf("e%d", i), "user1", "")
+	}
+	close(writer.release)
+
+	var batches [][]string
+	for len(batches) < 2 {
+		select {
+		case batch := <-writer.batches:
+			var actions []string
+			for _, e := range batch {
+				actions = append(actions, e.Action)
+			}
+			batches = append(batches, actions)
+		case <-time.After(time.Second):
+			require.FailNow(t, "events were not delivered")
+		}
+	}
+	assert.Equal(t, [][]string{{"e1", "e2", "e3"}, {"e4"}}, batches)
+}
+
+// failingWriter fails every event whose action is "bad".
+type failingWriter struct{}
+
+func (failingWriter) Write(_ context.Context, e AuditEvent) error {
+	if e.Action == "bad" {
+		return errors.New("destination unavailable")
+	}
+	return nil
+}
+
+func TestAuditManager_Stats(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		reported []string
+	)
+	manager := NewAuditManager(WithWorkers(1), WithErrorHandler(func(writer string, events int, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		reported = append(reported, fmt.Sprintf("%s: %d: %v", writer, events, err))
+	}))
+	manager.RegisterWriter(failingWriter{})
+	manager.RegisterWriter(&MockAuditWriter{})
+	manager.RegisterWriter(failingWriter{})
+
+	ctx := context.Background()
+	manager.LogEvent(ctx, "good", "user1", "")
+	manager.LogEvent(ctx, "bad", "user1", "")
+	require.NoError(t, manager.Close(ctx))
+
+	stats := manager.Stats()
+	require.Len(t, stats, 3)
+	assert.Equal(t, "failingWriter", stats[0].Writer)
+	assert.Equal(t, "MockAuditWriter", stats[1].Writer)
+	assert.Equal(t, "failingWriter#2", stats[2].Writer)
+
+	assert.Equal(t, uint64(1), stats[0].Delivered)
+	assert.Equal(t, uint64(1), stats[0].Failed)
+	assert.Equal(t, "destination unavailable", stats[0].LastError)
+	assert.False(t, stats[0].LastErrorAt.IsZero())
+	assert.False(t, stats[0].Healthy())
+
+	assert.Equal(t, uint64(2), stats[1].Delivered)
+	assert.Zero(t, stats[1].Failed)
+	assert.ElementsMatch(t, []string{
+		"failingWriter: 1: destination unavailable",
+		"failingWriter#2: 1: destination unavailable",
+	}, reported)
+	assert.Empty(t, stats[1].LastError)
+	assert.True(t, stats[1].Healthy())
+}
+
+func TestCollector(t *testing.T) {
+	manager := NewAuditManager(WithWorkers(1))
+	manager.RegisterWriter(failingWriter{})
+
+	ctx := context.Background()
+	manager.LogEvent(ctx, "good", "user1", "")
+	manager.LogEvent(ctx, "bad", "user1", "")
+	manager.LogEvent(ctx, "bad", "user1", "")
+	require.NoError(t, manager.Close(ctx))
+
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(NewCollector(manager))
+	expected := `
+# HELP audit_events_delivered_total Number of audit events delivered by writer.
+# TYPE audit_events_delivered_total counter
+audit_events_delivered_total{writer="failingWriter"} 1
+# HELP audit_events_failed_total Number of audit events a writer failed to deliver.
+# TYPE audit_events_failed_total counter
+audit_events_failed_total{writer="failingWriter"} 2
+`
+	assert.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(expected),
+		"audit_events_delivered_total", "audit_events_failed_total"))
+	assert.Equal(t, 6, testutil.CollectAndCount(reg))
+}
+
+// closeRecorder records events and whether it was closed.
+type closeRecorder struct {
+	MockAuditWriter
+	closed bool
+}
+
+func (c *closeRecorder) Close(_ context.Context) error {
+	c.closed = true
+	return nil
+}
+
+func TestAuditManager_Close(t *testing.T) {
+	writer := &closeRecorder{}
+	manager := NewAuditManager(WithWorkers(1))
+	manager.RegisterWriter(writer)
+
+	ctx := context.Background()
+	for i := 0; i < 10; i++ {
+		manager.LogEvent(ctx, "queued", "user1", "")
+	}
+	require.NoError(t, manager.Close(ctx))
+	assert.Len(t, writer.Events(), 10, "queued events must be delivered before Close returns")
+	assert.True(t, writer.closed)
+
+	manager.LogEvent(ctx, "late", "user1", "")
+	assert.Equal(t, uint64(1), manager.Dropped())
+	assert.NoError(t, manager.Close(ctx))
+}
+
+func TestRemoteAudit_Signed(t *testing.T) {
+	secret := []byte("shared-secret")
+	verified := make(chan error, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		verified <- VerifySignature(secret, r.Header.Get(TimestampHeader), r.Header.Get(SignatureHeader), body, time.Minute, time.Now())
+	}))
+	defer server.Close()
+
+	remoteAudit := NewRemoteAudit(server.URL, WithSigningSecret(secret))
+	remoteAudit.Write(context.Background(), AuditEvent{TimeStamp: time.Now().Unix(), Action: "test_action"})
+	assert.NoError(t, <-verified)
+}
+
+func TestVerifySignature(t *testing.T) {
+	secret := []byte("shared-secret")
+	body := []byte(`{"action":"shorten"}`)
+	now := time.Unix(1700000000, 0)
+	signature := Sign(secret, now.Unix(), body)
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+
+	assert.NoError(t, VerifySignature(secret, timestamp, signature, body, time.Minute, now.Add(30*time.Second)))
+	assert.ErrorIs(t, VerifySignature(secret, timestamp, signature, body, time.Minute, now.Add(2*time.Minute)), ErrStaleSignature)
+	assert.ErrorIs(t, VerifySignature([]byte("other"), timestamp, signature, body, time.Minute, now), ErrInvalidSignature)
+	assert.ErrorIs(t, VerifySignature(secret, timestamp, signature, []byte(`{"action":"delete"}`), time.Minute, now), ErrInvalidSignature)
+	assert.ErrorIs(t, VerifySignature(secret, "1700000001", signature, body, time.Minute, now), ErrInvalidSignature)
+	assert.ErrorIs(t, VerifySignature(secret, timestamp, "", body, time.Minute, now), ErrInvalidSignature)
+}
@@ -3,16 +3,85 @@ package audit
 import "context"
 
 // AuditEvent represents an audit log entry containing information about a user action.
-// It includes the timestamp, action type, user ID, and the URL involved.
+// It includes the timestamp, action type, user ID, the URL involved and the client IP.
+// Events recorded for HTTP requests also carry the method, route and outcome,
+// and events of operations on several links the number of links affected.
 type AuditEvent struct {
-	TimeStamp int    `json:"ts"`      // Unix timestamp of when the event occurred
-	Action    string `json:"action"`  // The action performed (e.g., "create", "delete", "update")
-	UserID    string `json:"user_id"` // ID of the user who performed the action
-	URL       string `json:"url"`     // The URL that was affected by the action
+	SchemaVersion int    `json:"schema_version"`    // Version of the event schema, CurrentSchemaVersion for new events
+	TimeStamp     int64  `json:"ts"`                // Unix timestamp of when the event occurred
+	Action        string `json:"action"`            // The action performed (e.g., "create", "delete", "update")
+	UserID        string `json:"user_id"`           // ID of the user who performed the action
+	URL           string `json:"url"`               // The URL that was affected by the action
+	IP            string `json:"ip,omitempty"`      // IP address of the client, if known
+	Method        string `json:"method,omitempty"`  // HTTP method of the request
+	Route         string `json:"route,omitempty"`   // Route pattern of the request (e.g., "/api/shorten")
+	Status        int    `json:"status,omitempty"`  // HTTP status of the response
+	Outcome       string `json:"outcome,omitempty"` // OutcomeSuccess or OutcomeFailure
+	Count         int    `json:"count,omitempty"`   // Number of links affected by a batch operation
+}
+
+// CurrentSchemaVersion is the version of the AuditEvent schema written by
+// this build. It is raised when the meaning of existing fields changes;
+// adding fields keeps the version. Events without a version predate it.
+const CurrentSchemaVersion = 1
+
+// Outcomes of audited requests.
+const (
+	// OutcomeSuccess marks a request answered with a status below 400
+	OutcomeSuccess = "success"
+	// OutcomeFailure marks a request answered with an error status
+	OutcomeFailure = "failure"
+)
+
+// clientIPKey is the context key under which the client IP is stored.
+type clientIPKey struct{}
+
+// ContextWithClientIP returns a copy of ctx carrying the IP address of the
+// client that triggered the action. LogEvent records it in the event.
+func ContextWithClientIP(ctx context.Context, ip string) context.Context {
+	return context.WithValue(ctx, clientIPKey{}, ip)
+}
+
+// Annotation holds the details of an audited request that only its handler
+// knows. The audit middleware stores an empty Annotation in the request
+// context and completes the event from it once the handler has returned.
+type Annotation struct {
+	Action string // The action performed, overrides the default "<method> <route>"
+	URL    string // The URL that was affected by the action
+	Count  int    // The number of links affected by a batch action
+}
+
+// annotationKey is the context key under which the request's Annotation is stored.
+type annotationKey struct{}
+
+// ContextWithAnnotation returns a copy of ctx carrying a, which Annotate fills in.
+func ContextWithAnnotation(ctx context.Context, a *Annotation) context.Context {
+	return context.WithValue(ctx, annotationKey{}, a)
+}
+
+// Annotate records the action and the affected URL of the request handled
+// under ctx. Annotated requests are audited even if they do not change state.
+// It does nothing if ctx carries no Annotation, i.e. requests are not audited.
+func Annotate(ctx context.Context, action, url string) {
+	if a, ok := ctx.Value(annotationKey{}).(*Annotation); ok {
+		a.Action = action
+		a.URL = url
+	}
+}
+
+// AnnotateBatch records the action of a request handled under ctx that
+// affects count links at once, like Annotate.
+func AnnotateBatch(ctx context.Context, action string, count int) {
+	if a, ok := ctx.Value(annotationKey{}).(*Annotation); ok {
+		a.Action = action
+		a.Count = count
+	}
 }
 
 // AuditWriter defines the interface for writing audit events to a specific destination.
 // Implementations should handle the actual writing logic, such as file I/O or network requests.
+// Write reports whether the event was delivered; AuditManager counts the
+// outcomes per writer (see AuditManager.Stats).
 type AuditWriter interface {
-	Write(ctx context.Context, e AuditEvent)
+	Write(ctx context.Context, e AuditEvent) error
 }
@@ -0,0 +1,155 @@
+package audit
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Defaults of the optional WriterConfig fields.
+const (
+	defaultNATSSubject     = "audit.events"
+	defaultNATSStream      = "AUDIT"
+	defaultClickHouseTable = "audit_events"
+)
+
+// Writer types accepted in WriterConfig.Type.
+const (
+	WriterFile       = "file"       // FileAudit, Path is the log file
+	WriterRemote     = "remote"     // RemoteAudit, URL is the endpoint
+	WriterStdout     = "stdout"     // StreamAudit on standard output
+	WriterStderr     = "stderr"     // StreamAudit on standard error
+	WriterNATS       = "nats"       // NATSAudit, URL is the server
+	WriterSyslog     = "syslog"     // SyslogAudit, URL is the server
+	WriterClickHouse = "clickhouse" // ClickHouseAudit, URL is the HTTP interface
+	WriterGELF       = "gelf"       // GELFAudit, URL is the GELF input
+)
+
+// Config is the "audit" section of an audit configuration file.
+type Config struct {
+	// Writers lists the writers to register; several writers of the same
+	// type, e.g. two remote endpoints, are allowed
+	Writers []WriterConfig `json:"writers" yaml:"writers"`
+}
+
+// WriterConfig describes a single audit writer. Which fields apply depends
+// on Type; the others are ignored.
+type WriterConfig struct {
+	// Type is one of the Writer* constants
+	Type string `json:"type" yaml:"type"`
+
+	// Path is the log file of a file writer
+	Path string `json:"path,omitempty" yaml:"path,omitempty"`
+
+	// URL is the destination of remote, nats, syslog, clickhouse and gelf writers
+	URL string `json:"url,omitempty" yaml:"url,omitempty"`
+
+	// Secret signs the payloads of a remote writer, empty sends them unsigned
+	Secret string `json:"secret,omitempty" yaml:"secret,omitempty"`
+
+	// Encoding is "json" (the default) or "protobuf" for remote and nats writers
+	Encoding string `json:"encoding,omitempty" yaml:"encoding,omitempty"`
+
+	// Subject and Stream are the JetStream subject and stream of a nats
+	// writer, "audit.events" and "AUDIT" if empty
+	Subject string `json:"subject,omitempty" yaml:"subject,omitempty"`
+	Stream  string `json:"stream,omitempty" yaml:"stream,omitempty"`
+
+	// Table, BatchSize and FlushInterval configure a clickhouse writer; the
+	// table defaults to "audit_events" and the interval is a duration string
+	// such as "5s"
+	Table         string `json:"table,omitempty" yaml:"table,omitempty"`
+	BatchSize     int    `json:"batch_size,omitempty" yaml:"batch_size,omitempty"`
+	FlushInterval string `json:"flush_interval,omitempty" yaml:"flush_interval,omitempty"`
+}
+
+// LoadConfig reads the "audit" section of the file at path, YAML if its
+// extension is .yaml or .yml and JSON otherwise, e.g.
+//
+//	{"audit": {"writers": [
+//	    {"type": "file", "path": "/var/log/shortener/audit.log"},
+//	    {"type": "remote", "url": "https://siem.example.com/events", "secret": "..."},
+//	    {"type": "stdout"}
+//	]}}
+//
+// Other top-level keys are ignored.
+func LoadConfig(path string) (Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Config{}, err
+	}
+	var file struct {
+		Audit Config `json:"audit" yaml:"audit"`
+	}
+	if ext := strings.ToLower(filepath.Ext(path)); ext == ".yaml" || ext == ".yml" {
+		err = yaml.Unmarshal(data, &file)
+	} else {
+		err = json.Unmarshal(data, &file)
+	}
+	if err != nil {
+		return Config{}, fmt.Errorf("parse %s: %w", path, err)
+	}
+	return file.Audit, nil
+}
+
+// NewWriter creates the writer described by cfg. Writers that connect to
+// their destination on creation, such as nats and syslog, fail if it is
+// unreachable.
+func NewWriter(ctx context.Context, cfg WriterConfig) (AuditWriter, error) {
+	switch cfg.Type {
+	case WriterFile:
+		if cfg.Path == "" {
+			return nil, fmt.Errorf("%s audit writer: path is required", cfg.Type)
+		}
+		return NewFileAudit(cfg.Path), nil
+	case WriterStdout:
+		return NewStreamAudit(os.Stdout), nil
+	case WriterStderr:
+		return NewStreamAudit(os.Stderr), nil
+	}
+
+	if cfg.URL == "" {
+		return nil, fmt.Errorf("%s audit writer: url is required", cfg.Type)
+	}
+	encoding, err := ParseEncoding(cfg.Encoding)
+	if err != nil {
+		return nil, fmt.Errorf("%s audit writer: %w", cfg.Type, err)
+	}
+	switch cfg.Type {
+	case WriterRemote:
+		return NewRemoteAudit(cfg.URL, WithSigningSecret([]byte(cfg.Secret)), WithEncoding(encoding)), nil
+	case WriterNATS:
+		return NewNATSAudit(ctx, NATSConfig{
+			URL:      cfg.URL,
+			Subject:  cmp.Or(cfg.Subject, defaultNATSSubject),
+			Stream:   cmp.Or(cfg.Stream, defaultNATSStream),
+			Encoding: encoding,
+		})
+	case WriterSyslog:
+		return NewSyslogAudit(cfg.URL)
+	case WriterGELF:
+		return NewGELFAudit(cfg.URL)
+	case WriterClickHouse:
+		var interval time.Duration
+		if cfg.FlushInterval != "" {
+			if interval, err = time.ParseDuration(cfg.FlushInterval); err != nil {
+				return nil, fmt.Errorf("%s audit writer: invalid flush_interval: %w", cfg.Type, err)
+			}
+		}
+		return NewClickHouseAudit(ClickHouseConfig{
+			URL:           cfg.URL,
+			Table:         cmp.Or(cfg.Table, defaultClickHouseTable),
+			BatchSize:     cfg.BatchSize,
+			FlushInterval: interval,
+		})
+	default:
+		return nil, fmt.Errorf("unknown audit writer type %q", cfg.Type)
+	}
+}
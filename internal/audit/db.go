@@ -0,0 +1,173 @@
+package audit
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+// dbCleanupInterval is how often DBAudit removes events past their retention.
+const dbCleanupInterval = time.Hour
+
+// dbAuditColumns is the number of audit_events columns set per inserted event.
+const dbAuditColumns = 10
+
+// DBAudit implements the AuditWriter interface for storing audit events in
+// the audit_events table of the application database, so that audit history
+// can be queried with SQL. The table is created by the database migrations.
+//
+// Events older than the retention period are deleted every dbCleanupInterval
+// until Close is called.
+type DBAudit struct {
+	db        *sql.DB
+	retention time.Duration // How long events are kept, non-positive keeps them forever
+	stopCh    chan struct{}
+	wg        sync.WaitGroup
+}
+
+// NewDBAudit creates a DBAudit writing to db and starts the retention
+// cleanup if retention is positive.
+func NewDBAudit(db *sql.DB, retention time.Duration) *DBAudit {
+	a := &DBAudit{
+		db:        db,
+		retention: retention,
+		stopCh:    make(chan struct{}),
+	}
+	if retention > 0 {
+		a.wg.Add(1)
+		go a.cleanupWorker()
+	}
+	return a
+}
+
+// Write inserts an audit event into the audit_events table.
+func (a *DBAudit) Write(ctx context.Context, e AuditEvent) error {
+	return a.WriteBatch(ctx, []AuditEvent{e})
+}
+
+// WriteBatch inserts several audit events with a single statement.
+// It implements BatchWriter.
+func (a *DBAudit) WriteBatch(ctx context.Context, events []AuditEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+	var query strings.Builder
+	query.WriteString(`INSERT INTO audit_events (ts, action, user_id, url, ip, method, route, status, outcome, count) VALUES `)
+	args := make([]any, 0, len(events)*dbAuditColumns)
+	for i, e := range events {
+		if i > 0 {
+			query.WriteString(", ")
+		}
+		query.WriteString("(")
+		for j := 1; j <= dbAuditColumns; j++ {
+			if j > 1 {
+				query.WriteString(", ")
+			}
+			fmt.Fprintf(&query, "$%d", i*dbAuditColumns+j)
+		}
+		query.WriteString(")")
+		args = append(args, time.Unix(e.TimeStamp, 0), e.Action, e.UserID, e.URL, e.IP, e.Method, e.Route, e.Status, e.Outcome, e.Count)
+	}
+	_, err := a.db.ExecContext(ctx, query.String(), args...)
+	return err
+}
+
+// Query selects audit events for Search. Empty fields do not restrict the result.
+type Query struct {
+	UserID string    // Only events of this user
+	Action string    // Only events with this action
+	From   time.Time // Only events at or after this time
+	To     time.Time // Only events before this time
+	Limit  int       // Maximum number of events returned
+	Offset int       // Number of matching events skipped
+}
+
+// Search returns the stored events matching q, newest first.
+func (a *DBAudit) Search(ctx context.Context, q Query) ([]AuditEvent, error) {
+	var where []string
+	var args []any
+	cond := func(expr string, arg any) {
+		args = append(args, arg)
+		where = append(where, fmt.Sprintf(expr, len(args)))
+	}
+	if q.UserID != "" {
+		cond("user_id = $%d", q.UserID)
+	}
+	if q.Action != "" {
+		cond("action = $%d", q.Action)
+	}
+	if !q.From.IsZero() {
+		cond("ts >= $%d", q.From)
+	}
+	if !q.To.IsZero() {
+		cond("ts < $%d", q.To)
+	}
+
+	query := `SELECT ts, action, user_id, url, ip, method, route, status, outcome, count FROM audit_events`
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	args = append(args, q.Limit, q.Offset)
+	query += fmt.Sprintf(" ORDER BY ts DESC, id DESC LIMIT $%d OFFSET $%d", len(args)-1, len(args))
+
+	rows, err := a.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := make([]AuditEvent, 0)
+	for rows.Next() {
+		var e AuditEvent
+		var ts time.Time
+		if err := rows.Scan(&ts, &e.Action, &e.UserID, &e.URL, &e.IP, &e.Method, &e.Route, &e.Status, &e.Outcome, &e.Count); err != nil {
+			return nil, err
+		}
+		e.TimeStamp = ts.Unix()
+		e.SchemaVersion = CurrentSchemaVersion
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
+
+// DeleteBefore removes the events that occurred before t and returns how many were removed.
+func (a *DBAudit) DeleteBefore(ctx context.Context, t time.Time) (int64, error) {
+	res, err := a.db.ExecContext(ctx, `DELETE FROM audit_events WHERE ts < $1`, t)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// Close stops the retention cleanup. The database connection is left open.
+// It implements Closer.
+func (a *DBAudit) Close(_ context.Context) error {
+	close(a.stopCh)
+	a.wg.Wait()
+	return nil
+}
+
+// cleanupWorker deletes expired events every dbCleanupInterval until stopCh is closed.
+func (a *DBAudit) cleanupWorker() {
+	defer a.wg.Done()
+	ticker := time.NewTicker(dbCleanupInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			n, err := a.DeleteBefore(context.Background(), time.Now().Add(-a.retention))
+			if err != nil {
+				log.Printf("[audit] retention cleanup error: %v", err)
+			} else if n > 0 {
+				log.Printf("[audit] removed %d expired audit events", n)
+			}
+		case <-a.stopCh:
+			return
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package audit
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// Encoding selects how writers that support several formats serialize events.
+type Encoding string
+
+// Supported encodings.
+const (
+	// EncodingJSON encodes events as JSON objects, the default
+	EncodingJSON Encoding = "json"
+	// EncodingProtobuf encodes events as shortener.audit.v1.AuditEvent
+	// messages, see api/audit/v1/audit_event.proto
+	EncodingProtobuf Encoding = "protobuf"
+)
+
+// Field numbers of shortener.audit.v1.AuditEvent.
+const (
+	protoSchemaVersion protowire.Number = iota + 1
+	protoTimeStamp
+	protoAction
+	protoUserID
+	protoURL
+	protoIP
+	protoMethod
+	protoRoute
+	protoStatus
+	protoOutcome
+	protoCount
+)
+
+// ParseEncoding returns the Encoding named name; an empty name means EncodingJSON.
+func ParseEncoding(name string) (Encoding, error) {
+	switch enc := Encoding(name); enc {
+	case "":
+		return EncodingJSON, nil
+	case EncodingJSON, EncodingProtobuf:
+		return enc, nil
+	default:
+		return "", fmt.Errorf("unknown audit encoding %q", name)
+	}
+}
+
+// ContentType returns the media type of events encoded with enc.
+func (enc Encoding) ContentType() string {
+	if enc == EncodingProtobuf {
+		return "application/x-protobuf"
+	}
+	return "application/json"
+}
+
+// Marshal encodes e with enc.
+func (enc Encoding) Marshal(e AuditEvent) ([]byte, error) {
+	if enc == EncodingProtobuf {
+		return MarshalProto(e), nil
+	}
+	return json.Marshal(e)
+}
+
+// MarshalProto encodes e as a shortener.audit.v1.AuditEvent message. Zero
+// fields are omitted, as proto3 does.
+func MarshalProto(e AuditEvent) []byte {
+	var b []byte
+	appendVarint := func(num protowire.Number, v uint64) {
+		if v != 0 {
+			b = protowire.AppendTag(b, num, protowire.VarintType)
+			b = protowire.AppendVarint(b, v)
+		}
+	}
+	appendString := func(num protowire.Number, v string) {
+		if v != "" {
+			b = protowire.AppendTag(b, num, protowire.BytesType)
+			b = protowire.AppendString(b, v)
+		}
+	}
+	appendVarint(protoSchemaVersion, uint64(e.SchemaVersion))
+	appendVarint(protoTimeStamp, uint64(e.TimeStamp))
+	appendString(protoAction, e.Action)
+	appendString(protoUserID, e.UserID)
+	appendString(protoURL, e.URL)
+	appendString(protoIP, e.IP)
+	appendString(protoMethod, e.Method)
+	appendString(protoRoute, e.Route)
+	appendVarint(protoStatus, uint64(int64(e.Status)))
+	appendString(protoOutcome, e.Outcome)
+	appendVarint(protoCount, uint64(int64(e.Count)))
+	return b
+}
+
+// errMalformedProto is returned by UnmarshalProto for invalid messages.
+var errMalformedProto = errors.New("malformed audit event message")
+
+// UnmarshalProto decodes a shortener.audit.v1.AuditEvent message, skipping
+// unknown fields so that consumers keep working when fields are added.
+func UnmarshalProto(b []byte) (AuditEvent, error) {
+	var e AuditEvent
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return AuditEvent{}, errMalformedProto
+		}
+		b = b[n:]
+
+		var v uint64
+		var s string
+		switch typ {
+		case protowire.VarintType:
+			v, n = protowire.ConsumeVarint(b)
+		case protowire.BytesType:
+			s, n = protowire.ConsumeString(b)
+		default:
+			n = protowire.ConsumeFieldValue(num, typ, b)
+		}
+		if n < 0 {
+			return AuditEvent{}, errMalformedProto
+		}
+		b = b[n:]
+
+		switch num {
+		case protoSchemaVersion:
+			e.SchemaVersion = int(v)
+		case protoTimeStamp:
+			e.TimeStamp = int64(v)
+		case protoAction:
+			e.Action = s
+		case protoUserID:
+			e.UserID = s
+		case protoURL:
+			e.URL = s
+		case protoIP:
+			e.IP = s
+		case protoMethod:
+			e.Method = s
+		case protoRoute:
+			e.Route = s
+		case protoStatus:
+			e.Status = int(int32(v))
+		case protoOutcome:
+			e.Outcome = s
+		case protoCount:
+			e.Count = int(int64(v))
+		}
+	}
+	return e, nil
+}
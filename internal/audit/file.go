@@ -25,21 +25,32 @@ func NewFileAudit(filePath string) *FileAudit {
 // Write persists an audit event to the log file in JSON format.
 // It handles context cancellation and ensures thread-safe file operations.
 // Each event is written as a new line in the file.
-func (a *FileAudit) Write(ctx context.Context, e AuditEvent) {
+func (a *FileAudit) Write(ctx context.Context, e AuditEvent) error {
+	return a.WriteBatch(ctx, []AuditEvent{e})
+}
+
+// WriteBatch appends several audit events to the log file, one line per
+// event, opening the file only once. It implements BatchWriter.
+func (a *FileAudit) WriteBatch(ctx context.Context, events []AuditEvent) error {
 	a.mu.Lock()
 	defer a.mu.Unlock()
 
 	select {
 	case <-ctx.Done():
-		return
+		return ctx.Err()
 	default:
 		file, err := os.OpenFile(a.filePath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
 		if err != nil {
-			return
+			return err
 		}
-		defer file.Close()
 
 		enc := json.NewEncoder(file)
-		enc.Encode(e)
+		for _, e := range events {
+			if err := enc.Encode(e); err != nil {
+				file.Close()
+				return err
+			}
+		}
+		return file.Close()
 	}
 }
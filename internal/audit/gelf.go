@@ -0,0 +1,205 @@
+package audit
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+)
+
+// GELF message settings.
+const (
+	// gelfVersion is the GELF specification version of the messages
+	gelfVersion = "1.1"
+	// gelfLevel is the syslog severity of the messages, informational
+	gelfLevel = 6
+	// gelfChunkSize is the largest UDP datagram sent; larger messages are
+	// chunked. It fits the usual WAN MTU.
+	gelfChunkSize = 1420
+	// gelfMaxChunks is the largest number of chunks a message may be split into
+	gelfMaxChunks = 128
+	// gelfChunkHeaderSize is the size of the magic bytes, message ID,
+	// sequence number and sequence count preceding every chunk
+	gelfChunkHeaderSize = 2 + 8 + 1 + 1
+	// gelfDialTimeout bounds how long connecting to the server may take
+	gelfDialTimeout = 5 * time.Second
+)
+
+// gelfChunkMagic starts every chunk of a chunked UDP message.
+var gelfChunkMagic = [2]byte{0x1e, 0x0f}
+
+// GELFAudit implements the AuditWriter interface for sending audit events to
+// Graylog in the Graylog Extended Log Format. The event fields are sent as
+// additional fields ("_action", "_user_id", ...).
+//
+// Over UDP every message is one datagram, or is split into chunks of at most
+// gelfChunkSize bytes if it does not fit. Over TCP messages are terminated by
+// a null byte, as GELF requires. A broken connection is re-established on the
+// next write.
+type GELFAudit struct {
+	network  string // "udp" or "tcp"
+	addr     string // Server address
+	hostname string // Host field of the messages
+
+	mu   sync.Mutex // Serializes writes and reconnects
+	conn net.Conn   // Current connection, nil if not connected
+}
+
+// NewGELFAudit creates a GELFAudit sending to the GELF input given as a URL:
+// "udp://graylog:12201" or "tcp://graylog:12201". The connection is opened
+// right away so that misconfiguration is reported at startup.
+func NewGELFAudit(rawURL string) (*GELFAudit, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	a := &GELFAudit{}
+	switch u.Scheme {
+	case "udp", "tcp":
+		a.network, a.addr = u.Scheme, u.Host
+	default:
+		return nil, fmt.Errorf("unsupported gelf scheme %q", u.Scheme)
+	}
+	if a.hostname, err = os.Hostname(); err != nil || a.hostname == "" {
+		a.hostname = "localhost"
+	}
+	if err := a.connect(); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// Write sends an audit event as a GELF message. If sending fails, it
+// reconnects and tries once more before reporting the error.
+func (a *GELFAudit) Write(ctx context.Context, e AuditEvent) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	packets, err := a.format(e)
+	if err != nil {
+		return err
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for attempt := 0; attempt < 2; attempt++ {
+		if a.conn == nil {
+			if err = a.connect(); err != nil {
+				return err
+			}
+		}
+		if err = writePackets(a.conn, packets); err == nil {
+			return nil
+		}
+		a.conn.Close()
+		a.conn = nil
+	}
+	return err
+}
+
+// Close closes the connection to the GELF input. It implements Closer.
+func (a *GELFAudit) Close(_ context.Context) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.conn == nil {
+		return nil
+	}
+	err := a.conn.Close()
+	a.conn = nil
+	return err
+}
+
+// connect opens a new connection to the GELF input.
+func (a *GELFAudit) connect() error {
+	conn, err := net.DialTimeout(a.network, a.addr, gelfDialTimeout)
+	if err != nil {
+		return err
+	}
+	a.conn = conn
+	return nil
+}
+
+// format encodes e as a GELF message and splits it into the packets to send
+// over the connection's network.
+func (a *GELFAudit) format(e AuditEvent) ([][]byte, error) {
+	msg := map[string]any{
+		"version":         gelfVersion,
+		"host":            a.hostname,
+		"short_message":   "audit: " + e.Action,
+		"timestamp":       e.TimeStamp,
+		"level":           gelfLevel,
+		"_app":            syslogAppName,
+		"_schema_version": e.SchemaVersion,
+	}
+	for name, value := range map[string]string{
+		"_action":  e.Action,
+		"_user_id": e.UserID,
+		"_url":     e.URL,
+		"_ip":      e.IP,
+		"_method":  e.Method,
+		"_route":   e.Route,
+		"_outcome": e.Outcome,
+	} {
+		if value != "" {
+			msg[name] = value
+		}
+	}
+	if e.Status != 0 {
+		msg["_status"] = e.Status
+	}
+	if e.Count != 0 {
+		msg["_count"] = e.Count
+	}
+
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return nil, err
+	}
+	if a.network == "tcp" {
+		return [][]byte{append(data, 0)}, nil
+	}
+	return chunkGELF(data)
+}
+
+// chunkGELF splits a UDP message into chunks if it exceeds gelfChunkSize.
+func chunkGELF(data []byte) ([][]byte, error) {
+	if len(data) <= gelfChunkSize {
+		return [][]byte{data}, nil
+	}
+	const payloadSize = gelfChunkSize - gelfChunkHeaderSize
+	count := (len(data) + payloadSize - 1) / payloadSize
+	if count > gelfMaxChunks {
+		return nil, fmt.Errorf("gelf message of %d bytes exceeds %d chunks", len(data), gelfMaxChunks)
+	}
+
+	var id [8]byte
+	if _, err := rand.Read(id[:]); err != nil {
+		return nil, err
+	}
+	chunks := make([][]byte, 0, count)
+	for seq := 0; seq < count; seq++ {
+		payload := data[seq*payloadSize : min((seq+1)*payloadSize, len(data))]
+		chunk := make([]byte, 0, gelfChunkHeaderSize+len(payload))
+		chunk = append(chunk, gelfChunkMagic[:]...)
+		chunk = append(chunk, id[:]...)
+		chunk = append(chunk, byte(seq), byte(count))
+		chunks = append(chunks, append(chunk, payload...))
+	}
+	return chunks, nil
+}
+
+// writePackets writes every packet with its own Write call, so that each
+// becomes a single datagram on packet connections.
+func writePackets(conn net.Conn, packets [][]byte) error {
+	for _, p := range packets {
+		if _, err := conn.Write(p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
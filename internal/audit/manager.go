@@ -2,54 +2,420 @@ package audit
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
+// Default delivery settings of AuditManager.
+const (
+	// defaultQueueSize is the number of events buffered per writer
+	defaultQueueSize = 1024
+	// defaultWorkers is the number of goroutines delivering events per writer
+	defaultWorkers = 2
+	// defaultBatchSize is the maximum number of events passed to a BatchWriter at once
+	defaultBatchSize = 100
+)
+
+// BatchWriter is implemented by writers that deliver several events more
+// efficiently at once, e.g. in a single insert. AuditManager passes such
+// writers all queued events, up to its batch size, in one call. The slice
+// is reused afterwards, so WriteBatch must not retain it. An error counts all
+// events of the batch as failed.
+type BatchWriter interface {
+	AuditWriter
+	WriteBatch(ctx context.Context, events []AuditEvent) error
+}
+
+// DeliveryReporter is implemented by writers that buffer events and deliver
+// them later, e.g. in periodic batch inserts, so that their Write only
+// accepts an event. AuditManager passes them a report function when they
+// are registered and counts their events once they report the outcome of
+// the delivery: report(n, nil) after n events were delivered and
+// report(n, err) when n events were lost. A writer keeping the events of a
+// failed attempt for a retry reports the error with n = 0, so that it shows
+// in the statistics without counting the events twice. An error returned
+// by Write still counts the event as failed right away.
+type DeliveryReporter interface {
+	AuditWriter
+	SetDeliveryReport(report func(events int, err error))
+}
+
+// Closer is implemented by writers that hold resources or buffer events.
+// AuditManager.Close calls Close once all queued events have been passed to
+// the writer; the writer should deliver what it still buffers before ctx is done.
+type Closer interface {
+	Close(ctx context.Context) error
+}
+
+// Option configures an AuditManager.
+type Option func(*AuditManager)
+
+// WithQueueSize sets how many events are buffered per writer before new
+// events for that writer are dropped. Non-positive values are ignored.
+func WithQueueSize(n int) Option {
+	return func(am *AuditManager) {
+		if n > 0 {
+			am.queueSize = n
+		}
+	}
+}
+
+// WithWorkers sets how many goroutines deliver events to each writer.
+// Non-positive values are ignored.
+func WithWorkers(n int) Option {
+	return func(am *AuditManager) {
+		if n > 0 {
+			am.workers = n
+		}
+	}
+}
+
+// WithBatchSize sets the maximum number of events passed to a BatchWriter
+// in one call. Non-positive values are ignored.
+func WithBatchSize(n int) Option {
+	return func(am *AuditManager) {
+		if n > 0 {
+			am.batchSize = n
+		}
+	}
+}
+
+// WithErrorHandler sets a function called with the writer name (see
+// WriterStats.Writer), the number of events affected and the error whenever
+// a writer fails to deliver events, e.g. to log a warning. It runs on the
+// writer's worker goroutines, so it must be safe for concurrent use and
+// should not block.
+func WithErrorHandler(fn func(writer string, events int, err error)) Option {
+	return func(am *AuditManager) {
+		am.onError = fn
+	}
+}
+
 // AuditManager coordinates multiple AuditWriter instances to handle audit logging.
 // It provides thread-safe registration of writers and concurrent event logging.
+//
+// Every writer has its own bounded queue drained by a fixed number of worker
+// goroutines, so a slow writer neither delays the others nor makes the number
+// of goroutines grow with the event rate. Events that do not fit into a full
+// queue are dropped and counted (see Dropped) instead of blocking the caller.
+// Delivery outcomes are tracked per writer and reported by Stats.
 type AuditManager struct {
-	writers []AuditWriter // List of registered audit writers
-	mu      sync.Mutex    // Mutex to protect concurrent access to writers slice
+	writers []*writerQueue // Queues of the registered audit writers
+	mu      sync.Mutex     // Mutex to protect concurrent access to writers slice and closed
+	closed  bool           // Whether Close has been called
+	wg      sync.WaitGroup // Running workers
+
+	queueSize int
+	workers   int
+	batchSize int
+	onError   func(writer string, events int, err error) // Optional, see WithErrorHandler
+}
+
+// WriterStats reports the delivery outcomes of a single writer.
+type WriterStats struct {
+	// Writer names the writer by its type, e.g. "FileAudit"; further writers
+	// of the same type get a "#2", "#3", ... suffix
+	Writer string `json:"writer"`
+
+	// Delivered is the number of events the writer accepted, or for a
+	// DeliveryReporter the number it reported as delivered
+	Delivered uint64 `json:"delivered"`
+
+	// Failed is the number of events the writer returned an error for, or
+	// for a DeliveryReporter reported as lost
+	Failed uint64 `json:"failed"`
+
+	// Dropped is the number of events dropped because the queue was full
+	Dropped uint64 `json:"dropped"`
+
+	// QueueLength is the number of events waiting in the queue
+	QueueLength int `json:"queue_length"`
+
+	// LastError is the message of the most recent error, empty if there was none
+	LastError string `json:"last_error,omitempty"`
+
+	// LastErrorAt is when the most recent error occurred
+	LastErrorAt time.Time `json:"last_error_at,omitzero"`
+
+	// LastSuccessAt is when the writer last accepted an event
+	LastSuccessAt time.Time `json:"last_success_at,omitzero"`
+}
+
+// Healthy reports whether the writer's latest delivery succeeded.
+func (s WriterStats) Healthy() bool {
+	return s.LastErrorAt.IsZero() || s.LastSuccessAt.After(s.LastErrorAt)
+}
+
+// writerQueue is the queue, worker pool and delivery statistics of a single writer.
+type writerQueue struct {
+	name     string
+	writer   AuditWriter
+	events   chan AuditEvent
+	reported bool // The writer is a DeliveryReporter, which counts accepted events itself
+
+	delivered atomic.Uint64
+	failed    atomic.Uint64
+	dropped   atomic.Uint64
+
+	mu            sync.Mutex // Protects the fields below
+	lastErr       error
+	lastErrAt     time.Time
+	lastSuccessAt time.Time
+}
+
+// record counts the outcome of delivering n events.
+func (q *writerQueue) record(n int, err error) {
+	now := time.Now()
+	if err != nil {
+		q.failed.Add(uint64(n))
+	} else {
+		q.delivered.Add(uint64(n))
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if err != nil {
+		q.lastErr, q.lastErrAt = err, now
+	} else {
+		q.lastSuccessAt = now
+	}
+}
+
+// stats returns a snapshot of the queue's statistics.
+func (q *writerQueue) stats() WriterStats {
+	s := WriterStats{
+		Writer:      q.name,
+		Delivered:   q.delivered.Load(),
+		Failed:      q.failed.Load(),
+		Dropped:     q.dropped.Load(),
+		QueueLength: len(q.events),
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.lastErr != nil {
+		s.LastError = q.lastErr.Error()
+	}
+	s.LastErrorAt, s.LastSuccessAt = q.lastErrAt, q.lastSuccessAt
+	return s
 }
 
 // NewAuditManager creates and initializes a new AuditManager instance.
 // The returned manager starts with no registered writers; use RegisterWriter to add them.
-func NewAuditManager() *AuditManager {
-	return &AuditManager{
-		writers: make([]AuditWriter, 0),
+func NewAuditManager(opts ...Option) *AuditManager {
+	am := &AuditManager{
+		writers:   make([]*writerQueue, 0),
+		queueSize: defaultQueueSize,
+		workers:   defaultWorkers,
+		batchSize: defaultBatchSize,
 	}
+	for _, opt := range opts {
+		opt(am)
+	}
+	return am
 }
 
-// RegisterWriter adds a new AuditWriter to the list of writers that will receive audit events.
-// This method is thread-safe and can be called concurrently.
+// RegisterWriter adds a new AuditWriter to the list of writers that will receive audit events
+// and starts its workers.
+// This method is thread-safe and can be called concurrently. Writers
+// registered after Close are ignored.
 func (am *AuditManager) RegisterWriter(writer AuditWriter) {
 	am.mu.Lock()
 	defer am.mu.Unlock()
-	am.writers = append(am.writers, writer)
+	if am.closed {
+		return
+	}
+
+	q := &writerQueue{
+		name:   am.writerName(writer),
+		writer: writer,
+		events: make(chan AuditEvent, am.queueSize),
+	}
+	if reporter, ok := writer.(DeliveryReporter); ok {
+		q.reported = true
+		reporter.SetDeliveryReport(func(n int, err error) { am.record(q, n, err) })
+	}
+	am.wg.Add(am.workers)
+	for i := 0; i < am.workers; i++ {
+		go am.deliver(q)
+	}
+	am.writers = append(am.writers, q)
 }
 
 // LogEvent creates and dispatches an audit event to all registered writers.
-// The event is sent asynchronously to each writer, and context cancellation is respected.
+// The event is queued for each writer without blocking; events for writers
+// whose queue is full are dropped.
 // Parameters:
-//   - ctx: Context for cancellation and timeout control
+//   - ctx: Context carrying the client IP (see ContextWithClientIP); events are not logged once it is done
 //   - action: The type of action being logged (e.g., "url_created", "url_deleted")
 //   - userID: ID of the user who performed the action
 //   - url: The URL that was affected by the action
 func (am *AuditManager) LogEvent(ctx context.Context, action, userID, url string) {
 	event := AuditEvent{
-		TimeStamp: int(time.Now().Unix()),
+		TimeStamp: time.Now().Unix(),
 		Action:    action,
 		UserID:    userID,
 		URL:       url,
 	}
+	event.IP, _ = ctx.Value(clientIPKey{}).(string)
+	am.Log(ctx, event)
+}
+
+// Log dispatches a prepared audit event to all registered writers, like
+// LogEvent. A zero TimeStamp is set to the current time and a zero
+// SchemaVersion to CurrentSchemaVersion. Events logged after Close are dropped.
+func (am *AuditManager) Log(ctx context.Context, event AuditEvent) {
+	if ctx.Err() != nil {
+		return
+	}
+	if event.TimeStamp == 0 {
+		event.TimeStamp = time.Now().Unix()
+	}
+	if event.SchemaVersion == 0 {
+		event.SchemaVersion = CurrentSchemaVersion
+	}
 
 	am.mu.Lock()
-	writers := make([]AuditWriter, len(am.writers))
-	copy(writers, am.writers)
+	defer am.mu.Unlock()
+	if am.closed {
+		for _, q := range am.writers {
+			q.dropped.Add(1)
+		}
+		return
+	}
+	for _, q := range am.writers {
+		select {
+		case q.events <- event:
+		default:
+			q.dropped.Add(1)
+		}
+	}
+}
+
+// Close stops accepting events, waits until the queued events have been
+// passed to the writers and then closes the writers implementing Closer, so
+// that buffered events are delivered. If ctx is done first, Close returns
+// ctx.Err() and the remaining events are lost.
+func (am *AuditManager) Close(ctx context.Context) error {
+	am.mu.Lock()
+	if am.closed {
+		am.mu.Unlock()
+		return nil
+	}
+	am.closed = true
+	writers := am.writers
+	for _, q := range writers {
+		close(q.events)
+	}
 	am.mu.Unlock()
 
-	for _, writer := range writers {
-		go writer.Write(ctx, event)
+	done := make(chan struct{})
+	go func() {
+		am.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	var errs []error
+	for _, q := range writers {
+		if closer, ok := q.writer.(Closer); ok {
+			errs = append(errs, closer.Close(ctx))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Dropped returns the number of events dropped because a writer's queue was
+// full, summed over all writers.
+func (am *AuditManager) Dropped() uint64 {
+	var n uint64
+	for _, s := range am.Stats() {
+		n += s.Dropped
+	}
+	return n
+}
+
+// Stats returns the delivery statistics of the registered writers in
+// registration order.
+func (am *AuditManager) Stats() []WriterStats {
+	am.mu.Lock()
+	writers := am.writers
+	am.mu.Unlock()
+
+	stats := make([]WriterStats, len(writers))
+	for i, q := range writers {
+		stats[i] = q.stats()
+	}
+	return stats
+}
+
+// writerName names writer by its type, numbering writers of the same type.
+// am.mu must be held.
+func (am *AuditManager) writerName(writer AuditWriter) string {
+	name := fmt.Sprintf("%T", writer)
+	if i := strings.LastIndexByte(name, '.'); i >= 0 {
+		name = name[i+1:]
+	}
+	n := 1
+	for _, q := range am.writers {
+		if base, _, _ := strings.Cut(q.name, "#"); base == name {
+			n++
+		}
+	}
+	if n > 1 {
+		name = fmt.Sprintf("%s#%d", name, n)
+	}
+	return name
+}
+
+// deliver passes the events queued for q to its writer. Events already
+// waiting in the queue are passed to a BatchWriter together.
+func (am *AuditManager) deliver(q *writerQueue) {
+	defer am.wg.Done()
+	batchWriter, batching := q.writer.(BatchWriter)
+	batch := make([]AuditEvent, 0, am.batchSize)
+	for event := range q.events {
+		if !batching {
+			am.accepted(q, 1, q.writer.Write(context.Background(), event))
+			continue
+		}
+
+		batch = append(batch[:0], event)
+	fill:
+		for len(batch) < am.batchSize {
+			select {
+			case event, ok := <-q.events:
+				if !ok {
+					break fill
+				}
+				batch = append(batch, event)
+			default:
+				break fill
+			}
+		}
+		am.accepted(q, len(batch), batchWriter.WriteBatch(context.Background(), batch))
+	}
+}
+
+// accepted counts the outcome of passing n events to the writer of q. The
+// events a DeliveryReporter accepted are counted when it reports them.
+func (am *AuditManager) accepted(q *writerQueue, n int, err error) {
+	if err != nil || !q.reported {
+		am.record(q, n, err)
+	}
+}
+
+// record counts the outcome of delivering n events to q and reports errors
+// to the error handler.
+func (am *AuditManager) record(q *writerQueue, n int, err error) {
+	q.record(n, err)
+	if err != nil && am.onError != nil {
+		am.onError(q.name, n, err)
 	}
 }
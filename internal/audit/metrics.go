@@ -0,0 +1,69 @@
+package audit
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// collector exports the writer statistics of an AuditManager as Prometheus metrics.
+type collector struct {
+	am            *AuditManager
+	delivered     *prometheus.Desc
+	failed        *prometheus.Desc
+	dropped       *prometheus.Desc
+	queueLength   *prometheus.Desc
+	lastError     *prometheus.Desc
+	lastSuccessAt *prometheus.Desc
+}
+
+// NewCollector returns a Prometheus collector reporting the statistics of
+// am's writers (see AuditManager.Stats), labelled by writer:
+//   - audit_events_delivered_total, audit_events_failed_total and
+//     audit_events_dropped_total: counters of delivery outcomes
+//   - audit_queue_length: gauge of events waiting in the writer's queue
+//   - audit_writer_last_error_timestamp_seconds and
+//     audit_writer_last_success_timestamp_seconds: Unix times of the latest
+//     outcomes, 0 if there was none
+func NewCollector(am *AuditManager) prometheus.Collector {
+	labels := []string{"writer"}
+	return &collector{
+		am:            am,
+		delivered:     prometheus.NewDesc("audit_events_delivered_total", "Number of audit events delivered by writer.", labels, nil),
+		failed:        prometheus.NewDesc("audit_events_failed_total", "Number of audit events a writer failed to deliver.", labels, nil),
+		dropped:       prometheus.NewDesc("audit_events_dropped_total", "Number of audit events dropped because the writer's queue was full.", labels, nil),
+		queueLength:   prometheus.NewDesc("audit_queue_length", "Number of audit events waiting in the writer's queue.", labels, nil),
+		lastError:     prometheus.NewDesc("audit_writer_last_error_timestamp_seconds", "Unix time of the writer's latest delivery error.", labels, nil),
+		lastSuccessAt: prometheus.NewDesc("audit_writer_last_success_timestamp_seconds", "Unix time of the writer's latest successful delivery.", labels, nil),
+	}
+}
+
+// Describe implements prometheus.Collector.
+func (c *collector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.delivered
+	ch <- c.failed
+	ch <- c.dropped
+	ch <- c.queueLength
+	ch <- c.lastError
+	ch <- c.lastSuccessAt
+}
+
+// Collect implements prometheus.Collector.
+func (c *collector) Collect(ch chan<- prometheus.Metric) {
+	for _, s := range c.am.Stats() {
+		ch <- prometheus.MustNewConstMetric(c.delivered, prometheus.CounterValue, float64(s.Delivered), s.Writer)
+		ch <- prometheus.MustNewConstMetric(c.failed, prometheus.CounterValue, float64(s.Failed), s.Writer)
+		ch <- prometheus.MustNewConstMetric(c.dropped, prometheus.CounterValue, float64(s.Dropped), s.Writer)
+		ch <- prometheus.MustNewConstMetric(c.queueLength, prometheus.GaugeValue, float64(s.QueueLength), s.Writer)
+		ch <- prometheus.MustNewConstMetric(c.lastError, prometheus.GaugeValue, unixSeconds(s.LastErrorAt), s.Writer)
+		ch <- prometheus.MustNewConstMetric(c.lastSuccessAt, prometheus.GaugeValue, unixSeconds(s.LastSuccessAt), s.Writer)
+	}
+}
+
+// unixSeconds returns t as fractional Unix seconds, 0 for the zero time.
+func unixSeconds(t time.Time) float64 {
+	if t.IsZero() {
+		return 0
+	}
+	return float64(t.UnixNano()) / 1e9
+}
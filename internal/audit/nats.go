@@ -0,0 +1,114 @@
+package audit
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+// NATS delivery settings.
+const (
+	// natsPublishAttempts bounds how often an event is published before it is dropped
+	natsPublishAttempts = 5
+	// natsPublishTimeout bounds how long a single attempt waits for the JetStream ack
+	natsPublishTimeout = 5 * time.Second
+	// natsRetryWait is the pause between attempts
+	natsRetryWait = 500 * time.Millisecond
+	// natsReconnectWait is the pause between reconnection attempts
+	natsReconnectWait = time.Second
+)
+
+// NATSConfig describes where NATSAudit publishes events.
+type NATSConfig struct {
+	URL      string   // NATS server URL(s), e.g. "nats://localhost:4222"
+	Subject  string   // Subject events are published to
+	Stream   string   // JetStream stream created or updated to capture Subject; empty if it is managed elsewhere
+	Encoding Encoding // Encoding of the messages, EncodingJSON if empty
+}
+
+// NATSAudit implements the AuditWriter interface for publishing audit events
+// to a NATS JetStream subject.
+//
+// Delivery is best-effort with bounded retries: an event is published until
+// JetStream acknowledges that it was stored, at most natsPublishAttempts
+// times, and dropped afterwards; the AuditManager counts it as failed then.
+// Events are not persisted locally, so an outage longer than the retries
+// loses them. Each event carries a unique Nats-Msg-Id so that the stream
+// drops duplicates caused by retries whose acks were lost. The connection
+// is re-established indefinitely after network failures.
+type NATSAudit struct {
+	nc       *nats.Conn
+	js       jetstream.JetStream
+	subject  string
+	encoding Encoding
+}
+
+// NewNATSAudit connects to the NATS server at cfg.URL and, if cfg.Stream is
+// set, makes sure a stream of that name captures cfg.Subject. The server has
+// to be reachable at startup; later disconnections are handled by reconnecting.
+func NewNATSAudit(ctx context.Context, cfg NATSConfig) (*NATSAudit, error) {
+	nc, err := nats.Connect(cfg.URL,
+		nats.Name("go-shortener audit"),
+		nats.MaxReconnects(-1),
+		nats.ReconnectWait(natsReconnectWait),
+	)
+	if err != nil {
+		return nil, err
+	}
+	js, err := jetstream.New(nc)
+	if err != nil {
+		nc.Close()
+		return nil, err
+	}
+	if cfg.Stream != "" {
+		if _, err := js.CreateOrUpdateStream(ctx, jetstream.StreamConfig{
+			Name:     cfg.Stream,
+			Subjects: []string{cfg.Subject},
+		}); err != nil {
+			nc.Close()
+			return nil, err
+		}
+	}
+	if cfg.Encoding == "" {
+		cfg.Encoding = EncodingJSON
+	}
+	return &NATSAudit{nc: nc, js: js, subject: cfg.Subject, encoding: cfg.Encoding}, nil
+}
+
+// Write publishes an audit event as a message in the configured encoding,
+// retrying up to natsPublishAttempts times until JetStream acknowledges it.
+// The event is dropped, and the last error returned, if ctx is cancelled or
+// every attempt fails, so that slow delivery never blocks the application.
+func (a *NATSAudit) Write(ctx context.Context, e AuditEvent) error {
+	data, err := a.encoding.Marshal(e)
+	if err != nil {
+		return err
+	}
+	msgID := uuid.New().String()
+
+	for attempt := 0; attempt < natsPublishAttempts; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(natsRetryWait):
+			}
+		}
+		pubCtx, cancel := context.WithTimeout(ctx, natsPublishTimeout)
+		_, err = a.js.Publish(pubCtx, a.subject, data, jetstream.WithMsgID(msgID))
+		cancel()
+		if err == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+// Close flushes pending messages and closes the connection. It implements Closer.
+func (a *NATSAudit) Close(ctx context.Context) error {
+	defer a.nc.Close()
+	return a.nc.FlushWithContext(ctx)
+}
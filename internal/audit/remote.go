@@ -3,59 +3,153 @@ package audit
 import (
 	"bytes"
 	"context"
-	"encoding/json"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 )
 
+// Headers of signed audit webhook requests.
+const (
+	// SignatureHeader carries "sha256=" followed by the hex-encoded
+	// HMAC-SHA256 of "<timestamp>.<body>" under the shared secret
+	SignatureHeader = "X-Signature"
+	// TimestampHeader carries the Unix time the request was signed at
+	TimestampHeader = "X-Timestamp"
+	// signaturePrefix names the signature algorithm in SignatureHeader
+	signaturePrefix = "sha256="
+)
+
+// Errors returned by VerifySignature.
+var (
+	// ErrInvalidSignature means the signature is missing or does not match the body
+	ErrInvalidSignature = errors.New("invalid audit signature")
+	// ErrStaleSignature means the request was signed too long ago, e.g. it is a replay
+	ErrStaleSignature = errors.New("stale audit signature")
+)
+
 // RemoteAudit implements the AuditWriter interface for sending audit events to a remote HTTP endpoint.
 // It uses a configurable HTTP client with timeout settings for reliable event delivery.
 type RemoteAudit struct {
 	url        string       // The target URL where audit events will be sent
 	httpClient *http.Client // HTTP client with configured timeout settings
+	secret     []byte       // Key payloads are signed with, nil if they are not signed
+	encoding   Encoding     // Encoding of the payloads
+}
+
+// RemoteOption configures a RemoteAudit.
+type RemoteOption func(*RemoteAudit)
+
+// WithSigningSecret makes RemoteAudit sign every payload with secret. The
+// request then carries TimestampHeader and SignatureHeader, which the
+// receiving service checks with VerifySignature to authenticate the event and
+// reject replays. An empty secret leaves payloads unsigned.
+func WithSigningSecret(secret []byte) RemoteOption {
+	return func(a *RemoteAudit) {
+		if len(secret) > 0 {
+			a.secret = secret
+		}
+	}
+}
+
+// WithEncoding sets how RemoteAudit encodes payloads; the request's
+// Content-Type names the encoding. The default is EncodingJSON.
+func WithEncoding(enc Encoding) RemoteOption {
+	return func(a *RemoteAudit) {
+		a.encoding = enc
+	}
 }
 
 // NewRemoteAudit creates a new RemoteAudit instance with the specified endpoint URL.
 // The HTTP client is configured with a 5-second timeout by default.
 // The URL should be the full endpoint where audit events should be posted.
-func NewRemoteAudit(url string) *RemoteAudit {
-	return &RemoteAudit{
-		url: url,
+func NewRemoteAudit(url string, opts ...RemoteOption) *RemoteAudit {
+	a := &RemoteAudit{
+		url:      url,
+		encoding: EncodingJSON,
 		httpClient: &http.Client{
 			Timeout: 5 * time.Second,
 		},
 	}
+	for _, opt := range opts {
+		opt(a)
+	}
+	return a
+}
+
+// Sign returns the SignatureHeader value of body signed with secret at the
+// Unix time timestamp.
+func Sign(secret []byte, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return signaturePrefix + hex.EncodeToString(mac.Sum(nil))
 }
 
-// Write sends an audit event to the configured remote endpoint as a JSON payload.
-// The request includes proper content-type headers and handles context cancellation.
-// Failures during the HTTP request or response are silently ignored to prevent
-// blocking the main application flow.
-func (a *RemoteAudit) Write(ctx context.Context, e AuditEvent) {
+// VerifySignature checks the TimestampHeader and SignatureHeader values of a
+// signed audit request against its body. Requests signed more than maxAge
+// before now, or that far in the future, are rejected with ErrStaleSignature;
+// receivers should additionally remember recently seen signatures within that
+// window if they must not accept a replay at all.
+func VerifySignature(secret []byte, timestamp, signature string, body []byte, maxAge time.Duration, now time.Time) error {
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil || !strings.HasPrefix(signature, signaturePrefix) {
+		return ErrInvalidSignature
+	}
+	if !hmac.Equal([]byte(Sign(secret, ts, body)), []byte(signature)) {
+		return ErrInvalidSignature
+	}
+	if age := now.Sub(time.Unix(ts, 0)); age > maxAge || age < -maxAge {
+		return ErrStaleSignature
+	}
+	return nil
+}
+
+// Write sends an audit event to the configured remote endpoint, encoded as
+// configured with WithEncoding.
+// The request includes proper content-type headers, the signature headers if
+// a signing secret is configured, and handles context cancellation.
+// Responses with a status other than 2xx are reported as errors.
+func (a *RemoteAudit) Write(ctx context.Context, e AuditEvent) error {
 	select {
 	case <-ctx.Done():
-		return
+		return ctx.Err()
 	default:
-		jsonData, err := json.Marshal(e)
+		body, err := a.encoding.Marshal(e)
 		if err != nil {
-			return
+			return err
 		}
 
 		req, err := http.NewRequestWithContext(
 			ctx,
 			http.MethodPost,
 			a.url,
-			bytes.NewBuffer(jsonData),
+			bytes.NewBuffer(body),
 		)
 		if err != nil {
-			return
+			return err
+		}
+		req.Header.Set("Content-Type", a.encoding.ContentType())
+		if a.secret != nil {
+			timestamp := time.Now().Unix()
+			req.Header.Set(TimestampHeader, strconv.FormatInt(timestamp, 10))
+			req.Header.Set(SignatureHeader, Sign(a.secret, timestamp, body))
 		}
-		req.Header.Set("Content-Type", "application/json")
 
 		resp, err := a.httpClient.Do(req)
 		if err != nil {
-			return
+			return err
 		}
 		defer resp.Body.Close()
+		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+			return fmt.Errorf("audit endpoint returned %s", resp.Status)
+		}
+		return nil
 	}
 }
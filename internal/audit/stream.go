@@ -0,0 +1,50 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"sync"
+)
+
+// StreamAudit implements the AuditWriter interface for writing audit events
+// as JSON lines to a stream, typically os.Stdout or os.Stderr, so that the
+// container platform's log pipeline collects them. Every batch is written
+// with a single Write call, so lines are not interleaved with other output
+// written to the same stream.
+type StreamAudit struct {
+	w   io.Writer    // Destination stream
+	mu  sync.Mutex   // Mutex to serialize writes
+	buf bytes.Buffer // Encoded batch, reused between writes
+}
+
+// NewStreamAudit creates a new StreamAudit writing to w.
+func NewStreamAudit(w io.Writer) *StreamAudit {
+	return &StreamAudit{w: w}
+}
+
+// Write writes an audit event as a single JSON line.
+func (a *StreamAudit) Write(ctx context.Context, e AuditEvent) error {
+	return a.WriteBatch(ctx, []AuditEvent{e})
+}
+
+// WriteBatch writes several audit events, one JSON line per event. It
+// implements BatchWriter.
+func (a *StreamAudit) WriteBatch(ctx context.Context, events []AuditEvent) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.buf.Reset()
+	enc := json.NewEncoder(&a.buf)
+	for _, e := range events {
+		if err := enc.Encode(e); err != nil {
+			return err
+		}
+	}
+	_, err := a.w.Write(a.buf.Bytes())
+	return err
+}
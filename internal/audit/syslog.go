@@ -0,0 +1,160 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Syslog message settings.
+const (
+	// syslogPriority is facility 13 (log audit) with severity 6 (informational)
+	syslogPriority = 13*8 + 6
+	// syslogAppName is the APP-NAME of the messages
+	syslogAppName = "go-shortener"
+	// syslogMsgID is the MSGID of the messages
+	syslogMsgID = "audit"
+	// syslogSDID is the ID of the structured data element carrying the event fields
+	syslogSDID = "audit@32473"
+	// syslogDialTimeout bounds how long connecting to the server may take
+	syslogDialTimeout = 5 * time.Second
+)
+
+// SyslogAudit implements the AuditWriter interface for sending audit events
+// as RFC 5424 syslog messages. Each message carries the event fields as
+// structured data and the JSON-encoded event as its body.
+//
+// Messages are sent to a local socket (unixgram) or a remote server over UDP
+// or TCP; TCP uses octet-counting framing (RFC 6587). A broken connection is
+// re-established on the next write.
+type SyslogAudit struct {
+	network  string // "udp", "tcp" or "unixgram"
+	addr     string // Server address or socket path
+	hostname string // HOSTNAME of the messages
+	procID   string // PROCID of the messages
+
+	mu   sync.Mutex // Serializes writes and reconnects
+	conn net.Conn   // Current connection, nil if not connected
+}
+
+// NewSyslogAudit creates a SyslogAudit sending to the server given as a URL:
+// "udp://host:514", "tcp://host:601" or "unix:///dev/log" for the local
+// syslog socket. The connection is opened right away so that
+// misconfiguration is reported at startup.
+func NewSyslogAudit(rawURL string) (*SyslogAudit, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	a := &SyslogAudit{procID: strconv.Itoa(os.Getpid())}
+	switch u.Scheme {
+	case "udp", "tcp":
+		a.network, a.addr = u.Scheme, u.Host
+	case "unix":
+		a.network, a.addr = "unixgram", u.Path
+	default:
+		return nil, fmt.Errorf("unsupported syslog scheme %q", u.Scheme)
+	}
+	if a.hostname, err = os.Hostname(); err != nil || a.hostname == "" {
+		a.hostname = "-"
+	}
+	if err := a.connect(); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// Write sends an audit event as a syslog message. If sending fails, it
+// reconnects and tries once more before reporting the error.
+func (a *SyslogAudit) Write(ctx context.Context, e AuditEvent) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	msg, err := a.format(e)
+	if err != nil {
+		return err
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for attempt := 0; attempt < 2; attempt++ {
+		if a.conn == nil {
+			if err = a.connect(); err != nil {
+				return err
+			}
+		}
+		if _, err = a.conn.Write(msg); err == nil {
+			return nil
+		}
+		a.conn.Close()
+		a.conn = nil
+	}
+	return err
+}
+
+// Close closes the connection to the syslog server. It implements Closer.
+func (a *SyslogAudit) Close(_ context.Context) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.conn == nil {
+		return nil
+	}
+	err := a.conn.Close()
+	a.conn = nil
+	return err
+}
+
+// connect opens a new connection to the syslog server.
+func (a *SyslogAudit) connect() error {
+	conn, err := net.DialTimeout(a.network, a.addr, syslogDialTimeout)
+	if err != nil {
+		return err
+	}
+	a.conn = conn
+	return nil
+}
+
+// format encodes e as an RFC 5424 message, framed for the connection's network.
+func (a *SyslogAudit) format(e AuditEvent) ([]byte, error) {
+	body, err := json.Marshal(e)
+	if err != nil {
+		return nil, err
+	}
+
+	var sd strings.Builder
+	sd.WriteString("[" + syslogSDID)
+	for _, param := range [][2]string{
+		{"action", e.Action},
+		{"user_id", e.UserID},
+		{"url", e.URL},
+		{"ip", e.IP},
+	} {
+		if param[1] != "" {
+			fmt.Fprintf(&sd, " %s=\"%s\"", param[0], escapeSDParam(param[1]))
+		}
+	}
+	sd.WriteString("]")
+
+	ts := time.Unix(e.TimeStamp, 0).UTC().Format(time.RFC3339)
+	msg := fmt.Sprintf("<%d>1 %s %s %s %s %s %s %s",
+		syslogPriority, ts, a.hostname, syslogAppName, a.procID, syslogMsgID, sd.String(), body)
+	if a.network == "tcp" {
+		msg = strconv.Itoa(len(msg)) + " " + msg
+	}
+	return []byte(msg), nil
+}
+
+// sdParamEscaper escapes the characters RFC 5424 reserves in structured data parameter values.
+var sdParamEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`)
+
+// escapeSDParam escapes a structured data parameter value.
+func escapeSDParam(value string) string {
+	return sdParamEscaper.Replace(value)
+}
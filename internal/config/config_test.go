@@ -2,11 +2,14 @@ package config
 
 import (
 	"flag"
+	"net"
 	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
-	"go.uber.org/zap/zapcore"
+	"github.com/stretchr/testify/require"
 )
 
 func TestParseFlags(t *testing.T) {
@@ -18,10 +21,126 @@ func TestParseFlags(t *testing.T) {
 	for _, env := range []string{
 		"SERVER_ADDRESS",
 		"BASE_URL",
+		"LOG_LEVEL",
 		"FILE_STORAGE_PATH",
 		"DATABASE_DSN",
 		"AUDIT_FILE",
 		"AUDIT_URL",
+		"AUTH_SECRET",
+		"RESOLVE_CACHE_SIZE",
+		"RESOLVE_CACHE_TTL",
+		"CLEANUP_INTERVAL",
+		"DELETED_RETENTION",
+		"CLEANUP_DRY_RUN",
+		"ALIAS_RESERVED",
+		"ALIAS_BLOCKED",
+		"STATS_BUCKET",
+		"STATS_FLUSH_INTERVAL",
+		"HASH_CODES",
+		"DEDUP_PER_USER",
+		"USER_RATE_LIMIT",
+		"USER_RATE_BURST",
+		"RETENTION_INTERVAL",
+		"ANONYMOUS_MAX_AGE",
+		"RETENTION_DRY_RUN",
+		"OIDC_ISSUER",
+		"OIDC_CLIENT_ID",
+		"OIDC_CLIENT_SECRET",
+		"OIDC_REDIRECT_URL",
+		"SHORTEN_RATE_LIMIT",
+		"SHORTEN_RATE_BURST",
+		"REDIRECT_RATE_LIMIT",
+		"REDIRECT_RATE_BURST",
+		"REQUEST_TIMEOUT",
+		"REDIRECT_TIMEOUT",
+		"BATCH_TIMEOUT",
+		"TRUSTED_SUBNET",
+		"TRUSTED_PROXIES",
+		"TRACING_ENDPOINT",
+		"TRACING_INSECURE",
+		"TRACING_SAMPLE_RATIO",
+		"SHORTEN_BODY_LIMIT",
+		"BATCH_BODY_LIMIT",
+		"MAINTENANCE_RETRY_AFTER",
+		"ADMIN_USER",
+		"ADMIN_PASSWORD",
+		"ADMIN_PASSWORD_FILE",
+		"COOKIE_SECURE",
+		"COOKIE_SAMESITE",
+		"COOKIE_DOMAIN",
+		"COOKIE_MAX_AGE",
+		"BLOCKLIST_FILE",
+		"BLOCK_REDIRECTS",
+		"AUDIT_NATS_URL",
+		"AUDIT_NATS_SUBJECT",
+		"AUDIT_NATS_STREAM",
+		"AUDIT_SYSLOG",
+		"AUDIT_DB",
+		"AUDIT_DB_RETENTION",
+		"AUDIT_CLICKHOUSE_URL",
+		"AUDIT_CLICKHOUSE_TABLE",
+		"AUDIT_CLICKHOUSE_BATCH_SIZE",
+		"AUDIT_CLICKHOUSE_FLUSH_INTERVAL",
+		"AUDIT_QUEUE_SIZE",
+		"AUDIT_WORKERS",
+		"AUDIT_URL_SECRET",
+		"AUDIT_STDOUT",
+		"AUDIT_CONFIG",
+		"AUDIT_URL_ENCODING",
+		"AUDIT_NATS_ENCODING",
+		"AUDIT_GELF",
+		"ENABLE_HTTPS",
+		"CONFIG",
+		"TLS_CERT_FILE",
+		"TLS_KEY_FILE",
+		"AUTOCERT_DOMAINS",
+		"AUTOCERT_CACHE_DIR",
+		"AUTOCERT_EMAIL",
+		"AUTOCERT_HTTP_ADDRESS",
+		"ENV_FILE",
+		"READ_TIMEOUT",
+		"WRITE_TIMEOUT",
+		"IDLE_TIMEOUT",
+		"SHUTDOWN_TIMEOUT",
+		"DB_QUERY_TIMEOUT",
+		"LOG_OUTPUT",
+		"ENABLE_AUDIT",
+		"ENABLE_STATS",
+		"ENABLE_PPROF",
+		"HTTP_ADDRESS",
+		"HTTP_REDIRECT",
+		"ENABLE_HTTP2",
+		"ENABLE_H2C",
+		"READ_HEADER_TIMEOUT",
+		"MAX_HEADER_BYTES",
+		"MAX_IN_FLIGHT",
+		"REUSE_PORT",
+		"SENTRY_DSN",
+		"SENTRY_ENVIRONMENT",
+		"TLS_CLIENT_CA_FILE",
+		"MTLS_ADDRESS",
+		"PROFILE_DIR",
+		"PROFILE_LATENCY_P99",
+		"PROFILE_GOROUTINES",
+		"PROFILE_SUSTAIN",
+		"PROFILE_COOLDOWN",
+		"PROFILE_CPU_DURATION",
+		"SNAPSHOT_INTERVAL",
+		"SNAPSHOT_CHANGES",
+		"STORAGE_QUEUE_SIZE",
+		"COMPACT_INTERVAL",
+		"SNAPSHOT_URL",
+		"SNAPSHOT_REGION",
+		"SNAPSHOT_ACCESS_KEY",
+		"SNAPSHOT_SECRET_KEY",
+		"STORAGE_KEY",
+		"STORAGE_KEY_FILE",
+		"DELETE_BATCH_SIZE",
+		"DELETE_FLUSH_INTERVAL",
+		"DELETE_QUEUE_SIZE",
+		"DELETE_WORKERS",
+		"DB_BREAKER_THRESHOLD",
+		"DB_BREAKER_COOLDOWN",
 	} {
 		if val, ok := os.LookupEnv(env); ok {
 			oldEnv[env] = val
@@ -39,11 +158,10 @@ func TestParseFlags(t *testing.T) {
 
 	// Test cases
 	testCases := []struct {
-		name          string
-		args          []string
-		envVars       map[string]string
-		expected      *Config
-		expectedLevel zapcore.Level
+		name     string
+		args     []string
+		envVars  map[string]string
+		expected *Config
 	}{
 		{
 			name:    "default values",
@@ -52,12 +170,99 @@ func TestParseFlags(t *testing.T) {
 			expected: &Config{
 				RunAddr:         "localhost:8080",
 				ReturnPrefix:    "http://localhost:8080",
+				LogLevel:        "info",
 				StorageFilePath: "./storage.json",
 				DatabaseDSN:     "", // Default is empty string
 				AuditFile:       "",
 				AuditURL:        "",
+
+				ResolveCacheSize: 0,
+				ResolveCacheTTL:  time.Minute,
+
+				CleanupInterval:  0,
+				DeletedRetention: 30 * 24 * time.Hour,
+				CleanupDryRun:    false,
+
+				StatsBucket:        time.Hour,
+				StatsFlushInterval: 0,
+
+				UserRateLimit: 0,
+				UserRateBurst: 20,
+
+				RetentionInterval: 0,
+				AnonymousMaxAge:   90 * 24 * time.Hour,
+				RetentionDryRun:   false,
+
+				ShortenRateBurst:  20,
+				RedirectRateBurst: 100,
+
+				RequestTimeout:  5 * time.Second,
+				RedirectTimeout: time.Second,
+				BatchTimeout:    30 * time.Second,
+
+				TracingSampleRatio: 1,
+
+				ShortenBodyLimit: 8 << 10,
+				BatchBodyLimit:   1 << 20,
+
+				MaintenanceRetryAfter: time.Minute,
+
+				CookieSameSite: "lax",
+				CookieMaxAge:   365 * 24 * time.Hour,
+
+				AuditNATSSubject: "audit.events",
+				AuditNATSStream:  "AUDIT",
+
+				AuditDBRetention: 90 * 24 * time.Hour,
+
+				AuditClickHouseTable:         "audit_events",
+				AuditClickHouseBatchSize:     1000,
+				AuditClickHouseFlushInterval: 5 * time.Second,
+
+				AuditQueueSize: 1024,
+				AuditWorkers:   2,
+
+				AuditURLEncoding:  "json",
+				AuditNATSEncoding: "json",
+
+				AutocertCacheDir: "./autocert",
+				AutocertHTTPAddr: ":80",
+
+				EnvFile: ".env",
+
+				ReadTimeout:     15 * time.Second,
+				WriteTimeout:    time.Minute,
+				IdleTimeout:     2 * time.Minute,
+				ShutdownTimeout: 10 * time.Second,
+				DBQueryTimeout:  5 * time.Second,
+
+				LogOutput: []string{"stdout"},
+
+				EnableAudit: true,
+				EnableStats: true,
+				EnablePprof: false,
+
+				HTTPRedirect: true,
+
+				EnableHTTP2: true,
+
+				ReadHeaderTimeout: 5 * time.Second,
+				MaxHeaderBytes:    1 << 20,
+
+				ProfileSustain:     time.Minute,
+				ProfileCooldown:    30 * time.Minute,
+				ProfileCPUDuration: 10 * time.Second,
+
+				SnapshotRegion: "us-east-1",
+
+				DeleteBatchSize:     50,
+				DeleteFlushInterval: 100 * time.Millisecond,
+				DeleteQueueSize:     100,
+				DeleteWorkers:       1,
+
+				DBBreakerThreshold: 5,
+				DBBreakerCooldown:  10 * time.Second,
 			},
-			expectedLevel: zapcore.InfoLevel,
 		},
 		{
 			name: "command line flags",
@@ -70,17 +275,296 @@ func TestParseFlags(t *testing.T) {
 				"-d=host=localhost port=5432 user=user password=pass dbname=db sslmode=disable",
 				"-audit-file=/tmp/audit.log",
 				"-audit-url=http://audit.example.com",
+				"-auth-secret=secret",
+				"-resolve-cache-size=1000",
+				"-resolve-cache-ttl=30s",
+				"-cleanup-interval=1h",
+				"-deleted-retention=48h",
+				"-cleanup-dry-run",
+				"-alias-reserved=login, logout",
+				"-alias-blocked=spam",
+				"-stats-bucket=5m",
+				"-stats-flush-interval=10s",
+				"-hash-codes",
+				"-dedup-per-user",
+				"-user-rate-limit=0.5",
+				"-user-rate-burst=5",
+				"-retention-interval=24h",
+				"-anonymous-max-age=720h",
+				"-retention-dry-run",
+				"-oidc-issuer=https://sso.example.com",
+				"-oidc-client-id=shortener",
+				"-oidc-client-secret=client-secret",
+				"-oidc-redirect-url=https://example.com/auth/callback",
+				"-shorten-rate-limit=2",
+				"-shorten-rate-burst=10",
+				"-redirect-rate-limit=50",
+				"-redirect-rate-burst=200",
+				"-request-timeout=3s",
+				"-redirect-timeout=500ms",
+				"-batch-timeout=1m",
+				"-t=10.0.0.0/8",
+				"-trusted-proxies=10.0.0.1, 172.16.0.0/12",
+				"-tracing-endpoint=otel:4318",
+				"-tracing-insecure",
+				"-tracing-sample-ratio=0.25",
+				"-shorten-body-limit=1024",
+				"-batch-body-limit=2048",
+				"-maintenance-retry-after=5m",
+				"-admin-user=root",
+				"-admin-password=s3cret",
+				"-admin-password-file=/run/secrets/admin",
+				"-cookie-secure=true",
+				"-cookie-samesite=strict",
+				"-cookie-domain=example.com",
+				"-cookie-max-age=720h",
+				"-blocklist-file=/etc/shortener/blocklist.json",
+				"-block-redirects",
+				"-audit-nats-url=nats://nats:4222",
+				"-audit-nats-subject=shortener.audit",
+				"-audit-nats-stream=SHORTENER_AUDIT",
+				"-audit-syslog=udp://siem:514",
+				"-audit-db",
+				"-audit-db-retention=720h",
+				"-audit-clickhouse-url=http://clickhouse:8123",
+				"-audit-clickhouse-table=analytics.audit",
+				"-audit-clickhouse-batch-size=500",
+				"-audit-clickhouse-flush-interval=10s",
+				"-audit-queue-size=4096",
+				"-audit-workers=4",
+				"-audit-url-secret=hook-secret",
+				"-audit-stdout=stderr",
+				"-audit-config=/etc/shortener/audit.json",
+				"-audit-url-encoding=protobuf",
+				"-audit-nats-encoding=protobuf",
+				"-audit-gelf=udp://graylog:12201",
+				"-s",
+				"-tls-cert-file=/etc/shortener/tls.crt",
+				"-tls-key-file=/etc/shortener/tls.key",
+				"-autocert-domains=sho.rt, www.sho.rt",
+				"-autocert-cache-dir=/var/lib/shortener/autocert",
+				"-autocert-email=ops@sho.rt",
+				"-autocert-http-address=:8080",
+				"-env-file=" + os.DevNull,
+				"-read-timeout=5s",
+				"-write-timeout=2m",
+				"-idle-timeout=30s",
+				"-shutdown-timeout=30s",
+				"-db-query-timeout=2s",
+				"-log-output=stdout,/var/log/shortener/shortener.log",
+				"-enable-audit=false",
+				"-enable-stats=false",
+				"-enable-pprof",
+				"-http-address=:8081",
+				"-http-redirect=false",
+				"-enable-http2=false",
+				"-enable-h2c",
+				"-read-header-timeout=2s",
+				"-max-header-bytes=65536",
+				"-max-in-flight=500",
+				"-reuse-port",
+				"-sentry-dsn=https://key@sentry.example.com/1",
+				"-sentry-environment=staging",
+				"-tls-client-ca-file=/etc/shortener/clients.pem",
+				"-mtls-address=:8443",
+				"-profile-dir=/var/lib/shortener/profiles",
+				"-profile-latency-p99=500ms",
+				"-profile-goroutines=10000",
+				"-profile-sustain=2m",
+				"-profile-cooldown=1h",
+				"-profile-cpu-duration=30s",
+				"-snapshot-interval=5s",
+				"-snapshot-changes=1000",
+				"-storage-queue-size=1024",
+				"-compact-interval=1h",
+				"-snapshot-url=https://s3.example.com/bucket/storage.json",
+				"-snapshot-region=eu-central-1",
+				"-snapshot-access-key=AKID",
+				"-snapshot-secret-key=secret",
+				"-storage-key=c2VjcmV0",
+				"-storage-key-file=/run/secrets/storage_key",
+				"-delete-batch-size=200",
+				"-delete-flush-interval=1s",
+				"-delete-queue-size=1000",
+				"-delete-workers=4",
+				"-db-breaker-threshold=3",
+				"-db-breaker-cooldown=30s",
 			},
 			envVars: map[string]string{},
 			expected: &Config{
 				RunAddr:         ":9090",
 				ReturnPrefix:    "https://example.com",
+				LogLevel:        "debug",
 				StorageFilePath: "/tmp/storage.json",
 				DatabaseDSN:     "host=localhost port=5432 user=user password=pass dbname=db sslmode=disable",
 				AuditFile:       "/tmp/audit.log",
 				AuditURL:        "http://audit.example.com",
+				AuthSecret:      "secret",
+
+				ResolveCacheSize: 1000,
+				ResolveCacheTTL:  30 * time.Second,
+
+				CleanupInterval:  time.Hour,
+				DeletedRetention: 48 * time.Hour,
+				CleanupDryRun:    true,
+
+				AliasReserved: []string{"login", "logout"},
+				AliasBlocked:  []string{"spam"},
+
+				StatsBucket:        5 * time.Minute,
+				StatsFlushInterval: 10 * time.Second,
+
+				HashCodes:    true,
+				DedupPerUser: true,
+
+				UserRateLimit: 0.5,
+				UserRateBurst: 5,
+
+				RetentionInterval: 24 * time.Hour,
+				AnonymousMaxAge:   720 * time.Hour,
+				RetentionDryRun:   true,
+
+				OIDCIssuer:       "https://sso.example.com",
+				OIDCClientID:     "shortener",
+				OIDCClientSecret: "client-secret",
+				OIDCRedirectURL:  "https://example.com/auth/callback",
+
+				ShortenRateLimit:  2,
+				ShortenRateBurst:  10,
+				RedirectRateLimit: 50,
+				RedirectRateBurst: 200,
+
+				RequestTimeout:  3 * time.Second,
+				RedirectTimeout: 500 * time.Millisecond,
+				BatchTimeout:    time.Minute,
+
+				TrustedSubnet: &net.IPNet{IP: net.IPv4(10, 0, 0, 0).To4(), Mask: net.CIDRMask(8, 32)},
+
+				TrustedProxies: []string{"10.0.0.1", "172.16.0.0/12"},
+
+				TracingEndpoint:    "otel:4318",
+				TracingInsecure:    true,
+				TracingSampleRatio: 0.25,
+
+				ShortenBodyLimit: 1024,
+				BatchBodyLimit:   2048,
+
+				MaintenanceRetryAfter: 5 * time.Minute,
+
+				AdminUser:         "root",
+				AdminPassword:     "s3cret",
+				AdminPasswordFile: "/run/secrets/admin",
+
+				CookieSecure:   "true",
+				CookieSameSite: "strict",
+				CookieDomain:   "example.com",
+				CookieMaxAge:   720 * time.Hour,
+
+				BlocklistFile:  "/etc/shortener/blocklist.json",
+				BlockRedirects: true,
+
+				AuditNATSURL:     "nats://nats:4222",
+				AuditNATSSubject: "shortener.audit",
+				AuditNATSStream:  "SHORTENER_AUDIT",
+
+				AuditSyslog: "udp://siem:514",
+
+				AuditDB:          true,
+				AuditDBRetention: 720 * time.Hour,
+
+				AuditClickHouseURL:           "http://clickhouse:8123",
+				AuditClickHouseTable:         "analytics.audit",
+				AuditClickHouseBatchSize:     500,
+				AuditClickHouseFlushInterval: 10 * time.Second,
+
+				AuditQueueSize: 4096,
+				AuditWorkers:   4,
+
+				AuditURLSecret: "hook-secret",
+
+				AuditStdout: "stderr",
+
+				AuditConfig: "/etc/shortener/audit.json",
+
+				AuditURLEncoding:  "protobuf",
+				AuditNATSEncoding: "protobuf",
+
+				AuditGELF: "udp://graylog:12201",
+
+				EnableHTTPS: true,
+				ConfigFile:  "",
+
+				TLSCertFile: "/etc/shortener/tls.crt",
+				TLSKeyFile:  "/etc/shortener/tls.key",
+
+				AutocertDomains:  []string{"sho.rt", "www.sho.rt"},
+				AutocertCacheDir: "/var/lib/shortener/autocert",
+				AutocertEmail:    "ops@sho.rt",
+				AutocertHTTPAddr: ":8080",
+
+				EnvFile: os.DevNull,
+
+				ReadTimeout:     5 * time.Second,
+				WriteTimeout:    2 * time.Minute,
+				IdleTimeout:     30 * time.Second,
+				ShutdownTimeout: 30 * time.Second,
+				DBQueryTimeout:  2 * time.Second,
+
+				LogOutput: []string{"stdout", "/var/log/shortener/shortener.log"},
+
+				EnableAudit: false,
+				EnableStats: false,
+				EnablePprof: true,
+
+				HTTPAddr:     ":8081",
+				HTTPRedirect: false,
+
+				EnableHTTP2: false,
+				EnableH2C:   true,
+
+				ReadHeaderTimeout: 2 * time.Second,
+				MaxHeaderBytes:    65536,
+
+				MaxInFlight: 500,
+
+				ReusePort: true,
+
+				SentryDSN:         "https://key@sentry.example.com/1",
+				SentryEnvironment: "staging",
+
+				TLSClientCAFile: "/etc/shortener/clients.pem",
+				MTLSAddr:        ":8443",
+
+				ProfileDir:         "/var/lib/shortener/profiles",
+				ProfileLatencyP99:  500 * time.Millisecond,
+				ProfileGoroutines:  10000,
+				ProfileSustain:     2 * time.Minute,
+				ProfileCooldown:    time.Hour,
+				ProfileCPUDuration: 30 * time.Second,
+
+				SnapshotInterval: 5 * time.Second,
+				SnapshotChanges:  1000,
+
+				StorageQueueSize: 1024,
+
+				CompactInterval: time.Hour,
+
+				SnapshotURL:       "https://s3.example.com/bucket/storage.json",
+				SnapshotRegion:    "eu-central-1",
+				SnapshotAccessKey: "AKID",
+				SnapshotSecretKey: "secret",
+
+				StorageKey:     "c2VjcmV0",
+				StorageKeyFile: "/run/secrets/storage_key",
+
+				DeleteBatchSize:     200,
+				DeleteFlushInterval: time.Second,
+				DeleteQueueSize:     1000,
+				DeleteWorkers:       4,
+
+				DBBreakerThreshold: 3,
+				DBBreakerCooldown:  30 * time.Second,
 			},
-			expectedLevel: zapcore.DebugLevel,
 		},
 	}
 
@@ -91,9 +575,6 @@ func TestParseFlags(t *testing.T) {
 				t.Setenv(k, v)
 			}
 
-			// Reset command line flags
-			flag.CommandLine = flag.NewFlagSet(tc.args[0], flag.ExitOnError)
-
 			// Set command line arguments
 			os.Args = tc.args
 
@@ -103,28 +584,319 @@ func TestParseFlags(t *testing.T) {
 			// Verify the config values
 			assert.Equal(t, tc.expected.RunAddr, config.RunAddr)
 			assert.Equal(t, tc.expected.ReturnPrefix, config.ReturnPrefix)
+			assert.Equal(t, tc.expected.LogLevel, config.LogLevel)
 			assert.Equal(t, tc.expected.StorageFilePath, config.StorageFilePath)
 			assert.Equal(t, tc.expected.DatabaseDSN, config.DatabaseDSN)
 			assert.Equal(t, tc.expected.AuditFile, config.AuditFile)
 			assert.Equal(t, tc.expected.AuditURL, config.AuditURL)
-
-			// Verify the logger level
-			assert.Equal(t, tc.expectedLevel, config.Logger.Level())
+			assert.Equal(t, tc.expected.AuthSecret, config.AuthSecret)
+			assert.Equal(t, tc.expected.ResolveCacheSize, config.ResolveCacheSize)
+			assert.Equal(t, tc.expected.ResolveCacheTTL, config.ResolveCacheTTL)
+			assert.Equal(t, tc.expected.CleanupInterval, config.CleanupInterval)
+			assert.Equal(t, tc.expected.DeletedRetention, config.DeletedRetention)
+			assert.Equal(t, tc.expected.CleanupDryRun, config.CleanupDryRun)
+			assert.Equal(t, tc.expected.AliasReserved, config.AliasReserved)
+			assert.Equal(t, tc.expected.AliasBlocked, config.AliasBlocked)
+			assert.Equal(t, tc.expected.StatsBucket, config.StatsBucket)
+			assert.Equal(t, tc.expected.StatsFlushInterval, config.StatsFlushInterval)
+			assert.Equal(t, tc.expected.HashCodes, config.HashCodes)
+			assert.Equal(t, tc.expected.DedupPerUser, config.DedupPerUser)
+			assert.Equal(t, tc.expected.UserRateLimit, config.UserRateLimit)
+			assert.Equal(t, tc.expected.UserRateBurst, config.UserRateBurst)
+			assert.Equal(t, tc.expected.RetentionInterval, config.RetentionInterval)
+			assert.Equal(t, tc.expected.AnonymousMaxAge, config.AnonymousMaxAge)
+			assert.Equal(t, tc.expected.RetentionDryRun, config.RetentionDryRun)
+			assert.Equal(t, tc.expected.OIDCIssuer, config.OIDCIssuer)
+			assert.Equal(t, tc.expected.OIDCClientID, config.OIDCClientID)
+			assert.Equal(t, tc.expected.OIDCClientSecret, config.OIDCClientSecret)
+			assert.Equal(t, tc.expected.OIDCRedirectURL, config.OIDCRedirectURL)
+			assert.Equal(t, tc.expected.ShortenRateLimit, config.ShortenRateLimit)
+			assert.Equal(t, tc.expected.ShortenRateBurst, config.ShortenRateBurst)
+			assert.Equal(t, tc.expected.RedirectRateLimit, config.RedirectRateLimit)
+			assert.Equal(t, tc.expected.RedirectRateBurst, config.RedirectRateBurst)
+			assert.Equal(t, tc.expected.RequestTimeout, config.RequestTimeout)
+			assert.Equal(t, tc.expected.RedirectTimeout, config.RedirectTimeout)
+			assert.Equal(t, tc.expected.BatchTimeout, config.BatchTimeout)
+			assert.Equal(t, tc.expected.TrustedSubnet, config.TrustedSubnet)
+			assert.Equal(t, tc.expected.TrustedProxies, config.TrustedProxies)
+			assert.Equal(t, tc.expected.TracingEndpoint, config.TracingEndpoint)
+			assert.Equal(t, tc.expected.TracingInsecure, config.TracingInsecure)
+			assert.Equal(t, tc.expected.TracingSampleRatio, config.TracingSampleRatio)
+			assert.Equal(t, tc.expected.ShortenBodyLimit, config.ShortenBodyLimit)
+			assert.Equal(t, tc.expected.BatchBodyLimit, config.BatchBodyLimit)
+			assert.Equal(t, tc.expected.MaintenanceRetryAfter, config.MaintenanceRetryAfter)
+			assert.Equal(t, tc.expected.AdminUser, config.AdminUser)
+			assert.Equal(t, tc.expected.AdminPassword, config.AdminPassword)
+			assert.Equal(t, tc.expected.AdminPasswordFile, config.AdminPasswordFile)
+			assert.Equal(t, tc.expected.CookieSecure, config.CookieSecure)
+			assert.Equal(t, tc.expected.CookieSameSite, config.CookieSameSite)
+			assert.Equal(t, tc.expected.CookieDomain, config.CookieDomain)
+			assert.Equal(t, tc.expected.CookieMaxAge, config.CookieMaxAge)
+			assert.Equal(t, tc.expected.BlocklistFile, config.BlocklistFile)
+			assert.Equal(t, tc.expected.BlockRedirects, config.BlockRedirects)
+			assert.Equal(t, tc.expected.AuditNATSURL, config.AuditNATSURL)
+			assert.Equal(t, tc.expected.AuditNATSSubject, config.AuditNATSSubject)
+			assert.Equal(t, tc.expected.AuditNATSStream, config.AuditNATSStream)
+			assert.Equal(t, tc.expected.AuditSyslog, config.AuditSyslog)
+			assert.Equal(t, tc.expected.AuditDB, config.AuditDB)
+			assert.Equal(t, tc.expected.AuditDBRetention, config.AuditDBRetention)
+			assert.Equal(t, tc.expected.AuditClickHouseURL, config.AuditClickHouseURL)
+			assert.Equal(t, tc.expected.AuditClickHouseTable, config.AuditClickHouseTable)
+			assert.Equal(t, tc.expected.AuditClickHouseBatchSize, config.AuditClickHouseBatchSize)
+			assert.Equal(t, tc.expected.AuditClickHouseFlushInterval, config.AuditClickHouseFlushInterval)
+			assert.Equal(t, tc.expected.AuditQueueSize, config.AuditQueueSize)
+			assert.Equal(t, tc.expected.AuditWorkers, config.AuditWorkers)
+			assert.Equal(t, tc.expected.AuditURLSecret, config.AuditURLSecret)
+			assert.Equal(t, tc.expected.AuditStdout, config.AuditStdout)
+			assert.Equal(t, tc.expected.AuditConfig, config.AuditConfig)
+			assert.Equal(t, tc.expected.AuditURLEncoding, config.AuditURLEncoding)
+			assert.Equal(t, tc.expected.AuditNATSEncoding, config.AuditNATSEncoding)
+			assert.Equal(t, tc.expected.AuditGELF, config.AuditGELF)
+			assert.Equal(t, tc.expected.EnableHTTPS, config.EnableHTTPS)
+			assert.Equal(t, tc.expected.ConfigFile, config.ConfigFile)
+			assert.Equal(t, tc.expected.TLSCertFile, config.TLSCertFile)
+			assert.Equal(t, tc.expected.TLSKeyFile, config.TLSKeyFile)
+			assert.Equal(t, tc.expected.AutocertDomains, config.AutocertDomains)
+			assert.Equal(t, tc.expected.AutocertCacheDir, config.AutocertCacheDir)
+			assert.Equal(t, tc.expected.AutocertEmail, config.AutocertEmail)
+			assert.Equal(t, tc.expected.AutocertHTTPAddr, config.AutocertHTTPAddr)
+			assert.Equal(t, tc.expected.EnvFile, config.EnvFile)
+			assert.Equal(t, tc.expected.ReadTimeout, config.ReadTimeout)
+			assert.Equal(t, tc.expected.WriteTimeout, config.WriteTimeout)
+			assert.Equal(t, tc.expected.IdleTimeout, config.IdleTimeout)
+			assert.Equal(t, tc.expected.ShutdownTimeout, config.ShutdownTimeout)
+			assert.Equal(t, tc.expected.DBQueryTimeout, config.DBQueryTimeout)
+			assert.Equal(t, tc.expected.LogOutput, config.LogOutput)
+			assert.Equal(t, tc.expected.EnableAudit, config.EnableAudit)
+			assert.Equal(t, tc.expected.EnableStats, config.EnableStats)
+			assert.Equal(t, tc.expected.EnablePprof, config.EnablePprof)
+			assert.Equal(t, tc.expected.HTTPAddr, config.HTTPAddr)
+			assert.Equal(t, tc.expected.HTTPRedirect, config.HTTPRedirect)
+			assert.Equal(t, tc.expected.EnableHTTP2, config.EnableHTTP2)
+			assert.Equal(t, tc.expected.EnableH2C, config.EnableH2C)
+			assert.Equal(t, tc.expected.ReadHeaderTimeout, config.ReadHeaderTimeout)
+			assert.Equal(t, tc.expected.MaxHeaderBytes, config.MaxHeaderBytes)
+			assert.Equal(t, tc.expected.MaxInFlight, config.MaxInFlight)
+			assert.Equal(t, tc.expected.ReusePort, config.ReusePort)
+			assert.Equal(t, tc.expected.SentryDSN, config.SentryDSN)
+			assert.Equal(t, tc.expected.SentryEnvironment, config.SentryEnvironment)
+			assert.Equal(t, tc.expected.TLSClientCAFile, config.TLSClientCAFile)
+			assert.Equal(t, tc.expected.MTLSAddr, config.MTLSAddr)
+			assert.Equal(t, tc.expected.ProfileDir, config.ProfileDir)
+			assert.Equal(t, tc.expected.ProfileLatencyP99, config.ProfileLatencyP99)
+			assert.Equal(t, tc.expected.ProfileGoroutines, config.ProfileGoroutines)
+			assert.Equal(t, tc.expected.ProfileSustain, config.ProfileSustain)
+			assert.Equal(t, tc.expected.ProfileCooldown, config.ProfileCooldown)
+			assert.Equal(t, tc.expected.ProfileCPUDuration, config.ProfileCPUDuration)
+			assert.Equal(t, tc.expected.SnapshotInterval, config.SnapshotInterval)
+			assert.Equal(t, tc.expected.SnapshotChanges, config.SnapshotChanges)
+			assert.Equal(t, tc.expected.StorageQueueSize, config.StorageQueueSize)
+			assert.Equal(t, tc.expected.CompactInterval, config.CompactInterval)
+			assert.Equal(t, tc.expected.SnapshotURL, config.SnapshotURL)
+			assert.Equal(t, tc.expected.SnapshotRegion, config.SnapshotRegion)
+			assert.Equal(t, tc.expected.SnapshotAccessKey, config.SnapshotAccessKey)
+			assert.Equal(t, tc.expected.SnapshotSecretKey, config.SnapshotSecretKey)
+			assert.Equal(t, tc.expected.StorageKey, config.StorageKey)
+			assert.Equal(t, tc.expected.StorageKeyFile, config.StorageKeyFile)
+			assert.Equal(t, tc.expected.DeleteBatchSize, config.DeleteBatchSize)
+			assert.Equal(t, tc.expected.DeleteFlushInterval, config.DeleteFlushInterval)
+			assert.Equal(t, tc.expected.DeleteQueueSize, config.DeleteQueueSize)
+			assert.Equal(t, tc.expected.DeleteWorkers, config.DeleteWorkers)
+			assert.Equal(t, tc.expected.DBBreakerThreshold, config.DBBreakerThreshold)
+			assert.Equal(t, tc.expected.DBBreakerCooldown, config.DBBreakerCooldown)
 		})
 	}
 }
 
 func TestNewConfig(t *testing.T) {
-	// Save and restore command line args
+	// NewConfig ignores flags and the environment and can be called repeatedly.
 	oldArgs := os.Args
 	defer func() { os.Args = oldArgs }()
+	os.Args = []string{"test", "-a=:9090"}
+	t.Setenv("SERVER_ADDRESS", "env:8080")
 
-	// Reset command line flags
-	flag.CommandLine = flag.NewFlagSet("test", flag.ExitOnError)
-	os.Args = []string{"test"}
-
-	// Test that NewConfig doesn't panic and returns a valid config
 	config := NewConfig()
-	assert.NotNil(t, config)
-	assert.NotNil(t, config.Logger)
+	assert.Equal(t, "info", config.LogLevel)
+	assert.Equal(t, "localhost:8080", config.RunAddr)
+	assert.Equal(t, "http://localhost:8080", config.ReturnPrefix)
+	assert.Equal(t, 5*time.Second, config.RequestTimeout)
+	assert.NoError(t, config.Validate())
+
+	_, subnet, err := net.ParseCIDR("10.0.0.0/8")
+	require.NoError(t, err)
+	config = NewConfig(
+		WithAddress(":9090"),
+		WithBaseURL("https://sho.rt"),
+		WithStorageFile("/data/storage.json"),
+		WithDatabaseDSN("postgres://db/shortener"),
+		WithLogLevel("debug"),
+		WithAuthSecret("secret"),
+		WithTrustedSubnet(subnet),
+	)
+	assert.Equal(t, ":9090", config.RunAddr)
+	assert.Equal(t, "https://sho.rt", config.ReturnPrefix)
+	assert.Equal(t, "/data/storage.json", config.StorageFilePath)
+	assert.Equal(t, "postgres://db/shortener", config.DatabaseDSN)
+	assert.Equal(t, "debug", config.LogLevel)
+	assert.Equal(t, "secret", config.AuthSecret)
+	assert.Equal(t, subnet, config.TrustedSubnet)
+}
+
+func TestParseFlags_ConfigFile(t *testing.T) {
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+	unsetenv(t, "CONFIG", "SERVER_ADDRESS", "BASE_URL", "FILE_STORAGE_PATH", "DATABASE_DSN", "ENABLE_HTTPS", "AUDIT_FILE", "AUDIT_QUEUE_SIZE", "AUDIT_CONFIG")
+
+	path := filepath.Join(t.TempDir(), "config.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{
+		"server_address": "file:8080",
+		"base_url": "http://file",
+		"file_storage_path": "/data/file.json",
+		"database_dsn": "postgres://file",
+		"enable_https": true,
+		"audit": {"file": "/var/log/audit.log", "queue_size": 4096, "writers": [{"type": "stdout"}]}
+	}`), 0644))
+
+	parse := func(args ...string) *Config {
+		os.Args = append([]string{"cmd"}, args...)
+		return ParseFlags()
+	}
+
+	t.Run("file values", func(t *testing.T) {
+		config := parse("-c", path)
+		assert.Equal(t, path, config.ConfigFile)
+		assert.Equal(t, "file:8080", config.RunAddr)
+		assert.Equal(t, "http://file", config.ReturnPrefix)
+		assert.Equal(t, "/data/file.json", config.StorageFilePath)
+		assert.Equal(t, "postgres://file", config.DatabaseDSN)
+		assert.True(t, config.EnableHTTPS)
+		assert.Equal(t, "/var/log/audit.log", config.AuditFile)
+		assert.Equal(t, 4096, config.AuditQueueSize)
+		assert.Equal(t, path, config.AuditConfig, "audit writers are loaded from the config file")
+		assert.Equal(t, 2, config.AuditWorkers, "absent keys keep their defaults")
+	})
+
+	t.Run("env over flags over file", func(t *testing.T) {
+		t.Setenv("CONFIG", path)
+		t.Setenv("SERVER_ADDRESS", "env:8080")
+		config := parse("-a", "flag:8080", "-b", "http://flag")
+		assert.Equal(t, "env:8080", config.RunAddr)
+		assert.Equal(t, "http://flag", config.ReturnPrefix)
+		assert.Equal(t, "/data/file.json", config.StorageFilePath)
+	})
+
+	t.Run("long flag", func(t *testing.T) {
+		config := parse("--config=" + path)
+		assert.Equal(t, "file:8080", config.RunAddr)
+	})
+}
+
+func TestParseArgs(t *testing.T) {
+	unsetenv(t, "ENV_FILE", "CONFIG", "FILE_STORAGE_PATH", "DATABASE_DSN")
+
+	fs := flag.NewFlagSet("shortener export", flag.ContinueOnError)
+	config := ParseArgs(fs, []string{"-f", "/data/storage.json", "urls.json"})
+	assert.Equal(t, "/data/storage.json", config.StorageFilePath)
+	assert.Equal(t, "shortener export", fs.Name())
+	assert.Equal(t, []string{"urls.json"}, fs.Args(), "positional arguments are left to the subcommand")
+}
+
+func TestParseFlags_YAMLConfigFile(t *testing.T) {
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+	unsetenv(t, "CONFIG", "SERVER_ADDRESS", "DATABASE_DSN", "LOG_LEVEL", "TRUSTED_PROXIES", "RESOLVE_CACHE_TTL", "AUDIT_FILE", "AUDIT_CONFIG", "AUTH_SECRET", "COOKIE_SECURE", "SHORTEN_RATE_LIMIT", "ALIAS_RESERVED", "ENABLE_PPROF", "ENABLE_AUDIT")
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+server:
+  address: yaml:8080
+  log_level: debug
+  trusted_proxies: [10.0.0.0/8, 192.168.0.0/16]
+storage:
+  database_dsn: postgres://yaml
+  resolve_cache_ttl: 5m
+audit:
+  file: /var/log/audit.log
+  writers:
+    - type: stdout
+auth:
+  secret: yaml-secret
+  cookie_secure: true
+limits:
+  shorten_rate_limit: 2.5
+  alias_reserved: [admin, api]
+features:
+  enable_pprof: true
+`), 0644))
+
+	parse := func(args ...string) *Config {
+		os.Args = append([]string{"cmd"}, args...)
+		return ParseFlags()
+	}
+
+	config := parse("-c", path, "-l", "warn")
+	assert.Equal(t, "yaml:8080", config.RunAddr)
+	assert.Equal(t, "warn", config.LogLevel, "flags override the file")
+	assert.Equal(t, []string{"10.0.0.0/8", "192.168.0.0/16"}, config.TrustedProxies)
+	assert.Equal(t, "postgres://yaml", config.DatabaseDSN)
+	assert.Equal(t, 5*time.Minute, config.ResolveCacheTTL)
+	assert.Equal(t, "/var/log/audit.log", config.AuditFile)
+	assert.Equal(t, path, config.AuditConfig)
+	assert.Equal(t, "yaml-secret", config.AuthSecret)
+	assert.Equal(t, "true", config.CookieSecure)
+	assert.Equal(t, 2.5, config.ShortenRateLimit)
+	assert.Equal(t, []string{"admin", "api"}, config.AliasReserved)
+	assert.True(t, config.EnablePprof)
+	assert.True(t, config.EnableAudit)
+}
+
+func TestApplyConfigFile_Errors(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name    string
+		file    string
+		content string
+	}{
+		{"unknown key", "config.yaml", "server:\n  adress: :8080\n"},
+		{"unknown section", "config.json", `{"cache": {"size": 10}}`},
+		{"invalid value", "config.yml", "storage:\n  resolve_cache_ttl: soon\n"},
+		{"nested list", "config.yaml", "limits:\n  alias_reserved: [[admin]]\n"},
+		{"malformed", "config.yaml", "server: [\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, tt.file)
+			require.NoError(t, os.WriteFile(path, []byte(tt.content), 0644))
+			fs := flag.NewFlagSet("cmd", flag.ContinueOnError)
+			fs.String("a", "", "")
+			fs.String("alias-reserved", "", "")
+			fs.Duration("resolve-cache-ttl", 0, "")
+			assert.Error(t, applyConfigFile(fs, path))
+		})
+	}
+}
+
+func TestConfigFilePath(t *testing.T) {
+	unsetenv(t, "CONFIG")
+	assert.Equal(t, "", configFilePath([]string{"-a", ":8080"}))
+	assert.Equal(t, "a.json", configFilePath([]string{"-c", "a.json"}))
+	assert.Equal(t, "b.json", configFilePath([]string{"-c=a.json", "--config", "b.json"}))
+	assert.Equal(t, "", configFilePath([]string{"--", "-c", "a.json"}))
+
+	t.Setenv("CONFIG", "env.json")
+	assert.Equal(t, "env.json", configFilePath([]string{"-c", "a.json"}))
+
+	t.Setenv("CONFIG", "")
+	assert.Equal(t, "", configFilePath([]string{"-c", "a.json"}), "an empty CONFIG disables the file")
+}
+
+// unsetenv unsets the environment variables for the duration of the test;
+// setting them to an empty string would reset their settings instead.
+func unsetenv(t *testing.T, names ...string) {
+	t.Helper()
+	for _, name := range names {
+		t.Setenv(name, "") // restores the variable after the test
+		require.NoError(t, os.Unsetenv(name))
+	}
 }
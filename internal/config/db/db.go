@@ -1,8 +1,10 @@
 package db
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
+	"os"
 
 	_ "github.com/lib/pq"
 
@@ -29,6 +31,22 @@ func ApplyMigrations(db *sql.DB) error {
 		return fmt.Errorf("failed to apply migrations: %w", err)
 	}
 
-	fmt.Println("Migrations applied successfully!")
+	// stderr keeps the output of subcommands such as export clean.
+	fmt.Fprintln(os.Stderr, "Migrations applied successfully!")
+	return nil
+}
+
+// Migrate runs the goose command, e.g. "up", "down" or "status", with args
+// against the migrations of the "./migrations/" directory. It backs the
+// migrate subcommand, so that the schema can be managed without starting
+// the server, which applies pending migrations on its own.
+func Migrate(ctx context.Context, db *sql.DB, command string, args ...string) error {
+	if err := goose.SetDialect("postgres"); err != nil {
+		return fmt.Errorf("failed to set dialect: %w", err)
+	}
+
+	if err := goose.RunContext(ctx, command, db, "./migrations/", args...); err != nil {
+		return fmt.Errorf("failed to run migrations %s: %w", command, err)
+	}
 	return nil
 }
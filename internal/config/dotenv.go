@@ -0,0 +1,105 @@
+package config
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// defaultEnvFile is the dotenv file loaded when ENV_FILE and -env-file are not set.
+const defaultEnvFile = ".env"
+
+// envFilePath returns the dotenv file named by ENV_FILE or, if it is not
+// set, by the last -env-file argument in args. explicit reports whether it
+// was named at all; otherwise defaultEnvFile is returned.
+func envFilePath(args []string) (path string, explicit bool) {
+	if env := os.Getenv("ENV_FILE"); env != "" {
+		return env, true
+	}
+	if path := argValue(args, "env-file"); path != "" {
+		return path, true
+	}
+	return defaultEnvFile, false
+}
+
+// loadEnvFile sets the environment variables listed in the dotenv file at
+// path, one KEY=VALUE per line. Variables that are already set to a
+// non-empty value are left alone, so the real environment wins. Blank lines
+// and lines starting with # are skipped, an "export " prefix is allowed, and
+// values may be quoted: double-quoted values support the \n, \t, \" and \\
+// escapes, single-quoted values are taken literally. A missing file is an
+// error only if required is set.
+func loadEnvFile(path string, required bool) error {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) && !required {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, ok := strings.Cut(strings.TrimPrefix(line, "export "), "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" || strings.ContainsAny(key, " \t") {
+			return fmt.Errorf("%s:%d: expected KEY=VALUE", path, n)
+		}
+		if value, err = parseEnvValue(strings.TrimSpace(value)); err != nil {
+			return fmt.Errorf("%s:%d: %s: %w", path, n, key, err)
+		}
+		if os.Getenv(key) != "" {
+			continue
+		}
+		if err := os.Setenv(key, value); err != nil {
+			return fmt.Errorf("%s:%d: %w", path, n, err)
+		}
+	}
+	return scanner.Err()
+}
+
+// parseEnvValue unquotes a dotenv value. Unquoted values end at a " #"
+// comment.
+func parseEnvValue(value string) (string, error) {
+	switch {
+	case strings.HasPrefix(value, `"`):
+		end := closingQuote(value)
+		if end < 0 {
+			return "", errors.New("unterminated double quote")
+		}
+		return strconv.Unquote(value[:end+1])
+	case strings.HasPrefix(value, "'"):
+		end := strings.IndexByte(value[1:], '\'')
+		if end < 0 {
+			return "", errors.New("unterminated single quote")
+		}
+		return value[1 : end+1], nil
+	}
+	if i := strings.Index(value, " #"); i >= 0 {
+		value = strings.TrimSpace(value[:i])
+	}
+	return value, nil
+}
+
+// closingQuote returns the index of the unescaped double quote closing the
+// string value starts with, or -1.
+func closingQuote(value string) int {
+	for i := 1; i < len(value); i++ {
+		switch value[i] {
+		case '\\':
+			i++
+		case '"':
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,63 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadEnvFile(t *testing.T) {
+	for _, env := range []string{"DOTENV_PLAIN", "DOTENV_EXPORTED", "DOTENV_DOUBLE", "DOTENV_SINGLE", "DOTENV_COMMENT", "DOTENV_EMPTY", "DOTENV_SET"} {
+		t.Setenv(env, "")
+	}
+	t.Setenv("DOTENV_SET", "from environment")
+
+	path := filepath.Join(t.TempDir(), ".env")
+	require.NoError(t, os.WriteFile(path, []byte(`
+# local settings
+DOTENV_PLAIN=plain value
+export DOTENV_EXPORTED = exported
+DOTENV_DOUBLE="line\nbreak \"quoted\"" # comment
+DOTENV_SINGLE='literal\n # not a comment'
+DOTENV_COMMENT=value # comment
+DOTENV_EMPTY=
+DOTENV_SET=from file
+`), 0644))
+
+	require.NoError(t, loadEnvFile(path, true))
+	assert.Equal(t, "plain value", os.Getenv("DOTENV_PLAIN"))
+	assert.Equal(t, "exported", os.Getenv("DOTENV_EXPORTED"))
+	assert.Equal(t, "line\nbreak \"quoted\"", os.Getenv("DOTENV_DOUBLE"))
+	assert.Equal(t, `literal\n # not a comment`, os.Getenv("DOTENV_SINGLE"))
+	assert.Equal(t, "value", os.Getenv("DOTENV_COMMENT"))
+	assert.Equal(t, "", os.Getenv("DOTENV_EMPTY"))
+	assert.Equal(t, "from environment", os.Getenv("DOTENV_SET"), "the environment wins")
+
+	missing := filepath.Join(t.TempDir(), ".env")
+	assert.NoError(t, loadEnvFile(missing, false))
+	assert.Error(t, loadEnvFile(missing, true))
+
+	for _, content := range []string{"NO_EQUALS\n", "BAD KEY=1\n", "DOTENV_PLAIN=\"unterminated\n"} {
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+		assert.Error(t, loadEnvFile(path, true), content)
+	}
+}
+
+func TestEnvFilePath(t *testing.T) {
+	t.Setenv("ENV_FILE", "")
+	path, explicit := envFilePath([]string{"-a", ":8080"})
+	assert.Equal(t, ".env", path)
+	assert.False(t, explicit)
+
+	path, explicit = envFilePath([]string{"-env-file", "dev.env"})
+	assert.Equal(t, "dev.env", path)
+	assert.True(t, explicit)
+
+	t.Setenv("ENV_FILE", "prod.env")
+	path, explicit = envFilePath([]string{"-env-file", "dev.env"})
+	assert.Equal(t, "prod.env", path)
+	assert.True(t, explicit)
+}
@@ -0,0 +1,203 @@
+package config
+
+import (
+	"flag"
+	"fmt"
+	"sort"
+)
+
+// envVars maps the environment variables to the flags they set.
+var envVars = map[string]string{
+	"SERVER_ADDRESS":                  "a",
+	"BASE_URL":                        "b",
+	"LOG_LEVEL":                       "l",
+	"FILE_STORAGE_PATH":               "f",
+	"DATABASE_DSN":                    "d",
+	"AUDIT_FILE":                      "audit-file",
+	"AUDIT_URL":                       "audit-url",
+	"AUTH_SECRET":                     "auth-secret",
+	"RESOLVE_CACHE_SIZE":              "resolve-cache-size",
+	"RESOLVE_CACHE_TTL":               "resolve-cache-ttl",
+	"CLEANUP_INTERVAL":                "cleanup-interval",
+	"DELETED_RETENTION":               "deleted-retention",
+	"CLEANUP_DRY_RUN":                 "cleanup-dry-run",
+	"ALIAS_RESERVED":                  "alias-reserved",
+	"ALIAS_BLOCKED":                   "alias-blocked",
+	"STATS_BUCKET":                    "stats-bucket",
+	"STATS_FLUSH_INTERVAL":            "stats-flush-interval",
+	"HASH_CODES":                      "hash-codes",
+	"DEDUP_PER_USER":                  "dedup-per-user",
+	"USER_RATE_LIMIT":                 "user-rate-limit",
+	"USER_RATE_BURST":                 "user-rate-burst",
+	"RETENTION_INTERVAL":              "retention-interval",
+	"ANONYMOUS_MAX_AGE":               "anonymous-max-age",
+	"RETENTION_DRY_RUN":               "retention-dry-run",
+	"OIDC_ISSUER":                     "oidc-issuer",
+	"OIDC_CLIENT_ID":                  "oidc-client-id",
+	"OIDC_CLIENT_SECRET":              "oidc-client-secret",
+	"OIDC_REDIRECT_URL":               "oidc-redirect-url",
+	"SHORTEN_RATE_LIMIT":              "shorten-rate-limit",
+	"SHORTEN_RATE_BURST":              "shorten-rate-burst",
+	"REDIRECT_RATE_LIMIT":             "redirect-rate-limit",
+	"REDIRECT_RATE_BURST":             "redirect-rate-burst",
+	"REQUEST_TIMEOUT":                 "request-timeout",
+	"REDIRECT_TIMEOUT":                "redirect-timeout",
+	"BATCH_TIMEOUT":                   "batch-timeout",
+	"TRUSTED_SUBNET":                  "t",
+	"TRUSTED_PROXIES":                 "trusted-proxies",
+	"TRACING_ENDPOINT":                "tracing-endpoint",
+	"TRACING_INSECURE":                "tracing-insecure",
+	"TRACING_SAMPLE_RATIO":            "tracing-sample-ratio",
+	"SHORTEN_BODY_LIMIT":              "shorten-body-limit",
+	"BATCH_BODY_LIMIT":                "batch-body-limit",
+	"MAINTENANCE_RETRY_AFTER":         "maintenance-retry-after",
+	"ADMIN_USER":                      "admin-user",
+	"ADMIN_PASSWORD":                  "admin-password",
+	"ADMIN_PASSWORD_FILE":             "admin-password-file",
+	"COOKIE_SECURE":                   "cookie-secure",
+	"COOKIE_SAMESITE":                 "cookie-samesite",
+	"COOKIE_DOMAIN":                   "cookie-domain",
+	"COOKIE_MAX_AGE":                  "cookie-max-age",
+	"BLOCKLIST_FILE":                  "blocklist-file",
+	"BLOCK_REDIRECTS":                 "block-redirects",
+	"AUDIT_NATS_URL":                  "audit-nats-url",
+	"AUDIT_NATS_SUBJECT":              "audit-nats-subject",
+	"AUDIT_NATS_STREAM":               "audit-nats-stream",
+	"AUDIT_SYSLOG":                    "audit-syslog",
+	"AUDIT_DB":                        "audit-db",
+	"AUDIT_DB_RETENTION":              "audit-db-retention",
+	"AUDIT_CLICKHOUSE_URL":            "audit-clickhouse-url",
+	"AUDIT_CLICKHOUSE_TABLE":          "audit-clickhouse-table",
+	"AUDIT_CLICKHOUSE_BATCH_SIZE":     "audit-clickhouse-batch-size",
+	"AUDIT_CLICKHOUSE_FLUSH_INTERVAL": "audit-clickhouse-flush-interval",
+	"AUDIT_QUEUE_SIZE":                "audit-queue-size",
+	"AUDIT_WORKERS":                   "audit-workers",
+	"AUDIT_URL_SECRET":                "audit-url-secret",
+	"AUDIT_STDOUT":                    "audit-stdout",
+	"AUDIT_CONFIG":                    "audit-config",
+	"AUDIT_URL_ENCODING":              "audit-url-encoding",
+	"AUDIT_NATS_ENCODING":             "audit-nats-encoding",
+	"AUDIT_GELF":                      "audit-gelf",
+	"ENABLE_HTTPS":                    "s",
+	"CONFIG":                          "c",
+	"TLS_CERT_FILE":                   "tls-cert-file",
+	"TLS_KEY_FILE":                    "tls-key-file",
+	"AUTOCERT_DOMAINS":                "autocert-domains",
+	"AUTOCERT_CACHE_DIR":              "autocert-cache-dir",
+	"AUTOCERT_EMAIL":                  "autocert-email",
+	"AUTOCERT_HTTP_ADDRESS":           "autocert-http-address",
+	"ENV_FILE":                        "env-file",
+	"READ_TIMEOUT":                    "read-timeout",
+	"WRITE_TIMEOUT":                   "write-timeout",
+	"IDLE_TIMEOUT":                    "idle-timeout",
+	"SHUTDOWN_TIMEOUT":                "shutdown-timeout",
+	"DB_QUERY_TIMEOUT":                "db-query-timeout",
+	"LOG_OUTPUT":                      "log-output",
+	"ENABLE_AUDIT":                    "enable-audit",
+	"ENABLE_STATS":                    "enable-stats",
+	"ENABLE_PPROF":                    "enable-pprof",
+	"HTTP_ADDRESS":                    "http-address",
+	"HTTP_REDIRECT":                   "http-redirect",
+	"ENABLE_HTTP2":                    "enable-http2",
+	"ENABLE_H2C":                      "enable-h2c",
+	"READ_HEADER_TIMEOUT":             "read-header-timeout",
+	"MAX_HEADER_BYTES":                "max-header-bytes",
+	"MAX_IN_FLIGHT":                   "max-in-flight",
+	"REUSE_PORT":                      "reuse-port",
+	"SENTRY_DSN":                      "sentry-dsn",
+	"SENTRY_ENVIRONMENT":              "sentry-environment",
+	"TLS_CLIENT_CA_FILE":              "tls-client-ca-file",
+	"MTLS_ADDRESS":                    "mtls-address",
+	"PROFILE_DIR":                     "profile-dir",
+	"PROFILE_LATENCY_P99":             "profile-latency-p99",
+	"PROFILE_GOROUTINES":              "profile-goroutines",
+	"PROFILE_SUSTAIN":                 "profile-sustain",
+	"PROFILE_COOLDOWN":                "profile-cooldown",
+	"PROFILE_CPU_DURATION":            "profile-cpu-duration",
+	"SNAPSHOT_INTERVAL":               "snapshot-interval",
+	"SNAPSHOT_CHANGES":                "snapshot-changes",
+	"STORAGE_QUEUE_SIZE":              "storage-queue-size",
+	"COMPACT_INTERVAL":                "compact-interval",
+	"SNAPSHOT_URL":                    "snapshot-url",
+	"SNAPSHOT_REGION":                 "snapshot-region",
+	"SNAPSHOT_ACCESS_KEY":             "snapshot-access-key",
+	"SNAPSHOT_SECRET_KEY":             "snapshot-secret-key",
+	"STORAGE_KEY":                     "storage-key",
+	"STORAGE_KEY_FILE":                "storage-key-file",
+	"DELETE_BATCH_SIZE":               "delete-batch-size",
+	"DELETE_FLUSH_INTERVAL":           "delete-flush-interval",
+	"DELETE_QUEUE_SIZE":               "delete-queue-size",
+	"DELETE_WORKERS":                  "delete-workers",
+	"DB_BREAKER_THRESHOLD":            "db-breaker-threshold",
+	"DB_BREAKER_COOLDOWN":             "db-breaker-cooldown",
+}
+
+// applyEnv sets the flags of fs from the environment variables listed in
+// envVars, looked up with lookupEnv, so that they override both the
+// command line and the configuration file. A variable that is set but empty
+// resets its flag to the default value, e.g. DATABASE_DSN= switches back to
+// the in-memory storage. Values the flag rejects are returned as errors
+// naming the variable instead of being ignored.
+//
+// explicit records the variables whose settings are given explicitly: set
+// variables are added, emptied ones removed.
+func applyEnv(fs *flag.FlagSet, lookupEnv func(string) (string, bool), explicit map[string]bool) []error {
+	names := make([]string, 0, len(envVars))
+	for name := range envVars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var errs []error
+	for _, name := range names {
+		value, ok := lookupEnv(name)
+		if !ok {
+			continue
+		}
+		f := fs.Lookup(envVars[name])
+		reset := value == ""
+		if reset {
+			value = f.DefValue
+		}
+		// The flag values store their zero value on parse errors, so the
+		// previous value is restored.
+		previous := f.Value.String()
+		if err := fs.Set(f.Name, value); err != nil {
+			_ = fs.Set(f.Name, previous)
+			errs = append(errs, fmt.Errorf("%s: invalid value %q", name, value))
+			continue
+		}
+		if reset {
+			delete(explicit, name)
+		} else {
+			explicit[name] = true
+		}
+	}
+	return errs
+}
+
+// explicitSettings returns the environment variables whose flags were set
+// on fs, by the command line or the configuration file.
+func explicitSettings(fs *flag.FlagSet) map[string]bool {
+	flagEnv := make(map[string]string, len(envVars))
+	for name, flagName := range envVars {
+		flagEnv[flagName] = name
+	}
+	// -config is an alias of -c.
+	flagEnv["config"] = flagEnv["c"]
+
+	explicit := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) {
+		if name, ok := flagEnv[f.Name]; ok {
+			explicit[name] = true
+		}
+	})
+	return explicit
+}
+
+// IsSet reports whether the setting of the environment variable name, e.g.
+// "FILE_STORAGE_PATH", was given explicitly by the variable, its flag or the
+// configuration file rather than left at its default value.
+func (c *Config) IsSet(name string) bool {
+	return c.explicit[name]
+}
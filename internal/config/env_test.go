@@ -0,0 +1,55 @@
+package config
+
+import (
+	"flag"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseFlags_Environment(t *testing.T) {
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+	unsetenv(t, "CONFIG", "SERVER_ADDRESS", "BASE_URL", "FILE_STORAGE_PATH", "DATABASE_DSN", "REQUEST_TIMEOUT", "HASH_CODES", "AUDIT_QUEUE_SIZE")
+
+	os.Args = []string{"cmd", "-a", "flag:8080", "-b", "https://flag", "-d", "postgres://flag", "-f", defaultStorageFilePath}
+	config := ParseFlags()
+	assert.Equal(t, "flag:8080", config.RunAddr)
+	assert.True(t, config.IsSet("SERVER_ADDRESS"))
+	assert.True(t, config.IsSet("FILE_STORAGE_PATH"), "explicit even when equal to the default")
+	assert.False(t, config.IsSet("AUDIT_QUEUE_SIZE"))
+	assert.ErrorContains(t, config.Validate(), "FILE_STORAGE_PATH and DATABASE_DSN are mutually exclusive")
+
+	t.Setenv("SERVER_ADDRESS", "env:8080")
+	t.Setenv("DATABASE_DSN", "")
+	t.Setenv("AUDIT_QUEUE_SIZE", "64")
+	config = ParseFlags()
+	assert.Equal(t, "env:8080", config.RunAddr, "the environment overrides flags")
+	assert.Equal(t, "https://flag", config.ReturnPrefix)
+	assert.Equal(t, "", config.DatabaseDSN, "an empty variable resets the flag")
+	assert.False(t, config.IsSet("DATABASE_DSN"))
+	assert.Equal(t, 64, config.AuditQueueSize)
+	assert.True(t, config.IsSet("AUDIT_QUEUE_SIZE"))
+	assert.NoError(t, config.Validate())
+
+	t.Setenv("REQUEST_TIMEOUT", "soon")
+	t.Setenv("HASH_CODES", "yes")
+	config = ParseFlags()
+	assert.Equal(t, 5*time.Second, config.RequestTimeout)
+	err := config.Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `REQUEST_TIMEOUT: invalid value "soon"`)
+	assert.Contains(t, err.Error(), `HASH_CODES: invalid value "yes"`)
+}
+
+func TestEnvVars(t *testing.T) {
+	fs := flag.NewFlagSet("cmd", flag.ContinueOnError)
+	_, err := parse(fs, nil, func(string) (string, bool) { return "", false }, "")
+	require.NoError(t, err)
+	for name, flagName := range envVars {
+		assert.NotNil(t, fs.Lookup(flagName), "%s sets the unknown flag -%s", name, flagName)
+	}
+}
@@ -0,0 +1,300 @@
+package config
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// fileKeys maps the keys of the configuration file given with -c/-config or
+// CONFIG to the flags they set. Keys of nested sections are joined with
+// dots, e.g. "server: {address: ...}" is "server.address". The flat keys of
+// the original JSON format are kept for compatibility.
+var fileKeys = map[string]string{
+	"server_address":    "a",
+	"base_url":          "b",
+	"file_storage_path": "f",
+	"database_dsn":      "d",
+	"enable_https":      "s",
+
+	"server.address":                 "a",
+	"server.base_url":                "b",
+	"server.enable_https":            "s",
+	"server.tls_cert_file":           "tls-cert-file",
+	"server.tls_key_file":            "tls-key-file",
+	"server.tls_client_ca_file":      "tls-client-ca-file",
+	"server.mtls_address":            "mtls-address",
+	"server.autocert_domains":        "autocert-domains",
+	"server.autocert_cache_dir":      "autocert-cache-dir",
+	"server.autocert_email":          "autocert-email",
+	"server.autocert_http_address":   "autocert-http-address",
+	"server.http_address":            "http-address",
+	"server.http_redirect":           "http-redirect",
+	"server.enable_http2":            "enable-http2",
+	"server.enable_h2c":              "enable-h2c",
+	"server.log_level":               "l",
+	"server.log_output":              "log-output",
+	"server.trusted_subnet":          "t",
+	"server.trusted_proxies":         "trusted-proxies",
+	"server.request_timeout":         "request-timeout",
+	"server.redirect_timeout":        "redirect-timeout",
+	"server.batch_timeout":           "batch-timeout",
+	"server.read_timeout":            "read-timeout",
+	"server.write_timeout":           "write-timeout",
+	"server.idle_timeout":            "idle-timeout",
+	"server.read_header_timeout":     "read-header-timeout",
+	"server.max_header_bytes":        "max-header-bytes",
+	"server.max_in_flight":           "max-in-flight",
+	"server.reuse_port":              "reuse-port",
+	"server.shutdown_timeout":        "shutdown-timeout",
+	"server.maintenance_retry_after": "maintenance-retry-after",
+	"server.tracing_endpoint":        "tracing-endpoint",
+	"server.tracing_insecure":        "tracing-insecure",
+	"server.tracing_sample_ratio":    "tracing-sample-ratio",
+	"server.sentry_dsn":              "sentry-dsn",
+	"server.sentry_environment":      "sentry-environment",
+	"server.profile_dir":             "profile-dir",
+	"server.profile_latency_p99":     "profile-latency-p99",
+	"server.profile_goroutines":      "profile-goroutines",
+	"server.profile_sustain":         "profile-sustain",
+	"server.profile_cooldown":        "profile-cooldown",
+	"server.profile_cpu_duration":    "profile-cpu-duration",
+
+	"storage.file_path":             "f",
+	"storage.database_dsn":          "d",
+	"storage.db_query_timeout":      "db-query-timeout",
+	"storage.db_breaker_threshold":  "db-breaker-threshold",
+	"storage.db_breaker_cooldown":   "db-breaker-cooldown",
+	"storage.resolve_cache_size":    "resolve-cache-size",
+	"storage.resolve_cache_ttl":     "resolve-cache-ttl",
+	"storage.cleanup_interval":      "cleanup-interval",
+	"storage.deleted_retention":     "deleted-retention",
+	"storage.cleanup_dry_run":       "cleanup-dry-run",
+	"storage.retention_interval":    "retention-interval",
+	"storage.anonymous_max_age":     "anonymous-max-age",
+	"storage.retention_dry_run":     "retention-dry-run",
+	"storage.stats_bucket":          "stats-bucket",
+	"storage.stats_flush_interval":  "stats-flush-interval",
+	"storage.hash_codes":            "hash-codes",
+	"storage.dedup_per_user":        "dedup-per-user",
+	"storage.snapshot_interval":     "snapshot-interval",
+	"storage.snapshot_changes":      "snapshot-changes",
+	"storage.snapshot_url":          "snapshot-url",
+	"storage.snapshot_region":       "snapshot-region",
+	"storage.snapshot_access_key":   "snapshot-access-key",
+	"storage.snapshot_secret_key":   "snapshot-secret-key",
+	"storage.queue_size":            "storage-queue-size",
+	"storage.compact_interval":      "compact-interval",
+	"storage.key":                   "storage-key",
+	"storage.key_file":              "storage-key-file",
+	"storage.delete_batch_size":     "delete-batch-size",
+	"storage.delete_flush_interval": "delete-flush-interval",
+	"storage.delete_queue_size":     "delete-queue-size",
+	"storage.delete_workers":        "delete-workers",
+
+	"audit.file":                      "audit-file",
+	"audit.url":                       "audit-url",
+	"audit.url_secret":                "audit-url-secret",
+	"audit.url_encoding":              "audit-url-encoding",
+	"audit.stdout":                    "audit-stdout",
+	"audit.syslog":                    "audit-syslog",
+	"audit.gelf":                      "audit-gelf",
+	"audit.nats_url":                  "audit-nats-url",
+	"audit.nats_subject":              "audit-nats-subject",
+	"audit.nats_stream":               "audit-nats-stream",
+	"audit.nats_encoding":             "audit-nats-encoding",
+	"audit.db":                        "audit-db",
+	"audit.db_retention":              "audit-db-retention",
+	"audit.clickhouse_url":            "audit-clickhouse-url",
+	"audit.clickhouse_table":          "audit-clickhouse-table",
+	"audit.clickhouse_batch_size":     "audit-clickhouse-batch-size",
+	"audit.clickhouse_flush_interval": "audit-clickhouse-flush-interval",
+	"audit.queue_size":                "audit-queue-size",
+	"audit.workers":                   "audit-workers",
+
+	"auth.secret":              "auth-secret",
+	"auth.cookie_secure":       "cookie-secure",
+	"auth.cookie_samesite":     "cookie-samesite",
+	"auth.cookie_domain":       "cookie-domain",
+	"auth.cookie_max_age":      "cookie-max-age",
+	"auth.oidc_issuer":         "oidc-issuer",
+	"auth.oidc_client_id":      "oidc-client-id",
+	"auth.oidc_client_secret":  "oidc-client-secret",
+	"auth.oidc_redirect_url":   "oidc-redirect-url",
+	"auth.admin_user":          "admin-user",
+	"auth.admin_password":      "admin-password",
+	"auth.admin_password_file": "admin-password-file",
+
+	"limits.user_rate_limit":     "user-rate-limit",
+	"limits.user_rate_burst":     "user-rate-burst",
+	"limits.shorten_rate_limit":  "shorten-rate-limit",
+	"limits.shorten_rate_burst":  "shorten-rate-burst",
+	"limits.redirect_rate_limit": "redirect-rate-limit",
+	"limits.redirect_rate_burst": "redirect-rate-burst",
+	"limits.shorten_body_limit":  "shorten-body-limit",
+	"limits.batch_body_limit":    "batch-body-limit",
+	"limits.blocklist_file":      "blocklist-file",
+	"limits.block_redirects":     "block-redirects",
+	"limits.alias_reserved":      "alias-reserved",
+	"limits.alias_blocked":       "alias-blocked",
+
+	"features.enable_audit": "enable-audit",
+	"features.enable_stats": "enable-stats",
+	"features.enable_pprof": "enable-pprof",
+}
+
+// auditWritersKey is the file key listing additional audit writers. The
+// writers are read by audit.LoadConfig, so the key makes the file the audit
+// config file rather than setting a flag.
+const auditWritersKey = "audit.writers"
+
+// configFilePath returns the configuration file named by CONFIG or, if it
+// is not set, by the last -c/-config argument in args.
+func configFilePath(args []string) string {
+	if env, ok := os.LookupEnv("CONFIG"); ok {
+		return env
+	}
+	return argValue(args, "c", "config")
+}
+
+// argValue returns the value of the last flag in args with one of the given
+// names, or "" if there is none. It is used for the flags that have to be
+// known before the flags are parsed, so args are scanned directly.
+func argValue(args []string, names ...string) string {
+	var value string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			break
+		}
+		if !strings.HasPrefix(arg, "-") {
+			continue
+		}
+		name, v, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		if !slices.Contains(names, name) {
+			continue
+		}
+		if !hasValue && i+1 < len(args) {
+			i++
+			v = args[i]
+		}
+		value = v
+	}
+	return value
+}
+
+// applyConfigFile reads the configuration file at path, YAML if its
+// extension is .yaml or .yml and JSON otherwise, and uses its values as the
+// values of the corresponding flags of fs (see fileKeys). It must be called
+// before fs is parsed, so that flags given on the command line take
+// precedence. Unknown keys are reported as errors to catch typos.
+func applyConfigFile(fs *flag.FlagSet, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var file map[string]any
+	if isYAML(path) {
+		err = yaml.Unmarshal(data, &file)
+	} else {
+		err = json.Unmarshal(data, &file)
+	}
+	if err != nil {
+		return fmt.Errorf("parse %s: %w", path, err)
+	}
+
+	values := make(map[string]string)
+	if err := flattenFileConfig("", file, values); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		name, value := fileKeys[key], values[key]
+		if key == auditWritersKey {
+			name, value = "audit-config", path
+		}
+		if name == "" {
+			return fmt.Errorf("%s: unknown key %q", path, key)
+		}
+		if err := fs.Set(name, value); err != nil {
+			return fmt.Errorf("%s: invalid value %q for %s: %w", path, value, key, err)
+		}
+	}
+	return nil
+}
+
+// isYAML reports whether path names a YAML file.
+func isYAML(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	return ext == ".yaml" || ext == ".yml"
+}
+
+// flattenFileConfig stores the scalar values of section in values under
+// their dotted keys prefixed with prefix. Lists of scalars are joined with
+// commas, like list flags; the audit writers list is kept as is.
+func flattenFileConfig(prefix string, section map[string]any, values map[string]string) error {
+	for name, value := range section {
+		key := name
+		if prefix != "" {
+			key = prefix + "." + name
+		}
+		switch v := value.(type) {
+		case nil:
+			// An empty YAML key or a JSON null leaves the setting unset.
+			continue
+		case map[string]any:
+			if err := flattenFileConfig(key, v, values); err != nil {
+				return err
+			}
+		case []any:
+			if key == auditWritersKey {
+				values[key] = ""
+				continue
+			}
+			items := make([]string, len(v))
+			for i, item := range v {
+				s, ok := scalarString(item)
+				if !ok {
+					return fmt.Errorf("%s: list items must be scalars", key)
+				}
+				items[i] = s
+			}
+			values[key] = strings.Join(items, ",")
+		default:
+			s, ok := scalarString(v)
+			if !ok {
+				return fmt.Errorf("%s: unsupported value", key)
+			}
+			values[key] = s
+		}
+	}
+	return nil
+}
+
+// scalarString formats a scalar decoded from JSON or YAML as a flag value.
+func scalarString(value any) (string, bool) {
+	switch v := value.(type) {
+	case string:
+		return v, true
+	case bool:
+		return strconv.FormatBool(v), true
+	case int:
+		return strconv.Itoa(v), true
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), true
+	default:
+		return "", false
+	}
+}
@@ -0,0 +1,72 @@
+package config
+
+import (
+	"flag"
+	"net"
+)
+
+// Option configures a Config created by NewConfig.
+type Option func(*Config)
+
+// NewConfig returns a Config with the default settings modified by opts.
+// Unlike ParseFlags, it reads neither command-line flags nor environment
+// variables nor files, so it can be called any number of times, e.g. by
+// programs embedding the shortener and by tests.
+func NewConfig(opts ...Option) *Config {
+	// Without arguments, environment and configuration file parse cannot fail.
+	c, _ := parse(flag.NewFlagSet("config", flag.ContinueOnError), nil, func(string) (string, bool) { return "", false }, "")
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// WithAddress sets the address the server listens on, e.g. "localhost:8080".
+func WithAddress(addr string) Option {
+	return func(c *Config) {
+		c.RunAddr = addr
+	}
+}
+
+// WithBaseURL sets the base URL of the returned short URLs.
+func WithBaseURL(baseURL string) Option {
+	return func(c *Config) {
+		c.ReturnPrefix = baseURL
+	}
+}
+
+// WithStorageFile makes the in-memory repository persist URLs to path.
+func WithStorageFile(path string) Option {
+	return func(c *Config) {
+		c.StorageFilePath = path
+	}
+}
+
+// WithDatabaseDSN selects the PostgreSQL repository, connected with dsn,
+// instead of the in-memory one.
+func WithDatabaseDSN(dsn string) Option {
+	return func(c *Config) {
+		c.DatabaseDSN = dsn
+	}
+}
+
+// WithLogLevel sets the minimum log level: debug, info, warn or error.
+func WithLogLevel(level string) Option {
+	return func(c *Config) {
+		c.LogLevel = level
+	}
+}
+
+// WithAuthSecret sets the key for signing user ID cookies.
+func WithAuthSecret(secret string) Option {
+	return func(c *Config) {
+		c.AuthSecret = secret
+	}
+}
+
+// WithTrustedSubnet sets the subnet allowed to access internal routes.
+func WithTrustedSubnet(subnet *net.IPNet) Option {
+	return func(c *Config) {
+		c.TrustedSubnet = subnet
+	}
+}
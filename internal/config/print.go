@@ -0,0 +1,110 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net"
+	"net/url"
+	"reflect"
+	"regexp"
+	"time"
+)
+
+// redacted replaces secrets in the output of WriteJSON.
+const redacted = "xxxxx"
+
+// secretFields lists the Config fields whose values are never printed.
+var secretFields = map[string]bool{
+	"AuthSecret":        true,
+	"OIDCClientSecret":  true,
+	"AdminPassword":     true,
+	"AuditURLSecret":    true,
+	"SnapshotSecretKey": true,
+	"StorageKey":        true,
+	"SentryDSN":         true,
+}
+
+// credentialFields lists the Config fields that are URLs or connection
+// strings which may embed a password.
+var credentialFields = map[string]bool{
+	"DatabaseDSN":        true,
+	"AuditURL":           true,
+	"AuditNATSURL":       true,
+	"AuditClickHouseURL": true,
+	"TracingEndpoint":    true,
+}
+
+// dsnPassword matches the password of a key=value connection string.
+var dsnPassword = regexp.MustCompile(`(password\s*=\s*)('(?:[^'\\]|\\.)*'|\S+)`)
+
+// WriteJSON writes the configuration to w as an indented JSON object keyed
+// by field name, in declaration order. Durations are printed like "5s" and
+// the trusted subnet in CIDR notation. Secrets and passwords embedded in
+// URLs and connection strings are replaced with "xxxxx", so the output can
+// be shared when debugging which setting won.
+func (c *Config) WriteJSON(w io.Writer) error {
+	v := reflect.ValueOf(c).Elem()
+	t := v.Type()
+
+	var buf bytes.Buffer
+	buf.WriteString("{")
+	first := true
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		value, err := json.Marshal(printValue(field.Name, v.Field(i).Interface()))
+		if err != nil {
+			return err
+		}
+		if !first {
+			buf.WriteString(",")
+		}
+		first = false
+		name, _ := json.Marshal(field.Name)
+		buf.WriteString("\n  ")
+		buf.Write(name)
+		buf.WriteString(": ")
+		buf.Write(value)
+	}
+	buf.WriteString("\n}\n")
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// printValue returns the printable form of the value of the named field.
+func printValue(name string, value any) any {
+	switch v := value.(type) {
+	case time.Duration:
+		return v.String()
+	case *net.IPNet:
+		if v == nil {
+			return ""
+		}
+		return v.String()
+	case string:
+		switch {
+		case v == "":
+			return v
+		case secretFields[name]:
+			return redacted
+		case credentialFields[name]:
+			return redactCredentials(v)
+		}
+	}
+	return value
+}
+
+// redactCredentials replaces the password of a URL or a key=value
+// connection string.
+func redactCredentials(s string) string {
+	if u, err := url.Parse(s); err == nil && u.User != nil {
+		if _, ok := u.User.Password(); ok {
+			u.User = url.UserPassword(u.User.Username(), redacted)
+			return u.String()
+		}
+	}
+	return dsnPassword.ReplaceAllString(s, "${1}"+redacted)
+}
@@ -0,0 +1,6 @@
+//go:build aix || darwin || dragonfly || freebsd || linux || netbsd || openbsd
+
+package config
+
+// reusePortSupported reports whether the platform has SO_REUSEPORT.
+const reusePortSupported = true
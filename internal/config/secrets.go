@@ -0,0 +1,44 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// secretVars lists the environment variables holding credentials. Each of
+// them may be given as NAME_FILE instead, naming a file with the value, so
+// that Docker and Kubernetes secrets can be mounted without putting the
+// credentials into the environment or the process list. ADMIN_PASSWORD has
+// its own ADMIN_PASSWORD_FILE setting.
+var secretVars = []string{
+	"DATABASE_DSN",
+	"AUTH_SECRET",
+	"OIDC_CLIENT_SECRET",
+	"AUDIT_URL_SECRET",
+	"SENTRY_DSN",
+	"SNAPSHOT_SECRET_KEY",
+}
+
+// loadSecretFiles sets each variable of names whose NAME_FILE variable is
+// set to the contents of that file, without trailing line breaks. Setting
+// both NAME and NAME_FILE is an error, as it is unclear which one is meant.
+func loadSecretFiles(names []string) error {
+	for _, name := range names {
+		path := os.Getenv(name + "_FILE")
+		if path == "" {
+			continue
+		}
+		if os.Getenv(name) != "" {
+			return fmt.Errorf("both %s and %s_FILE are set", name, name)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("%s_FILE: %w", name, err)
+		}
+		if err := os.Setenv(name, strings.TrimRight(string(data), "\r\n")); err != nil {
+			return fmt.Errorf("%s_FILE: %w", name, err)
+		}
+	}
+	return nil
+}
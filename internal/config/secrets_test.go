@@ -0,0 +1,44 @@
+package config
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadSecretFiles(t *testing.T) {
+	for _, env := range []string{"SECRET_A", "SECRET_A_FILE", "SECRET_B", "SECRET_B_FILE"} {
+		t.Setenv(env, "")
+	}
+	dir := t.TempDir()
+	path := filepath.Join(dir, "secret")
+	require.NoError(t, os.WriteFile(path, []byte("postgres://user:p a ss@db/shortener\n"), 0600))
+
+	t.Setenv("SECRET_A_FILE", path)
+	t.Setenv("SECRET_B", "plain")
+	require.NoError(t, loadSecretFiles([]string{"SECRET_A", "SECRET_B"}))
+	assert.Equal(t, "postgres://user:p a ss@db/shortener", os.Getenv("SECRET_A"))
+	assert.Equal(t, "plain", os.Getenv("SECRET_B"))
+
+	t.Setenv("SECRET_B_FILE", path)
+	assert.ErrorContains(t, loadSecretFiles([]string{"SECRET_B"}), "both SECRET_B and SECRET_B_FILE are set")
+
+	t.Setenv("SECRET_A", "")
+	t.Setenv("SECRET_A_FILE", filepath.Join(dir, "missing"))
+	assert.ErrorContains(t, loadSecretFiles([]string{"SECRET_A"}), "SECRET_A_FILE")
+}
+
+func TestParseArgs_SecretFile(t *testing.T) {
+	unsetenv(t, "ENV_FILE", "CONFIG", "SNAPSHOT_ACCESS_KEY", "SNAPSHOT_SECRET_KEY", "SNAPSHOT_SECRET_KEY_FILE")
+	path := filepath.Join(t.TempDir(), "snapshot-secret")
+	require.NoError(t, os.WriteFile(path, []byte("s3cr3t\n"), 0600))
+	t.Setenv("SNAPSHOT_ACCESS_KEY", "access")
+	t.Setenv("SNAPSHOT_SECRET_KEY_FILE", path)
+
+	config := ParseArgs(flag.NewFlagSet("shortener", flag.ContinueOnError), nil)
+	assert.Equal(t, "s3cr3t", config.SnapshotSecretKey)
+}
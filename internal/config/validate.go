@@ -0,0 +1,251 @@
+package config
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/lib/pq"
+	"go.uber.org/zap/zapcore"
+)
+
+// defaultStorageFilePath is the storage file used when FILE_STORAGE_PATH is not set.
+const defaultStorageFilePath = "./storage.json"
+
+// storageKeySize is the size of the storage encryption key, see storage.KeySize.
+const storageKeySize = 32
+
+// Validate checks the assembled configuration for settings that would
+// otherwise fail deep inside the server, e.g. in sql.Open or ListenAndServe,
+// or be silently ignored. All problems are reported at once, joined with
+// errors.Join, so that they can be fixed in one go.
+func (c *Config) Validate() error {
+	errs := slices.Clone(c.errs)
+	if err := validateAddr(c.RunAddr); err != nil {
+		errs = append(errs, fmt.Errorf("SERVER_ADDRESS: %w", err))
+	}
+	if err := validateBaseURL(c.ReturnPrefix); err != nil {
+		errs = append(errs, fmt.Errorf("BASE_URL: %w", err))
+	}
+	if _, err := zapcore.ParseLevel(c.LogLevel); err != nil {
+		errs = append(errs, fmt.Errorf("LOG_LEVEL: %w", err))
+	}
+
+	if c.DatabaseDSN != "" {
+		if err := validateDSN(c.DatabaseDSN); err != nil {
+			errs = append(errs, fmt.Errorf("DATABASE_DSN: %w", err))
+		}
+		// The storage file is only used without a database, so a file
+		// configured next to it would never be written.
+		if c.StorageFilePath != "" && (c.StorageFilePath != defaultStorageFilePath || c.IsSet("FILE_STORAGE_PATH")) {
+			errs = append(errs, errors.New("FILE_STORAGE_PATH and DATABASE_DSN are mutually exclusive"))
+		}
+	}
+
+	if (c.TLSCertFile == "") != (c.TLSKeyFile == "") {
+		errs = append(errs, errors.New("TLS_CERT_FILE and TLS_KEY_FILE must be set together"))
+	}
+	if len(c.AutocertDomains) > 0 {
+		if c.TLSCertFile != "" || c.TLSKeyFile != "" {
+			errs = append(errs, errors.New("AUTOCERT_DOMAINS and TLS_CERT_FILE/TLS_KEY_FILE are mutually exclusive"))
+		}
+		if err := validateAddr(c.AutocertHTTPAddr); err != nil {
+			errs = append(errs, fmt.Errorf("AUTOCERT_HTTP_ADDRESS: %w", err))
+		}
+	} else if c.EnableHTTPS && c.TLSCertFile == "" && c.TLSKeyFile == "" {
+		errs = append(errs, errors.New("ENABLE_HTTPS requires TLS_CERT_FILE and TLS_KEY_FILE or AUTOCERT_DOMAINS"))
+	}
+
+	// http.Server replaces a non-positive limit with its 1 MB default.
+	if c.MaxHeaderBytes <= 0 {
+		errs = append(errs, fmt.Errorf("MAX_HEADER_BYTES: %d is not positive", c.MaxHeaderBytes))
+	}
+	if c.EnablePprof && c.TrustedSubnet == nil {
+		errs = append(errs, errors.New("ENABLE_PPROF requires TRUSTED_SUBNET, the profiles are not served to anyone else"))
+	}
+	if c.ReusePort && !reusePortSupported {
+		errs = append(errs, errors.New("REUSE_PORT is not supported on this platform"))
+	}
+	if c.HTTPAddr != "" {
+		if err := validateAddr(c.HTTPAddr); err != nil {
+			errs = append(errs, fmt.Errorf("HTTP_ADDRESS: %w", err))
+		}
+		switch {
+		case !c.EnableHTTPS && len(c.AutocertDomains) == 0:
+			errs = append(errs, errors.New("HTTP_ADDRESS requires ENABLE_HTTPS or AUTOCERT_DOMAINS"))
+		case c.HTTPAddr == c.RunAddr:
+			errs = append(errs, errors.New("HTTP_ADDRESS and SERVER_ADDRESS must differ"))
+		case len(c.AutocertDomains) > 0 && c.HTTPAddr == c.AutocertHTTPAddr:
+			errs = append(errs, errors.New("HTTP_ADDRESS and AUTOCERT_HTTP_ADDRESS must differ"))
+		}
+	}
+	if c.SnapshotInterval > 0 {
+		switch {
+		case c.DatabaseDSN != "":
+			errs = append(errs, errors.New("SNAPSHOT_INTERVAL and DATABASE_DSN are mutually exclusive"))
+		case c.StorageFilePath == "" && c.SnapshotURL == "":
+			errs = append(errs, errors.New("SNAPSHOT_INTERVAL requires FILE_STORAGE_PATH or SNAPSHOT_URL"))
+		}
+	}
+	if c.SnapshotURL != "" {
+		if c.SnapshotInterval <= 0 {
+			errs = append(errs, errors.New("SNAPSHOT_URL requires SNAPSHOT_INTERVAL"))
+		}
+		if err := validateObjectURL(c.SnapshotURL); err != nil {
+			errs = append(errs, fmt.Errorf("SNAPSHOT_URL: %w", err))
+		}
+		if (c.SnapshotAccessKey == "") != (c.SnapshotSecretKey == "") {
+			errs = append(errs, errors.New("SNAPSHOT_ACCESS_KEY and SNAPSHOT_SECRET_KEY must be set together"))
+		}
+	}
+	if c.SnapshotChanges < 0 {
+		errs = append(errs, fmt.Errorf("SNAPSHOT_CHANGES: %d is negative", c.SnapshotChanges))
+	} else if c.SnapshotChanges > 0 && c.SnapshotInterval <= 0 {
+		errs = append(errs, errors.New("SNAPSHOT_CHANGES requires SNAPSHOT_INTERVAL"))
+	}
+	if c.DBBreakerThreshold < 0 {
+		errs = append(errs, fmt.Errorf("DB_BREAKER_THRESHOLD: %d is negative", c.DBBreakerThreshold))
+	} else if c.DBBreakerThreshold > 0 && c.DBBreakerCooldown <= 0 {
+		errs = append(errs, fmt.Errorf("DB_BREAKER_COOLDOWN: %s is not positive", c.DBBreakerCooldown))
+	}
+	if c.DeleteBatchSize <= 0 {
+		errs = append(errs, fmt.Errorf("DELETE_BATCH_SIZE: %d is not positive", c.DeleteBatchSize))
+	}
+	if c.DeleteFlushInterval <= 0 {
+		errs = append(errs, fmt.Errorf("DELETE_FLUSH_INTERVAL: %s is not positive", c.DeleteFlushInterval))
+	}
+	if c.DeleteQueueSize <= 0 {
+		errs = append(errs, fmt.Errorf("DELETE_QUEUE_SIZE: %d is not positive", c.DeleteQueueSize))
+	}
+	if c.DeleteWorkers <= 0 {
+		errs = append(errs, fmt.Errorf("DELETE_WORKERS: %d is not positive", c.DeleteWorkers))
+	}
+	if c.StorageQueueSize < 0 {
+		errs = append(errs, fmt.Errorf("STORAGE_QUEUE_SIZE: %d is negative", c.StorageQueueSize))
+	} else if c.StorageQueueSize > 0 && c.SnapshotInterval > 0 {
+		// Snapshots leave the repository nothing to queue.
+		errs = append(errs, errors.New("STORAGE_QUEUE_SIZE and SNAPSHOT_INTERVAL are mutually exclusive"))
+	}
+	if c.CompactInterval > 0 {
+		// Snapshots rewrite the file with the live state, which leaves
+		// nothing to compact.
+		switch {
+		case c.DatabaseDSN != "":
+			errs = append(errs, errors.New("COMPACT_INTERVAL and DATABASE_DSN are mutually exclusive"))
+		case c.SnapshotInterval > 0:
+			errs = append(errs, errors.New("COMPACT_INTERVAL and SNAPSHOT_INTERVAL are mutually exclusive"))
+		case c.StorageFilePath == "":
+			errs = append(errs, errors.New("COMPACT_INTERVAL requires FILE_STORAGE_PATH"))
+		}
+	}
+	if c.StorageKey != "" {
+		if c.StorageKeyFile != "" {
+			errs = append(errs, errors.New("STORAGE_KEY and STORAGE_KEY_FILE are mutually exclusive"))
+		}
+		// The key file is only read on start, see storage.LoadKey.
+		if key, err := base64.StdEncoding.DecodeString(c.StorageKey); err != nil || len(key) != storageKeySize {
+			errs = append(errs, fmt.Errorf("STORAGE_KEY: not a base64-encoded %d-byte key", storageKeySize))
+		}
+	}
+	if c.ProfileDir != "" {
+		if c.ProfileLatencyP99 <= 0 && c.ProfileGoroutines <= 0 {
+			errs = append(errs, errors.New("PROFILE_DIR requires PROFILE_LATENCY_P99 or PROFILE_GOROUTINES"))
+		}
+		if c.ProfileCPUDuration <= 0 {
+			errs = append(errs, fmt.Errorf("PROFILE_CPU_DURATION: %s is not positive", c.ProfileCPUDuration))
+		}
+	}
+	errs = append(errs, c.validateMTLS()...)
+	return errors.Join(errs...)
+}
+
+// validateMTLS checks the client certificate settings. Without MTLS_ADDRESS
+// the main server requires the certificates, so it must serve HTTPS, and a
+// plain HTTP server next to it would serve the API without them.
+func (c *Config) validateMTLS() []error {
+	var errs []error
+	hasCert := c.TLSCertFile != "" || len(c.AutocertDomains) > 0
+	switch {
+	case c.MTLSAddr != "":
+		if err := validateAddr(c.MTLSAddr); err != nil {
+			errs = append(errs, fmt.Errorf("MTLS_ADDRESS: %w", err))
+		}
+		if c.TLSClientCAFile == "" {
+			errs = append(errs, errors.New("MTLS_ADDRESS requires TLS_CLIENT_CA_FILE"))
+		}
+		if !hasCert {
+			errs = append(errs, errors.New("MTLS_ADDRESS requires TLS_CERT_FILE and TLS_KEY_FILE or AUTOCERT_DOMAINS"))
+		}
+		if slices.Contains([]string{c.RunAddr, c.HTTPAddr}, c.MTLSAddr) ||
+			len(c.AutocertDomains) > 0 && c.MTLSAddr == c.AutocertHTTPAddr {
+			errs = append(errs, errors.New("MTLS_ADDRESS must differ from the other server addresses"))
+		}
+	case c.TLSClientCAFile != "":
+		if !c.EnableHTTPS && len(c.AutocertDomains) == 0 {
+			errs = append(errs, errors.New("TLS_CLIENT_CA_FILE requires ENABLE_HTTPS or AUTOCERT_DOMAINS, or MTLS_ADDRESS"))
+		}
+		if c.HTTPAddr != "" && !c.HTTPRedirect {
+			errs = append(errs, errors.New("TLS_CLIENT_CA_FILE requires HTTP_REDIRECT when HTTP_ADDRESS is set, or MTLS_ADDRESS"))
+		}
+	}
+	return errs
+}
+
+// validateAddr checks that addr is a "host:port" listen address with a
+// numeric port; the host may be empty to listen on all interfaces.
+func validateAddr(addr string) error {
+	_, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return err
+	}
+	if n, err := strconv.Atoi(port); err != nil || n < 0 || n > 65535 {
+		return fmt.Errorf("invalid port %q", port)
+	}
+	return nil
+}
+
+// validateBaseURL checks that base is an absolute http or https URL.
+func validateBaseURL(base string) error {
+	u, err := url.Parse(base)
+	if err != nil {
+		return err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("%q is not an http or https URL", base)
+	}
+	if u.Host == "" {
+		return fmt.Errorf("%q has no host", base)
+	}
+	return nil
+}
+
+// validateObjectURL checks that object is a path-style URL of an object in
+// a bucket, i.e. an http or https URL with a bucket and a key in its path.
+func validateObjectURL(object string) error {
+	if err := validateBaseURL(object); err != nil {
+		return err
+	}
+	u, _ := url.Parse(object)
+	if bucket, key, _ := strings.Cut(strings.TrimPrefix(u.Path, "/"), "/"); bucket == "" || key == "" {
+		return fmt.Errorf("%q does not name a bucket and an object key", object)
+	}
+	return nil
+}
+
+// validateDSN checks that dsn looks like a PostgreSQL connection string,
+// either a postgres:// URL or space-separated key=value settings.
+func validateDSN(dsn string) error {
+	if strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") {
+		_, err := pq.ParseURL(dsn)
+		return err
+	}
+	if !strings.Contains(dsn, "=") {
+		return errors.New("not a postgres:// URL or key=value connection string")
+	}
+	return nil
+}
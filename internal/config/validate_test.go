@@ -0,0 +1,301 @@
+package config
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfig_Validate(t *testing.T) {
+	valid := func() *Config {
+		return &Config{
+			RunAddr:             "localhost:8080",
+			ReturnPrefix:        "http://localhost:8080",
+			StorageFilePath:     defaultStorageFilePath,
+			AutocertHTTPAddr:    ":80",
+			MaxHeaderBytes:      1 << 20,
+			DeleteBatchSize:     50,
+			DeleteFlushInterval: 100 * time.Millisecond,
+			DeleteQueueSize:     100,
+			DeleteWorkers:       1,
+		}
+	}
+
+	tests := []struct {
+		name    string
+		modify  func(c *Config)
+		wantErr []string
+	}{
+		{name: "defaults", modify: func(c *Config) {}},
+		{name: "all interfaces", modify: func(c *Config) { c.RunAddr = ":9090" }},
+		{name: "dsn url", modify: func(c *Config) { c.DatabaseDSN = "postgres://user:pass@db:5432/shortener?sslmode=disable" }},
+		{name: "dsn key value", modify: func(c *Config) { c.DatabaseDSN = "host=db user=user dbname=shortener" }},
+		{name: "https with files", modify: func(c *Config) {
+			c.EnableHTTPS, c.TLSCertFile, c.TLSKeyFile = true, "tls.crt", "tls.key"
+		}},
+		{name: "autocert", modify: func(c *Config) { c.AutocertDomains = []string{"sho.rt"} }},
+		{name: "https and http", modify: func(c *Config) {
+			c.EnableHTTPS, c.TLSCertFile, c.TLSKeyFile, c.HTTPAddr = true, "tls.crt", "tls.key", ":8081"
+		}},
+		{
+			name:    "address without port",
+			modify:  func(c *Config) { c.RunAddr = "localhost" },
+			wantErr: []string{"SERVER_ADDRESS"},
+		},
+		{
+			name:    "address with invalid port",
+			modify:  func(c *Config) { c.RunAddr = "localhost:http8080" },
+			wantErr: []string{"SERVER_ADDRESS", `invalid port "http8080"`},
+		},
+		{
+			name:    "relative base url",
+			modify:  func(c *Config) { c.ReturnPrefix = "/short" },
+			wantErr: []string{"BASE_URL", "not an http or https URL"},
+		},
+		{
+			name:    "base url without host",
+			modify:  func(c *Config) { c.ReturnPrefix = "http://" },
+			wantErr: []string{"BASE_URL", "has no host"},
+		},
+		{
+			name:    "unknown log level",
+			modify:  func(c *Config) { c.LogLevel = "verbose" },
+			wantErr: []string{"LOG_LEVEL"},
+		},
+		{
+			name:    "malformed dsn",
+			modify:  func(c *Config) { c.DatabaseDSN = "shortener" },
+			wantErr: []string{"DATABASE_DSN"},
+		},
+		{
+			name:    "file storage and database",
+			modify:  func(c *Config) { c.DatabaseDSN, c.StorageFilePath = "host=db", "/data/storage.json" },
+			wantErr: []string{"FILE_STORAGE_PATH and DATABASE_DSN are mutually exclusive"},
+		},
+		{
+			name:    "https without certificate",
+			modify:  func(c *Config) { c.EnableHTTPS = true },
+			wantErr: []string{"ENABLE_HTTPS requires"},
+		},
+		{
+			name:    "certificate without key",
+			modify:  func(c *Config) { c.TLSCertFile = "tls.crt" },
+			wantErr: []string{"must be set together"},
+		},
+		{
+			name: "autocert and files",
+			modify: func(c *Config) {
+				c.AutocertDomains, c.TLSCertFile, c.TLSKeyFile = []string{"sho.rt"}, "tls.crt", "tls.key"
+			},
+			wantErr: []string{"AUTOCERT_DOMAINS and TLS_CERT_FILE/TLS_KEY_FILE are mutually exclusive"},
+		},
+		{
+			name:    "non-positive header limit",
+			modify:  func(c *Config) { c.MaxHeaderBytes = 0 },
+			wantErr: []string{"MAX_HEADER_BYTES: 0 is not positive"},
+		},
+		{
+			name:    "pprof without trusted subnet",
+			modify:  func(c *Config) { c.EnablePprof = true },
+			wantErr: []string{"ENABLE_PPROF requires TRUSTED_SUBNET"},
+		},
+		{
+			name:   "snapshots",
+			modify: func(c *Config) { c.SnapshotInterval, c.SnapshotChanges = 5*time.Second, 1000 },
+		},
+		{
+			name: "snapshots with database",
+			modify: func(c *Config) {
+				c.SnapshotInterval = 5 * time.Second
+				c.DatabaseDSN, c.StorageFilePath = "postgres://db/shortener", ""
+			},
+			wantErr: []string{"SNAPSHOT_INTERVAL and DATABASE_DSN are mutually exclusive"},
+		},
+		{
+			name: "snapshot object",
+			modify: func(c *Config) {
+				c.SnapshotInterval, c.StorageFilePath = 5*time.Second, ""
+				c.SnapshotURL = "https://storage.googleapis.com/bucket/shortener/storage.json.gz"
+				c.SnapshotAccessKey, c.SnapshotSecretKey = "GOOG1E", "secret"
+			},
+		},
+		{
+			name: "invalid snapshot object",
+			modify: func(c *Config) {
+				c.SnapshotURL = "https://s3.amazonaws.com/bucket"
+				c.SnapshotAccessKey = "AKID"
+			},
+			wantErr: []string{
+				"SNAPSHOT_URL requires SNAPSHOT_INTERVAL",
+				`SNAPSHOT_URL: "https://s3.amazonaws.com/bucket" does not name a bucket and an object key`,
+				"SNAPSHOT_ACCESS_KEY and SNAPSHOT_SECRET_KEY must be set together",
+			},
+		},
+		{
+			name:    "snapshot changes without interval",
+			modify:  func(c *Config) { c.SnapshotChanges = 1000 },
+			wantErr: []string{"SNAPSHOT_CHANGES requires SNAPSHOT_INTERVAL"},
+		},
+		{
+			name:    "storage queue with snapshots",
+			modify:  func(c *Config) { c.StorageQueueSize, c.SnapshotInterval = 1024, 5*time.Second },
+			wantErr: []string{"STORAGE_QUEUE_SIZE and SNAPSHOT_INTERVAL are mutually exclusive"},
+		},
+		{
+			name:   "compaction",
+			modify: func(c *Config) { c.CompactInterval = time.Hour },
+		},
+		{
+			name:    "compaction with snapshots",
+			modify:  func(c *Config) { c.CompactInterval, c.SnapshotInterval = time.Hour, 5*time.Second },
+			wantErr: []string{"COMPACT_INTERVAL and SNAPSHOT_INTERVAL are mutually exclusive"},
+		},
+		{
+			name:   "storage key",
+			modify: func(c *Config) { c.StorageKey = "MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY=" },
+		},
+		{
+			name:    "invalid storage key",
+			modify:  func(c *Config) { c.StorageKey, c.StorageKeyFile = "c2VjcmV0", "/run/secrets/storage_key" },
+			wantErr: []string{"STORAGE_KEY and STORAGE_KEY_FILE are mutually exclusive", "STORAGE_KEY: not a base64-encoded 32-byte key"},
+		},
+		{
+			name: "profile watchdog",
+			modify: func(c *Config) {
+				c.ProfileDir = "/var/lib/shortener/profiles"
+				c.ProfileGoroutines = 10000
+				c.ProfileCPUDuration = 10 * time.Second
+			},
+		},
+		{
+			name: "profile watchdog without thresholds",
+			modify: func(c *Config) {
+				c.ProfileDir = "/var/lib/shortener/profiles"
+				c.ProfileCPUDuration = 0
+			},
+			wantErr: []string{"PROFILE_DIR requires PROFILE_LATENCY_P99 or PROFILE_GOROUTINES", "PROFILE_CPU_DURATION: 0s is not positive"},
+		},
+		{
+			name:    "http address without https",
+			modify:  func(c *Config) { c.HTTPAddr = ":8081" },
+			wantErr: []string{"HTTP_ADDRESS requires ENABLE_HTTPS or AUTOCERT_DOMAINS"},
+		},
+		{
+			name: "http address of the challenge server",
+			modify: func(c *Config) {
+				c.AutocertDomains, c.HTTPAddr = []string{"sho.rt"}, ":80"
+			},
+			wantErr: []string{"HTTP_ADDRESS and AUTOCERT_HTTP_ADDRESS must differ"},
+		},
+		{name: "mtls on the https server", modify: func(c *Config) {
+			c.EnableHTTPS, c.TLSCertFile, c.TLSKeyFile, c.TLSClientCAFile = true, "tls.crt", "tls.key", "ca.pem"
+		}},
+		{name: "mtls address", modify: func(c *Config) {
+			c.TLSCertFile, c.TLSKeyFile, c.TLSClientCAFile, c.MTLSAddr = "tls.crt", "tls.key", "ca.pem", ":8443"
+		}},
+		{
+			name:    "client ca without https",
+			modify:  func(c *Config) { c.TLSClientCAFile = "ca.pem" },
+			wantErr: []string{"TLS_CLIENT_CA_FILE requires ENABLE_HTTPS"},
+		},
+		{
+			name: "client ca with plain http api",
+			modify: func(c *Config) {
+				c.EnableHTTPS, c.TLSCertFile, c.TLSKeyFile, c.TLSClientCAFile, c.HTTPAddr = true, "tls.crt", "tls.key", "ca.pem", ":8081"
+			},
+			wantErr: []string{"TLS_CLIENT_CA_FILE requires HTTP_REDIRECT"},
+		},
+		{
+			name:    "mtls address without ca and certificate",
+			modify:  func(c *Config) { c.MTLSAddr = ":8443" },
+			wantErr: []string{"MTLS_ADDRESS requires TLS_CLIENT_CA_FILE", "MTLS_ADDRESS requires TLS_CERT_FILE"},
+		},
+		{
+			name: "mtls address of the main server",
+			modify: func(c *Config) {
+				c.TLSCertFile, c.TLSKeyFile, c.TLSClientCAFile, c.MTLSAddr = "tls.crt", "tls.key", "ca.pem", c.RunAddr
+			},
+			wantErr: []string{"MTLS_ADDRESS must differ"},
+		},
+		{
+			name: "non-positive delete batching",
+			modify: func(c *Config) {
+				c.DeleteBatchSize, c.DeleteFlushInterval = 0, -time.Second
+			},
+			wantErr: []string{"DELETE_BATCH_SIZE: 0 is not positive", "DELETE_FLUSH_INTERVAL: -1s is not positive"},
+		},
+		{
+			name: "non-positive delete queue",
+			modify: func(c *Config) {
+				c.DeleteQueueSize, c.DeleteWorkers = 0, -1
+			},
+			wantErr: []string{"DELETE_QUEUE_SIZE: 0 is not positive", "DELETE_WORKERS: -1 is not positive"},
+		},
+		{
+			name: "negative breaker threshold",
+			modify: func(c *Config) {
+				c.DBBreakerThreshold = -1
+			},
+			wantErr: []string{"DB_BREAKER_THRESHOLD: -1 is negative"},
+		},
+		{
+			name: "breaker without cooldown",
+			modify: func(c *Config) {
+				c.DBBreakerThreshold = 5
+			},
+			wantErr: []string{"DB_BREAKER_COOLDOWN: 0s is not positive"},
+		},
+		{
+			name: "disabled breaker without cooldown",
+			modify: func(c *Config) {
+				c.DBBreakerThreshold, c.DBBreakerCooldown = 0, 0
+			},
+		},
+		{
+			name: "errors are aggregated",
+			modify: func(c *Config) {
+				c.RunAddr, c.ReturnPrefix, c.DatabaseDSN = "localhost", "sho.rt", "shortener"
+			},
+			wantErr: []string{"SERVER_ADDRESS", "BASE_URL", "DATABASE_DSN"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := valid()
+			tt.modify(c)
+			err := c.Validate()
+			if len(tt.wantErr) == 0 {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			for _, want := range tt.wantErr {
+				assert.Contains(t, err.Error(), want)
+			}
+		})
+	}
+}
+
+func TestConfig_Validate_ReusePort(t *testing.T) {
+	c := NewConfig()
+	c.ReusePort = true
+	if reusePortSupported {
+		assert.NoError(t, c.Validate())
+	} else {
+		assert.ErrorContains(t, c.Validate(), "REUSE_PORT is not supported on this platform")
+	}
+}
+
+func TestParseFlags_InvalidTrustedSubnet(t *testing.T) {
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+	t.Setenv("TRUSTED_SUBNET", "10.0.0.0/33")
+
+	os.Args = []string{"cmd"}
+	config := ParseFlags()
+	assert.Nil(t, config.TrustedSubnet)
+	assert.ErrorContains(t, config.Validate(), "TRUSTED_SUBNET")
+}
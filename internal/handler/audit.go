@@ -0,0 +1,107 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Aleksey170999/go-shortener/internal/audit"
+	"github.com/Aleksey170999/go-shortener/internal/model"
+)
+
+// Audit read-back page sizes.
+const (
+	// defaultAuditPageSize is the page size when no limit is given
+	defaultAuditPageSize = 100
+	// maxAuditPageSize is the largest accepted limit
+	maxAuditPageSize = 1000
+)
+
+// AuditSearcher looks up stored audit events; it is implemented by audit.DBAudit.
+type AuditSearcher interface {
+	Search(ctx context.Context, q audit.Query) ([]audit.AuditEvent, error)
+}
+
+// AuditHandler provides the admin endpoint that reads back stored audit events.
+type AuditHandler struct {
+	Audit AuditSearcher
+}
+
+// NewAuditHandler creates a new instance of AuditHandler.
+//
+// Parameters:
+//   - searcher: The store of audit events, usually the database audit writer
+//
+// Returns:
+//   - *AuditHandler: A new AuditHandler instance
+func NewAuditHandler(searcher AuditSearcher) *AuditHandler {
+	return &AuditHandler{Audit: searcher}
+}
+
+// ListHandler returns a page of stored audit events, newest first.
+//
+// Request:
+//   - Method: GET
+//   - Query: user_id, action, from and to (RFC 3339 times, to is exclusive)
+//     filter the events; limit (default 100, at most 1000) and offset page through them
+//
+// Responses:
+//   - 200 OK with a JSON body {"events": [...], "next_offset": n}
+//   - 400 Bad Request if a parameter is invalid
+//   - 500 Internal Server Error if the events cannot be read
+func (h *AuditHandler) ListHandler(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+	q := audit.Query{
+		UserID: params.Get("user_id"),
+		Action: params.Get("action"),
+		Limit:  defaultAuditPageSize,
+	}
+	var err error
+	if q.From, err = parseTimeParam(params.Get("from")); err != nil {
+		httpError(w, "invalid from", http.StatusBadRequest)
+		return
+	}
+	if q.To, err = parseTimeParam(params.Get("to")); err != nil {
+		httpError(w, "invalid to", http.StatusBadRequest)
+		return
+	}
+	if v := params.Get("limit"); v != "" {
+		if q.Limit, err = strconv.Atoi(v); err != nil || q.Limit <= 0 || q.Limit > maxAuditPageSize {
+			httpError(w, "invalid limit", http.StatusBadRequest)
+			return
+		}
+	}
+	if v := params.Get("offset"); v != "" {
+		if q.Offset, err = strconv.Atoi(v); err != nil || q.Offset < 0 {
+			httpError(w, "invalid offset", http.StatusBadRequest)
+			return
+		}
+	}
+
+	// One extra event tells whether there is a next page.
+	limit := q.Limit
+	q.Limit++
+	events, err := h.Audit.Search(r.Context(), q)
+	if err != nil {
+		httpError(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	resp := model.AuditEventsResponse{Events: events}
+	if len(events) > limit {
+		resp.Events = events[:limit]
+		resp.NextOffset = q.Offset + limit
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// parseTimeParam parses an optional RFC 3339 query parameter.
+func parseTimeParam(v string) (time.Time, error) {
+	if v == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339, v)
+}
@@ -0,0 +1,63 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Aleksey170999/go-shortener/internal/audit"
+	"github.com/Aleksey170999/go-shortener/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeAuditSearcher returns events from a fixed slice and records the last query.
+type fakeAuditSearcher struct {
+	events []audit.AuditEvent
+	query  audit.Query
+}
+
+func (f *fakeAuditSearcher) Search(_ context.Context, q audit.Query) ([]audit.AuditEvent, error) {
+	f.query = q
+	events := f.events[min(q.Offset, len(f.events)):]
+	return events[:min(q.Limit, len(events))], nil
+}
+
+func TestAuditHandler_ListHandler(t *testing.T) {
+	searcher := &fakeAuditSearcher{}
+	for _, action := range []string{"a1", "a2", "a3"} {
+		searcher.events = append(searcher.events, audit.AuditEvent{Action: action, UserID: "user1"})
+	}
+	h := NewAuditHandler(searcher)
+
+	list := func(query string) (*httptest.ResponseRecorder, model.AuditEventsResponse) {
+		w := httptest.NewRecorder()
+		h.ListHandler(w, httptest.NewRequest(http.MethodGet, "/api/admin/audit?"+query, nil))
+		var resp model.AuditEventsResponse
+		if w.Code == http.StatusOK {
+			require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+		}
+		return w, resp
+	}
+
+	w, resp := list("user_id=user1&action=shorten&from=2026-01-01T00:00:00Z&limit=2")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "user1", searcher.query.UserID)
+	assert.Equal(t, "shorten", searcher.query.Action)
+	assert.Equal(t, time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC), searcher.query.From)
+	assert.Len(t, resp.Events, 2)
+	assert.Equal(t, 2, resp.NextOffset)
+
+	_, resp = list("limit=2&offset=2")
+	assert.Len(t, resp.Events, 1)
+	assert.Equal(t, "a3", resp.Events[0].Action)
+	assert.Zero(t, resp.NextOffset)
+
+	for _, query := range []string{"from=yesterday", "limit=0", "limit=5000", "offset=-1"} {
+		w, _ = list(query)
+		assert.Equal(t, http.StatusBadRequest, w.Code, query)
+	}
+}
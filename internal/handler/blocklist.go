@@ -0,0 +1,65 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/Aleksey170999/go-shortener/internal/middlewares"
+	"github.com/Aleksey170999/go-shortener/internal/model"
+)
+
+// BlocklistHandler provides the admin endpoints that inspect and replace the
+// IP and user blocklist.
+type BlocklistHandler struct {
+	Blocklist *middlewares.Blocklist
+}
+
+// NewBlocklistHandler creates a new instance of BlocklistHandler.
+//
+// Parameters:
+//   - blocklist: The blocklist guarding write routes
+//
+// Returns:
+//   - *BlocklistHandler: A new BlocklistHandler instance
+func NewBlocklistHandler(blocklist *middlewares.Blocklist) *BlocklistHandler {
+	return &BlocklistHandler{Blocklist: blocklist}
+}
+
+// GetHandler returns the blocklist contents.
+//
+// Request:
+//   - Method: GET
+//
+// Responses:
+//   - 200 OK with a JSON body {"ips": [...], "users": [...]}
+func (h *BlocklistHandler) GetHandler(w http.ResponseWriter, r *http.Request) {
+	h.writeList(w)
+}
+
+// SetHandler replaces the blocklist contents.
+//
+// Request:
+//   - Method: PUT
+//   - Body: JSON object {"ips": [...], "users": [...]}; IPs may be addresses or CIDRs
+//
+// Responses:
+//   - 200 OK with the new contents
+//   - 400 Bad Request if the body or one of the IPs is invalid
+func (h *BlocklistHandler) SetHandler(w http.ResponseWriter, r *http.Request) {
+	var req model.Blocklist
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httpError(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := h.Blocklist.Set(req); err != nil {
+		httpError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	h.writeList(w)
+}
+
+// writeList writes the current blocklist contents as JSON.
+func (h *BlocklistHandler) writeList(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.Blocklist.List())
+}
@@ -0,0 +1,81 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/Aleksey170999/go-shortener/internal/middlewares"
+	"github.com/Aleksey170999/go-shortener/internal/ratelimit"
+	"github.com/Aleksey170999/go-shortener/internal/service"
+)
+
+// serviceErrorStatus maps an error returned by URLService to the HTTP status
+// code reported to the client. Unknown errors map to 500.
+func serviceErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, service.ErrInvalidURL):
+		return http.StatusBadRequest
+	case errors.Is(err, service.ErrNotFound), errors.Is(err, service.ErrDeleteJobNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, service.ErrAliasTaken):
+		return http.StatusConflict
+	case errors.Is(err, service.ErrDeleted), errors.Is(err, service.ErrExpired):
+		return http.StatusGone
+	case errors.Is(err, service.ErrInvalidAlias):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, service.ErrQuotaExceeded):
+		return http.StatusTooManyRequests
+	case errors.Is(err, context.DeadlineExceeded):
+		return http.StatusGatewayTimeout
+	case errors.Is(err, service.ErrCircuitOpen),
+		errors.Is(err, service.ErrDeleteQueueFull),
+		errors.Is(err, service.ErrServiceClosed):
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusInternalServerError
+}
+
+// writeServiceError responds with the status code matching err. Alias
+// validation errors are described to the client; everything else gets the
+// generic status text so internal details do not leak. Rate limited calls
+// get a Retry-After header saying when the user's next token is due.
+func writeServiceError(w http.ResponseWriter, err error) {
+	status := serviceErrorStatus(err)
+	var quotaErr *service.QuotaExceededError
+	switch {
+	case errors.As(err, &quotaErr):
+		w.Header().Set("Retry-After", strconv.Itoa(ratelimit.RetryAfterSeconds(quotaErr.RetryAfter)))
+	case errors.Is(err, service.ErrDeleteQueueFull), errors.Is(err, service.ErrQuotaExceeded):
+		w.Header().Set("Retry-After", "1")
+	}
+	var aliasErr *service.AliasValidationError
+	if errors.As(err, &aliasErr) {
+		httpError(w, aliasErr.Error(), status)
+		return
+	}
+	httpError(w, http.StatusText(status), status)
+}
+
+// httpError replies with an error message like http.Error. The request ID set
+// by middlewares.RequestID is appended to the message, so users can quote it
+// when reporting a problem.
+func httpError(w http.ResponseWriter, msg string, status int) {
+	if id := w.Header().Get(middlewares.RequestIDHeader); id != "" {
+		msg += " (request id: " + id + ")"
+	}
+	http.Error(w, msg, status)
+}
+
+// isBodyTooLarge reports whether err was caused by a request body exceeding
+// the limit set by middlewares.MaxBodySize.
+func isBodyTooLarge(err error) bool {
+	var maxErr *http.MaxBytesError
+	return errors.As(err, &maxErr)
+}
+
+// writeBodyTooLarge responds with 413 Request Entity Too Large.
+func writeBodyTooLarge(w http.ResponseWriter) {
+	httpError(w, "request body too large", http.StatusRequestEntityTooLarge)
+}
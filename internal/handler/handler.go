@@ -8,16 +8,12 @@ import (
 	"log"
 	"net/http"
 
-	"github.com/google/uuid"
-
 	"github.com/Aleksey170999/go-shortener/internal/audit"
 
 	"github.com/Aleksey170999/go-shortener/internal/config"
 	"github.com/Aleksey170999/go-shortener/internal/middlewares"
 	"github.com/Aleksey170999/go-shortener/internal/model"
-	"github.com/Aleksey170999/go-shortener/internal/repository"
 	"github.com/Aleksey170999/go-shortener/internal/service"
-	"github.com/Aleksey170999/go-shortener/internal/storage"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-playground/validator/v10"
 	"go.uber.org/zap"
@@ -28,10 +24,9 @@ var validate = validator.New()
 // Handler provides all the HTTP handlers for the URL shortener service.
 // It contains all the necessary dependencies to handle incoming requests.
 type Handler struct {
-	URLService   *service.URLService
-	Cfg          *config.Config
-	Storage      *storage.Storage
-	AuditManager *audit.AuditManager
+	URLService *service.URLService
+	Cfg        *config.Config
+	Logger     *zap.Logger
 }
 
 // NewHandler creates a new instance of Handler with the provided dependencies.
@@ -39,20 +34,23 @@ type Handler struct {
 // Parameters:
 //   - urlService: Service for URL shortening and management operations
 //   - cfg: Application configuration
-//   - storage: Storage for persisting URLs
-//   - auditManager: Manager for handling audit logs
+//   - logger: Logger for request failures
 //
 // Returns:
 //   - *Handler: A new Handler instance with the provided dependencies
-func NewHandler(urlService *service.URLService, cfg *config.Config, storage *storage.Storage, auditManager *audit.AuditManager) *Handler {
+func NewHandler(urlService *service.URLService, cfg *config.Config, logger *zap.Logger) *Handler {
 	return &Handler{
-		URLService:   urlService,
-		Cfg:          cfg,
-		Storage:      storage,
-		AuditManager: auditManager,
+		URLService: urlService,
+		Cfg:        cfg,
+		Logger:     logger,
 	}
 }
 
+// logger returns the application logger annotated with the ID of request r.
+func (h *Handler) logger(r *http.Request) *zap.Logger {
+	return middlewares.LoggerWithRequestID(r.Context(), h.Logger)
+}
+
 // ShortenURLHandler handles the URL shortening request.
 // It reads the URL from the request body, validates it, and returns a shortened version.
 //
@@ -63,32 +61,29 @@ func NewHandler(urlService *service.URLService, cfg *config.Config, storage *sto
 // Responses:
 //   - 201 Created: On successful URL shortening, returns the shortened URL
 //   - 400 Bad Request: If the request body is empty or invalid
+//   - 409 Conflict: If the URL was already shortened
+//   - 413 Request Entity Too Large: If the request body exceeds the size limit
+//   - 429 Too Many Requests: If the user has exceeded their quota
 //   - 500 Internal Server Error: If there's an error processing the request
+//   - 503 Service Unavailable: If the storage is temporarily unavailable
 func (h *Handler) ShortenURLHandler(w http.ResponseWriter, r *http.Request) {
 	body, err := io.ReadAll(r.Body)
+	if isBodyTooLarge(err) {
+		writeBodyTooLarge(w)
+		return
+	}
 	if err != nil {
-		http.Error(w, "can't read body", http.StatusBadRequest)
+		httpError(w, "can't read body", http.StatusBadRequest)
 		return
 	}
 	original := string(body)
 	if original == "" {
-		http.Error(w, "empty url", http.StatusBadRequest)
+		httpError(w, "empty url", http.StatusBadRequest)
 		return
 	}
 	userID, _ := middlewares.GetUserID(r)
 
-	if userID == "" {
-		userID = uuid.New().String()
-
-		http.SetCookie(w, &http.Cookie{
-			Name:     "user_id",
-			Value:    userID,
-			Path:     "/",
-			HttpOnly: true,
-		})
-	}
-
-	url, err := h.URLService.Shorten(original, "", userID)
+	url, err := h.URLService.Shorten(r.Context(), original, "", userID)
 	if err != nil {
 		if errors.Is(err, model.ErrURLAlreadyExists) {
 			fullAddress := fmt.Sprintf("%s/%s", h.Cfg.ReturnPrefix, url.Short)
@@ -98,15 +93,15 @@ func (h *Handler) ShortenURLHandler(w http.ResponseWriter, r *http.Request) {
 			w.Write([]byte(fullAddress))
 			return
 		}
-		http.Error(w, "failed to shorten url", http.StatusInternalServerError)
+		if serviceErrorStatus(err) == http.StatusInternalServerError {
+			h.logger(r).Error("error shortening url", zap.Error(err))
+		}
+		writeServiceError(w, err)
 		return
 	}
 
-	if h.AuditManager != nil {
-		go h.AuditManager.LogEvent(r.Context(), "shorten", userID, original)
-	}
+	audit.Annotate(r.Context(), "shorten", original)
 
-	h.Storage.LoadToStorage(url)
 	fullAddress := fmt.Sprintf("%s/%s", h.Cfg.ReturnPrefix, url.Short)
 	w.WriteHeader(http.StatusCreated)
 	w.Write([]byte(fullAddress))
@@ -122,28 +117,28 @@ func (h *Handler) ShortenURLHandler(w http.ResponseWriter, r *http.Request) {
 // Responses:
 //   - 307 Temporary Redirect: Redirects to the original URL
 //   - 400 Bad Request: If the short URL ID is missing
-//   - 404 Not Found: If the short URL is not found or has been deleted
+//   - 404 Not Found: If the short URL is not found
+//   - 410 Gone: If the short URL has been deleted or has expired
 //   - 500 Internal Server Error: If there's an error processing the request
+//   - 503 Service Unavailable: If the storage is temporarily unavailable
 func (h *Handler) RedirectHandler(w http.ResponseWriter, r *http.Request) {
 	shortURL := chi.URLParam(r, "id")
 	if shortURL == "" {
-		http.Error(w, "missing short url id", http.StatusBadRequest)
+		httpError(w, "missing short url id", http.StatusBadRequest)
 		return
 	}
-	url, err := h.URLService.Resolve(shortURL)
+	url, err := h.URLService.Resolve(r.Context(), shortURL)
 	if err != nil {
-		http.Error(w, "not found", http.StatusNotFound)
-		return
-	}
-	if url.IsDeleted {
-		http.Error(w, "gone", http.StatusGone)
+		if serviceErrorStatus(err) == http.StatusInternalServerError {
+			h.logger(r).Error("error resolving url", zap.Error(err))
+		}
+		writeServiceError(w, err)
 		return
 	}
 
-	userID, _ := middlewares.GetUserID(r)
-	if h.AuditManager != nil && userID != "" {
-		go h.AuditManager.LogEvent(r.Context(), "follow", userID, url.Original)
-	}
+	h.URLService.RecordClick(url.Short)
+
+	audit.Annotate(r.Context(), "follow", url.Original)
 
 	http.Redirect(w, r, url.Original, http.StatusTemporaryRedirect)
 }
@@ -160,42 +155,48 @@ func (h *Handler) RedirectHandler(w http.ResponseWriter, r *http.Request) {
 // Example Request:
 //
 //	{
-//	  "url": "https://example.com/long/url/to/be/shortened"
+//	  "url": "https://example.com/long/url/to/be/shortened",
+//	  "alias": "my-link"
 //	}
 //
+// The optional "alias" field requests a custom short code instead of a generated one.
+//
 // Responses:
 //   - 201 Created: On successful shortening, returns a JSON response with the shortened URL
 //   - 400 Bad Request: If the request body is invalid or missing required fields
+//   - 409 Conflict: If the URL was already shortened or the alias is taken
+//   - 413 Request Entity Too Large: If the request body exceeds the size limit
+//   - 422 Unprocessable Entity: If the alias violates the alias policy
+//   - 429 Too Many Requests: If the user has exceeded their quota
 //   - 500 Internal Server Error: If there's an error processing the request
+//   - 503 Service Unavailable: If the storage is temporarily unavailable
 func (h *Handler) ShortenJSONURLHandler(w http.ResponseWriter, r *http.Request) {
 	var req model.ShortenJSONRequest
 
 	err := json.NewDecoder(r.Body).Decode(&req)
+	if isBodyTooLarge(err) {
+		writeBodyTooLarge(w)
+		return
+	}
 	if err != nil {
-		h.Cfg.Logger.Error("error decoding request body", zap.Error(err))
-		http.Error(w, "bad request", http.StatusBadRequest)
+		h.logger(r).Error("error decoding request body", zap.Error(err))
+		httpError(w, "bad request", http.StatusBadRequest)
 		return
 	}
 
 	if req.URL == "" {
-		http.Error(w, "empty url", http.StatusBadRequest)
+		httpError(w, "empty url", http.StatusBadRequest)
 		return
 	}
 
 	userID, _ := middlewares.GetUserID(r)
 
-	if userID == "" {
-		userID = uuid.New().String()
-
-		http.SetCookie(w, &http.Cookie{
-			Name:     "user_id",
-			Value:    userID,
-			Path:     "/",
-			HttpOnly: true,
-		})
+	var url *model.URL
+	if req.Alias != "" {
+		url, err = h.URLService.ShortenAlias(r.Context(), req.URL, req.Alias, userID)
+	} else {
+		url, err = h.URLService.Shorten(r.Context(), req.URL, "", userID)
 	}
-
-	url, err := h.URLService.Shorten(req.URL, "", userID)
 	if err != nil {
 		if errors.Is(err, model.ErrURLAlreadyExists) {
 			response := model.ShortenJSONResponse{
@@ -207,17 +208,14 @@ func (h *Handler) ShortenJSONURLHandler(w http.ResponseWriter, r *http.Request)
 			json.NewEncoder(w).Encode(response)
 			return
 		}
-
-		h.Cfg.Logger.Error("error shortening url", zap.Error(err))
-		http.Error(w, "internal server error", http.StatusInternalServerError)
+		if serviceErrorStatus(err) == http.StatusInternalServerError {
+			h.logger(r).Error("error shortening url", zap.Error(err))
+		}
+		writeServiceError(w, err)
 		return
 	}
 
-	if h.AuditManager != nil {
-		go h.AuditManager.LogEvent(r.Context(), "shorten", userID, req.URL)
-	}
-
-	h.Storage.LoadToStorage(url)
+	audit.Annotate(r.Context(), "shorten", req.URL)
 
 	response := model.ShortenJSONResponse{
 		Result: fmt.Sprintf("%s/%s", h.Cfg.ReturnPrefix, url.Short),
@@ -235,8 +233,8 @@ func (h *Handler) ShortenJSONURLHandler(w http.ResponseWriter, r *http.Request)
 //
 // This handler is used for health checks and monitoring.
 func (h *Handler) PingDBHandler(w http.ResponseWriter, r *http.Request) {
-	if err := h.URLService.PingDB(); err != nil {
-		http.Error(w, "failed to ping DB: "+err.Error(), http.StatusInternalServerError)
+	if err := h.URLService.PingDB(r.Context()); err != nil {
+		httpError(w, "failed to ping DB: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
 	w.WriteHeader(http.StatusOK)
@@ -259,15 +257,27 @@ func (h *Handler) PingDBHandler(w http.ResponseWriter, r *http.Request) {
 //	  ...
 //	]
 //
+// A batch with an invalid URL is rejected as a whole before anything is
+// stored. A URL that fails after the others were stored, e.g. because no
+// free short code was found, gets an "error" instead of a "short_url", so
+// that the response still maps every stored URL.
+//
 // Returns:
 //   - 201 Created on successful batch processing
 //   - 400 Bad Request for invalid input
+//   - 413 Request Entity Too Large if the request body exceeds the size limit
+//   - 429 Too Many Requests if the user has exceeded their quota
 //   - 500 Internal Server Error for processing failures
+//   - 503 Service Unavailable if the storage is temporarily unavailable
 func (h *Handler) ShortenJSONURLBatchHandler(w http.ResponseWriter, r *http.Request) {
 	var req []model.RequestURLItem
 	dec := json.NewDecoder(r.Body)
 	if err := dec.Decode(&req); err != nil {
-		h.Cfg.Logger.Debug("cannot decode request JSON body", zap.Error(err))
+		if isBodyTooLarge(err) {
+			writeBodyTooLarge(w)
+			return
+		}
+		h.logger(r).Debug("cannot decode request JSON body", zap.Error(err))
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
@@ -275,22 +285,45 @@ func (h *Handler) ShortenJSONURLBatchHandler(w http.ResponseWriter, r *http.Requ
 	for _, item := range req {
 		err := validate.Struct(item)
 		if err != nil {
-			http.Error(w, "invalid request body", http.StatusBadRequest)
+			httpError(w, "invalid request body", http.StatusBadRequest)
 			return
 		}
 	}
 
-	var resp []model.ResponseURLItem
+	audit.AnnotateBatch(r.Context(), "shorten_batch", len(req))
 	userID, _ := middlewares.GetUserID(r)
-	for _, item := range req {
-		url, _ := h.URLService.Shorten(item.OriginalURL, item.СorrelationID, userID)
+	originals := make([]string, len(req))
+	for i, item := range req {
+		originals[i] = item.OriginalURL
+	}
+
+	results, err := h.URLService.ShortenBatch(r.Context(), originals, userID)
+	if err != nil {
+		if serviceErrorStatus(err) == http.StatusInternalServerError {
+			h.logger(r).Error("error shortening batch", zap.Error(err))
+		}
+		writeServiceError(w, err)
+		return
+	}
+
+	resp := make([]model.ResponseURLItem, 0, len(results))
+	for i, res := range results {
+		if res.Err != nil && !errors.Is(res.Err, model.ErrURLAlreadyExists) {
+			status := serviceErrorStatus(res.Err)
+			if status == http.StatusInternalServerError {
+				h.logger(r).Error("error shortening batch item", zap.Error(res.Err))
+			}
+			resp = append(resp, model.ResponseURLItem{
+				CorrelationID: req[i].СorrelationID,
+				Error:         http.StatusText(status),
+			})
+			continue
+		}
 		resp = append(resp, model.ResponseURLItem{
-			CorrelationID: item.СorrelationID,
-			ShortURL:      fmt.Sprintf("%s/%s", h.Cfg.ReturnPrefix, url.Short),
+			CorrelationID: req[i].СorrelationID,
+			ShortURL:      fmt.Sprintf("%s/%s", h.Cfg.ReturnPrefix, res.URL.Short),
 		})
-		h.Storage.LoadToStorage(url)
 	}
-
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(resp)
@@ -309,31 +342,27 @@ func (h *Handler) ShortenJSONURLBatchHandler(w http.ResponseWriter, r *http.Requ
 // Returns:
 //   - 200 OK with the list of URLs
 //   - 204 No Content if no URLs found for the user
+//   - 401 Unauthorized if the user is not identified
 //   - 500 Internal Server Error for processing failures
+//   - 503 Service Unavailable if the storage is temporarily unavailable
 func (h *Handler) GetUserURLsHandler(w http.ResponseWriter, r *http.Request) {
 	userID, err := middlewares.GetUserID(r)
-
-	w.Header().Set("Content-Type", "application/json")
-	if userID == "" {
-		log.Printf("[GetUserURLsHandler] userID is empty")
-		w.WriteHeader(http.StatusNoContent)
-		return
-	}
 	if err != nil {
-		log.Printf("[GetUserURLsHandler] error getting userID: %v", err)
-		w.WriteHeader(http.StatusInternalServerError)
+		httpError(w, "unauthorized", http.StatusUnauthorized)
 		return
 	}
 
-	urls, err := h.URLService.GetUserURLs(userID)
+	w.Header().Set("Content-Type", "application/json")
+
+	urls, err := h.URLService.GetUserURLs(r.Context(), userID)
 	if err != nil {
-		if err == repository.ErrNotFound {
+		if errors.Is(err, service.ErrNotFound) {
 			log.Printf("[GetUserURLsHandler] no urls found for userID=%s", userID)
 			w.WriteHeader(http.StatusNoContent)
 			return
 		}
 		log.Printf("[GetUserURLsHandler] error fetching urls for userID=%s: %v", userID, err)
-		w.WriteHeader(http.StatusInternalServerError)
+		w.WriteHeader(serviceErrorStatus(err))
 		return
 	}
 
@@ -364,29 +393,85 @@ func (h *Handler) GetUserURLsHandler(w http.ResponseWriter, r *http.Request) {
 //
 //	["id1", "id2", ...]
 //
+// The 202 response carries the ID of the delete job and a Location header
+// pointing at its status endpoint:
+//
+//	{"job_id": "<job_id>"}
+//
 // Returns:
 //   - 202 Accepted if the deletion request was accepted for processing
 //   - 400 Bad Request for invalid input
 //   - 401 Unauthorized if user is not authenticated
+//   - 413 Request Entity Too Large if the request body exceeds the size limit
+//   - 429 Too Many Requests if the user has exceeded their quota
 //   - 500 Internal Server Error for processing failures
+//   - 503 Service Unavailable if the delete queue is full or the service is shutting down
 //
 // Note: This is an asynchronous operation. The actual deletion happens in a separate goroutine.
 func (h *Handler) BatchDeleteUserURLsHandler(w http.ResponseWriter, r *http.Request) {
 	userID, err := middlewares.GetUserID(r)
 	if err != nil {
-		log.Print(err)
+		httpError(w, "unauthorized", http.StatusUnauthorized)
+		return
 	}
 	var shortUrls []string
 	dec := json.NewDecoder(r.Body)
 	if err := dec.Decode(&shortUrls); err != nil {
+		if isBodyTooLarge(err) {
+			writeBodyTooLarge(w)
+			return
+		}
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
-	go func(shortUrls []string, userID string) {
-		err := h.URLService.BatchDelete(shortUrls, userID)
-		if err != nil {
-			log.Printf("[BatchDeleteUserURLsHandler] async BatchDelete error: %v", err)
-		}
-	}(shortUrls, userID)
+	audit.AnnotateBatch(r.Context(), "delete_batch", len(shortUrls))
+	jobID, err := h.URLService.BatchDelete(r.Context(), shortUrls, userID)
+	if err != nil {
+		log.Printf("[BatchDeleteUserURLsHandler] BatchDelete error: %v", err)
+		writeServiceError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", "/api/user/urls/delete-jobs/"+jobID)
 	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(model.DeleteAcceptedResponse{JobID: jobID})
+}
+
+// GetDeleteJobHandler reports the progress of a batch delete job created by
+// BatchDeleteUserURLsHandler. Jobs are only visible to the user who created them.
+//
+// Request:
+//   - Method: GET
+//   - Path: /api/user/urls/delete-jobs/{id}
+//
+// Response is a JSON object with the following structure:
+//
+//	{"id": "<job_id>", "status": "pending|completed|failed", "total": 3, "pending": 1, "completed": 2, "failed": 0}
+//
+// Returns:
+//   - 200 OK with the job progress
+//   - 401 Unauthorized if the user is not identified
+//   - 404 Not Found if the job does not exist, belongs to another user or has expired
+func (h *Handler) GetDeleteJobHandler(w http.ResponseWriter, r *http.Request) {
+	userID, _ := middlewares.GetUserID(r)
+	if userID == "" {
+		httpError(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	job, err := h.URLService.GetDeleteJob(r.Context(), chi.URLParam(r, "id"), userID)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(model.DeleteJobResponse{
+		ID:        job.ID,
+		Status:    string(job.Status()),
+		Total:     job.Total,
+		Pending:   job.Pending,
+		Completed: job.Completed,
+		Failed:    job.Failed,
+	})
 }
@@ -4,34 +4,34 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
-
-	"github.com/Aleksey170999/go-shortener/internal/audit"
+	"time"
 
 	"github.com/Aleksey170999/go-shortener/internal/config"
+	"github.com/Aleksey170999/go-shortener/internal/middlewares"
 	"github.com/Aleksey170999/go-shortener/internal/model"
 	"github.com/Aleksey170999/go-shortener/internal/repository"
 	"github.com/Aleksey170999/go-shortener/internal/service"
-	"github.com/Aleksey170999/go-shortener/internal/storage"
 	"github.com/go-chi/chi/v5"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
 )
 
 func setupTestHandler() *Handler {
 	cfg := config.Config{
-		RunAddr:         "localhost:8080",
-		ReturnPrefix:    "http://localhost:8080",
-		StorageFilePath: "./storage.json",
+		RunAddr:      "localhost:8080",
+		ReturnPrefix: "http://localhost:8080",
 	}
-	storage := storage.NewStorage(cfg.StorageFilePath)
 	repo := repository.NewMemoryURLRepository()
 	urlService := service.NewURLService(repo)
-	auditManager := audit.NewAuditManager()
-	return NewHandler(urlService, &cfg, storage, auditManager)
+	return NewHandler(urlService, &cfg, zap.NewNop())
 }
 
 func TestShortenURLHandler(t *testing.T) {
@@ -53,6 +53,17 @@ func TestShortenURLHandler(t *testing.T) {
 	}
 }
 
+func TestShortenURLHandler_BodyTooLarge(t *testing.T) {
+	h := setupTestHandler()
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("https://example.com/"+strings.Repeat("a", 100)))
+	req.ContentLength = -1
+	w := httptest.NewRecorder()
+
+	middlewares.MaxBodySize(32)(http.HandlerFunc(h.ShortenURLHandler)).ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+}
+
 func TestRedirectHandler(t *testing.T) {
 	h := setupTestHandler()
 	shortenReq := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("https://example.com"))
@@ -108,3 +119,214 @@ func TestShortenJSONURLHandler(t *testing.T) {
 		t.Errorf("expected status 201, got %d", resp.StatusCode)
 	}
 }
+
+func TestShortenJSONURLBatchHandler(t *testing.T) {
+	h := setupTestHandler()
+	reqBody := `[
+		{"correlation_id": "1", "original_url": "https://example.com/1"},
+		{"correlation_id": "2", "original_url": "https://example.com/2"}
+	]`
+	req := httptest.NewRequest(http.MethodPost, "/api/shorten/batch", strings.NewReader(reqBody))
+	w := httptest.NewRecorder()
+
+	h.ShortenJSONURLBatchHandler(w, req)
+
+	resp := w.Result()
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	var items []model.ResponseURLItem
+	err := json.NewDecoder(resp.Body).Decode(&items)
+	assert.NoError(t, err)
+	if assert.Len(t, items, 2) {
+		assert.Equal(t, "1", items[0].CorrelationID)
+		assert.Equal(t, "2", items[1].CorrelationID)
+		assert.True(t, strings.HasPrefix(items[0].ShortURL, "http://localhost:8080/"))
+		assert.NotEqual(t, items[0].ShortURL, items[1].ShortURL)
+	}
+}
+
+func TestShortenJSONURLBatchHandler_InvalidItem(t *testing.T) {
+	repo := repository.NewMemoryURLRepository()
+	cfg := config.Config{ReturnPrefix: "http://localhost:8080"}
+	h := NewHandler(service.NewURLService(repo), &cfg, zap.NewNop())
+	reqBody := `[
+		{"correlation_id": "1", "original_url": "https://example.com/1"},
+		{"correlation_id": "2", "original_url": "mailto:user@example.com"}
+	]`
+	w := httptest.NewRecorder()
+	h.ShortenJSONURLBatchHandler(w, httptest.NewRequest(http.MethodPost, "/api/shorten/batch", strings.NewReader(reqBody)))
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	urls, err := repo.List(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, urls, "nothing of a rejected batch is stored")
+}
+
+// conflictingRepository reports a short code conflict for every code of the
+// original URL conflicting, so that no free code is ever found for it.
+type conflictingRepository struct {
+	repository.URLRepository
+	conflicting string
+}
+
+func (r *conflictingRepository) SaveBatch(ctx context.Context, urls []*model.URL) ([]error, error) {
+	var save []*model.URL
+	for _, url := range urls {
+		if url.Original != r.conflicting {
+			save = append(save, url)
+		}
+	}
+	saveErrs, err := r.URLRepository.SaveBatch(ctx, save)
+	if err != nil {
+		return nil, err
+	}
+	errs := make([]error, 0, len(urls))
+	for _, url := range urls {
+		if url.Original == r.conflicting {
+			errs = append(errs, model.ErrShortURLConflict)
+			continue
+		}
+		errs = append(errs, saveErrs[0])
+		saveErrs = saveErrs[1:]
+	}
+	return errs, nil
+}
+
+func TestShortenJSONURLBatchHandler_ItemFailedAfterStore(t *testing.T) {
+	repo := repository.NewMemoryURLRepository()
+	cfg := config.Config{ReturnPrefix: "http://localhost:8080"}
+	conflicting := &conflictingRepository{URLRepository: repo, conflicting: "https://example.com/2"}
+	h := NewHandler(service.NewURLService(conflicting), &cfg, zap.NewNop())
+	reqBody := `[
+		{"correlation_id": "1", "original_url": "https://example.com/1"},
+		{"correlation_id": "2", "original_url": "https://example.com/2"}
+	]`
+	w := httptest.NewRecorder()
+	h.ShortenJSONURLBatchHandler(w, httptest.NewRequest(http.MethodPost, "/api/shorten/batch", strings.NewReader(reqBody)))
+
+	require.Equal(t, http.StatusCreated, w.Code)
+	var items []model.ResponseURLItem
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&items))
+	require.Len(t, items, 2)
+	assert.True(t, strings.HasPrefix(items[0].ShortURL, "http://localhost:8080/"), "the stored URL is mapped")
+	assert.Empty(t, items[0].Error)
+	assert.Equal(t, "2", items[1].CorrelationID)
+	assert.Empty(t, items[1].ShortURL)
+	assert.Equal(t, http.StatusText(http.StatusInternalServerError), items[1].Error)
+}
+
+func TestServiceErrorStatus(t *testing.T) {
+	tests := []struct {
+		err  error
+		want int
+	}{
+		{service.ErrInvalidURL, http.StatusBadRequest},
+		{fmt.Errorf("%w: %w", service.ErrNotFound, repository.ErrNotFound), http.StatusNotFound},
+		{service.ErrDeleted, http.StatusGone},
+		{service.ErrExpired, http.StatusGone},
+		{service.ErrAliasTaken, http.StatusConflict},
+		{&service.AliasValidationError{Alias: "a", Reason: "too short"}, http.StatusUnprocessableEntity},
+		{service.ErrQuotaExceeded, http.StatusTooManyRequests},
+		{service.ErrCircuitOpen, http.StatusServiceUnavailable},
+		{service.ErrDeleteQueueFull, http.StatusServiceUnavailable},
+		{errors.New("boom"), http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, serviceErrorStatus(tt.err), tt.err.Error())
+	}
+}
+
+func TestWriteServiceError_RetryAfter(t *testing.T) {
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{&service.QuotaExceededError{RetryAfter: 7500 * time.Millisecond}, "8"},
+		{fmt.Errorf("shorten: %w", &service.QuotaExceededError{RetryAfter: time.Millisecond}), "1"},
+		{service.ErrDeleteQueueFull, "1"},
+		{service.ErrNotFound, ""},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		writeServiceError(w, tt.err)
+		assert.Equal(t, tt.want, w.Header().Get("Retry-After"), tt.err.Error())
+	}
+}
+
+func TestRedirectHandler_NotFound(t *testing.T) {
+	h := setupTestHandler()
+	req := httptest.NewRequest(http.MethodGet, "/missing", nil)
+	chiCtx := chi.NewRouteContext()
+	chiCtx.URLParams.Add("id", "missing")
+	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, chiCtx))
+	w := httptest.NewRecorder()
+	h.RedirectHandler(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestDeleteJobFlow(t *testing.T) {
+	h := setupTestHandler()
+	req := httptest.NewRequest(http.MethodDelete, "/api/user/urls", strings.NewReader(`["abc"]`))
+	req = req.WithContext(middlewares.ContextWithUserID(req.Context(), "user1"))
+	w := httptest.NewRecorder()
+	h.BatchDeleteUserURLsHandler(w, req)
+	assert.Equal(t, http.StatusAccepted, w.Code)
+
+	var accepted model.DeleteAcceptedResponse
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&accepted))
+	assert.NotEmpty(t, accepted.JobID)
+	assert.Equal(t, "/api/user/urls/delete-jobs/"+accepted.JobID, w.Header().Get("Location"))
+
+	assert.NoError(t, h.URLService.Shutdown(context.Background()))
+
+	getJob := func(userID string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/user/urls/delete-jobs/"+accepted.JobID, nil)
+		chiCtx := chi.NewRouteContext()
+		chiCtx.URLParams.Add("id", accepted.JobID)
+		ctx := middlewares.ContextWithUserID(req.Context(), userID)
+		req = req.WithContext(context.WithValue(ctx, chi.RouteCtxKey, chiCtx))
+		w := httptest.NewRecorder()
+		h.GetDeleteJobHandler(w, req)
+		return w
+	}
+
+	w = getJob("user1")
+	assert.Equal(t, http.StatusOK, w.Code)
+	var job model.DeleteJobResponse
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&job))
+	assert.Equal(t, "completed", job.Status)
+	assert.Equal(t, 1, job.Total)
+	assert.Equal(t, 1, job.Completed)
+
+	assert.Equal(t, http.StatusNotFound, getJob("user2").Code)
+}
+
+func TestUserURLsOwnedByCookieUser(t *testing.T) {
+	h := setupTestHandler()
+	r := chi.NewRouter()
+	r.Use(middlewares.AuthMiddleware([]byte("secret"), middlewares.CookieOptions{}))
+	r.Post("/", h.ShortenURLHandler)
+	r.Get("/api/user/urls", h.GetUserURLsHandler)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/", strings.NewReader("https://example.com/owned")))
+	assert.Equal(t, http.StatusCreated, w.Code)
+	cookies := w.Result().Cookies()
+	assert.Len(t, cookies, 1)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/user/urls", nil)
+	req.AddCookie(cookies[0])
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var urls []model.UserURLsResponse
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&urls))
+	assert.Len(t, urls, 1)
+	assert.Equal(t, "https://example.com/owned", urls[0].OriginalURL)
+
+	// Another client gets a new user ID and sees none of the links.
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/user/urls", nil))
+	assert.Equal(t, http.StatusNoContent, w.Code)
+}
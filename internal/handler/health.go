@@ -0,0 +1,59 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/Aleksey170999/go-shortener/internal/audit"
+	"github.com/Aleksey170999/go-shortener/internal/model"
+)
+
+// Health statuses reported by HealthHandler.
+const (
+	// HealthOK means every component works
+	HealthOK = "ok"
+	// HealthDegraded means the service works but a component is failing
+	HealthDegraded = "degraded"
+)
+
+// AuditStatsProvider reports the delivery statistics of the audit writers;
+// it is implemented by audit.AuditManager.
+type AuditStatsProvider interface {
+	Stats() []audit.WriterStats
+}
+
+// HealthHandler provides the endpoint reporting the health of the service's components.
+type HealthHandler struct {
+	Audit AuditStatsProvider
+}
+
+// NewHealthHandler creates a new instance of HealthHandler.
+//
+// Parameters:
+//   - auditStats: The source of the audit writers' statistics
+//
+// Returns:
+//   - *HealthHandler: A new HealthHandler instance
+func NewHealthHandler(auditStats AuditStatsProvider) *HealthHandler {
+	return &HealthHandler{Audit: auditStats}
+}
+
+// GetHandler reports the health of the audit writers. The status is
+// "degraded" if the latest delivery of any writer failed; audit failures do
+// not make the service unavailable, so the response is 200 OK either way.
+//
+// Request:
+//   - Method: GET
+//
+// Responses:
+//   - 200 OK with a JSON body {"status": "ok"|"degraded", "audit": [...]}
+func (h *HealthHandler) GetHandler(w http.ResponseWriter, r *http.Request) {
+	resp := model.HealthResponse{Status: HealthOK, Audit: h.Audit.Stats()}
+	for _, s := range resp.Audit {
+		if !s.Healthy() {
+			resp.Status = HealthDegraded
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,52 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Aleksey170999/go-shortener/internal/audit"
+	"github.com/Aleksey170999/go-shortener/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeAuditStats returns fixed writer statistics.
+type fakeAuditStats []audit.WriterStats
+
+func (f fakeAuditStats) Stats() []audit.WriterStats {
+	return f
+}
+
+func TestHealthHandler_GetHandler(t *testing.T) {
+	now := time.Now()
+	healthy := audit.WriterStats{Writer: "FileAudit", Delivered: 3, LastSuccessAt: now}
+	recovered := audit.WriterStats{Writer: "RemoteAudit", Delivered: 2, Failed: 1,
+		LastError: "timeout", LastErrorAt: now.Add(-time.Minute), LastSuccessAt: now}
+	failing := audit.WriterStats{Writer: "NATSAudit", Failed: 1,
+		LastError: "no responders", LastErrorAt: now}
+
+	tests := []struct {
+		name   string
+		stats  fakeAuditStats
+		status string
+	}{
+		{name: "no writers", stats: nil, status: HealthOK},
+		{name: "recovered writer", stats: fakeAuditStats{healthy, recovered}, status: HealthOK},
+		{name: "failing writer", stats: fakeAuditStats{healthy, failing}, status: HealthDegraded},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			NewHealthHandler(tt.stats).GetHandler(w, httptest.NewRequest(http.MethodGet, "/health", nil))
+
+			require.Equal(t, http.StatusOK, w.Code)
+			var resp model.HealthResponse
+			require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+			assert.Equal(t, tt.status, resp.Status)
+			assert.Len(t, resp.Audit, len(tt.stats))
+		})
+	}
+}
@@ -0,0 +1,46 @@
+package handler
+
+import (
+	"net"
+	"net/http"
+)
+
+// HTTPSRedirectHandler redirects plain HTTP requests to the same URL on HTTPS.
+type HTTPSRedirectHandler struct {
+	Port string // HTTPS port, omitted from the location if it is the default 443
+}
+
+// NewHTTPSRedirectHandler creates a new instance of HTTPSRedirectHandler.
+//
+// Parameters:
+//   - port: The port HTTPS is served on
+//
+// Returns:
+//   - *HTTPSRedirectHandler: A new HTTPSRedirectHandler instance
+func NewHTTPSRedirectHandler(port string) *HTTPSRedirectHandler {
+	return &HTTPSRedirectHandler{Port: port}
+}
+
+// ServeHTTP redirects the request to HTTPS on the same host. GET and HEAD
+// requests get 301 Moved Permanently; other methods get 308 Permanent
+// Redirect, so that clients repeat them with the same body instead of
+// turning them into a GET.
+//
+// Responses:
+//   - 301 Moved Permanently or 308 Permanent Redirect with a Location header
+func (h *HTTPSRedirectHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	host := r.Host
+	if hostname, _, err := net.SplitHostPort(host); err == nil {
+		host = hostname
+	}
+	if h.Port != "" && h.Port != "443" {
+		host = net.JoinHostPort(host, h.Port)
+	}
+
+	target := "https://" + host + r.URL.RequestURI()
+	code := http.StatusPermanentRedirect
+	if r.Method == http.MethodGet || r.Method == http.MethodHead {
+		code = http.StatusMovedPermanently
+	}
+	http.Redirect(w, r, target, code)
+}
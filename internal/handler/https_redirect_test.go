@@ -0,0 +1,62 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHTTPSRedirectHandler_ServeHTTP(t *testing.T) {
+	tests := []struct {
+		name     string
+		port     string
+		method   string
+		target   string
+		status   int
+		location string
+	}{
+		{
+			name:     "default port",
+			port:     "443",
+			method:   http.MethodGet,
+			target:   "http://sho.rt/abc?utm=1",
+			status:   http.StatusMovedPermanently,
+			location: "https://sho.rt/abc?utm=1",
+		},
+		{
+			name:     "custom port replaces the http port",
+			port:     "8443",
+			method:   http.MethodGet,
+			target:   "http://sho.rt:8080/abc",
+			status:   http.StatusMovedPermanently,
+			location: "https://sho.rt:8443/abc",
+		},
+		{
+			name:     "ipv6 host",
+			port:     "8443",
+			method:   http.MethodHead,
+			target:   "http://[::1]:8080/",
+			status:   http.StatusMovedPermanently,
+			location: "https://[::1]:8443/",
+		},
+		{
+			name:     "post keeps its method",
+			port:     "443",
+			method:   http.MethodPost,
+			target:   "http://sho.rt/api/shorten",
+			status:   http.StatusPermanentRedirect,
+			location: "https://sho.rt/api/shorten",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.target, nil)
+			w := httptest.NewRecorder()
+			NewHTTPSRedirectHandler(tt.port).ServeHTTP(w, req)
+			assert.Equal(t, tt.status, w.Code)
+			assert.Equal(t, tt.location, w.Header().Get("Location"))
+		})
+	}
+}
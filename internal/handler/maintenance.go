@@ -0,0 +1,62 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/Aleksey170999/go-shortener/internal/middlewares"
+	"github.com/Aleksey170999/go-shortener/internal/model"
+)
+
+// MaintenanceHandler provides the admin endpoints that inspect and toggle
+// maintenance mode.
+type MaintenanceHandler struct {
+	Maintenance *middlewares.Maintenance
+}
+
+// NewMaintenanceHandler creates a new instance of MaintenanceHandler.
+//
+// Parameters:
+//   - maintenance: The maintenance mode switch guarding write routes
+//
+// Returns:
+//   - *MaintenanceHandler: A new MaintenanceHandler instance
+func NewMaintenanceHandler(maintenance *middlewares.Maintenance) *MaintenanceHandler {
+	return &MaintenanceHandler{Maintenance: maintenance}
+}
+
+// GetHandler reports whether maintenance mode is on.
+//
+// Request:
+//   - Method: GET
+//
+// Responses:
+//   - 200 OK with a JSON body {"enabled": bool}
+func (h *MaintenanceHandler) GetHandler(w http.ResponseWriter, r *http.Request) {
+	h.writeStatus(w)
+}
+
+// SetHandler turns maintenance mode on or off.
+//
+// Request:
+//   - Method: PUT
+//   - Body: JSON object {"enabled": bool}
+//
+// Responses:
+//   - 200 OK with the new state
+//   - 400 Bad Request if the body is invalid
+func (h *MaintenanceHandler) SetHandler(w http.ResponseWriter, r *http.Request) {
+	var req model.MaintenanceStatus
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httpError(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	h.Maintenance.Set(req.Enabled)
+	h.writeStatus(w)
+}
+
+// writeStatus writes the current maintenance state as JSON.
+func (h *MaintenanceHandler) writeStatus(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(model.MaintenanceStatus{Enabled: h.Maintenance.Enabled()})
+}
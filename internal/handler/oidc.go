@@ -0,0 +1,123 @@
+package handler
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"net/http"
+	"time"
+
+	"github.com/Aleksey170999/go-shortener/internal/middlewares"
+	"github.com/Aleksey170999/go-shortener/internal/oidc"
+	"go.uber.org/zap"
+)
+
+// Login state cookie settings.
+const (
+	// oidcStateCookieName is the name of the cookie holding the login state
+	oidcStateCookieName = "oidc_state"
+	// oidcStateTTL bounds how long a started login may take to complete
+	oidcStateTTL = 10 * time.Minute
+	// oidcStateSize is the number of random bytes in the login state
+	oidcStateSize = 16
+)
+
+// OIDCHandler provides the HTTP handlers of the OpenID Connect login flow.
+type OIDCHandler struct {
+	Provider *oidc.Provider
+	Secret   []byte                    // Key used to sign the user ID cookie
+	Cookie   middlewares.CookieOptions // Attributes of the user ID cookie
+	Logger   *zap.Logger
+}
+
+// NewOIDCHandler creates a new instance of OIDCHandler.
+//
+// Parameters:
+//   - provider: The discovered identity provider
+//   - secret: The key used to sign user ID cookies, see middlewares.AuthMiddleware
+//   - cookie: The attributes of user ID cookies; Secure also applies to the login state cookie
+//   - logger: Logger for login failures
+//
+// Returns:
+//   - *OIDCHandler: A new OIDCHandler instance
+func NewOIDCHandler(provider *oidc.Provider, secret []byte, cookie middlewares.CookieOptions, logger *zap.Logger) *OIDCHandler {
+	return &OIDCHandler{
+		Provider: provider,
+		Secret:   secret,
+		Cookie:   cookie,
+		Logger:   logger,
+	}
+}
+
+// LoginHandler starts the login flow by redirecting the user to the identity provider.
+//
+// Request:
+//   - Method: GET
+//
+// Responses:
+//   - 302 Found: Redirect to the provider's authorization endpoint
+//   - 500 Internal Server Error: If the login state cannot be generated
+func (h *OIDCHandler) LoginHandler(w http.ResponseWriter, r *http.Request) {
+	buf := make([]byte, oidcStateSize)
+	if _, err := rand.Read(buf); err != nil {
+		httpError(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	state := base64.RawURLEncoding.EncodeToString(buf)
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcStateCookieName,
+		Value:    state,
+		Path:     "/",
+		MaxAge:   int(oidcStateTTL.Seconds()),
+		Secure:   h.Cookie.Secure,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.Redirect(w, r, h.Provider.AuthCodeURL(state), http.StatusFound)
+}
+
+// CallbackHandler completes the login flow. It checks the state against the
+// cookie set by LoginHandler, exchanges the authorization code for the user's
+// identity and authenticates subsequent requests as the internal user mapped
+// to that identity.
+//
+// Request:
+//   - Method: GET
+//   - Query: code and state issued by the provider
+//
+// Responses:
+//   - 302 Found: Redirect to "/" after a successful login
+//   - 400 Bad Request: If the state does not match or the provider reported an error
+//   - 401 Unauthorized: If the code could not be exchanged for an identity
+func (h *OIDCHandler) CallbackHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	stateCookie, err := r.Cookie(oidcStateCookieName)
+	if err != nil || stateCookie.Value == "" ||
+		subtle.ConstantTimeCompare([]byte(stateCookie.Value), []byte(query.Get("state"))) != 1 {
+		httpError(w, "invalid login state", http.StatusBadRequest)
+		return
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcStateCookieName,
+		Path:     "/",
+		MaxAge:   -1,
+		Secure:   h.Cookie.Secure,
+		HttpOnly: true,
+	})
+
+	if errCode := query.Get("error"); errCode != "" {
+		httpError(w, "login failed: "+errCode, http.StatusBadRequest)
+		return
+	}
+
+	identity, err := h.Provider.Exchange(r.Context(), query.Get("code"))
+	if err != nil {
+		middlewares.LoggerWithRequestID(r.Context(), h.Logger).Warn("oidc login failed", zap.Error(err))
+		httpError(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		return
+	}
+
+	middlewares.SetUserIDCookie(w, h.Secret, identity.UserID(), h.Cookie)
+	http.Redirect(w, r, "/", http.StatusFound)
+}
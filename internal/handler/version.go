@@ -0,0 +1,36 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/Aleksey170999/go-shortener/internal/model"
+)
+
+// VersionHandler provides the endpoint reporting which binary is running.
+type VersionHandler struct {
+	Info model.BuildInfo
+}
+
+// NewVersionHandler creates a new instance of VersionHandler.
+//
+// Parameters:
+//   - info: The build metadata of the running binary
+//
+// Returns:
+//   - *VersionHandler: A new VersionHandler instance
+func NewVersionHandler(info model.BuildInfo) *VersionHandler {
+	return &VersionHandler{Info: info}
+}
+
+// GetHandler reports the build metadata of the running binary.
+//
+// Request:
+//   - Method: GET
+//
+// Responses:
+//   - 200 OK with a JSON body {"version": "...", "date": "...", "commit": "..."}
+func (h *VersionHandler) GetHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.Info)
+}
@@ -0,0 +1,24 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Aleksey170999/go-shortener/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVersionHandler_GetHandler(t *testing.T) {
+	info := model.BuildInfo{Version: "v1.2.0", Date: "2025-11-20", Commit: "N/A"}
+	w := httptest.NewRecorder()
+	NewVersionHandler(info).GetHandler(w, httptest.NewRequest(http.MethodGet, "/version", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	var got model.BuildInfo
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&got))
+	assert.Equal(t, info, got)
+}
@@ -0,0 +1,168 @@
+// Package jobs runs periodic background work, such as purging expired links
+// or flushing buffered statistics, next to the HTTP servers.
+//
+// Every Job runs in its own goroutine on its own Schedule, so a slow job
+// delays only its own next run and runs of the same job never overlap. The
+// Runner is started and stopped together with the servers; stopping it
+// cancels the running jobs and gives those with RunOnStop a final run, e.g.
+// to flush what they buffered.
+package jobs
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Schedule decides when a job runs.
+type Schedule interface {
+	// Next returns when the job runs next after a run that ended at t.
+	Next(t time.Time) time.Time
+}
+
+// Every schedules a job at a fixed interval between the end of a run and
+// the start of the next one.
+type Every time.Duration
+
+// Next implements Schedule.
+func (e Every) Next(t time.Time) time.Time {
+	return t.Add(time.Duration(e))
+}
+
+// Job is a named unit of periodic work.
+type Job struct {
+	Name     string                          // Identifies the job in logs
+	Schedule Schedule                        // When the job runs; the first run is one period after Start
+	Run      func(ctx context.Context) error // The work; ctx is canceled when the Runner stops
+	// RunOnStop makes the Runner run the job once more when it stops, with
+	// a context that is not canceled by the stop.
+	RunOnStop bool
+}
+
+// Option configures a Runner.
+type Option func(*Runner)
+
+// WithErrorHandler sets a function called with the job name and the error
+// whenever a run fails, in addition to logging it, e.g. to report it. It
+// runs on the job's goroutine, so it must be safe for concurrent use and
+// should not block.
+func WithErrorHandler(fn func(job string, err error)) Option {
+	return func(r *Runner) {
+		r.onError = fn
+	}
+}
+
+// Runner runs jobs on their schedules. It is safe for concurrent use.
+type Runner struct {
+	logger  *zap.Logger
+	onError func(job string, err error) // Optional, see WithErrorHandler
+
+	mu     sync.Mutex
+	jobs   []Job
+	ctx    context.Context    // Context of the running jobs, nil before Start
+	cancel context.CancelFunc // Cancels ctx
+	wg     sync.WaitGroup     // Tracks the job goroutines
+}
+
+// NewRunner creates a Runner that logs failed runs to logger.
+func NewRunner(logger *zap.Logger, opts ...Option) *Runner {
+	r := &Runner{logger: logger}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// Add registers jobs. Jobs added after Start are started right away.
+func (r *Runner) Add(jobs ...Job) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.jobs = append(r.jobs, jobs...)
+	if r.ctx != nil {
+		r.wg.Add(len(jobs))
+		for _, job := range jobs {
+			go r.loop(r.ctx, job)
+		}
+	}
+}
+
+// Start starts running the registered jobs until ctx is done or Stop is
+// called. Starting a Runner more than once has no effect.
+func (r *Runner) Start(ctx context.Context) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.ctx != nil {
+		return
+	}
+	r.ctx, r.cancel = context.WithCancel(ctx)
+	r.wg.Add(len(r.jobs))
+	for _, job := range r.jobs {
+		go r.loop(r.ctx, job)
+	}
+}
+
+// Stop cancels the running jobs, runs the jobs with RunOnStop a last time
+// and waits for all of them to return. It returns ctx.Err() if ctx is done
+// first; the jobs then finish in the background. Calling Stop more than
+// once, or before Start, is safe.
+func (r *Runner) Stop(ctx context.Context) error {
+	r.mu.Lock()
+	if r.cancel != nil {
+		r.cancel()
+	}
+	r.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		r.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// loop runs job on its schedule until ctx is done.
+func (r *Runner) loop(ctx context.Context, job Job) {
+	defer r.wg.Done()
+	timer := time.NewTimer(r.until(job))
+	defer timer.Stop()
+	for {
+		select {
+		case <-timer.C:
+			r.run(ctx, job)
+			timer.Reset(r.until(job))
+		case <-ctx.Done():
+			if job.RunOnStop {
+				r.run(context.WithoutCancel(ctx), job)
+			}
+			return
+		}
+	}
+}
+
+// until returns how long to wait for the next run of job.
+func (r *Runner) until(job Job) time.Duration {
+	now := time.Now()
+	return job.Schedule.Next(now).Sub(now)
+}
+
+// run runs job once and logs its outcome.
+func (r *Runner) run(ctx context.Context, job Job) {
+	start := time.Now()
+	err := job.Run(ctx)
+	duration := time.Since(start)
+	if err != nil {
+		r.logger.Error("job failed", zap.String("job", job.Name), zap.Duration("duration", duration), zap.Error(err))
+		if r.onError != nil {
+			r.onError(job.Name, err)
+		}
+		return
+	}
+	r.logger.Debug("job completed", zap.String("job", job.Name), zap.Duration("duration", duration))
+}
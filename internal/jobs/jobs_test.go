@@ -0,0 +1,123 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestEvery(t *testing.T) {
+	now := time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)
+	assert.Equal(t, now.Add(time.Minute), Every(time.Minute).Next(now))
+}
+
+func TestRunner(t *testing.T) {
+	ran := make(chan struct{}, 1)
+	failed := make(chan struct{}, 1)
+	signal := func(ch chan struct{}) {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+	reported := make(chan string, 1)
+	r := NewRunner(zap.NewNop(), WithErrorHandler(func(job string, err error) {
+		assert.EqualError(t, err, "boom")
+		select {
+		case reported <- job:
+		default:
+		}
+	}))
+	r.Add(
+		Job{Name: "ok", Schedule: Every(time.Millisecond), Run: func(context.Context) error {
+			signal(ran)
+			return nil
+		}},
+		Job{Name: "failing", Schedule: Every(time.Millisecond), Run: func(context.Context) error {
+			signal(failed)
+			return errors.New("boom")
+		}},
+	)
+	r.Start(context.Background())
+	r.Start(context.Background())
+
+	for _, ch := range []chan struct{}{ran, failed, ran, failed} {
+		select {
+		case <-ch:
+		case <-time.After(time.Second):
+			t.Fatal("job did not run")
+		}
+	}
+	require.NoError(t, r.Stop(context.Background()))
+	require.NoError(t, r.Stop(context.Background()))
+	assert.Equal(t, "failing", <-reported)
+}
+
+func TestRunner_Stop(t *testing.T) {
+	started := make(chan struct{})
+	var canceled, final bool
+	r := NewRunner(zap.NewNop())
+	r.Add(
+		Job{Name: "long", Schedule: Every(time.Millisecond), Run: func(ctx context.Context) error {
+			close(started)
+			<-ctx.Done()
+			canceled = true
+			return ctx.Err()
+		}},
+		Job{Name: "flush", Schedule: Every(time.Hour), RunOnStop: true, Run: func(ctx context.Context) error {
+			final = ctx.Err() == nil
+			return nil
+		}},
+	)
+	r.Start(context.Background())
+	<-started
+
+	require.NoError(t, r.Stop(context.Background()))
+	assert.True(t, canceled, "the running job is canceled")
+	assert.True(t, final, "RunOnStop jobs run once more with a live context")
+}
+
+func TestRunner_Stop_Timeout(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	r := NewRunner(zap.NewNop())
+	r.Add(Job{Name: "stuck", Schedule: Every(time.Hour), RunOnStop: true, Run: func(context.Context) error {
+		<-release
+		return nil
+	}})
+	r.Start(context.Background())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, r.Stop(ctx), context.DeadlineExceeded)
+}
+
+func TestRunner_StopBeforeStart(t *testing.T) {
+	r := NewRunner(zap.NewNop())
+	r.Add(Job{Name: "never", Schedule: Every(time.Millisecond), Run: func(context.Context) error {
+		t.Error("job ran without Start")
+		return nil
+	}})
+	require.NoError(t, r.Stop(context.Background()))
+}
+
+func TestRunner_AddAfterStart(t *testing.T) {
+	ran := make(chan struct{})
+	r := NewRunner(zap.NewNop())
+	r.Start(context.Background())
+	r.Add(Job{Name: "late", Schedule: Every(time.Hour), RunOnStop: true, Run: func(context.Context) error {
+		close(ran)
+		return nil
+	}})
+	require.NoError(t, r.Stop(context.Background()))
+	select {
+	case <-ran:
+	default:
+		t.Fatal("job added after Start was not started")
+	}
+}
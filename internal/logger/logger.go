@@ -8,14 +8,18 @@ import (
 	"go.uber.org/zap/zapcore"
 )
 
-// Initialize creates and configures a new zap.Logger instance with the specified log level.
-// The logger is configured with JSON formatting, ISO8601 timestamps, and outputs to stdout/stderr.
+// New creates and configures a new zap.Logger instance writing to the given outputs.
+// The logger is configured with JSON formatting and ISO8601 timestamps.
 //
 // Parameters:
-//   - level: The minimum log level to output (e.g., zapcore.InfoLevel, zapcore.DebugLevel)
+//   - level: The minimum log level to output; it is shared with the logger, so
+//     changing it later, e.g. through level.ServeHTTP, takes effect immediately
+//   - outputPaths: Where log entries are written: "stdout", "stderr" or file
+//     paths, which are created if needed and appended to; stdout if empty
 //
 // Returns:
 //   - *zap.Logger: A configured logger instance
+//   - error: If an output file cannot be opened
 //
 // The logger includes the following fields by default in each log entry:
 //   - ts: ISO8601 formatted timestamp
@@ -23,11 +27,14 @@ import (
 //   - caller: Source file and line number of the log call
 //   - msg: The actual log message
 //
-// Note: The function will panic if the logger cannot be created, as logging is considered
-// a critical part of the application's operation.
-func Initialize(level zapcore.Level) *zap.Logger {
+// Callers should Sync the logger before exiting so that buffered entries of
+// file outputs are flushed.
+func New(level zap.AtomicLevel, outputPaths ...string) (*zap.Logger, error) {
+	if len(outputPaths) == 0 {
+		outputPaths = []string{"stdout"}
+	}
 	config := zap.Config{
-		Level:       zap.NewAtomicLevelAt(level),
+		Level:       level,
 		Development: false,
 		Encoding:    "json",
 		EncoderConfig: zapcore.EncoderConfig{
@@ -43,14 +50,8 @@ func Initialize(level zapcore.Level) *zap.Logger {
 			EncodeDuration: zapcore.SecondsDurationEncoder,
 			EncodeCaller:   zapcore.ShortCallerEncoder,
 		},
-		OutputPaths:      []string{"stdout"},
+		OutputPaths:      outputPaths,
 		ErrorOutputPaths: []string{"stderr"},
 	}
-
-	logger, err := config.Build()
-	if err != nil {
-		panic(err)
-	}
-	defer logger.Sync()
-	return logger
+	return config.Build()
 }
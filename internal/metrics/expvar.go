@@ -0,0 +1,87 @@
+package metrics
+
+import (
+	"context"
+	"expvar"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Aleksey170999/go-shortener/internal/service"
+)
+
+// Vars is an expvar.Map of counters for deployments that scrape /debug/vars
+// instead of Prometheus:
+//   - requests: HTTP requests, counted by Middleware
+//   - shortens, resolves, deletes: completed URLService operations, counted
+//     as service.Hooks
+//   - errors: operations whose result is ResultError
+//   - runtime: the service.RuntimeStats (queue depth and resolve cache hits),
+//     once TrackService is called
+//
+// A Vars is not published, since expvar names are global to the process and
+// several servers may run in one, e.g. in tests; Handler serves it next to
+// the published variables.
+type Vars struct {
+	expvar.Map
+}
+
+var _ service.Hooks = (*Vars)(nil)
+
+// NewVars creates Vars with all counters set to zero.
+func NewVars() *Vars {
+	v := &Vars{}
+	for _, name := range []string{"requests", "shortens", "resolves", "deletes", "errors"} {
+		v.Set(name, new(expvar.Int))
+	}
+	return v
+}
+
+// Handler serves the published expvar variables, like expvar.Handler, with
+// v added under name.
+func (v *Vars) Handler(name string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		fmt.Fprintf(w, "{\n%q: %s", name, v.String())
+		expvar.Do(func(kv expvar.KeyValue) {
+			fmt.Fprintf(w, ",\n%q: %s", kv.Key, kv.Value)
+		})
+		fmt.Fprintf(w, "\n}\n")
+	})
+}
+
+// Middleware counts every request passing through it.
+func (v *Vars) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		v.Add("requests", 1)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// TrackService publishes the RuntimeStats of s under "runtime"; they are
+// read on every request to /debug/vars.
+func (v *Vars) TrackService(s *service.URLService) {
+	v.Set("runtime", expvar.Func(func() any { return s.RuntimeStats() }))
+}
+
+// OnShorten implements service.Hooks.
+func (v *Vars) OnShorten(_ context.Context, _ time.Duration, err error) {
+	v.count("shortens", err)
+}
+
+// OnResolve implements service.Hooks.
+func (v *Vars) OnResolve(_ context.Context, _ time.Duration, err error) {
+	v.count("resolves", err)
+}
+
+// OnDelete implements service.Hooks.
+func (v *Vars) OnDelete(_ context.Context, _ time.Duration, err error) {
+	v.count("deletes", err)
+}
+
+func (v *Vars) count(name string, err error) {
+	v.Add(name, 1)
+	if Result(err) == ResultError {
+		v.Add("errors", 1)
+	}
+}
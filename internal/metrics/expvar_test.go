@@ -0,0 +1,56 @@
+package metrics
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Aleksey170999/go-shortener/internal/repository"
+	"github.com/Aleksey170999/go-shortener/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVars(t *testing.T) {
+	vars := NewVars()
+	s := service.NewURLService(repository.NewMemoryURLRepository(),
+		service.WithHooks(vars), service.WithResolveCache(10, 0), service.WithDeleteQueueSize(8))
+	vars.TrackService(s)
+	ctx := context.Background()
+
+	url, err := s.Shorten(ctx, "https://example.com", "", "user1")
+	require.NoError(t, err)
+	for range 2 {
+		_, err = s.Resolve(ctx, url.Short)
+		require.NoError(t, err)
+	}
+	_, err = s.Resolve(ctx, "missing")
+	require.Error(t, err)
+	require.NoError(t, s.Shutdown(ctx))
+
+	h := vars.Middleware(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	var got struct {
+		Requests int64                `json:"requests"`
+		Shortens int64                `json:"shortens"`
+		Resolves int64                `json:"resolves"`
+		Deletes  int64                `json:"deletes"`
+		Errors   int64                `json:"errors"`
+		Runtime  service.RuntimeStats `json:"runtime"`
+	}
+	w := httptest.NewRecorder()
+	vars.Handler("shortener").ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/debug/vars", nil))
+	var all map[string]json.RawMessage
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &all))
+	assert.Contains(t, all, "memstats", "published variables are served as well")
+	require.NoError(t, json.Unmarshal(all["shortener"], &got))
+	assert.Equal(t, int64(1), got.Requests)
+	assert.Equal(t, int64(1), got.Shortens)
+	assert.Equal(t, int64(3), got.Resolves)
+	assert.Equal(t, int64(0), got.Deletes)
+	assert.Equal(t, int64(0), got.Errors, "a missing URL is not an error")
+	assert.Equal(t, service.RuntimeStats{DeleteQueueCapacity: 8, CacheEntries: 1, CacheHits: 1, CacheMisses: 2}, got.Runtime)
+}
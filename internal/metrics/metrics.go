@@ -0,0 +1,106 @@
+// Package metrics provides the Prometheus registry of the application and
+// the instrumentation of URLService operations and repository calls.
+//
+// All collectors are registered with the registry created by NewRegistry in
+// main, rather than the global prometheus.DefaultRegisterer, so that the
+// exposed series are exactly the ones wired there and tests can use
+// registries of their own.
+package metrics
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/Aleksey170999/go-shortener/internal/model"
+	"github.com/Aleksey170999/go-shortener/internal/repository"
+	"github.com/Aleksey170999/go-shortener/internal/service"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+)
+
+// Results of an operation, used as the "result" label.
+const (
+	ResultSuccess  = "success"   // The operation succeeded
+	ResultNotFound = "not_found" // The URL does not exist, was deleted or expired
+	ResultConflict = "conflict"  // The URL or short code already exists
+	ResultError    = "error"     // Any other failure
+)
+
+// NewRegistry returns a registry with the Go runtime and process collectors.
+func NewRegistry() *prometheus.Registry {
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	return reg
+}
+
+// Hooks implements service.Hooks by recording Prometheus metrics for every
+// completed URLService operation:
+//   - shortener_operations_total: counter labelled by operation and result
+//   - shortener_operation_duration_seconds: histogram labelled by operation
+//
+// The operations are "shorten", "resolve" and "delete".
+type Hooks struct {
+	operations *prometheus.CounterVec
+	duration   *prometheus.HistogramVec
+}
+
+var _ service.Hooks = (*Hooks)(nil)
+
+// NewHooks creates Hooks whose collectors are registered with reg; it panics
+// if they are already registered there.
+func NewHooks(reg prometheus.Registerer) *Hooks {
+	h := &Hooks{
+		operations: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "shortener_operations_total",
+			Help: "Number of URL service operations by operation and result.",
+		}, []string{"operation", "result"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "shortener_operation_duration_seconds",
+			Help:    "URL service operation latency by operation.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"operation"}),
+	}
+	reg.MustRegister(h.operations, h.duration)
+	return h
+}
+
+// OnShorten implements service.Hooks.
+func (h *Hooks) OnShorten(_ context.Context, d time.Duration, err error) {
+	h.observe("shorten", d, err)
+}
+
+// OnResolve implements service.Hooks.
+func (h *Hooks) OnResolve(_ context.Context, d time.Duration, err error) {
+	h.observe("resolve", d, err)
+}
+
+// OnDelete implements service.Hooks.
+func (h *Hooks) OnDelete(_ context.Context, d time.Duration, err error) {
+	h.observe("delete", d, err)
+}
+
+func (h *Hooks) observe(operation string, d time.Duration, err error) {
+	h.operations.WithLabelValues(operation, Result(err)).Inc()
+	h.duration.WithLabelValues(operation).Observe(d.Seconds())
+}
+
+// Result returns the "result" label of an operation that returned err.
+// Missing and duplicate URLs are expected outcomes and are told apart from
+// failures, so that error rates can be alerted on.
+func Result(err error) string {
+	switch {
+	case err == nil:
+		return ResultSuccess
+	case errors.Is(err, service.ErrNotFound), errors.Is(err, service.ErrDeleted), errors.Is(err, service.ErrExpired),
+		errors.Is(err, repository.ErrNotFound), errors.Is(err, model.ErrURLNotFound), errors.Is(err, model.ErrURLDeleted):
+		return ResultNotFound
+	case errors.Is(err, model.ErrURLAlreadyExists), errors.Is(err, model.ErrShortURLConflict), errors.Is(err, service.ErrAliasTaken):
+		return ResultConflict
+	default:
+		return ResultError
+	}
+}
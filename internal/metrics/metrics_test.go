@@ -0,0 +1,70 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/Aleksey170999/go-shortener/internal/model"
+	"github.com/Aleksey170999/go-shortener/internal/repository"
+	"github.com/Aleksey170999/go-shortener/internal/service"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResult(t *testing.T) {
+	testCases := []struct {
+		err  error
+		want string
+	}{
+		{nil, ResultSuccess},
+		{service.ErrNotFound, ResultNotFound},
+		{fmt.Errorf("resolve: %w", service.ErrExpired), ResultNotFound},
+		{repository.ErrNotFound, ResultNotFound},
+		{model.ErrURLDeleted, ResultNotFound},
+		{model.ErrURLAlreadyExists, ResultConflict},
+		{service.ErrAliasTaken, ResultConflict},
+		{errors.New("connection refused"), ResultError},
+	}
+	for _, tc := range testCases {
+		assert.Equal(t, tc.want, Result(tc.err), "%v", tc.err)
+	}
+}
+
+func TestHooks(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	repo := InstrumentRepository(repository.NewMemoryURLRepository(), reg)
+	s := service.NewURLService(repo, service.WithHooks(NewHooks(reg)))
+	ctx := context.Background()
+
+	url, err := s.Shorten(ctx, "https://example.com", "", "user1")
+	require.NoError(t, err)
+	_, err = s.Resolve(ctx, url.Short)
+	require.NoError(t, err)
+	_, err = s.Resolve(ctx, "missing")
+	require.Error(t, err)
+	require.NoError(t, s.Shutdown(ctx))
+
+	expected := `
+# HELP shortener_operations_total Number of URL service operations by operation and result.
+# TYPE shortener_operations_total counter
+shortener_operations_total{operation="resolve",result="not_found"} 1
+shortener_operations_total{operation="resolve",result="success"} 1
+shortener_operations_total{operation="shorten",result="success"} 1
+`
+	assert.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(expected), "shortener_operations_total"))
+	assert.Equal(t, 2, testutil.CollectAndCount(reg, "shortener_operation_duration_seconds"))
+
+	expected = `
+# HELP repository_operations_total Number of repository calls by method and result.
+# TYPE repository_operations_total counter
+repository_operations_total{method="GetByShortURL",result="not_found"} 1
+repository_operations_total{method="GetByShortURL",result="success"} 1
+repository_operations_total{method="Save",result="success"} 1
+`
+	assert.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(expected), "repository_operations_total"))
+}
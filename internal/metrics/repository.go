@@ -0,0 +1,111 @@
+package metrics
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/Aleksey170999/go-shortener/internal/model"
+	"github.com/Aleksey170999/go-shortener/internal/repository"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// instrumentedRepository records metrics for the calls of a URLRepository.
+type instrumentedRepository struct {
+	next       repository.URLRepository
+	operations *prometheus.CounterVec
+	duration   *prometheus.HistogramVec
+}
+
+// InstrumentRepository wraps repo so that every call is recorded:
+//   - repository_operations_total: counter labelled by method and result
+//   - repository_operation_duration_seconds: histogram labelled by method
+//
+// The collectors are registered with reg; InstrumentRepository panics if
+// they are already registered there. The wrapper implements io.Closer and
+// closes repo if it does.
+func InstrumentRepository(repo repository.URLRepository, reg prometheus.Registerer) repository.URLRepository {
+	r := &instrumentedRepository{
+		next: repo,
+		operations: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "repository_operations_total",
+			Help: "Number of repository calls by method and result.",
+		}, []string{"method", "result"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "repository_operation_duration_seconds",
+			Help:    "Repository call latency by method.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"method"}),
+	}
+	reg.MustRegister(r.operations, r.duration)
+	return r
+}
+
+func (r *instrumentedRepository) observe(method string, start time.Time, err error) {
+	r.operations.WithLabelValues(method, Result(err)).Inc()
+	r.duration.WithLabelValues(method).Observe(time.Since(start).Seconds())
+}
+
+// Save implements repository.URLRepository.
+func (r *instrumentedRepository) Save(ctx context.Context, url *model.URL) (_ *model.URL, err error) {
+	start := time.Now()
+	defer func() { r.observe("Save", start, err) }()
+	return r.next.Save(ctx, url)
+}
+
+// SaveBatch implements repository.URLRepository.
+func (r *instrumentedRepository) SaveBatch(ctx context.Context, urls []*model.URL) (_ []error, err error) {
+	start := time.Now()
+	defer func() { r.observe("SaveBatch", start, err) }()
+	return r.next.SaveBatch(ctx, urls)
+}
+
+// GetByShortURL implements repository.URLRepository.
+func (r *instrumentedRepository) GetByShortURL(ctx context.Context, shortURL string) (_ *model.URL, err error) {
+	start := time.Now()
+	defer func() { r.observe("GetByShortURL", start, err) }()
+	return r.next.GetByShortURL(ctx, shortURL)
+}
+
+// GetByUserID implements repository.URLRepository.
+func (r *instrumentedRepository) GetByUserID(ctx context.Context, userID string) (_ []model.URL, err error) {
+	start := time.Now()
+	defer func() { r.observe("GetByUserID", start, err) }()
+	return r.next.GetByUserID(ctx, userID)
+}
+
+// BatchDelete implements repository.URLRepository.
+func (r *instrumentedRepository) BatchDelete(ctx context.Context, shortURLs []string, userID string) (err error) {
+	start := time.Now()
+	defer func() { r.observe("BatchDelete", start, err) }()
+	return r.next.BatchDelete(ctx, shortURLs, userID)
+}
+
+// Purge implements repository.URLRepository.
+func (r *instrumentedRepository) Purge(ctx context.Context, expiredBefore, deletedBefore time.Time, dryRun bool) (_ repository.PurgeStats, err error) {
+	start := time.Now()
+	defer func() { r.observe("Purge", start, err) }()
+	return r.next.Purge(ctx, expiredBefore, deletedBefore, dryRun)
+}
+
+// PurgeMatching implements repository.URLRepository.
+func (r *instrumentedRepository) PurgeMatching(ctx context.Context, filter repository.PurgeFilter, dryRun bool) (_ []model.URL, err error) {
+	start := time.Now()
+	defer func() { r.observe("PurgeMatching", start, err) }()
+	return r.next.PurgeMatching(ctx, filter, dryRun)
+}
+
+// AddClickStats implements repository.URLRepository.
+func (r *instrumentedRepository) AddClickStats(ctx context.Context, stats []model.ClickStat) (err error) {
+	start := time.Now()
+	defer func() { r.observe("AddClickStats", start, err) }()
+	return r.next.AddClickStats(ctx, stats)
+}
+
+// Close closes the wrapped repository if it implements io.Closer.
+func (r *instrumentedRepository) Close() error {
+	if closer, ok := r.next.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+// Package middlewares provides HTTP middleware functions for the application.
+// This file implements audit logging of HTTP requests.
+package middlewares
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/Aleksey170999/go-shortener/internal/audit"
+)
+
+// Audit returns a middleware that records an audit event for every request
+// that may change state (any method other than GET, HEAD and OPTIONS) and
+// for every request whose handler called audit.Annotate. The event carries
+// the method, route pattern, user, client IP and response status; the action,
+// affected URL and link count come from the handler's annotation, and the action
+// defaults to "<method> <route>". It must run after AuthMiddleware so that
+// the user is known.
+//
+// Parameters:
+//   - manager: The audit manager events are dispatched to
+//
+// Returns:
+//   - A middleware function that can be used with http.Handler
+func Audit(manager *audit.AuditManager) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			annotation := &audit.Annotation{}
+			r = r.WithContext(audit.ContextWithAnnotation(r.Context(), annotation))
+			responseData := &responseData{}
+			next.ServeHTTP(&loggingResponseWriter{ResponseWriter: w, responseData: responseData}, r)
+
+			switch r.Method {
+			case http.MethodGet, http.MethodHead, http.MethodOptions:
+				if annotation.Action == "" {
+					return
+				}
+			}
+
+			status := responseData.status
+			if status == 0 {
+				status = http.StatusOK
+			}
+			route := routePattern(r)
+			event := audit.AuditEvent{
+				Action:  annotation.Action,
+				URL:     annotation.URL,
+				Count:   annotation.Count,
+				IP:      ClientIP(r),
+				Method:  r.Method,
+				Route:   route,
+				Status:  status,
+				Outcome: audit.OutcomeSuccess,
+			}
+			if event.Action == "" {
+				event.Action = r.Method + " " + route
+			}
+			event.UserID, _ = GetUserID(r)
+			if status >= http.StatusBadRequest {
+				event.Outcome = audit.OutcomeFailure
+			}
+			// Writers outlive the request, so they must not be cancelled with it.
+			manager.Log(context.WithoutCancel(r.Context()), event)
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package middlewares
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Aleksey170999/go-shortener/internal/audit"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// chanAuditWriter sends written events to a channel.
+type chanAuditWriter chan audit.AuditEvent
+
+func (c chanAuditWriter) Write(_ context.Context, e audit.AuditEvent) error {
+	c <- e
+	return nil
+}
+
+func TestAudit(t *testing.T) {
+	events := make(chanAuditWriter, 10)
+	manager := audit.NewAuditManager()
+	manager.RegisterWriter(events)
+
+	r := chi.NewRouter()
+	r.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(ContextWithUserID(r.Context(), "user1")))
+		})
+	})
+	r.Use(Audit(manager))
+	r.Post("/api/shorten", func(w http.ResponseWriter, r *http.Request) {
+		audit.Annotate(r.Context(), "shorten", "https://example.com")
+		w.WriteHeader(http.StatusCreated)
+	})
+	r.Delete("/api/user/urls", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	})
+	r.Post("/api/shorten/batch", func(w http.ResponseWriter, r *http.Request) {
+		audit.AnnotateBatch(r.Context(), "shorten_batch", 3)
+		w.WriteHeader(http.StatusCreated)
+	})
+	r.Get("/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if chi.URLParam(r, "id") == "followed" {
+			audit.Annotate(r.Context(), "follow", "https://example.com")
+		}
+	})
+
+	next := func() audit.AuditEvent {
+		t.Helper()
+		select {
+		case e := <-events:
+			return e
+		case <-time.After(time.Second):
+			require.FailNow(t, "no audit event")
+			return audit.AuditEvent{}
+		}
+	}
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/api/shorten", nil))
+	e := next()
+	assert.Equal(t, "shorten", e.Action)
+	assert.Equal(t, "user1", e.UserID)
+	assert.Equal(t, "https://example.com", e.URL)
+	assert.Equal(t, http.MethodPost, e.Method)
+	assert.Equal(t, "/api/shorten", e.Route)
+	assert.Equal(t, http.StatusCreated, e.Status)
+	assert.Equal(t, audit.OutcomeSuccess, e.Outcome)
+	assert.NotZero(t, e.TimeStamp)
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodDelete, "/api/user/urls", nil))
+	e = next()
+	assert.Equal(t, "DELETE /api/user/urls", e.Action)
+	assert.Equal(t, http.StatusBadRequest, e.Status)
+	assert.Equal(t, audit.OutcomeFailure, e.Outcome)
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/api/shorten/batch", nil))
+	e = next()
+	assert.Equal(t, "shorten_batch", e.Action)
+	assert.Equal(t, 3, e.Count)
+	assert.Empty(t, e.URL)
+
+	// Reads are only audited when the handler annotates them.
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/plain", nil))
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/followed", nil))
+	e = next()
+	assert.Equal(t, "follow", e.Action)
+	assert.Equal(t, "/{id}", e.Route)
+	assert.Empty(t, events)
+}
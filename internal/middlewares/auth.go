@@ -3,7 +3,15 @@
 package middlewares
 
 import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
 )
@@ -12,65 +20,148 @@ import (
 const (
 	// userIDCookieName is the name of the cookie used to store the user's unique identifier
 	userIDCookieName = "user_id"
+	// signatureSeparator separates the user ID from its signature in the cookie value
+	signatureSeparator = "."
 )
 
-// AuthMiddleware is an HTTP middleware that ensures each request has a valid user ID.
-// If the request doesn't have a user ID cookie, it generates a new one.
-// The middleware adds the user ID to the request context for use in handlers.
+// ErrNoUserID is returned by GetUserID when the request carries no user ID.
+var ErrNoUserID = errors.New("no user id in request context")
+
+// CookieOptions holds the configurable attributes of the user ID cookie.
+type CookieOptions struct {
+	Secure   bool          // Only send the cookie over HTTPS
+	SameSite http.SameSite // SameSite attribute; http.SameSiteDefaultMode omits it
+	Domain   string        // Domain attribute; empty for a host-only cookie
+	MaxAge   time.Duration // Cookie lifetime; 0 makes it a session cookie
+}
+
+// ParseSameSite converts a SameSite attribute name ("lax", "strict", "none",
+// case-insensitive) to its http.SameSite value. An empty name yields
+// http.SameSiteDefaultMode, which leaves the attribute out.
+func ParseSameSite(name string) (http.SameSite, error) {
+	switch strings.ToLower(name) {
+	case "":
+		return http.SameSiteDefaultMode, nil
+	case "lax":
+		return http.SameSiteLaxMode, nil
+	case "strict":
+		return http.SameSiteStrictMode, nil
+	case "none":
+		return http.SameSiteNoneMode, nil
+	}
+	return 0, fmt.Errorf("unknown SameSite mode %q", name)
+}
+
+// userIDKey is the context key under which the authenticated user ID is stored.
+type userIDKey struct{}
+
+// AuthMiddleware returns an HTTP middleware that identifies the user by a
+// signed user ID cookie. The cookie value is "<user ID>.<signature>", where the
+// signature is the base64url-encoded HMAC-SHA256 of the user ID under secret,
+// so clients cannot impersonate another user by editing the cookie.
 //
 // The middleware performs the following actions:
-//  1. Checks for an existing user ID cookie
-//  2. If not found, creates a new user ID and sets it as a cookie
-//  3. Continues to the next handler in the chain
+//  1. Verifies the signature of an existing user ID cookie
+//  2. If the cookie is missing or its signature is invalid, creates a new user ID
+//     and sets it as a signed cookie
+//  3. Stores the user ID in the request context (see GetUserID) and continues
+//     to the next handler in the chain
 //
-// The cookie is set with HttpOnly flag for security and is valid for all paths.
-func AuthMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		userIDCookie, err := r.Cookie(userIDCookieName)
-		if err != nil || userIDCookie.Value == "" {
-			setNewUserCookie(w)
-			next.ServeHTTP(w, r)
-			return
-		}
+// The cookie is set with HttpOnly flag for security, is valid for all paths
+// and carries the attributes in opts (see SetUserIDCookie).
+func AuthMiddleware(secret []byte, opts CookieOptions) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			userID := ""
+			if userIDCookie, err := r.Cookie(userIDCookieName); err == nil {
+				userID, _ = verifyUserID(secret, userIDCookie.Value)
+			}
+			if userID == "" {
+				userID = setNewUserCookie(w, secret, opts)
+			}
 
-		next.ServeHTTP(w, r)
-	})
+			next.ServeHTTP(w, r.WithContext(ContextWithUserID(r.Context(), userID)))
+		})
+	}
+}
+
+// ContextWithUserID returns a copy of ctx carrying userID as the authenticated user.
+func ContextWithUserID(ctx context.Context, userID string) context.Context {
+	return context.WithValue(ctx, userIDKey{}, userID)
 }
 
-// GetUserID retrieves the user ID from the request's cookies.
+// GetUserID retrieves the authenticated user ID stored by AuthMiddleware.
 //
 // Parameters:
-//   - r: The HTTP request containing the user ID cookie
+//   - r: The HTTP request that passed through AuthMiddleware
 //
 // Returns:
 //   - string: The user ID if found
-//   - error: An error if the user ID cookie is not present or invalid
+//   - error: ErrNoUserID if the request carries no user ID
 //
 // This function is typically used by handlers that need to identify the current user.
 func GetUserID(r *http.Request) (string, error) {
-	userIDCookie, err := r.Cookie(userIDCookieName)
-	if err != nil {
-		return "", err
+	userID, _ := r.Context().Value(userIDKey{}).(string)
+	if userID == "" {
+		return "", ErrNoUserID
 	}
-	return userIDCookie.Value, nil
+	return userID, nil
 }
 
-// setNewUserCookie generates a new UUID and sets it as a user ID cookie.
-// The cookie is set with the following attributes:
-//   - Name: user_id
-//   - Value: A new UUID v4 string
-//   - Path: "/" (valid for all paths)
-//   - HttpOnly: true (not accessible via JavaScript)
+// signUserID returns the cookie value for userID signed with secret.
+func signUserID(secret []byte, userID string) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(userID))
+	return userID + signatureSeparator + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// verifyUserID checks the signature of a cookie value and returns the user ID it carries.
+func verifyUserID(secret []byte, value string) (string, bool) {
+	i := strings.LastIndex(value, signatureSeparator)
+	if i <= 0 {
+		return "", false
+	}
+	userID := value[:i]
+	if !hmac.Equal([]byte(signUserID(secret, userID)), []byte(value)) {
+		return "", false
+	}
+	return userID, true
+}
+
+// setNewUserCookie generates a new UUID and sets it as a signed user ID cookie
+// (see SetUserIDCookie).
 //
 // Parameters:
 //   - w: The HTTP response writer to set the cookie on
-func setNewUserCookie(w http.ResponseWriter) {
+//   - secret: The key used to sign the user ID
+//   - opts: The cookie attributes
+//
+// Returns:
+//   - string: The new user ID
+func setNewUserCookie(w http.ResponseWriter, secret []byte, opts CookieOptions) string {
 	userID := uuid.New().String()
+	SetUserIDCookie(w, secret, userID, opts)
+	return userID
+}
 
+// SetUserIDCookie sets userID as the signed user ID cookie, so that subsequent
+// requests are authenticated as that user. It is used by login flows that
+// establish the user's identity by other means.
+// The cookie is set with the following attributes:
+//   - Name: user_id
+//   - Value: The user ID followed by its signature
+//   - Path: "/" (valid for all paths)
+//   - HttpOnly: true (not accessible via JavaScript)
+//   - Secure, SameSite, Domain, Max-Age: Taken from opts
+func SetUserIDCookie(w http.ResponseWriter, secret []byte, userID string, opts CookieOptions) {
 	http.SetCookie(w, &http.Cookie{
 		Name:     userIDCookieName,
-		Value:    userID,
+		Value:    signUserID(secret, userID),
 		Path:     "/",
+		Domain:   opts.Domain,
+		MaxAge:   int(opts.MaxAge.Seconds()),
+		Secure:   opts.Secure,
 		HttpOnly: true,
+		SameSite: opts.SameSite,
 	})
 }
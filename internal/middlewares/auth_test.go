@@ -0,0 +1,127 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuthMiddlewareCookieOptions(t *testing.T) {
+	secret := []byte("secret")
+	opts := CookieOptions{
+		Secure:   true,
+		SameSite: http.SameSiteLaxMode,
+		Domain:   "example.com",
+		MaxAge:   24 * time.Hour,
+	}
+	var userID string
+	h := AuthMiddleware(secret, opts)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userID, _ = GetUserID(r)
+	}))
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	cookies := w.Result().Cookies()
+	require.Len(t, cookies, 1)
+	c := cookies[0]
+	assert.Equal(t, userIDCookieName, c.Name)
+	assert.Equal(t, signUserID(secret, userID), c.Value)
+	assert.True(t, c.Secure)
+	assert.True(t, c.HttpOnly)
+	assert.Equal(t, http.SameSiteLaxMode, c.SameSite)
+	assert.Equal(t, "example.com", c.Domain)
+	assert.Equal(t, 86400, c.MaxAge)
+
+	// A valid cookie is accepted without issuing a new one.
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.AddCookie(&http.Cookie{Name: userIDCookieName, Value: c.Value})
+	w = httptest.NewRecorder()
+	prev := userID
+	h.ServeHTTP(w, req)
+	assert.Empty(t, w.Result().Cookies())
+	assert.Equal(t, prev, userID)
+}
+
+func TestVerifyUserID(t *testing.T) {
+	secret := []byte("secret")
+	valid := signUserID(secret, "user1")
+	signature := valid[len("user1"):]
+
+	tests := []struct {
+		name   string
+		value  string
+		wantID string
+		wantOK bool
+	}{
+		{name: "valid", value: valid, wantID: "user1", wantOK: true},
+		{name: "tampered user ID", value: "user2" + signature},
+		{name: "tampered signature", value: valid[:len(valid)-1] + "A"},
+		{name: "unsigned", value: "user1"},
+		{name: "empty signature", value: "user1" + signatureSeparator},
+		{name: "signature only", value: signature},
+		{name: "wrong key", value: signUserID([]byte("other"), "user1")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			userID, ok := verifyUserID(secret, tt.value)
+			assert.Equal(t, tt.wantOK, ok)
+			assert.Equal(t, tt.wantID, userID)
+		})
+	}
+}
+
+func TestAuthMiddlewareInvalidCookie(t *testing.T) {
+	secret := []byte("secret")
+	var userID string
+	h := AuthMiddleware(secret, CookieOptions{})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userID, _ = GetUserID(r)
+	}))
+
+	for _, value := range []string{
+		"user1",
+		signUserID([]byte("other"), "user1"),
+		"user2" + signUserID(secret, "user1")[len("user1"):],
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.AddCookie(&http.Cookie{Name: userIDCookieName, Value: value})
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+
+		// The cookie is replaced by a newly signed one for a new user.
+		cookies := w.Result().Cookies()
+		require.Len(t, cookies, 1, value)
+		assert.NotEqual(t, "user1", userID)
+		assert.NotEqual(t, "user2", userID)
+		assert.Equal(t, signUserID(secret, userID), cookies[0].Value)
+	}
+}
+
+func TestParseSameSite(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    http.SameSite
+		wantErr bool
+	}{
+		{name: "", want: http.SameSiteDefaultMode},
+		{name: "Lax", want: http.SameSiteLaxMode},
+		{name: "strict", want: http.SameSiteStrictMode},
+		{name: "none", want: http.SameSiteNoneMode},
+		{name: "loose", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseSameSite(tt.name)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,37 @@
+// Package middlewares provides HTTP middleware functions for the application.
+// This file implements HTTP Basic authentication for administrative routes.
+package middlewares
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"net/http"
+)
+
+// BasicAuth returns a middleware that requires HTTP Basic credentials
+// matching user and password. Requests without valid credentials get 401
+// Unauthorized with a WWW-Authenticate challenge for realm. Credentials are
+// compared in constant time. An empty user disables the check, so the
+// protection stays optional for deployments behind an auth proxy.
+func BasicAuth(realm, user, password string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if user == "" {
+			return next
+		}
+		wantUser := sha256.Sum256([]byte(user))
+		wantPassword := sha256.Sum256([]byte(password))
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			gotUser, gotPassword, ok := r.BasicAuth()
+			userHash := sha256.Sum256([]byte(gotUser))
+			passwordHash := sha256.Sum256([]byte(gotPassword))
+			userOK := subtle.ConstantTimeCompare(userHash[:], wantUser[:]) == 1
+			passwordOK := subtle.ConstantTimeCompare(passwordHash[:], wantPassword[:]) == 1
+			if !ok || !userOK || !passwordOK {
+				w.Header().Set("WWW-Authenticate", `Basic realm="`+realm+`", charset="UTF-8"`)
+				http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,113 @@
+// Package middlewares provides HTTP middleware functions for the application.
+// This file implements the IP and user blocklist.
+package middlewares
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+
+	"github.com/Aleksey170999/go-shortener/internal/model"
+)
+
+// Blocklist denies access to the routes it guards to blocked client IPs,
+// networks and users. Its contents can be replaced at runtime; it is safe for
+// concurrent use. A new Blocklist is empty and blocks nobody.
+type Blocklist struct {
+	mu       sync.RWMutex
+	list     model.Blocklist     // Entries as configured, returned by List
+	networks []*net.IPNet        // Parsed list.IPs
+	users    map[string]struct{} // Set of list.Users
+}
+
+// NewBlocklist creates an empty blocklist.
+func NewBlocklist() *Blocklist {
+	return &Blocklist{users: make(map[string]struct{})}
+}
+
+// Set replaces the blocklist contents. IPs may be bare addresses or CIDRs;
+// if any of them is invalid the blocklist is left unchanged.
+func (b *Blocklist) Set(list model.Blocklist) error {
+	networks, err := ParseNetworks(list.IPs)
+	if err != nil {
+		return err
+	}
+	users := make(map[string]struct{}, len(list.Users))
+	for _, user := range list.Users {
+		users[user] = struct{}{}
+	}
+	list = model.Blocklist{
+		IPs:   append([]string{}, list.IPs...),
+		Users: append([]string{}, list.Users...),
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.list = list
+	b.networks = networks
+	b.users = users
+	return nil
+}
+
+// List returns a copy of the blocklist contents.
+func (b *Blocklist) List() model.Blocklist {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return model.Blocklist{
+		IPs:   append([]string{}, b.list.IPs...),
+		Users: append([]string{}, b.list.Users...),
+	}
+}
+
+// LoadFile replaces the blocklist contents with the JSON-encoded
+// model.Blocklist stored in the file at path.
+func (b *Blocklist) LoadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var list model.Blocklist
+	if err := json.Unmarshal(data, &list); err != nil {
+		return err
+	}
+	return b.Set(list)
+}
+
+// Blocked reports whether the client of r (see ClientIP) or its user (see
+// GetUserID) is blocked.
+func (b *Blocklist) Blocked(r *http.Request) bool {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	if userID, err := GetUserID(r); err == nil {
+		if _, ok := b.users[userID]; ok {
+			return true
+		}
+	}
+	if len(b.networks) == 0 {
+		return false
+	}
+	ip := net.ParseIP(ClientIP(r))
+	if ip == nil {
+		return false
+	}
+	for _, network := range b.networks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// Middleware rejects requests from blocked clients with 403 Forbidden. It
+// must run after AuthMiddleware for user entries to take effect.
+func (b *Blocklist) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if b.Blocked(r) {
+			http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,60 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Aleksey170999/go-shortener/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBlocklist(t *testing.T) {
+	b := NewBlocklist()
+	require.NoError(t, b.Set(model.Blocklist{
+		IPs:   []string{"192.0.2.1", "198.51.100.0/24"},
+		Users: []string{"bad-user"},
+	}))
+	h := b.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	tests := []struct {
+		name   string
+		remote string
+		userID string
+		want   int
+	}{
+		{name: "allowed", remote: "203.0.113.5:1234", userID: "user1", want: http.StatusOK},
+		{name: "blocked ip", remote: "192.0.2.1:1234", userID: "user1", want: http.StatusForbidden},
+		{name: "blocked network", remote: "198.51.100.77:1234", userID: "user1", want: http.StatusForbidden},
+		{name: "blocked user", remote: "203.0.113.5:1234", userID: "bad-user", want: http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/", nil)
+			req.RemoteAddr = tt.remote
+			req = req.WithContext(ContextWithUserID(req.Context(), tt.userID))
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, req)
+			assert.Equal(t, tt.want, w.Code)
+		})
+	}
+
+	assert.Error(t, b.Set(model.Blocklist{IPs: []string{"not-an-ip"}}))
+	assert.Equal(t, []string{"bad-user"}, b.List().Users, "invalid update must leave the blocklist unchanged")
+}
+
+func TestBlocklistLoadFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "blocklist.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"ips":["10.0.0.0/8"],"users":["u1"]}`), 0o600))
+
+	b := NewBlocklist()
+	require.NoError(t, b.LoadFile(path))
+	assert.Equal(t, model.Blocklist{IPs: []string{"10.0.0.0/8"}, Users: []string{"u1"}}, b.List())
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = "10.1.2.3:80"
+	assert.True(t, b.Blocked(req))
+}
@@ -0,0 +1,28 @@
+// Package middlewares provides HTTP middleware functions for the application.
+// This file implements request body size limits.
+package middlewares
+
+import "net/http"
+
+// MaxBodySize returns a middleware that limits request bodies to n bytes.
+// Requests that declare a larger Content-Length are rejected with 413 Request
+// Entity Too Large right away; for the others the body is wrapped with
+// http.MaxBytesReader, so reading past the limit fails with an
+// *http.MaxBytesError that handlers report as 413. The limit applies to the
+// decompressed body when GzipMiddleware runs first. A non-positive n disables
+// the limit.
+func MaxBodySize(n int64) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if n <= 0 {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.ContentLength > n {
+				http.Error(w, http.StatusText(http.StatusRequestEntityTooLarge), http.StatusRequestEntityTooLarge)
+				return
+			}
+			r.Body = http.MaxBytesReader(w, r.Body, n)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -7,19 +7,53 @@ import (
 	"io"
 	"net/http"
 	"strings"
+	"sync"
 )
 
+// gzipWriterPool reuses gzip writers between responses; allocating a new
+// writer per response dominates the middleware's garbage.
+var gzipWriterPool = sync.Pool{
+	New: func() any { return gzip.NewWriter(io.Discard) },
+}
+
+// gzipReaderPool reuses gzip readers between requests. It starts empty,
+// since a gzip.Reader can only be created from a valid gzip stream.
+var gzipReaderPool sync.Pool
+
+// gzipMinSize is the smallest response body worth compressing; below it the
+// gzip header and footer outweigh the savings.
+const gzipMinSize = 512
+
+// compressibleTypes are the media types whose responses are compressed.
+// Types ending in "/" match every subtype.
+var compressibleTypes = []string{
+	"text/",
+	"application/json",
+	"application/javascript",
+	"application/xml",
+	"image/svg+xml",
+}
+
 // compressWriter wraps http.ResponseWriter to provide gzip compression.
 // It implements http.ResponseWriter interface and can be used to compress
 // HTTP responses on-the-fly.
+//
+// The response is buffered until gzipMinSize bytes have been written or the
+// writer is closed. At that point it is compressed only if it is large
+// enough, has a compressible content type and a status that carries a body;
+// otherwise it is passed through unchanged and no Content-Encoding is set.
 type compressWriter struct {
-	w  http.ResponseWriter
-	zw *gzip.Writer
+	w        http.ResponseWriter
+	zw       *gzip.Writer // Non-nil once the response is being compressed
+	buf      []byte       // Body written before the decision was made
+	status   int          // Status passed to WriteHeader, 0 if not called yet
+	decided  bool         // Whether headers have been sent to w
+	compress bool         // Whether the body goes through zw
 }
 
 // newCompressWriter creates a new compressWriter that wraps the provided
-// http.ResponseWriter. The returned writer will compress all data written to it
-// using gzip compression.
+// http.ResponseWriter. A gzip.Writer is taken from gzipWriterPool only once
+// the response turns out to be worth compressing.
 //
 // Parameters:
 //   - w: The original http.ResponseWriter to wrap
@@ -27,10 +61,7 @@ type compressWriter struct {
 // Returns:
 //   - *compressWriter: A new compressWriter instance
 func newCompressWriter(w http.ResponseWriter) *compressWriter {
-	return &compressWriter{
-		w:  w,
-		zw: gzip.NewWriter(w),
-	}
+	return &compressWriter{w: w}
 }
 
 // Header returns the header map of the underlying http.ResponseWriter.
@@ -39,26 +70,114 @@ func (c *compressWriter) Header() http.Header {
 	return c.w.Header()
 }
 
-// Write writes compressed data to the underlying gzip.Writer.
+// Write buffers p until the compression decision is made, then writes
+// compressed or plain data to the underlying writer.
 // Implements the io.Writer interface.
 func (c *compressWriter) Write(p []byte) (int, error) {
-	return c.zw.Write(p)
+	if c.status == 0 {
+		c.status = http.StatusOK
+	}
+	if !c.decided {
+		c.buf = append(c.buf, p...)
+		if len(c.buf) < gzipMinSize {
+			return len(p), nil
+		}
+		if err := c.decide(); err != nil {
+			return 0, err
+		}
+		return len(p), nil
+	}
+	if c.compress {
+		return c.zw.Write(p)
+	}
+	return c.w.Write(p)
 }
 
-// WriteHeader sends an HTTP response header with the provided status code.
-// It sets the Content-Encoding header to gzip if not already set.
+// WriteHeader records the status code; it is sent together with the
+// compression headers once the decision is made.
 // Implements the http.ResponseWriter interface.
 func (c *compressWriter) WriteHeader(statusCode int) {
-	if statusCode < 300 {
-		c.w.Header().Set("Content-Encoding", "gzip")
+	if c.status != 0 {
+		return
+	}
+	c.status = statusCode
+	if !bodyAllowed(statusCode) {
+		_ = c.decide()
+	}
+}
+
+// decide chooses whether to compress the response, sends the headers and
+// flushes the buffered body.
+func (c *compressWriter) decide() error {
+	c.decided = true
+	h := c.w.Header()
+	if h.Get("Content-Type") == "" && len(c.buf) > 0 {
+		h.Set("Content-Type", http.DetectContentType(c.buf))
+	}
+	c.compress = len(c.buf) >= gzipMinSize &&
+		bodyAllowed(c.status) &&
+		h.Get("Content-Encoding") == "" &&
+		isCompressible(h.Get("Content-Type"))
+
+	if c.compress {
+		h.Set("Content-Encoding", "gzip")
+		h.Add("Vary", "Accept-Encoding")
+		h.Del("Content-Length")
+		c.zw = gzipWriterPool.Get().(*gzip.Writer)
+		c.zw.Reset(c.w)
+	}
+	if c.status != 0 {
+		c.w.WriteHeader(c.status)
+	}
+	if len(c.buf) == 0 {
+		return nil
 	}
-	c.w.WriteHeader(statusCode)
+	var err error
+	if c.compress {
+		_, err = c.zw.Write(c.buf)
+	} else {
+		_, err = c.w.Write(c.buf)
+	}
+	c.buf = nil
+	return err
 }
 
-// Close flushes any pending compressed data and closes the gzip.Writer.
-// This method should be called to ensure all data is properly written.
+// Close sends whatever is still buffered, flushes any pending compressed
+// data and returns the gzip.Writer to gzipWriterPool. This method should be
+// called to ensure all data is properly written; the compressWriter must not
+// be written to afterwards, and further calls to Close do nothing.
 func (c *compressWriter) Close() error {
-	return c.zw.Close()
+	if !c.decided && (c.status != 0 || len(c.buf) > 0) {
+		if err := c.decide(); err != nil {
+			return err
+		}
+	}
+	if c.zw == nil {
+		return nil
+	}
+	err := c.zw.Close()
+	gzipWriterPool.Put(c.zw)
+	c.zw = nil
+	return err
+}
+
+// bodyAllowed reports whether a response with the given status may carry a
+// body worth compressing. Informational, 204 No Content, 304 Not Modified and
+// redirect responses are never compressed.
+func bodyAllowed(status int) bool {
+	return status >= 200 && status < 300 && status != http.StatusNoContent
+}
+
+// isCompressible reports whether contentType is one of compressibleTypes.
+func isCompressible(contentType string) bool {
+	mediaType, _, _ := strings.Cut(contentType, ";")
+	mediaType = strings.ToLower(strings.TrimSpace(mediaType))
+	for _, t := range compressibleTypes {
+		if strings.HasSuffix(t, "/") && strings.HasPrefix(mediaType, t) || mediaType == t {
+			return true
+		}
+	}
+	return false
 }
 
 // compressReader wraps an io.ReadCloser to provide gzip decompression.
@@ -70,7 +189,8 @@ type compressReader struct {
 }
 
 // newCompressReader creates a new compressReader that wraps the provided
-// io.ReadCloser. The returned reader will decompress gzipped data read from it.
+// io.ReadCloser. The returned reader will decompress gzipped data read from it
+// using a gzip.Reader from gzipReaderPool when one is available.
 //
 // Parameters:
 //   - r: The original io.ReadCloser to wrap
@@ -79,9 +199,18 @@ type compressReader struct {
 //   - *compressReader: A new compressReader instance
 //   - error: An error if the gzip reader cannot be created
 func newCompressReader(r io.ReadCloser) (*compressReader, error) {
-	zr, err := gzip.NewReader(r)
-	if err != nil {
-		return nil, err
+	var zr *gzip.Reader
+	if pooled, ok := gzipReaderPool.Get().(*gzip.Reader); ok {
+		if err := pooled.Reset(r); err != nil {
+			gzipReaderPool.Put(pooled)
+			return nil, err
+		}
+		zr = pooled
+	} else {
+		var err error
+		if zr, err = gzip.NewReader(r); err != nil {
+			return nil, err
+		}
 	}
 
 	return &compressReader{
@@ -92,17 +221,24 @@ func newCompressReader(r io.ReadCloser) (*compressReader, error) {
 
 // Read reads decompressed data from the underlying gzip.Reader.
 // Implements the io.Reader interface.
-func (c compressReader) Read(p []byte) (n int, err error) {
+func (c *compressReader) Read(p []byte) (n int, err error) {
 	return c.zr.Read(p)
 }
 
-// Close closes both the gzip.Reader and the underlying io.ReadCloser.
+// Close closes both the gzip.Reader and the underlying io.ReadCloser and
+// returns the gzip.Reader to gzipReaderPool.
 // This method should always be called to prevent resource leaks.
 func (c *compressReader) Close() error {
-	if err := c.r.Close(); err != nil {
-		return err
+	if c.zr == nil {
+		return nil
+	}
+	err := c.r.Close()
+	if zerr := c.zr.Close(); err == nil {
+		err = zerr
 	}
-	return c.zr.Close()
+	gzipReaderPool.Put(c.zr)
+	c.zr = nil
+	return err
 }
 
 // GzipMiddleware is an HTTP middleware that provides transparent gzip compression
@@ -110,7 +246,9 @@ func (c *compressReader) Close() error {
 //
 // For responses:
 //   - Checks if the client accepts gzip encoding (Accept-Encoding: gzip)
-//   - If so, compresses the response body and sets appropriate headers
+//   - If so, compresses responses of at least gzipMinSize bytes with a
+//     compressible content type and a 2xx status other than 204, and sets
+//     appropriate headers; other responses are sent unchanged
 //
 // For requests:
 //   - Checks if the request body is gzipped (Content-Encoding: gzip)
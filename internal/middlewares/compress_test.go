@@ -0,0 +1,121 @@
+package middlewares
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func gzipBytes(t *testing.T, data string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	_, err := zw.Write([]byte(data))
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+	return buf.Bytes()
+}
+
+func TestGzipMiddleware_RoundTrip(t *testing.T) {
+	h := GzipMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		w.WriteHeader(http.StatusOK)
+		w.Write(bytes.ToUpper(body))
+	}))
+
+	// Run several requests so that pooled readers and writers are reused.
+	for _, payload := range []string{
+		strings.Repeat("first request ", 100),
+		strings.Repeat("second ", 200),
+		strings.Repeat("third request body ", 50),
+	} {
+		req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(gzipBytes(t, payload)))
+		req.Header.Set("Content-Encoding", "gzip")
+		req.Header.Set("Accept-Encoding", "gzip")
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+
+		assert.Equal(t, "gzip", w.Header().Get("Content-Encoding"))
+		zr, err := gzip.NewReader(w.Body)
+		require.NoError(t, err)
+		got, err := io.ReadAll(zr)
+		require.NoError(t, err)
+		assert.Equal(t, string(bytes.ToUpper([]byte(payload))), string(got))
+	}
+}
+
+func TestGzipMiddleware_Passthrough(t *testing.T) {
+	large := strings.Repeat("a", 2*gzipMinSize)
+	tests := []struct {
+		name        string
+		status      int
+		contentType string
+		body        string
+		wantGzip    bool
+	}{
+		{name: "large json", status: http.StatusOK, contentType: "application/json", body: large, wantGzip: true},
+		{name: "large sniffed text", status: http.StatusCreated, body: large, wantGzip: true},
+		{name: "small body", status: http.StatusOK, contentType: "text/plain", body: "short"},
+		{name: "not compressible", status: http.StatusOK, contentType: "image/png", body: large},
+		{name: "no content", status: http.StatusNoContent},
+		{name: "redirect", status: http.StatusTemporaryRedirect, contentType: "text/html", body: large},
+		{name: "error", status: http.StatusInternalServerError, contentType: "text/plain", body: large},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := GzipMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tt.contentType != "" {
+					w.Header().Set("Content-Type", tt.contentType)
+				}
+				w.WriteHeader(tt.status)
+				io.WriteString(w, tt.body)
+			}))
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("Accept-Encoding", "gzip")
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.status, w.Code)
+			if tt.wantGzip {
+				assert.Equal(t, "gzip", w.Header().Get("Content-Encoding"))
+				zr, err := gzip.NewReader(w.Body)
+				require.NoError(t, err)
+				got, err := io.ReadAll(zr)
+				require.NoError(t, err)
+				assert.Equal(t, tt.body, string(got))
+				return
+			}
+			assert.Empty(t, w.Header().Get("Content-Encoding"))
+			assert.Equal(t, tt.body, w.Body.String())
+		})
+	}
+}
+
+func TestGzipMiddleware_InvalidBody(t *testing.T) {
+	h := GzipMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader([]byte("not gzip")))
+	req.Header.Set("Content-Encoding", "gzip")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func BenchmarkGzipMiddleware(b *testing.B) {
+	h := GzipMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTemporaryRedirect)
+	}))
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		h.ServeHTTP(httptest.NewRecorder(), req)
+	}
+}
@@ -0,0 +1,47 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCSRF(t *testing.T) {
+	secret := []byte("secret")
+	h := CSRF(secret)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	newRequest := func(method, userID string, form url.Values) *http.Request {
+		req := httptest.NewRequest(method, "/", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		return req.WithContext(ContextWithUserID(req.Context(), userID))
+	}
+	token := csrfToken(secret, "user1")
+
+	tests := []struct {
+		name string
+		req  *http.Request
+		want int
+	}{
+		{name: "safe method", req: newRequest(http.MethodGet, "user1", nil), want: http.StatusOK},
+		{name: "valid form token", req: newRequest(http.MethodPost, "user1", url.Values{CSRFFormField: {token}}), want: http.StatusOK},
+		{name: "missing token", req: newRequest(http.MethodPost, "user1", nil), want: http.StatusForbidden},
+		{name: "token of another user", req: newRequest(http.MethodPost, "user2", url.Values{CSRFFormField: {token}}), want: http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, tt.req)
+			assert.Equal(t, tt.want, w.Code)
+		})
+	}
+
+	req := newRequest(http.MethodDelete, "user1", nil)
+	req.Header.Set(CSRFHeader, CSRFToken(req, secret))
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
@@ -0,0 +1,30 @@
+// Package middlewares provides HTTP middleware functions for the application.
+// This file implements the limit on concurrently handled requests.
+package middlewares
+
+import "net/http"
+
+// MaxInFlight returns a middleware that handles at most n requests at a
+// time. Requests arriving while n are in flight are shed right away with
+// 503 Service Unavailable and a Retry-After header rather than queued, so
+// that an overloaded server keeps its memory bounded and answers the
+// requests it accepted in time. A non-positive n disables the limit.
+func MaxInFlight(n int) func(http.Handler) http.Handler {
+	if n <= 0 {
+		return func(next http.Handler) http.Handler { return next }
+	}
+	slots := make(chan struct{}, n)
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			select {
+			case slots <- struct{}{}:
+			default:
+				w.Header().Set("Retry-After", "1")
+				http.Error(w, "server is overloaded", http.StatusServiceUnavailable)
+				return
+			}
+			defer func() { <-slots }()
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -45,6 +45,8 @@ func (r *loggingResponseWriter) WriteHeader(statusCode int) {
 
 // WithLogging creates a middleware that logs HTTP request details using the provided zap.Logger.
 // The middleware logs the following information for each request:
+//   - Request ID, if RequestID runs before this middleware
+//   - Client IP, see ClientIP
 //   - HTTP method
 //   - Request URI
 //   - Response status code
@@ -81,6 +83,8 @@ func WithLogging(logger *zap.Logger) func(http.Handler) http.Handler {
 
 			duration := time.Since(start)
 			logger.Sugar().Infow("request completed",
+				"request_id", GetRequestID(r.Context()),
+				"client_ip", ClientIP(r),
 				"method", r.Method,
 				"uri", uri,
 				"status", responseData.status,
@@ -0,0 +1,60 @@
+// Package middlewares provides HTTP middleware functions for the application.
+// This file implements the maintenance mode switch.
+package middlewares
+
+import (
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// Maintenance is a runtime switch that rejects requests to the routes it
+// guards while maintenance mode is on. It is safe for concurrent use; the
+// zero value is a switch that is off.
+type Maintenance struct {
+	enabled    atomic.Bool
+	retryAfter time.Duration
+}
+
+// NewMaintenance creates a switch that is off. While it is on, rejected
+// clients are told to retry after retryAfter.
+func NewMaintenance(retryAfter time.Duration) *Maintenance {
+	return &Maintenance{retryAfter: retryAfter}
+}
+
+// Enabled reports whether maintenance mode is on.
+func (m *Maintenance) Enabled() bool {
+	return m.enabled.Load()
+}
+
+// Set turns maintenance mode on or off.
+func (m *Maintenance) Set(enabled bool) {
+	m.enabled.Store(enabled)
+}
+
+// Toggle flips maintenance mode and returns the new state.
+func (m *Maintenance) Toggle() bool {
+	for {
+		old := m.enabled.Load()
+		if m.enabled.CompareAndSwap(old, !old) {
+			return !old
+		}
+	}
+}
+
+// Middleware rejects requests with 503 Service Unavailable and a Retry-After
+// header while maintenance mode is on. It is meant for write routes; routes
+// that keep working during maintenance, such as redirects, are left unwrapped.
+func (m *Maintenance) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if m.Enabled() {
+			if seconds := int(m.retryAfter.Seconds()); seconds > 0 {
+				w.Header().Set("Retry-After", strconv.Itoa(seconds))
+			}
+			http.Error(w, "service is under maintenance", http.StatusServiceUnavailable)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
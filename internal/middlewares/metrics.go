@@ -0,0 +1,69 @@
+// Package middlewares provides HTTP middleware functions for the application.
+// This file implements Prometheus HTTP metrics.
+package middlewares
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// unmatchedRoute is the route label of requests that matched no route.
+const unmatchedRoute = "unmatched"
+
+// Metrics returns a middleware that records Prometheus metrics for every request:
+//   - http_requests_total: counter labelled by method, route and status class ("2xx", "4xx", ...)
+//   - http_request_duration_seconds: histogram labelled by method and route
+//
+// The route label is the chi route pattern (e.g. "/{id}") rather than the raw
+// path, so the number of series stays bounded. The collectors are registered
+// with reg; Metrics panics if they are already registered there.
+//
+// Parameters:
+//   - reg: The registry the collectors are registered with
+//
+// Returns:
+//   - A middleware function that can be used with http.Handler
+func Metrics(reg prometheus.Registerer) func(http.Handler) http.Handler {
+	requests := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_requests_total",
+		Help: "Number of HTTP requests by method, route and status class.",
+	}, []string{"method", "route", "status"})
+	duration := prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_request_duration_seconds",
+		Help:    "HTTP request latency by method and route.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "route"})
+	reg.MustRegister(requests, duration)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			responseData := &responseData{}
+			next.ServeHTTP(&loggingResponseWriter{ResponseWriter: w, responseData: responseData}, r)
+
+			status := responseData.status
+			if status == 0 {
+				status = http.StatusOK
+			}
+			route := routePattern(r)
+			requests.WithLabelValues(r.Method, route, strconv.Itoa(status/100)+"xx").Inc()
+			duration.WithLabelValues(r.Method, route).Observe(time.Since(start).Seconds())
+		})
+	}
+}
+
+// routePattern returns the chi route pattern r was matched against. Requests
+// that only matched the catch-all of a mounted subrouter count as unmatched.
+func routePattern(r *http.Request) string {
+	if rctx := chi.RouteContext(r.Context()); rctx != nil {
+		if pattern := rctx.RoutePattern(); pattern != "" && !strings.HasSuffix(pattern, "/*") {
+			return pattern
+		}
+	}
+	return unmatchedRoute
+}
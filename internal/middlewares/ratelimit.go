@@ -0,0 +1,35 @@
+// Package middlewares provides HTTP middleware functions for the application.
+// This file implements per-client-IP rate limiting.
+package middlewares
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/Aleksey170999/go-shortener/internal/ratelimit"
+)
+
+// IPRateLimit returns a middleware that limits how often each client IP may
+// call the wrapped routes. Every request takes one token from the client's
+// bucket, which holds up to burst tokens and refills at rate tokens per
+// second; requests on an empty bucket get 429 Too Many Requests with a
+// Retry-After header saying when the next token is due. Each call creates an
+// independent set of buckets, so route groups wrapped by different
+// IPRateLimit middlewares have separate budgets. A non-positive rate or burst
+// disables the limit.
+func IPRateLimit(rate float64, burst int) func(http.Handler) http.Handler {
+	l := ratelimit.New(rate, burst)
+	if l == nil {
+		return func(next http.Handler) http.Handler { return next }
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if ok, wait := l.Allow(ClientIP(r)); !ok {
+				w.Header().Set("Retry-After", strconv.Itoa(ratelimit.RetryAfterSeconds(wait)))
+				http.Error(w, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
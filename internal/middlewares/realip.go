@@ -0,0 +1,127 @@
+// Package middlewares provides HTTP middleware functions for the application.
+// This file implements real client IP resolution behind reverse proxies.
+package middlewares
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// forwardedForHeader is the header listing the proxies a request went through.
+const forwardedForHeader = "X-Forwarded-For"
+
+// clientIPKey is the context key under which the resolved client IP is stored.
+type clientIPKey struct{}
+
+// RealIP returns a middleware that resolves the IP of the client that sent
+// the request and stores it in the request context (see ClientIP).
+//
+// Forwarding headers are only honoured when the connection comes from one of
+// trustedProxies, since anyone else can set them to arbitrary values. In that
+// case X-Forwarded-For is walked from right to left, skipping trusted proxies,
+// and the first untrusted address is taken as the client; if the header is
+// absent, X-Real-IP is used. Otherwise the remote address of the connection
+// is the client IP.
+func RealIP(trustedProxies []*net.IPNet) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ip := remoteIP(r)
+			if ip == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if isTrusted(trustedProxies, ip) {
+				ip = forwardedIP(r, trustedProxies, ip)
+			}
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), clientIPKey{}, ip.String())))
+		})
+	}
+}
+
+// ClientIP returns the IP address of the client that sent r. It is the
+// address resolved by RealIP if that middleware ran, otherwise the remote
+// address of the connection.
+func ClientIP(r *http.Request) string {
+	if ip, ok := r.Context().Value(clientIPKey{}).(string); ok {
+		return ip
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// ParseNetworks parses a list of CIDRs and bare IP addresses. A bare address
+// is treated as a network containing only that address.
+func ParseNetworks(list []string) ([]*net.IPNet, error) {
+	networks := make([]*net.IPNet, 0, len(list))
+	for _, item := range list {
+		if !strings.Contains(item, "/") {
+			ip := net.ParseIP(item)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid ip address %q", item)
+			}
+			bits := 8 * net.IPv6len
+			if ip4 := ip.To4(); ip4 != nil {
+				ip, bits = ip4, 8*net.IPv4len
+			}
+			networks = append(networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, network, err := net.ParseCIDR(item)
+		if err != nil {
+			return nil, err
+		}
+		networks = append(networks, network)
+	}
+	return networks, nil
+}
+
+// remoteIP returns the IP of the connection peer.
+func remoteIP(r *http.Request) net.IP {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return net.ParseIP(host)
+}
+
+// forwardedIP returns the client IP reported by trusted proxies, or peer if
+// the forwarding headers carry no usable address.
+func forwardedIP(r *http.Request, trustedProxies []*net.IPNet, peer net.IP) net.IP {
+	if values := r.Header.Values(forwardedForHeader); len(values) > 0 {
+		hops := strings.Split(strings.Join(values, ","), ",")
+		for i := len(hops) - 1; i >= 0; i-- {
+			ip := net.ParseIP(strings.TrimSpace(hops[i]))
+			if ip == nil {
+				break
+			}
+			peer = ip
+			if !isTrusted(trustedProxies, ip) {
+				break
+			}
+		}
+		return peer
+	}
+	if ip := net.ParseIP(strings.TrimSpace(r.Header.Get(realIPHeader))); ip != nil {
+		return ip
+	}
+	return peer
+}
+
+// isTrusted reports whether ip belongs to one of networks.
+func isTrusted(networks []*net.IPNet, ip net.IP) bool {
+	if ip == nil {
+		return false
+	}
+	for _, network := range networks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,55 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRealIP(t *testing.T) {
+	proxies, err := ParseNetworks([]string{"10.0.0.1", "172.16.0.0/12"})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name         string
+		remoteAddr   string
+		forwardedFor string
+		realIP       string
+		wantClientIP string
+	}{
+		{name: "direct client", remoteAddr: "203.0.113.5:1234", wantClientIP: "203.0.113.5"},
+		{name: "untrusted peer headers ignored", remoteAddr: "203.0.113.5:1234", forwardedFor: "198.51.100.1", realIP: "198.51.100.2", wantClientIP: "203.0.113.5"},
+		{name: "trusted proxy forwarded for", remoteAddr: "10.0.0.1:1234", forwardedFor: "198.51.100.1", wantClientIP: "198.51.100.1"},
+		{name: "proxy chain skips trusted hops", remoteAddr: "10.0.0.1:1234", forwardedFor: "1.2.3.4, 198.51.100.1, 172.16.0.9", wantClientIP: "198.51.100.1"},
+		{name: "trusted proxy real ip", remoteAddr: "10.0.0.1:1234", realIP: "198.51.100.2", wantClientIP: "198.51.100.2"},
+		{name: "trusted proxy without headers", remoteAddr: "10.0.0.1:1234", wantClientIP: "10.0.0.1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			h := RealIP(proxies)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = ClientIP(r)
+			}))
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = tt.remoteAddr
+			if tt.forwardedFor != "" {
+				req.Header.Set("X-Forwarded-For", tt.forwardedFor)
+			}
+			if tt.realIP != "" {
+				req.Header.Set("X-Real-IP", tt.realIP)
+			}
+			h.ServeHTTP(httptest.NewRecorder(), req)
+			assert.Equal(t, tt.wantClientIP, got)
+		})
+	}
+}
+
+func TestParseNetworks_Invalid(t *testing.T) {
+	_, err := ParseNetworks([]string{"not-an-ip"})
+	assert.Error(t, err)
+	_, err = ParseNetworks([]string{"10.0.0.0/33"})
+	assert.Error(t, err)
+}
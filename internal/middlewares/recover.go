@@ -0,0 +1,58 @@
+// Package middlewares provides HTTP middleware functions for the application.
+// This file implements recovery from handler panics.
+package middlewares
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/Aleksey170999/go-shortener/internal/model"
+	"go.uber.org/zap"
+)
+
+// Recoverer returns a middleware that recovers from panics in the wrapped
+// handler. The panic value and stack trace are logged together with the
+// request ID, and the client gets a 500 Internal Server Error JSON body
+// instead of a dropped connection.
+//
+// Panics with http.ErrAbortHandler are propagated, since they are the
+// standard way to abort a response on purpose.
+//
+// Parameters:
+//   - logger: A configured zap.Logger instance for structured logging
+//
+// Returns:
+//   - A middleware function that can be used with http.Handler
+func Recoverer(logger *zap.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer func() {
+				rec := recover()
+				if rec == nil {
+					return
+				}
+				if err, ok := rec.(error); ok && errors.Is(err, http.ErrAbortHandler) {
+					panic(rec)
+				}
+
+				LoggerWithRequestID(r.Context(), logger).Error("handler panic",
+					zap.Any("panic", rec),
+					zap.String("method", r.Method),
+					zap.String("uri", r.URL.RequestURI()),
+					zap.ByteString("stack", debug.Stack()),
+				)
+
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusInternalServerError)
+				_ = json.NewEncoder(w).Encode(model.ErrorResponse{
+					Error:     http.StatusText(http.StatusInternalServerError),
+					RequestID: GetRequestID(r.Context()),
+				})
+			}()
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,66 @@
+// Package middlewares provides HTTP middleware functions for the application.
+// This file implements error reporting of HTTP requests to Sentry.
+package middlewares
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/getsentry/sentry-go"
+)
+
+// ErrorReporting returns a middleware that reports handler panics and 5xx
+// responses to Sentry. Every request gets its own clone of hub, whose scope
+// holds the request (without cookies, authorization headers and the client
+// address) and the request ID; it is stored in the request context, so
+// handlers can report further errors with sentry.GetHubFromContext. Events
+// are tagged with the chi route pattern, so those of one route are grouped
+// together regardless of the short code.
+//
+// Panics are reported and then re-raised, so ErrorReporting must run inside
+// Recoverer, which answers them. Panics with http.ErrAbortHandler are not
+// reported.
+//
+// Parameters:
+//   - hub: The hub whose client events are sent with
+//
+// Returns:
+//   - A middleware function that can be used with http.Handler
+func ErrorReporting(hub *sentry.Hub) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			hub := hub.Clone()
+			hub.Scope().SetRequest(r)
+			if id := GetRequestID(r.Context()); id != "" {
+				hub.Scope().SetTag("request_id", id)
+			}
+			r = r.WithContext(sentry.SetHubOnContext(r.Context(), hub))
+
+			defer func() {
+				rec := recover()
+				if rec == nil {
+					return
+				}
+				if err, ok := rec.(error); !ok || !errors.Is(err, http.ErrAbortHandler) {
+					hub.Scope().SetTag("http.route", routePattern(r))
+					hub.RecoverWithContext(r.Context(), rec)
+				}
+				panic(rec)
+			}()
+
+			responseData := &responseData{}
+			next.ServeHTTP(&loggingResponseWriter{ResponseWriter: w, responseData: responseData}, r)
+
+			status := responseData.status
+			if status < http.StatusInternalServerError {
+				return
+			}
+			route := routePattern(r)
+			hub.Scope().SetTag("http.route", route)
+			hub.Scope().SetTag("http.status_code", strconv.Itoa(status))
+			hub.CaptureMessage(fmt.Sprintf("%s %s: %d %s", r.Method, route, status, http.StatusText(status)))
+		})
+	}
+}
@@ -0,0 +1,71 @@
+package middlewares
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/getsentry/sentry-go"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestErrorReporting(t *testing.T) {
+	transport := &sentry.MockTransport{}
+	client, err := sentry.NewClient(sentry.ClientOptions{Dsn: "https://key@sentry.example.com/1", Transport: transport})
+	require.NoError(t, err)
+	hub := sentry.NewHub(client, sentry.NewScope())
+
+	r := chi.NewRouter()
+	r.Use(Recoverer(zap.NewNop()), ErrorReporting(hub))
+	r.Get("/ok", func(w http.ResponseWriter, r *http.Request) {})
+	r.Get("/{id}", func(w http.ResponseWriter, r *http.Request) {
+		assert.NotNil(t, sentry.GetHubFromContext(r.Context()))
+		w.WriteHeader(http.StatusBadGateway)
+	})
+	r.Get("/panic", func(w http.ResponseWriter, r *http.Request) { panic("boom") })
+
+	serve := func(path string) int {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Cookie", "user_id=secret")
+		req = req.WithContext(context.WithValue(req.Context(), requestIDKey{}, "req-1"))
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+	assert.Equal(t, http.StatusOK, serve("/ok"))
+	assert.Equal(t, http.StatusBadGateway, serve("/abc"))
+	assert.Equal(t, http.StatusInternalServerError, serve("/panic"))
+
+	events := transport.Events()
+	require.Len(t, events, 2, "successful requests are not reported")
+
+	assert.Equal(t, "GET /{id}: 502 Bad Gateway", events[0].Message)
+	assert.Equal(t, "/{id}", events[0].Tags["http.route"])
+	assert.Equal(t, "502", events[0].Tags["http.status_code"])
+	assert.Equal(t, "req-1", events[0].Tags["request_id"])
+	require.NotNil(t, events[0].Request)
+	assert.Equal(t, "http://example.com/abc", events[0].Request.URL)
+	assert.Empty(t, events[0].Request.Cookies, "cookies are not sent")
+
+	assert.Equal(t, "boom", events[1].Message)
+	assert.Equal(t, sentry.LevelFatal, events[1].Level)
+	assert.Equal(t, "/panic", events[1].Tags["http.route"])
+}
+
+func TestErrorReporting_AbortHandler(t *testing.T) {
+	transport := &sentry.MockTransport{}
+	client, err := sentry.NewClient(sentry.ClientOptions{Dsn: "https://key@sentry.example.com/1", Transport: transport})
+	require.NoError(t, err)
+
+	h := ErrorReporting(sentry.NewHub(client, sentry.NewScope()))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic(http.ErrAbortHandler)
+	}))
+	assert.Panics(t, func() {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	})
+	assert.Empty(t, transport.Events())
+}
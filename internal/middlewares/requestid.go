@@ -0,0 +1,67 @@
+// Package middlewares provides HTTP middleware functions for the application.
+// This file implements request ID propagation.
+package middlewares
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// RequestIDHeader is the header carrying the request ID in requests and responses.
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength bounds the length of a request ID accepted from the client.
+const maxRequestIDLength = 128
+
+// requestIDKey is the context key under which the request ID is stored.
+type requestIDKey struct{}
+
+// RequestID is an HTTP middleware that assigns an ID to every request.
+// The ID is taken from the X-Request-ID request header if the client sent a
+// valid one, otherwise a new UUID is generated. The ID is stored in the
+// request context (see GetRequestID) and returned in the X-Request-ID
+// response header, so a request can be correlated with its log lines.
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(RequestIDHeader)
+		if !validRequestID(id) {
+			id = uuid.New().String()
+		}
+		w.Header().Set(RequestIDHeader, id)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), requestIDKey{}, id)))
+	})
+}
+
+// GetRequestID returns the request ID stored in ctx by RequestID, or an empty
+// string if there is none.
+func GetRequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// LoggerWithRequestID returns logger annotated with the request ID stored in
+// ctx, or logger itself if ctx carries no request ID.
+func LoggerWithRequestID(ctx context.Context, logger *zap.Logger) *zap.Logger {
+	if id := GetRequestID(ctx); id != "" {
+		return logger.With(zap.String("request_id", id))
+	}
+	return logger
+}
+
+// validRequestID reports whether a client-supplied request ID is non-empty,
+// reasonably short and consists of printable ASCII only, so it is safe to
+// echo in headers and logs.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
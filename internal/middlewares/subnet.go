@@ -0,0 +1,36 @@
+// Package middlewares provides HTTP middleware functions for the application.
+// This file implements trusted-subnet access control.
+package middlewares
+
+import (
+	"net"
+	"net/http"
+	"strings"
+)
+
+// realIPHeader is the header a reverse proxy uses to pass the client IP.
+const realIPHeader = "X-Real-IP"
+
+// TrustedSubnet returns a middleware that only lets through requests from
+// clients inside subnet. The client IP is the one resolved by RealIP if that
+// middleware ran; otherwise it is taken from the X-Real-IP header, falling
+// back to the remote address of the connection. Other requests get
+// 403 Forbidden. A nil subnet denies every request, so internal routes stay
+// closed unless a subnet is configured.
+func TrustedSubnet(subnet *net.IPNet) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var ip net.IP
+			if resolved, ok := r.Context().Value(clientIPKey{}).(string); ok {
+				ip = net.ParseIP(resolved)
+			} else if ip = net.ParseIP(strings.TrimSpace(r.Header.Get(realIPHeader))); ip == nil {
+				ip = net.ParseIP(ClientIP(r))
+			}
+			if subnet == nil || ip == nil || !subnet.Contains(ip) {
+				http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
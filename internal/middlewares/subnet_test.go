@@ -0,0 +1,42 @@
+package middlewares
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTrustedSubnet(t *testing.T) {
+	_, subnet, err := net.ParseCIDR("192.168.1.0/24")
+	assert.NoError(t, err)
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+	tests := []struct {
+		name       string
+		subnet     *net.IPNet
+		remoteAddr string
+		realIP     string
+		want       int
+	}{
+		{name: "remote addr inside", subnet: subnet, remoteAddr: "192.168.1.10:1234", want: http.StatusOK},
+		{name: "remote addr outside", subnet: subnet, remoteAddr: "10.0.0.1:1234", want: http.StatusForbidden},
+		{name: "real ip inside", subnet: subnet, remoteAddr: "10.0.0.1:1234", realIP: "192.168.1.20", want: http.StatusOK},
+		{name: "real ip outside", subnet: subnet, remoteAddr: "192.168.1.10:1234", realIP: "10.0.0.2", want: http.StatusForbidden},
+		{name: "no subnet configured", remoteAddr: "192.168.1.10:1234", want: http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = tt.remoteAddr
+			if tt.realIP != "" {
+				req.Header.Set("X-Real-IP", tt.realIP)
+			}
+			w := httptest.NewRecorder()
+			TrustedSubnet(tt.subnet)(ok).ServeHTTP(w, req)
+			assert.Equal(t, tt.want, w.Code)
+		})
+	}
+}
@@ -0,0 +1,54 @@
+// Package middlewares provides HTTP middleware functions for the application.
+// This file implements request timeouts.
+package middlewares
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// timeoutWriter wraps http.ResponseWriter to record whether a response has
+// been started.
+type timeoutWriter struct {
+	http.ResponseWriter
+	wroteHeader bool
+}
+
+// WriteHeader records that the response has been started.
+func (tw *timeoutWriter) WriteHeader(statusCode int) {
+	tw.wroteHeader = true
+	tw.ResponseWriter.WriteHeader(statusCode)
+}
+
+// Write records that the response has been started.
+func (tw *timeoutWriter) Write(b []byte) (int, error) {
+	tw.wroteHeader = true
+	return tw.ResponseWriter.Write(b)
+}
+
+// Timeout returns a middleware that bounds how long the wrapped handler may
+// run. The request context is cancelled after d, which aborts pending
+// repository calls; if the handler returns without having written a response
+// by then, the client gets 504 Gateway Timeout. Handlers are expected to map
+// context.DeadlineExceeded to 504 themselves when they write the response.
+// A non-positive d disables the timeout.
+func Timeout(d time.Duration) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if d <= 0 {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx, cancel := context.WithTimeout(r.Context(), d)
+			defer cancel()
+
+			tw := &timeoutWriter{ResponseWriter: w}
+			next.ServeHTTP(tw, r.WithContext(ctx))
+
+			if !tw.wroteHeader && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				http.Error(w, http.StatusText(http.StatusGatewayTimeout), http.StatusGatewayTimeout)
+			}
+		})
+	}
+}
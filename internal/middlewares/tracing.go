@@ -0,0 +1,63 @@
+// Package middlewares provides HTTP middleware functions for the application.
+// This file implements OpenTelemetry tracing of HTTP requests.
+package middlewares
+
+import (
+	"net/http"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Tracing returns a middleware that records a server span for every request.
+// The parent trace is extracted from the request headers (e.g. traceparent)
+// with propagator, and the span is stored in the request context so that
+// spans started further down, such as service operations, become its
+// children. The span is named after the chi route pattern once routing is
+// done; responses with a 5xx status mark the span as failed.
+//
+// Parameters:
+//   - tracer: The tracer spans are started with
+//   - propagator: The propagator used to extract the incoming trace context
+//
+// Returns:
+//   - A middleware function that can be used with http.Handler
+func Tracing(tracer trace.Tracer, propagator propagation.TextMapPropagator) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := propagator.Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+			ctx, span := tracer.Start(ctx, r.Method,
+				trace.WithSpanKind(trace.SpanKindServer),
+				trace.WithAttributes(
+					attribute.String("http.request.method", r.Method),
+					attribute.String("url.path", r.URL.Path),
+					attribute.String("client.address", ClientIP(r)),
+				),
+			)
+			defer span.End()
+
+			responseData := &responseData{}
+			r = r.WithContext(ctx)
+			next.ServeHTTP(&loggingResponseWriter{ResponseWriter: w, responseData: responseData}, r)
+
+			status := responseData.status
+			if status == 0 {
+				status = http.StatusOK
+			}
+			route := routePattern(r)
+			span.SetName(r.Method + " " + route)
+			span.SetAttributes(
+				attribute.String("http.route", route),
+				attribute.Int("http.response.status_code", status),
+			)
+			if id := GetRequestID(r.Context()); id != "" {
+				span.SetAttributes(attribute.String("request_id", id))
+			}
+			if status >= http.StatusInternalServerError {
+				span.SetStatus(codes.Error, http.StatusText(status))
+			}
+		})
+	}
+}
@@ -5,9 +5,12 @@
 package mocks
 
 import (
+	context "context"
 	reflect "reflect"
+	time "time"
 
 	model "github.com/Aleksey170999/go-shortener/internal/model"
+	repository "github.com/Aleksey170999/go-shortener/internal/repository"
 	gomock "github.com/golang/mock/gomock"
 )
 
@@ -34,61 +37,120 @@ func (m *MockURLRepository) EXPECT() *MockURLRepositoryMockRecorder {
 	return m.recorder
 }
 
+// AddClickStats mocks base method.
+func (m *MockURLRepository) AddClickStats(ctx context.Context, stats []model.ClickStat) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddClickStats", ctx, stats)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AddClickStats indicates an expected call of AddClickStats.
+func (mr *MockURLRepositoryMockRecorder) AddClickStats(ctx, stats interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddClickStats", reflect.TypeOf((*MockURLRepository)(nil).AddClickStats), ctx, stats)
+}
+
 // BatchDelete mocks base method.
-func (m *MockURLRepository) BatchDelete(shortURLs []string, userID string) error {
+func (m *MockURLRepository) BatchDelete(ctx context.Context, shortURLs []string, userID string) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "BatchDelete", shortURLs, userID)
+	ret := m.ctrl.Call(m, "BatchDelete", ctx, shortURLs, userID)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // BatchDelete indicates an expected call of BatchDelete.
-func (mr *MockURLRepositoryMockRecorder) BatchDelete(shortURLs, userID interface{}) *gomock.Call {
+func (mr *MockURLRepositoryMockRecorder) BatchDelete(ctx, shortURLs, userID interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BatchDelete", reflect.TypeOf((*MockURLRepository)(nil).BatchDelete), shortURLs, userID)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BatchDelete", reflect.TypeOf((*MockURLRepository)(nil).BatchDelete), ctx, shortURLs, userID)
 }
 
 // GetByShortURL mocks base method.
-func (m *MockURLRepository) GetByShortURL(shortURL string) (*model.URL, error) {
+func (m *MockURLRepository) GetByShortURL(ctx context.Context, shortURL string) (*model.URL, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetByShortURL", shortURL)
+	ret := m.ctrl.Call(m, "GetByShortURL", ctx, shortURL)
 	ret0, _ := ret[0].(*model.URL)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetByShortURL indicates an expected call of GetByShortURL.
-func (mr *MockURLRepositoryMockRecorder) GetByShortURL(shortURL interface{}) *gomock.Call {
+func (mr *MockURLRepositoryMockRecorder) GetByShortURL(ctx, shortURL interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByShortURL", reflect.TypeOf((*MockURLRepository)(nil).GetByShortURL), shortURL)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByShortURL", reflect.TypeOf((*MockURLRepository)(nil).GetByShortURL), ctx, shortURL)
 }
 
 // GetByUserID mocks base method.
-func (m *MockURLRepository) GetByUserID(userID string) ([]model.URL, error) {
+func (m *MockURLRepository) GetByUserID(ctx context.Context, userID string) ([]model.URL, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetByUserID", userID)
+	ret := m.ctrl.Call(m, "GetByUserID", ctx, userID)
 	ret0, _ := ret[0].([]model.URL)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetByUserID indicates an expected call of GetByUserID.
-func (mr *MockURLRepositoryMockRecorder) GetByUserID(userID interface{}) *gomock.Call {
+func (mr *MockURLRepositoryMockRecorder) GetByUserID(ctx, userID interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByUserID", reflect.TypeOf((*MockURLRepository)(nil).GetByUserID), userID)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByUserID", reflect.TypeOf((*MockURLRepository)(nil).GetByUserID), ctx, userID)
+}
+
+// Purge mocks base method.
+func (m *MockURLRepository) Purge(ctx context.Context, expiredBefore, deletedBefore time.Time, dryRun bool) (repository.PurgeStats, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Purge", ctx, expiredBefore, deletedBefore, dryRun)
+	ret0, _ := ret[0].(repository.PurgeStats)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Purge indicates an expected call of Purge.
+func (mr *MockURLRepositoryMockRecorder) Purge(ctx, expiredBefore, deletedBefore, dryRun interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Purge", reflect.TypeOf((*MockURLRepository)(nil).Purge), ctx, expiredBefore, deletedBefore, dryRun)
+}
+
+// PurgeMatching mocks base method.
+func (m *MockURLRepository) PurgeMatching(ctx context.Context, filter repository.PurgeFilter, dryRun bool) ([]model.URL, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PurgeMatching", ctx, filter, dryRun)
+	ret0, _ := ret[0].([]model.URL)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PurgeMatching indicates an expected call of PurgeMatching.
+func (mr *MockURLRepositoryMockRecorder) PurgeMatching(ctx, filter, dryRun interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PurgeMatching", reflect.TypeOf((*MockURLRepository)(nil).PurgeMatching), ctx, filter, dryRun)
 }
 
 // Save mocks base method.
-func (m *MockURLRepository) Save(url *model.URL) (*model.URL, error) {
+func (m *MockURLRepository) Save(ctx context.Context, url *model.URL) (*model.URL, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Save", url)
+	ret := m.ctrl.Call(m, "Save", ctx, url)
 	ret0, _ := ret[0].(*model.URL)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // Save indicates an expected call of Save.
-func (mr *MockURLRepositoryMockRecorder) Save(url interface{}) *gomock.Call {
+func (mr *MockURLRepositoryMockRecorder) Save(ctx, url interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Save", reflect.TypeOf((*MockURLRepository)(nil).Save), ctx, url)
+}
+
+// SaveBatch mocks base method.
+func (m *MockURLRepository) SaveBatch(ctx context.Context, urls []*model.URL) ([]error, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveBatch", ctx, urls)
+	ret0, _ := ret[0].([]error)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SaveBatch indicates an expected call of SaveBatch.
+func (mr *MockURLRepositoryMockRecorder) SaveBatch(ctx, urls interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Save", reflect.TypeOf((*MockURLRepository)(nil).Save), url)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveBatch", reflect.TypeOf((*MockURLRepository)(nil).SaveBatch), ctx, urls)
 }
@@ -2,7 +2,12 @@
 // It contains the domain models and DTOs (Data Transfer Objects) for the API.
 package model
 
-import "errors"
+import (
+	"errors"
+	"time"
+
+	"github.com/Aleksey170999/go-shortener/internal/audit"
+)
 
 // URL represents a shortened URL in the system.
 // It contains both the original URL and its shortened version,
@@ -21,7 +26,33 @@ type URL struct {
 	UserID string `json:"user_id,omitempty" db:"user_id"`
 
 	// IsDeleted indicates if the URL has been soft-deleted
-	IsDeleted bool `json:"-" db:"is_deleted"`
+	IsDeleted bool `json:"is_deleted,omitempty" db:"is_deleted"`
+
+	// ExpiresAt is the moment after which the URL no longer resolves; nil means it never expires
+	ExpiresAt *time.Time `json:"expires_at,omitempty" db:"expires_at"`
+
+	// DeletedAt is when the URL was soft-deleted; nil while the URL is active
+	DeletedAt *time.Time `json:"deleted_at,omitempty" db:"deleted_at"`
+
+	// CreatedAt is when the URL was shortened; zero if unknown
+	CreatedAt time.Time `json:"created_at,omitzero" db:"created_at"`
+}
+
+// IsExpired reports whether the URL has an expiry time that is not after now.
+func (u *URL) IsExpired(now time.Time) bool {
+	return u.ExpiresAt != nil && !u.ExpiresAt.After(now)
+}
+
+// ClickStat is the number of redirects through a short URL within one time bucket.
+type ClickStat struct {
+	// Short is the short URL identifier
+	Short string `json:"short_url" db:"short_url"`
+
+	// Bucket is the start of the aggregation interval
+	Bucket time.Time `json:"bucket" db:"bucket"`
+
+	// Clicks is the number of redirects within the bucket
+	Clicks int64 `json:"clicks" db:"clicks"`
 }
 
 // UserURLsResponse represents the response structure when
@@ -38,6 +69,9 @@ type UserURLsResponse struct {
 type ShortenJSONRequest struct {
 	// URL is the original URL to be shortened
 	URL string `json:"url" validate:"required,url"`
+
+	// Alias is an optional custom short code requested by the client
+	Alias string `json:"alias,omitempty"`
 }
 
 // ShortenJSONResponse represents the response after creating a short URL
@@ -62,6 +96,89 @@ type ResponseURLItem struct {
 
 	// ShortURL is the generated short URL
 	ShortURL string `json:"short_url"`
+
+	// Error describes why the URL was not shortened; ShortURL is empty then
+	Error string `json:"error,omitempty"`
+}
+
+// DeleteAcceptedResponse is returned when a batch delete request has been queued
+type DeleteAcceptedResponse struct {
+	// JobID identifies the delete job for the status endpoint
+	JobID string `json:"job_id"`
+}
+
+// DeleteJobResponse reports the progress of a batch delete job
+type DeleteJobResponse struct {
+	// ID is the delete job identifier
+	ID string `json:"id"`
+
+	// Status is one of "pending", "completed" or "failed"
+	Status string `json:"status"`
+
+	// Total is the number of short URLs in the delete request
+	Total int `json:"total"`
+
+	// Pending is the number of short URLs not processed yet
+	Pending int `json:"pending"`
+
+	// Completed is the number of short URLs processed successfully
+	Completed int `json:"completed"`
+
+	// Failed is the number of short URLs whose deletion failed
+	Failed int `json:"failed"`
+}
+
+// MaintenanceStatus reports or sets the state of maintenance mode
+type MaintenanceStatus struct {
+	// Enabled is true while write endpoints are disabled for maintenance
+	Enabled bool `json:"enabled"`
+}
+
+// Blocklist lists the clients denied access to write endpoints
+type Blocklist struct {
+	// IPs holds blocked client IP addresses and CIDRs
+	IPs []string `json:"ips"`
+	// Users holds blocked user IDs
+	Users []string `json:"users"`
+}
+
+// AuditEventsResponse is a page of stored audit events, newest first
+type AuditEventsResponse struct {
+	// Events holds the events of the page
+	Events []audit.AuditEvent `json:"events"`
+
+	// NextOffset is the offset of the next page, absent on the last page
+	NextOffset int `json:"next_offset,omitempty"`
+}
+
+// HealthResponse reports the health of the service's components
+type HealthResponse struct {
+	// Status is "ok", or "degraded" if a component is failing
+	Status string `json:"status"`
+
+	// Audit holds the delivery statistics of the audit writers
+	Audit []audit.WriterStats `json:"audit"`
+}
+
+// BuildInfo identifies the running binary
+type BuildInfo struct {
+	// Version is the release version
+	Version string `json:"version"`
+
+	// Date is when the binary was built
+	Date string `json:"date"`
+
+	// Commit is the source commit the binary was built from
+	Commit string `json:"commit"`
+}
+
+// ErrorResponse is the JSON body of an error reply
+type ErrorResponse struct {
+	// Error is a human-readable error message
+	Error string `json:"error"`
+
+	// RequestID identifies the failed request for support correlation
+	RequestID string `json:"request_id,omitempty"`
 }
 
 // Common errors
@@ -74,4 +191,7 @@ var (
 
 	// ErrURLDeleted is returned when attempting to access a deleted URL
 	ErrURLDeleted = errors.New("url has been deleted")
+
+	// ErrShortURLConflict is returned when a generated short code is already taken by another URL
+	ErrShortURLConflict = errors.New("short url already taken")
 )
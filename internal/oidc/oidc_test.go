@@ -0,0 +1,88 @@
+package oidc
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestProvider(t *testing.T) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	mux.HandleFunc(discoveryPath, func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(discovery{
+			Issuer:           srv.URL,
+			AuthEndpoint:     srv.URL + "/authorize",
+			TokenEndpoint:    srv.URL + "/token",
+			UserInfoEndpoint: srv.URL + "/userinfo",
+		})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		id, secret, _ := r.BasicAuth()
+		if id != "client" || secret != "secret" || r.FormValue("code") != "good-code" {
+			http.Error(w, "invalid_grant", http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"access_token": "token", "token_type": "Bearer"})
+	})
+	mux.HandleFunc("/userinfo", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"sub": "alice", "email": "alice@example.com"})
+	})
+	return srv
+}
+
+func TestProvider_Flow(t *testing.T) {
+	srv := newTestProvider(t)
+	p, err := Discover(context.Background(), Config{
+		Issuer:       srv.URL + "/",
+		ClientID:     "client",
+		ClientSecret: "secret",
+		RedirectURL:  "http://localhost:8080/auth/callback",
+	})
+	require.NoError(t, err)
+
+	authURL, err := url.Parse(p.AuthCodeURL("xyz"))
+	require.NoError(t, err)
+	assert.Equal(t, "/authorize", authURL.Path)
+	assert.Equal(t, "xyz", authURL.Query().Get("state"))
+	assert.Equal(t, "client", authURL.Query().Get("client_id"))
+	assert.Equal(t, "openid profile email", authURL.Query().Get("scope"))
+
+	identity, err := p.Exchange(context.Background(), "good-code")
+	require.NoError(t, err)
+	assert.Equal(t, "alice", identity.Subject)
+	assert.Equal(t, "alice@example.com", identity.Email)
+
+	again, err := p.Exchange(context.Background(), "good-code")
+	require.NoError(t, err)
+	assert.Equal(t, identity.UserID(), again.UserID())
+
+	_, err = p.Exchange(context.Background(), "bad-code")
+	assert.Error(t, err)
+}
+
+func TestDiscover_IssuerMismatch(t *testing.T) {
+	srv := newTestProvider(t)
+	_, err := Discover(context.Background(), Config{Issuer: srv.URL + "/tenant"})
+	assert.Error(t, err)
+}
+
+func TestIdentity_UserID(t *testing.T) {
+	a := Identity{Issuer: "https://a.example.com", Subject: "alice"}
+	b := Identity{Issuer: "https://b.example.com", Subject: "alice"}
+	assert.Equal(t, a.UserID(), a.UserID())
+	assert.NotEqual(t, a.UserID(), b.UserID())
+}
@@ -0,0 +1,199 @@
+// Package profiling captures profiles of the running server when it
+// degrades, to catch performance regressions that only production load
+// brings out and that are gone by the time someone opens /debug/pprof.
+//
+// The Watchdog samples the request latency and the goroutine count; when
+// either stays above its threshold for a sustained period, it writes a CPU,
+// a heap and a goroutine profile to a directory, from where they can be
+// inspected with "go tool pprof" or shipped to profile storage.
+package profiling
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/pprof"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/Aleksey170999/go-shortener/internal/jobs"
+	"go.uber.org/zap"
+)
+
+// CheckInterval is how often the Watchdog job compares the samples against
+// the thresholds. A threshold counts as exceeded for the whole interval.
+const CheckInterval = 10 * time.Second
+
+// maxSamples bounds the latencies kept between two checks; under heavier
+// load the most recent ones are kept.
+const maxSamples = 8192
+
+// Config describes when and where the Watchdog captures profiles.
+type Config struct {
+	Dir         string        // Directory the profiles are written to, created if missing
+	LatencyP99  time.Duration // p99 request latency that triggers a capture, 0 disables the trigger
+	Goroutines  int           // Goroutine count that triggers a capture, 0 disables the trigger
+	Sustain     time.Duration // How long a threshold must be exceeded before a capture
+	Cooldown    time.Duration // Minimum time between two captures
+	CPUDuration time.Duration // Length of the CPU profile
+}
+
+// Watchdog captures profiles when the latency or the goroutine count stays
+// too high. Its Middleware is safe for concurrent use; Check must not be
+// called concurrently, which the jobs.Runner guarantees for its Job.
+type Watchdog struct {
+	cfg          Config
+	logger       *zap.Logger
+	now          func() time.Time
+	numGoroutine func() int
+
+	mu        sync.Mutex
+	latencies []time.Duration // Request latencies since the last check
+	next      int             // Slot overwritten next once latencies is full
+
+	exceededSince time.Time // Start of the current period above a threshold, zero if below
+	lastCapture   time.Time
+}
+
+// NewWatchdog creates a Watchdog for cfg. It samples the latency of the
+// requests passing its Middleware and checks the thresholds when the Job
+// returned by Job runs.
+func NewWatchdog(cfg Config, logger *zap.Logger) *Watchdog {
+	return &Watchdog{
+		cfg:          cfg,
+		logger:       logger,
+		now:          time.Now,
+		numGoroutine: runtime.NumGoroutine,
+	}
+}
+
+// Middleware records the latency of every request.
+func (w *Watchdog) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		next.ServeHTTP(rw, r)
+		w.observe(time.Since(start))
+	})
+}
+
+// observe records the latency d of a request.
+func (w *Watchdog) observe(d time.Duration) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if len(w.latencies) < maxSamples {
+		w.latencies = append(w.latencies, d)
+		return
+	}
+	w.latencies[w.next] = d
+	w.next = (w.next + 1) % maxSamples
+}
+
+// p99 returns the 99th percentile of the latencies recorded since the
+// previous call and starts a new window; ok is false if there were none.
+func (w *Watchdog) p99() (p99 time.Duration, ok bool) {
+	w.mu.Lock()
+	latencies := w.latencies
+	w.latencies, w.next = make([]time.Duration, 0, len(latencies)), 0
+	w.mu.Unlock()
+
+	if len(latencies) == 0 {
+		return 0, false
+	}
+	slices.Sort(latencies)
+	return latencies[(len(latencies)-1)*99/100], true
+}
+
+// Job returns the job checking the thresholds every CheckInterval.
+func (w *Watchdog) Job() jobs.Job {
+	return jobs.Job{Name: "profile-watchdog", Schedule: jobs.Every(CheckInterval), Run: w.Check}
+}
+
+// Check compares the latencies recorded since the previous check and the
+// current goroutine count against the thresholds, and captures profiles
+// once a threshold has been exceeded at every check for Sustain, unless
+// the previous capture was less than Cooldown ago. The CPU profile takes
+// CPUDuration, during which Check blocks; ctx cuts it short.
+func (w *Watchdog) Check(ctx context.Context) error {
+	now := w.now()
+	var reasons []string
+	if p99, ok := w.p99(); ok && w.cfg.LatencyP99 > 0 && p99 > w.cfg.LatencyP99 {
+		reasons = append(reasons, fmt.Sprintf("p99 latency %s above %s", p99, w.cfg.LatencyP99))
+	}
+	if n := w.numGoroutine(); w.cfg.Goroutines > 0 && n > w.cfg.Goroutines {
+		reasons = append(reasons, fmt.Sprintf("%d goroutines above %d", n, w.cfg.Goroutines))
+	}
+	if len(reasons) == 0 {
+		w.exceededSince = time.Time{}
+		return nil
+	}
+	if w.exceededSince.IsZero() {
+		w.exceededSince = now
+	}
+	if now.Sub(w.exceededSince) < w.cfg.Sustain {
+		return nil
+	}
+	if !w.lastCapture.IsZero() && now.Sub(w.lastCapture) < w.cfg.Cooldown {
+		return nil
+	}
+	w.lastCapture = now
+	w.logger.Warn("capturing profiles", zap.Strings("reasons", reasons), zap.String("dir", w.cfg.Dir))
+	return w.capture(ctx, now)
+}
+
+// capture writes the goroutine, heap and CPU profiles, in this order so
+// that the first two show the state that triggered the capture. The files
+// are named after now, e.g. 20261016T120000Z-cpu.pprof. A failed profile
+// does not keep the others from being written.
+func (w *Watchdog) capture(ctx context.Context, now time.Time) error {
+	if err := os.MkdirAll(w.cfg.Dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create profile directory: %w", err)
+	}
+	prefix := filepath.Join(w.cfg.Dir, now.UTC().Format("20060102T150405Z"))
+	return errors.Join(
+		w.writeProfile(prefix+"-goroutine.pprof", func(f *os.File) error {
+			return pprof.Lookup("goroutine").WriteTo(f, 0)
+		}),
+		w.writeProfile(prefix+"-heap.pprof", func(f *os.File) error {
+			return pprof.Lookup("heap").WriteTo(f, 0)
+		}),
+		w.writeProfile(prefix+"-cpu.pprof", func(f *os.File) error {
+			// Fails while /debug/pprof/profile is recording, as only one
+			// CPU profile can run at a time.
+			if err := pprof.StartCPUProfile(f); err != nil {
+				return err
+			}
+			defer pprof.StopCPUProfile()
+			timer := time.NewTimer(w.cfg.CPUDuration)
+			defer timer.Stop()
+			select {
+			case <-timer.C:
+			case <-ctx.Done():
+			}
+			return nil
+		}),
+	)
+}
+
+// writeProfile creates the file at path and writes a profile to it with
+// write. The file is removed if that fails.
+func (w *Watchdog) writeProfile(path string, write func(f *os.File) error) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create profile: %w", err)
+	}
+	err = write(f)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(path)
+		return fmt.Errorf("failed to write %s: %w", filepath.Base(path), err)
+	}
+	w.logger.Info("profile captured", zap.String("path", path))
+	return nil
+}
@@ -0,0 +1,116 @@
+package profiling
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestWatchdog_P99(t *testing.T) {
+	w := NewWatchdog(Config{}, zap.NewNop())
+	_, ok := w.p99()
+	assert.False(t, ok, "no requests, no latency")
+
+	for i := 1; i <= 100; i++ {
+		w.observe(time.Duration(i) * time.Millisecond)
+	}
+	p99, ok := w.p99()
+	require.True(t, ok)
+	assert.Equal(t, 99*time.Millisecond, p99)
+
+	_, ok = w.p99()
+	assert.False(t, ok, "every check starts a new window")
+
+	for i := 0; i < maxSamples+10; i++ {
+		w.observe(time.Millisecond)
+	}
+	assert.Len(t, w.latencies, maxSamples)
+}
+
+func TestWatchdog_Middleware(t *testing.T) {
+	w := NewWatchdog(Config{}, zap.NewNop())
+	h := w.Middleware(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		time.Sleep(5 * time.Millisecond)
+	}))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	p99, ok := w.p99()
+	require.True(t, ok)
+	assert.GreaterOrEqual(t, p99, 5*time.Millisecond)
+}
+
+func TestWatchdog_Check(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "profiles")
+	w := NewWatchdog(Config{
+		Dir:         dir,
+		Goroutines:  100,
+		Sustain:     time.Minute,
+		Cooldown:    time.Hour,
+		CPUDuration: 10 * time.Millisecond,
+	}, zap.NewNop())
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	goroutines := 200
+	w.now = func() time.Time { return now }
+	w.numGoroutine = func() int { return goroutines }
+	profiles := func() []string {
+		names, _ := filepath.Glob(filepath.Join(dir, "*.pprof"))
+		return names
+	}
+	check := func(after time.Duration) {
+		t.Helper()
+		now = now.Add(after)
+		require.NoError(t, w.Check(context.Background()))
+	}
+
+	check(0)
+	check(30 * time.Second)
+	assert.Empty(t, profiles(), "threshold not exceeded for long enough yet")
+
+	goroutines = 50
+	check(20 * time.Second)
+	goroutines = 200
+	check(20 * time.Second)
+	check(50 * time.Second)
+	assert.Empty(t, profiles(), "a check below the threshold restarts the period")
+
+	check(10 * time.Second)
+	assert.ElementsMatch(t, []string{
+		filepath.Join(dir, "20261016T120210Z-goroutine.pprof"),
+		filepath.Join(dir, "20261016T120210Z-heap.pprof"),
+		filepath.Join(dir, "20261016T120210Z-cpu.pprof"),
+	}, profiles())
+	for _, name := range profiles() {
+		info, err := os.Stat(name)
+		require.NoError(t, err)
+		assert.NotZero(t, info.Size(), name)
+	}
+
+	check(10 * time.Minute)
+	assert.Len(t, profiles(), 3, "no capture during the cooldown")
+	check(time.Hour)
+	assert.Len(t, profiles(), 6, "captured again after the cooldown")
+}
+
+func TestWatchdog_CheckLatency(t *testing.T) {
+	dir := t.TempDir()
+	w := NewWatchdog(Config{Dir: dir, LatencyP99: 100 * time.Millisecond, CPUDuration: time.Millisecond}, zap.NewNop())
+
+	w.observe(50 * time.Millisecond)
+	require.NoError(t, w.Check(context.Background()))
+	require.NoError(t, w.Check(context.Background()))
+	names, _ := filepath.Glob(filepath.Join(dir, "*.pprof"))
+	assert.Empty(t, names, "latency below the threshold, and no requests since")
+
+	w.observe(200 * time.Millisecond)
+	require.NoError(t, w.Check(context.Background()))
+	names, _ = filepath.Glob(filepath.Join(dir, "*.pprof"))
+	assert.Len(t, names, 3)
+}
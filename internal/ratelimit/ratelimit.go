@@ -0,0 +1,95 @@
+// Package ratelimit implements a token bucket rate limiter keyed by string,
+// shared by the per-IP middleware and the per-user limit of URLService.
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// sweepInterval is how often idle buckets are dropped from the limiter.
+const sweepInterval = time.Minute
+
+// bucket is the state of a single key's token bucket.
+type bucket struct {
+	tokens float64   // Tokens available at time last
+	last   time.Time // When tokens was last updated
+}
+
+// Limiter keeps a token bucket per key. Every bucket holds up to burst tokens
+// and refills at rate tokens per second. Buckets that have refilled
+// completely are dropped periodically, since a new bucket starts full anyway.
+// A nil *Limiter allows every call.
+type Limiter struct {
+	rate      float64
+	burst     float64
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+// New creates a limiter with full buckets. It returns nil, which allows every
+// call, if rate or burst is not positive.
+func New(rate float64, burst int) *Limiter {
+	if rate <= 0 || burst <= 0 {
+		return nil
+	}
+	return &Limiter{
+		rate:    rate,
+		burst:   float64(burst),
+		buckets: make(map[string]*bucket),
+		now:     time.Now,
+	}
+}
+
+// Allow takes a token from key's bucket and reports whether one was
+// available. If not, it also returns how long until the bucket holds a
+// token again.
+func (l *Limiter) Allow(key string) (bool, time.Duration) {
+	if l == nil {
+		return true, 0
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	if now.Sub(l.lastSweep) >= sweepInterval {
+		l.sweep(now)
+	}
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: l.burst, last: now}
+		l.buckets[key] = b
+	}
+	b.tokens = l.refill(b, now)
+	b.last = now
+	if b.tokens < 1 {
+		return false, time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+	}
+	b.tokens--
+	return true, 0
+}
+
+// refill returns the number of tokens in b at now.
+func (l *Limiter) refill(b *bucket, now time.Time) float64 {
+	return math.Min(b.tokens+now.Sub(b.last).Seconds()*l.rate, l.burst)
+}
+
+// sweep drops buckets that are full again. The caller must hold l.mu.
+func (l *Limiter) sweep(now time.Time) {
+	for key, b := range l.buckets {
+		if l.refill(b, now) >= l.burst {
+			delete(l.buckets, key)
+		}
+	}
+	l.lastSweep = now
+}
+
+// RetryAfterSeconds rounds the wait returned by Allow up to whole seconds, as
+// required by the Retry-After header. The result is at least 1.
+func RetryAfterSeconds(wait time.Duration) int {
+	return max(int(math.Ceil(wait.Seconds())), 1)
+}
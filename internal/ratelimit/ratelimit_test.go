@@ -0,0 +1,66 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLimiter(t *testing.T) {
+	now := time.Now()
+	l := New(1, 2)
+	l.now = func() time.Time { return now }
+
+	ok, _ := l.Allow("a")
+	require.True(t, ok)
+	ok, _ = l.Allow("a")
+	require.True(t, ok)
+	ok, wait := l.Allow("a")
+	assert.False(t, ok)
+	assert.Equal(t, time.Second, wait)
+	ok, _ = l.Allow("b")
+	assert.True(t, ok, "buckets are per key")
+
+	now = now.Add(time.Second)
+	ok, _ = l.Allow("a")
+	assert.True(t, ok)
+	ok, _ = l.Allow("a")
+	assert.False(t, ok)
+
+	now = now.Add(sweepInterval)
+	ok, _ = l.Allow("c")
+	require.True(t, ok)
+	assert.NotContains(t, l.buckets, "a", "refilled buckets are swept")
+	assert.Contains(t, l.buckets, "c")
+}
+
+func TestLimiter_SlowRate(t *testing.T) {
+	now := time.Now()
+	l := New(0.1, 1)
+	l.now = func() time.Time { return now }
+
+	ok, _ := l.Allow("a")
+	require.True(t, ok)
+	now = now.Add(2500 * time.Millisecond)
+	ok, wait := l.Allow("a")
+	assert.False(t, ok)
+	assert.Equal(t, 7500*time.Millisecond, wait, "the deficit of 0.75 tokens refills in 7.5s")
+	assert.Equal(t, 8, RetryAfterSeconds(wait))
+}
+
+func TestLimiter_Disabled(t *testing.T) {
+	for _, l := range []*Limiter{New(0, 1), New(1, 0)} {
+		assert.Nil(t, l)
+		ok, _ := l.Allow("a")
+		assert.True(t, ok)
+	}
+}
+
+func TestRetryAfterSeconds(t *testing.T) {
+	assert.Equal(t, 1, RetryAfterSeconds(0))
+	assert.Equal(t, 1, RetryAfterSeconds(200*time.Millisecond))
+	assert.Equal(t, 1, RetryAfterSeconds(time.Second))
+	assert.Equal(t, 2, RetryAfterSeconds(1001*time.Millisecond))
+}
@@ -0,0 +1,52 @@
+// Package reporting sends errors to Sentry or a Sentry-compatible service,
+// such as GlitchTip: handler panics and 5xx responses, reported by
+// middlewares.ErrorReporting with the request they occurred in, and failures
+// of background work, such as the periodic jobs and the audit writers,
+// reported with Error.
+//
+// Until Setup is called, the global hub has no client and reporting is a
+// no-op, so callers need not check whether it is enabled.
+package reporting
+
+import (
+	"context"
+
+	"github.com/getsentry/sentry-go"
+)
+
+// Config describes where and how errors are reported.
+type Config struct {
+	DSN         string // Sentry DSN, including the project key
+	Environment string // Environment reported with every event, e.g. "production"
+	Release     string // Version reported with every event
+}
+
+// Setup installs a global Sentry client sending events to cfg.DSN. The
+// returned function sends the pending events; it must be called before the
+// process exits, and returns ctx.Err() if ctx is done first.
+func Setup(cfg Config) (func(context.Context) error, error) {
+	err := sentry.Init(sentry.ClientOptions{
+		Dsn:              cfg.DSN,
+		Environment:      cfg.Environment,
+		Release:          cfg.Release,
+		AttachStacktrace: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return func(ctx context.Context) error {
+		if !sentry.FlushWithContext(ctx) {
+			return ctx.Err()
+		}
+		return nil
+	}, nil
+}
+
+// Error reports the failure of a background worker. The event is tagged
+// with worker, e.g. "job:cleanup" or "audit:file".
+func Error(worker string, err error) {
+	sentry.WithScope(func(scope *sentry.Scope) {
+		scope.SetTag("worker", worker)
+		sentry.CaptureException(err)
+	})
+}
@@ -0,0 +1,47 @@
+package reporting
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/getsentry/sentry-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestError(t *testing.T) {
+	transport := &sentry.MockTransport{}
+	client, err := sentry.NewClient(sentry.ClientOptions{Dsn: "https://key@sentry.example.com/1", Transport: transport})
+	require.NoError(t, err)
+	hub := sentry.CurrentHub()
+	previous := hub.Client()
+	hub.BindClient(client)
+	t.Cleanup(func() { hub.BindClient(previous) })
+
+	Error("job:cleanup", errors.New("purge: db down"))
+	sentry.CaptureMessage("unrelated")
+
+	events := transport.Events()
+	require.Len(t, events, 2)
+	assert.Equal(t, "job:cleanup", events[0].Tags["worker"])
+	require.Len(t, events[0].Exception, 1)
+	assert.Equal(t, "purge: db down", events[0].Exception[0].Value)
+	assert.NotContains(t, events[1].Tags, "worker", "the tag does not leak into other events")
+}
+
+func TestSetup_InvalidDSN(t *testing.T) {
+	_, err := Setup(Config{DSN: "not a dsn"})
+	assert.Error(t, err)
+}
+
+func TestSetup_Flush(t *testing.T) {
+	flush, err := Setup(Config{DSN: "https://key@sentry.example.com/1", Environment: "test", Release: "v1.0.0"})
+	require.NoError(t, err)
+	t.Cleanup(func() { sentry.CurrentHub().BindClient(nil) })
+
+	opts := sentry.CurrentHub().Client().Options()
+	assert.Equal(t, "test", opts.Environment)
+	assert.Equal(t, "v1.0.0", opts.Release)
+	assert.NoError(t, flush(context.Background()))
+}
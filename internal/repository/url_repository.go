@@ -1,10 +1,14 @@
 package repository
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"log"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/Aleksey170999/go-shortener/internal/config"
 	db "github.com/Aleksey170999/go-shortener/internal/config/db"
@@ -13,38 +17,143 @@ import (
 	"github.com/lib/pq"
 )
 
+const (
+	// uniqueViolationCode is the PostgreSQL error code for unique constraint violations.
+	uniqueViolationCode = "23505"
+	// shortURLIndexName is the unique index guarding short_url against collisions.
+	shortURLIndexName = "unique_short_url"
+	// originalURLIndexName is the unique index enforcing global deduplication of original URLs.
+	originalURLIndexName = "unique_orig_name"
+)
+
 // URLRepository defines the interface for URL storage operations.
 // Implementations must be safe for concurrent use by multiple goroutines.
+// The context passed to each method carries the caller's deadline and cancellation.
 type URLRepository interface {
 	// Save stores a new URL or returns an existing one if the original URL already exists.
+	// Returns model.ErrShortURLConflict if the short identifier is taken by another URL.
 	// Returns the saved URL and any error encountered.
-	Save(url *model.URL) (*model.URL, error)
+	Save(ctx context.Context, url *model.URL) (*model.URL, error)
+
+	// SaveBatch stores multiple URLs in a single operation.
+	// The returned slice holds one error per input URL, in the same order:
+	// nil if the URL was stored, model.ErrURLAlreadyExists if the original URL is
+	// already known (the URL is then updated with the existing ID and short code),
+	// or model.ErrShortURLConflict if the short identifier is taken.
+	// The second return value reports a failure of the whole batch.
+	SaveBatch(ctx context.Context, urls []*model.URL) ([]error, error)
 
 	// GetByShortURL retrieves a URL by its short identifier.
 	// Returns ErrNotFound if no URL with the given short identifier exists.
-	GetByShortURL(shortURL string) (*model.URL, error)
+	GetByShortURL(ctx context.Context, shortURL string) (*model.URL, error)
 
 	// GetByUserID retrieves all URLs created by a specific user.
 	// Returns an empty slice if no URLs are found for the user.
-	GetByUserID(userID string) ([]model.URL, error)
+	GetByUserID(ctx context.Context, userID string) ([]model.URL, error)
 
 	// BatchDelete marks multiple URLs as deleted for a specific user.
 	// This is a soft delete operation that sets the IsDeleted flag on the URLs.
 	// ShortURLs that don't belong to the user or don't exist are silently ignored.
-	BatchDelete(shortURLs []string, userID string) error
+	BatchDelete(ctx context.Context, shortURLs []string, userID string) error
+
+	// Purge permanently removes URLs that expired before expiredBefore and
+	// soft-deleted URLs whose deletion happened before deletedBefore.
+	// When dryRun is true nothing is removed and only the counts are reported.
+	Purge(ctx context.Context, expiredBefore, deletedBefore time.Time, dryRun bool) (PurgeStats, error)
+
+	// PurgeMatching permanently removes the URLs selected by filter and returns them.
+	// When dryRun is true nothing is removed and the matching URLs are only returned.
+	// Returns ErrEmptyPurgeFilter if filter would select every URL.
+	PurgeMatching(ctx context.Context, filter PurgeFilter, dryRun bool) ([]model.URL, error)
+
+	// AddClickStats adds the given click counts to the stored per-bucket totals.
+	AddClickStats(ctx context.Context, stats []model.ClickStat) error
+}
+
+// Lister is implemented by repositories that can enumerate their URLs, e.g.
+// for exporting them. It is not part of URLRepository so that embedders do
+// not have to support it.
+type Lister interface {
+	// List returns all URLs, soft-deleted ones included, in no particular order.
+	List(ctx context.Context) ([]model.URL, error)
+}
+
+// Importer is implemented by repositories whose SaveBatch does not keep the
+// whole state of a URL, to store URLs migrated from another repository
+// with their creation, expiration and deletion. Repositories that keep the
+// whole state anyway, as the in-memory one, need not implement it.
+type Importer interface {
+	// Import stores urls like SaveBatch, with the same per-item results,
+	// keeping their CreatedAt, ExpiresAt, IsDeleted and DeletedAt.
+	Import(ctx context.Context, urls []*model.URL) ([]error, error)
+}
+
+// DedupReporter is implemented by repositories that deduplicate original
+// URLs, so that the service follows the policy the repository enforces
+// instead of being configured separately.
+type DedupReporter interface {
+	// PerUserDedup reports whether original URLs are unique per user
+	// instead of globally.
+	PerUserDedup() bool
+}
+
+// PurgeStats reports how many URLs a Purge call removed (or would remove in dry-run mode).
+type PurgeStats struct {
+	Expired int64    // URLs removed because they expired
+	Deleted int64    // Soft-deleted URLs removed after the retention period
+	Shorts  []string // Short codes of the removed URLs, nil in dry-run mode
+}
+
+// ErrEmptyPurgeFilter is returned by PurgeMatching for a filter without any time bound.
+var ErrEmptyPurgeFilter = errors.New("purge filter selects every url")
+
+// PurgeFilter selects URLs for PurgeMatching. All set conditions must hold,
+// and at least one of CreatedBefore and DeletedBefore must be set.
+type PurgeFilter struct {
+	CreatedBefore time.Time // Only URLs created before this time; zero disables the condition
+	DeletedBefore time.Time // Only URLs soft-deleted before this time; zero disables the condition
+	Anonymous     bool      // Only URLs without an owner
+}
+
+// empty reports whether the filter has no time bound.
+func (f PurgeFilter) empty() bool {
+	return f.CreatedBefore.IsZero() && f.DeletedBefore.IsZero()
+}
+
+// match reports whether url is selected by the filter. URLs with an unknown
+// creation time never match CreatedBefore.
+func (f PurgeFilter) match(url *model.URL) bool {
+	if f.Anonymous && url.UserID != "" {
+		return false
+	}
+	if !f.CreatedBefore.IsZero() && (url.CreatedAt.IsZero() || !url.CreatedAt.Before(f.CreatedBefore)) {
+		return false
+	}
+	if !f.DeletedBefore.IsZero() && !(url.IsDeleted && url.DeletedAt != nil && url.DeletedAt.Before(f.DeletedBefore)) {
+		return false
+	}
+	return true
 }
 
 // memoryURLRepository is an in-memory implementation of URLRepository.
 // It stores URLs in a map and is safe for concurrent access.
 type memoryURLRepository struct {
-	data map[string]*model.URL
-	mu   sync.RWMutex
+	data   map[string]*model.URL
+	clicks map[clickKey]int64
+	mu     sync.RWMutex
+}
+
+// clickKey identifies a click counter in memoryURLRepository.
+type clickKey struct {
+	short  string
+	bucket time.Time
 }
 
 // DataBaseURLRepository is a PostgreSQL implementation of URLRepository.
 // It stores URLs in a PostgreSQL database and handles all SQL operations.
 type DataBaseURLRepository struct {
-	DB *sql.DB
+	DB           *sql.DB
+	perUserDedup bool // Original URLs are unique per user instead of globally
 }
 
 // NewMemoryURLRepository creates a new in-memory URL repository.
@@ -54,7 +163,8 @@ type DataBaseURLRepository struct {
 //   - *memoryURLRepository: A new instance of in-memory URL repository
 func NewMemoryURLRepository() *memoryURLRepository {
 	repo := memoryURLRepository{
-		data: make(map[string]*model.URL),
+		data:   make(map[string]*model.URL),
+		clicks: make(map[clickKey]int64),
 	}
 	return &repo
 }
@@ -64,39 +174,122 @@ func NewMemoryURLRepository() *memoryURLRepository {
 //
 // Parameters:
 //   - cfg: Application configuration containing database connection details
+//     and the deduplication policy (cfg.DedupPerUser)
 //
 // Returns:
 //   - *DataBaseURLRepository: A new instance of database URL repository
-//   - error: If database connection fails
-func NewDataBaseURLRepository(cfg *config.Config) *DataBaseURLRepository {
+//   - error: If the database cannot be opened, migrated or set up for the
+//     deduplication policy
+func NewDataBaseURLRepository(cfg *config.Config) (*DataBaseURLRepository, error) {
 	dbCon, err := sql.Open("postgres", cfg.DatabaseDSN)
 	if err != nil {
-		fmt.Println(err)
+		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
 	repo := DataBaseURLRepository{
-		DB: dbCon,
+		DB:           dbCon,
+		perUserDedup: cfg.DedupPerUser,
 	}
 
-	db.ApplyMigrations(dbCon)
-	return &repo
+	if err := db.ApplyMigrations(dbCon); err != nil {
+		dbCon.Close()
+		return nil, err
+	}
+	if err := repo.applyDedupPolicy(context.Background()); err != nil {
+		dbCon.Close()
+		return nil, err
+	}
+	return &repo, nil
+}
+
+// Close closes the database connection pool. It is called on shutdown,
+// after in-flight requests, queued deletions and audit events are done.
+func (r *DataBaseURLRepository) Close() error {
+	return r.DB.Close()
+}
+
+// PerUserDedup reports whether original URLs are unique per user instead
+// of globally.
+//
+// Implements DedupReporter interface.
+func (r *DataBaseURLRepository) PerUserDedup() bool {
+	return r.perUserDedup
+}
+
+// applyDedupPolicy makes the global unique index on original_url match the
+// configured policy. The per-user index created by the migrations is always
+// present; the global one is dropped in per-user mode and restored otherwise.
+// Restoring fails if different users have stored the same original URL; as
+// Save then has no index to resolve conflicts on original_url with, the
+// repository must not be used in that case.
+func (r *DataBaseURLRepository) applyDedupPolicy(ctx context.Context) error {
+	query := `CREATE UNIQUE INDEX IF NOT EXISTS ` + originalURLIndexName + ` ON urls (original_url)`
+	if r.perUserDedup {
+		query = `DROP INDEX IF EXISTS ` + originalURLIndexName
+	}
+	if _, err := r.DB.ExecContext(ctx, query); err != nil {
+		return fmt.Errorf("failed to apply deduplication policy: %w", err)
+	}
+	return nil
+}
+
+// originalConflictTarget returns the ON CONFLICT target identifying a
+// duplicate original URL under the configured deduplication policy.
+func (r *DataBaseURLRepository) originalConflictTarget() string {
+	if r.perUserDedup {
+		return "(user_id, original_url)"
+	}
+	return "(original_url)"
+}
+
+// originalScope returns the WHERE condition matching rows that count as the
+// same original URL; $n and $m are the original URL and user ID parameters.
+// The user ID parameter is only referenced in per-user mode.
+func (r *DataBaseURLRepository) originalScope(n, m int) string {
+	if r.perUserDedup {
+		return fmt.Sprintf("original_url = $%d AND user_id = $%d", n, m)
+	}
+	return fmt.Sprintf("original_url = $%d", n)
 }
 
 // Save stores a URL in the in-memory repository.
-// If a URL with the same original URL already exists, it returns the existing URL.
+// If the short identifier is already used by a different URL, it returns
+// model.ErrShortURLConflict instead of overwriting the existing entry.
 //
 // Implements URLRepository interface.
-func (r *memoryURLRepository) Save(url *model.URL) (*model.URL, error) {
+func (r *memoryURLRepository) Save(_ context.Context, url *model.URL) (*model.URL, error) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
+	if existing, exists := r.data[url.Short]; exists && existing.ID != url.ID {
+		return nil, model.ErrShortURLConflict
+	}
 	r.data[url.Short] = url
 	return url, nil
 }
 
+// SaveBatch stores multiple URLs in the in-memory repository under a single lock.
+// Per-item results follow the same rules as Save.
+//
+// Implements URLRepository interface.
+func (r *memoryURLRepository) SaveBatch(_ context.Context, urls []*model.URL) ([]error, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	errs := make([]error, len(urls))
+	for i, url := range urls {
+		if existing, exists := r.data[url.Short]; exists && existing.ID != url.ID {
+			errs[i] = model.ErrShortURLConflict
+			continue
+		}
+		r.data[url.Short] = url
+	}
+	return errs, nil
+}
+
 // GetByShortURL retrieves a URL by its short identifier from memory.
 // Returns ErrNotFound if no URL with the given ID exists.
 //
 // Implements URLRepository interface.
-func (r *memoryURLRepository) GetByShortURL(id string) (*model.URL, error) {
+func (r *memoryURLRepository) GetByShortURL(_ context.Context, id string) (*model.URL, error) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
@@ -111,7 +304,7 @@ func (r *memoryURLRepository) GetByShortURL(id string) (*model.URL, error) {
 // Returns an empty slice if no URLs are found for the user.
 //
 // Implements URLRepository interface.
-func (r *memoryURLRepository) GetByUserID(userID string) ([]model.URL, error) {
+func (r *memoryURLRepository) GetByUserID(_ context.Context, userID string) ([]model.URL, error) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
@@ -129,18 +322,34 @@ func (r *memoryURLRepository) GetByUserID(userID string) ([]model.URL, error) {
 	return userURLs, nil
 }
 
+// List returns all URLs in memory.
+//
+// Implements Lister interface.
+func (r *memoryURLRepository) List(_ context.Context) ([]model.URL, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	urls := make([]model.URL, 0, len(r.data))
+	for _, url := range r.data {
+		urls = append(urls, *url)
+	}
+	return urls, nil
+}
+
 // BatchDelete marks multiple URLs as deleted for a specific user in memory.
 // This is a soft delete operation that sets the IsDeleted flag on the URLs.
 // ShortURLs that don't belong to the user or don't exist are silently ignored.
 // Implements URLRepository interface with in-memory implementation.
-func (r *memoryURLRepository) BatchDelete(shortURLs []string, userID string) error {
+func (r *memoryURLRepository) BatchDelete(_ context.Context, shortURLs []string, userID string) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
 	for _, short := range shortURLs {
 		if url, exists := r.data[short]; exists {
 			if url.UserID == userID && !url.IsDeleted {
+				now := time.Now()
 				url.IsDeleted = true
+				url.DeletedAt = &now
 				r.data[short] = url
 			}
 		}
@@ -149,25 +358,92 @@ func (r *memoryURLRepository) BatchDelete(shortURLs []string, userID string) err
 	return nil
 }
 
+// Purge permanently removes expired and long-deleted URLs from memory.
+//
+// Implements URLRepository interface.
+func (r *memoryURLRepository) Purge(_ context.Context, expiredBefore, deletedBefore time.Time, dryRun bool) (PurgeStats, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var stats PurgeStats
+	for short, url := range r.data {
+		switch {
+		case url.ExpiresAt != nil && url.ExpiresAt.Before(expiredBefore):
+			stats.Expired++
+		case url.IsDeleted && url.DeletedAt != nil && url.DeletedAt.Before(deletedBefore):
+			stats.Deleted++
+		default:
+			continue
+		}
+		if !dryRun {
+			delete(r.data, short)
+			stats.Shorts = append(stats.Shorts, short)
+		}
+	}
+	return stats, nil
+}
+
+// PurgeMatching removes the URLs selected by filter from memory.
+//
+// Implements URLRepository interface.
+func (r *memoryURLRepository) PurgeMatching(_ context.Context, filter PurgeFilter, dryRun bool) ([]model.URL, error) {
+	if filter.empty() {
+		return nil, ErrEmptyPurgeFilter
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var removed []model.URL
+	for short, url := range r.data {
+		if !filter.match(url) {
+			continue
+		}
+		removed = append(removed, *url)
+		if !dryRun {
+			delete(r.data, short)
+		}
+	}
+	return removed, nil
+}
+
+// AddClickStats adds click counts to the in-memory per-bucket totals.
+//
+// Implements URLRepository interface.
+func (r *memoryURLRepository) AddClickStats(_ context.Context, stats []model.ClickStat) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, stat := range stats {
+		r.clicks[clickKey{short: stat.Short, bucket: stat.Bucket.UTC()}] += stat.Clicks
+	}
+	return nil
+}
+
 // Save stores a URL in the database.
 // If a URL with the same original URL already exists, it returns the existing URL.
+// With per-user deduplication only URLs of the same user are considered.
+// A unique violation on the short_url column is reported as model.ErrShortURLConflict.
 // Implements URLRepository interface with PostgreSQL-specific implementation.
-func (r *DataBaseURLRepository) Save(url *model.URL) (*model.URL, error) {
+func (r *DataBaseURLRepository) Save(ctx context.Context, url *model.URL) (*model.URL, error) {
 	var isConflict bool
 	insertSQL := `WITH inserted AS (
 						INSERT INTO urls (id, short_url, original_url, user_id)
 						VALUES ($1, $2, $3, $4)
-						ON CONFLICT (original_url) DO NOTHING
+						ON CONFLICT ` + r.originalConflictTarget() + ` DO NOTHING
 						RETURNING *
 					)
 					select id, short_url, false as is_conflict FROM inserted
 					UNION
 					SELECT id, short_url, true as is_conflict FROM urls 
-					WHERE original_url = $3 AND NOT EXISTS (SELECT 1 FROM inserted)`
-	err := r.DB.QueryRow(insertSQL, url.ID, url.Short, url.Original, url.UserID).
+					WHERE ` + r.originalScope(3, 4) + ` AND NOT EXISTS (SELECT 1 FROM inserted)`
+	err := r.DB.QueryRowContext(ctx, insertSQL, url.ID, url.Short, url.Original, url.UserID).
 		Scan(&url.ID, &url.Short, &isConflict)
 
 	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == uniqueViolationCode && pqErr.Constraint == shortURLIndexName {
+			return nil, model.ErrShortURLConflict
+		}
 		return nil, err
 	}
 	if isConflict {
@@ -176,13 +452,100 @@ func (r *DataBaseURLRepository) Save(url *model.URL) (*model.URL, error) {
 	return url, nil
 }
 
+// SaveBatch stores multiple URLs in the database within a single transaction.
+// Rows that hit any unique constraint are skipped instead of aborting the
+// transaction; each skipped row is then classified as an existing original URL
+// or a short code collision.
+// Implements URLRepository interface with PostgreSQL-specific implementation.
+func (r *DataBaseURLRepository) SaveBatch(ctx context.Context, urls []*model.URL) ([]error, error) {
+	return r.insertBatch(ctx, urls, `INSERT INTO urls (id, short_url, original_url, user_id)
+						VALUES ($1, $2, $3, $4)
+						ON CONFLICT DO NOTHING`,
+		func(url *model.URL) []any {
+			return []any{url.ID, url.Short, url.Original, url.UserID}
+		})
+}
+
+// Import stores URLs like SaveBatch, along with their creation time,
+// expiration and deletion state; a zero CreatedAt becomes the current time.
+// Implements Importer interface with PostgreSQL-specific implementation.
+func (r *DataBaseURLRepository) Import(ctx context.Context, urls []*model.URL) ([]error, error) {
+	return r.insertBatch(ctx, urls, `INSERT INTO urls (id, short_url, original_url, user_id, created_at, expires_at, is_deleted, deleted_at)
+						VALUES ($1, $2, $3, $4, COALESCE($5, NOW()), $6, $7, $8)
+						ON CONFLICT DO NOTHING`,
+		func(url *model.URL) []any {
+			var createdAt *time.Time
+			if !url.CreatedAt.IsZero() {
+				createdAt = &url.CreatedAt
+			}
+			return []any{url.ID, url.Short, url.Original, url.UserID, createdAt, url.ExpiresAt, url.IsDeleted, url.DeletedAt}
+		})
+}
+
+// insertBatch inserts urls with the insert statement, which must skip rows
+// that hit a unique constraint, passing it the arguments values returns for
+// each URL. See SaveBatch for the per-item results.
+func (r *DataBaseURLRepository) insertBatch(ctx context.Context, urls []*model.URL, insert string, values func(url *model.URL) []any) ([]error, error) {
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	insertStmt, err := tx.PrepareContext(ctx, insert)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare insert: %w", err)
+	}
+	defer insertStmt.Close()
+
+	selectStmt, err := tx.PrepareContext(ctx, `SELECT id, short_url FROM urls WHERE `+r.originalScope(1, 2))
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare select: %w", err)
+	}
+	defer selectStmt.Close()
+
+	errs := make([]error, len(urls))
+	for i, url := range urls {
+		res, err := insertStmt.ExecContext(ctx, values(url)...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to insert url: %w", err)
+		}
+		inserted, err := res.RowsAffected()
+		if err != nil {
+			return nil, fmt.Errorf("failed to insert url: %w", err)
+		}
+		if inserted == 1 {
+			continue
+		}
+
+		args := []any{url.Original}
+		if r.perUserDedup {
+			args = append(args, url.UserID)
+		}
+		err = selectStmt.QueryRowContext(ctx, args...).Scan(&url.ID, &url.Short)
+		switch {
+		case err == nil:
+			errs[i] = model.ErrURLAlreadyExists
+		case errors.Is(err, sql.ErrNoRows):
+			errs[i] = model.ErrShortURLConflict
+		default:
+			return nil, fmt.Errorf("failed to look up existing url: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit batch: %w", err)
+	}
+	return errs, nil
+}
+
 // GetByShortURL retrieves a URL by its short identifier from the database.
 // Returns ErrNotFound if no URL with the given ID exists.
 // Implements URLRepository interface with PostgreSQL-specific implementation.
-func (r *DataBaseURLRepository) GetByShortURL(id string) (*model.URL, error) {
+func (r *DataBaseURLRepository) GetByShortURL(ctx context.Context, id string) (*model.URL, error) {
 	var url model.URL
-	err := r.DB.QueryRow("SELECT id, short_url, original_url, user_id, is_deleted FROM urls WHERE short_url = $1", id).
-		Scan(&url.ID, &url.Short, &url.Original, &url.UserID, &url.IsDeleted)
+	err := r.DB.QueryRowContext(ctx, "SELECT id, short_url, original_url, user_id, is_deleted, expires_at, deleted_at FROM urls WHERE short_url = $1", id).
+		Scan(&url.ID, &url.Short, &url.Original, &url.UserID, &url.IsDeleted, &url.ExpiresAt, &url.DeletedAt)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, fmt.Errorf("url not found: %w", ErrNotFound)
@@ -195,8 +558,8 @@ func (r *DataBaseURLRepository) GetByShortURL(id string) (*model.URL, error) {
 // GetByUserID retrieves all URLs created by a specific user from the database.
 // Returns an empty slice if no URLs are found for the user.
 // Implements URLRepository interface with PostgreSQL-specific implementation.
-func (r *DataBaseURLRepository) GetByUserID(userID string) ([]model.URL, error) {
-	rows, err := r.DB.Query("SELECT id, short_url, original_url, user_id FROM urls WHERE user_id = $1", userID)
+func (r *DataBaseURLRepository) GetByUserID(ctx context.Context, userID string) ([]model.URL, error) {
+	rows, err := r.DB.QueryContext(ctx, "SELECT id, short_url, original_url, user_id FROM urls WHERE user_id = $1", userID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query user urls: %w", err)
 	}
@@ -222,16 +585,39 @@ func (r *DataBaseURLRepository) GetByUserID(userID string) ([]model.URL, error)
 	return urls, nil
 }
 
+// List returns all URLs in the database.
+// Implements Lister interface with PostgreSQL-specific implementation.
+func (r *DataBaseURLRepository) List(ctx context.Context) ([]model.URL, error) {
+	rows, err := r.DB.QueryContext(ctx, "SELECT id, short_url, original_url, COALESCE(user_id, ''), COALESCE(is_deleted, false), expires_at, deleted_at, created_at FROM urls")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query urls: %w", err)
+	}
+	defer rows.Close()
+
+	var urls []model.URL
+	for rows.Next() {
+		var url model.URL
+		if err := rows.Scan(&url.ID, &url.Short, &url.Original, &url.UserID, &url.IsDeleted, &url.ExpiresAt, &url.DeletedAt, &url.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan url: %w", err)
+		}
+		urls = append(urls, url)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating urls: %w", err)
+	}
+	return urls, nil
+}
+
 // BatchDelete marks multiple URLs as deleted for a specific user in the database.
 // This is a soft delete operation that sets the is_deleted flag on the URLs.
 // ShortURLs that don't belong to the user or don't exist are silently ignored.
 // Implements URLRepository interface with PostgreSQL-specific implementation.
-func (r *DataBaseURLRepository) BatchDelete(shortURLs []string, userID string) error {
+func (r *DataBaseURLRepository) BatchDelete(ctx context.Context, shortURLs []string, userID string) error {
 	if len(shortURLs) == 0 {
 		return nil
 	}
-	query := `UPDATE urls SET is_deleted = TRUE WHERE short_url = ANY($1) AND user_id = $2`
-	_, err := r.DB.Exec(query, pq.Array(shortURLs), userID)
+	query := `UPDATE urls SET is_deleted = TRUE, deleted_at = COALESCE(deleted_at, NOW()) WHERE short_url = ANY($1) AND user_id = $2`
+	_, err := r.DB.ExecContext(ctx, query, pq.Array(shortURLs), userID)
 	if err != nil {
 		log.Printf("BatchDelete error: %v", err)
 		return err
@@ -239,6 +625,146 @@ func (r *DataBaseURLRepository) BatchDelete(shortURLs []string, userID string) e
 	return nil
 }
 
+// Purge permanently removes expired and long-deleted URLs from the database.
+// Both deletions run in a single transaction; in dry-run mode the matching rows
+// are only counted.
+// Implements URLRepository interface with PostgreSQL-specific implementation.
+func (r *DataBaseURLRepository) Purge(ctx context.Context, expiredBefore, deletedBefore time.Time, dryRun bool) (PurgeStats, error) {
+	var stats PurgeStats
+	if dryRun {
+		err := r.DB.QueryRowContext(ctx, `SELECT
+				COUNT(*) FILTER (WHERE expires_at < $1),
+				COUNT(*) FILTER (WHERE NOT (expires_at IS NOT NULL AND expires_at < $1) AND is_deleted AND deleted_at < $2)
+			FROM urls`, expiredBefore, deletedBefore).
+			Scan(&stats.Expired, &stats.Deleted)
+		if err != nil {
+			return stats, fmt.Errorf("failed to count purgeable urls: %w", err)
+		}
+		return stats, nil
+	}
+
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return stats, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	expired, err := purgeShorts(ctx, tx, `DELETE FROM urls WHERE expires_at < $1 RETURNING short_url`, expiredBefore)
+	if err != nil {
+		return stats, fmt.Errorf("failed to purge expired urls: %w", err)
+	}
+	deleted, err := purgeShorts(ctx, tx, `DELETE FROM urls WHERE is_deleted AND deleted_at < $1 RETURNING short_url`, deletedBefore)
+	if err != nil {
+		return stats, fmt.Errorf("failed to purge deleted urls: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return PurgeStats{}, fmt.Errorf("failed to commit purge: %w", err)
+	}
+	stats.Expired, stats.Deleted = int64(len(expired)), int64(len(deleted))
+	stats.Shorts = append(expired, deleted...)
+	return stats, nil
+}
+
+// purgeShorts runs the DELETE ... RETURNING short_url statement query in tx
+// and returns the short codes of the removed rows.
+func purgeShorts(ctx context.Context, tx *sql.Tx, query string, before time.Time) ([]string, error) {
+	rows, err := tx.QueryContext(ctx, query, before)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var shorts []string
+	for rows.Next() {
+		var short string
+		if err := rows.Scan(&short); err != nil {
+			return nil, err
+		}
+		shorts = append(shorts, short)
+	}
+	return shorts, rows.Err()
+}
+
+// PurgeMatching removes the URLs selected by filter from the database with a
+// single DELETE ... RETURNING statement; in dry-run mode they are only selected.
+// Implements URLRepository interface with PostgreSQL-specific implementation.
+func (r *DataBaseURLRepository) PurgeMatching(ctx context.Context, filter PurgeFilter, dryRun bool) ([]model.URL, error) {
+	if filter.empty() {
+		return nil, ErrEmptyPurgeFilter
+	}
+	var conds []string
+	var args []any
+	if !filter.CreatedBefore.IsZero() {
+		args = append(args, filter.CreatedBefore)
+		conds = append(conds, fmt.Sprintf("created_at < $%d", len(args)))
+	}
+	if !filter.DeletedBefore.IsZero() {
+		args = append(args, filter.DeletedBefore)
+		conds = append(conds, fmt.Sprintf("is_deleted AND deleted_at < $%d", len(args)))
+	}
+	if filter.Anonymous {
+		conds = append(conds, "(user_id IS NULL OR user_id = '')")
+	}
+	where := strings.Join(conds, " AND ")
+
+	query := `DELETE FROM urls WHERE ` + where + ` RETURNING id, short_url, original_url, COALESCE(user_id, ''), created_at`
+	if dryRun {
+		query = `SELECT id, short_url, original_url, COALESCE(user_id, ''), created_at FROM urls WHERE ` + where
+	}
+	rows, err := r.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to purge urls: %w", err)
+	}
+	defer rows.Close()
+
+	var removed []model.URL
+	for rows.Next() {
+		var url model.URL
+		if err := rows.Scan(&url.ID, &url.Short, &url.Original, &url.UserID, &url.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan purged url: %w", err)
+		}
+		removed = append(removed, url)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to purge urls: %w", err)
+	}
+	return removed, nil
+}
+
+// AddClickStats upserts click counts into the url_clicks table within a single
+// transaction, adding to any totals already stored for the same bucket.
+// Implements URLRepository interface with PostgreSQL-specific implementation.
+func (r *DataBaseURLRepository) AddClickStats(ctx context.Context, stats []model.ClickStat) error {
+	if len(stats) == 0 {
+		return nil
+	}
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, `INSERT INTO url_clicks (short_url, bucket, clicks)
+						VALUES ($1, $2, $3)
+						ON CONFLICT (short_url, bucket) DO UPDATE SET clicks = url_clicks.clicks + EXCLUDED.clicks`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare click stats upsert: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, stat := range stats {
+		if _, err := stmt.ExecContext(ctx, stat.Short, stat.Bucket, stat.Clicks); err != nil {
+			return fmt.Errorf("failed to upsert click stats: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit click stats: %w", err)
+	}
+	return nil
+}
+
 // ErrNotFound is a singleton instance of NotFoundError that is returned
 // when a requested resource is not found in the repository.
 // It should be used for all "not found" error returns to ensure consistency.
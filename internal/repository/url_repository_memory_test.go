@@ -1,9 +1,11 @@
 package repository_test
 
 import (
+	"context"
 	"fmt"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/Aleksey170999/go-shortener/internal/model"
 	"github.com/Aleksey170999/go-shortener/internal/repository"
@@ -13,6 +15,7 @@ import (
 
 func TestMemoryURLRepository(t *testing.T) {
 	repo := repository.NewMemoryURLRepository()
+	ctx := context.Background()
 	testURL := &model.URL{
 		ID:        "test1",
 		Short:     "test1",
@@ -22,43 +25,43 @@ func TestMemoryURLRepository(t *testing.T) {
 	}
 
 	t.Run("Save and GetByShortURL", func(t *testing.T) {
-		savedURL, err := repo.Save(testURL)
+		savedURL, err := repo.Save(ctx, testURL)
 		require.NoError(t, err)
 		assert.Equal(t, testURL, savedURL)
 
-		foundURL, err := repo.GetByShortURL(testURL.ID)
+		foundURL, err := repo.GetByShortURL(ctx, testURL.ID)
 		require.NoError(t, err)
 		assert.Equal(t, testURL, foundURL)
 
-		_, err = repo.GetByShortURL("nonexistent")
+		_, err = repo.GetByShortURL(ctx, "nonexistent")
 		assert.ErrorIs(t, err, repository.ErrNotFound)
 	})
 
 	t.Run("GetByUserID", func(t *testing.T) {
 		// Save the test URL first
-		savedURL, err := repo.Save(testURL)
+		savedURL, err := repo.Save(ctx, testURL)
 		require.NoError(t, err)
 
 		// Now try to get it by user ID
-		urls, err := repo.GetByUserID("user1")
+		urls, err := repo.GetByUserID(ctx, "user1")
 		require.NoError(t, err)
 		require.Len(t, urls, 1)
 		assert.Equal(t, savedURL.Short, urls[0].Short)
 
 		// Test with non-existent user
-		_, err = repo.GetByUserID("nonexistent")
+		_, err = repo.GetByUserID(ctx, "nonexistent")
 		require.ErrorIs(t, err, repository.ErrNotFound)
 	})
 
 	t.Run("BatchDelete", func(t *testing.T) {
-		err := repo.BatchDelete([]string{testURL.ID}, "user1")
+		err := repo.BatchDelete(ctx, []string{testURL.ID}, "user1")
 		require.NoError(t, err)
 
-		url, err := repo.GetByShortURL(testURL.ID)
+		url, err := repo.GetByShortURL(ctx, testURL.ID)
 		require.NoError(t, err)
 		assert.True(t, url.IsDeleted)
 
-		err = repo.BatchDelete([]string{"nonexistent"}, "user1")
+		err = repo.BatchDelete(ctx, []string{"nonexistent"}, "user1")
 		require.NoError(t, err)
 	})
 
@@ -66,17 +69,32 @@ func TestMemoryURLRepository(t *testing.T) {
 
 	t.Run("Save duplicate URL", func(t *testing.T) {
 		// First save should succeed
-		_, err := repo.Save(testURL)
+		_, err := repo.Save(ctx, testURL)
 		require.NoError(t, err)
 
 		// Second save with same ID should also succeed in memory implementation
 		// (database implementation would return error)
-		_, err = repo.Save(testURL)
+		_, err = repo.Save(ctx, testURL)
 		require.NoError(t, err)
 	})
 
+	t.Run("Save short URL collision", func(t *testing.T) {
+		other := &model.URL{
+			ID:       "other",
+			Short:    testURL.Short,
+			Original: "https://example.org",
+			UserID:   "user2",
+		}
+		_, err := repo.Save(ctx, other)
+		require.ErrorIs(t, err, model.ErrShortURLConflict)
+
+		url, err := repo.GetByShortURL(ctx, testURL.Short)
+		require.NoError(t, err)
+		assert.Equal(t, testURL.Original, url.Original)
+	})
+
 	t.Run("BatchDelete empty slice", func(t *testing.T) {
-		err := repo.BatchDelete([]string{}, "user1")
+		err := repo.BatchDelete(ctx, []string{}, "user1")
 		require.NoError(t, err)
 	})
 
@@ -98,7 +116,7 @@ func TestMemoryURLRepository(t *testing.T) {
 						UserID:    fmt.Sprintf("user-%d", workerID%3), // Distribute across 3 users
 						IsDeleted: false,
 					}
-					_, err := repo.Save(url)
+					_, err := repo.Save(ctx, url)
 					require.NoError(t, err)
 				}
 			}(i)
@@ -110,7 +128,7 @@ func TestMemoryURLRepository(t *testing.T) {
 		for i := 0; i < numWorkers; i++ {
 			for j := 0; j < urlsPerWorker; j++ {
 				shortURL := fmt.Sprintf("short-%d-%d", i, j)
-				url, err := repo.GetByShortURL(shortURL)
+				url, err := repo.GetByShortURL(ctx, shortURL)
 				require.NoError(t, err)
 				require.Equal(t, shortURL, url.Short)
 			}
@@ -122,7 +140,7 @@ func TestMemoryURLRepository(t *testing.T) {
 			go func(userNum int) {
 				defer wg.Done()
 				userID := fmt.Sprintf("user-%d", userNum)
-				urls, err := repo.GetByUserID(userID)
+				urls, err := repo.GetByUserID(ctx, userID)
 				require.NoError(t, err)
 
 				var ids []string
@@ -130,10 +148,110 @@ func TestMemoryURLRepository(t *testing.T) {
 					ids = append(ids, url.Short)
 				}
 
-				err = repo.BatchDelete(ids, userID)
+				err = repo.BatchDelete(ctx, ids, userID)
 				require.NoError(t, err)
 			}(i)
 		}
 		wg.Wait()
 	})
 }
+
+func TestMemoryURLRepository_Purge(t *testing.T) {
+	repo := repository.NewMemoryURLRepository()
+	ctx := context.Background()
+	now := time.Now()
+	past := now.Add(-time.Hour)
+	future := now.Add(time.Hour)
+
+	urls := []*model.URL{
+		{ID: "1", Short: "expired", Original: "https://example.com/1", UserID: "user1", ExpiresAt: &past},
+		{ID: "2", Short: "active", Original: "https://example.com/2", UserID: "user1", ExpiresAt: &future},
+		{ID: "3", Short: "deleted", Original: "https://example.com/3", UserID: "user1"},
+	}
+	for _, url := range urls {
+		_, err := repo.Save(ctx, url)
+		require.NoError(t, err)
+	}
+	require.NoError(t, repo.BatchDelete(ctx, []string{"deleted"}, "user1"))
+
+	stats, err := repo.Purge(ctx, now, now.Add(time.Minute), true)
+	require.NoError(t, err)
+	assert.Equal(t, repository.PurgeStats{Expired: 1, Deleted: 1}, stats)
+	_, err = repo.GetByShortURL(ctx, "expired")
+	require.NoError(t, err, "dry run must not remove anything")
+
+	stats, err = repo.Purge(ctx, now, now.Add(time.Minute), false)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), stats.Expired)
+	assert.Equal(t, int64(1), stats.Deleted)
+	assert.ElementsMatch(t, []string{"expired", "deleted"}, stats.Shorts)
+
+	_, err = repo.GetByShortURL(ctx, "expired")
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+	_, err = repo.GetByShortURL(ctx, "deleted")
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+	_, err = repo.GetByShortURL(ctx, "active")
+	assert.NoError(t, err)
+}
+
+func TestMemoryURLRepository_PurgeMatching(t *testing.T) {
+	repo := repository.NewMemoryURLRepository()
+	ctx := context.Background()
+	now := time.Now()
+	old := now.Add(-time.Hour)
+
+	urls := []*model.URL{
+		{ID: "1", Short: "old-anon", Original: "https://example.com/1", CreatedAt: old},
+		{ID: "2", Short: "old-owned", Original: "https://example.com/2", UserID: "user1", CreatedAt: old},
+		{ID: "3", Short: "new-anon", Original: "https://example.com/3", CreatedAt: now},
+		{ID: "4", Short: "unknown-anon", Original: "https://example.com/4"},
+	}
+	for _, url := range urls {
+		_, err := repo.Save(ctx, url)
+		require.NoError(t, err)
+	}
+
+	_, err := repo.PurgeMatching(ctx, repository.PurgeFilter{Anonymous: true}, false)
+	assert.ErrorIs(t, err, repository.ErrEmptyPurgeFilter)
+
+	filter := repository.PurgeFilter{CreatedBefore: now.Add(-time.Minute), Anonymous: true}
+	removed, err := repo.PurgeMatching(ctx, filter, true)
+	require.NoError(t, err)
+	require.Len(t, removed, 1)
+	assert.Equal(t, "old-anon", removed[0].Short)
+	_, err = repo.GetByShortURL(ctx, "old-anon")
+	require.NoError(t, err, "dry run must not remove anything")
+
+	removed, err = repo.PurgeMatching(ctx, filter, false)
+	require.NoError(t, err)
+	require.Len(t, removed, 1)
+	_, err = repo.GetByShortURL(ctx, "old-anon")
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+	for _, short := range []string{"old-owned", "new-anon", "unknown-anon"} {
+		_, err = repo.GetByShortURL(ctx, short)
+		assert.NoError(t, err, short)
+	}
+}
+
+func TestMemoryURLRepository_List(t *testing.T) {
+	repo := repository.NewMemoryURLRepository()
+	ctx := context.Background()
+	for _, url := range []*model.URL{
+		{ID: "1", Short: "a", Original: "https://a.example", UserID: "user1"},
+		{ID: "2", Short: "b", Original: "https://b.example", UserID: "user1"},
+		{ID: "3", Short: "c", Original: "https://c.example"},
+	} {
+		_, err := repo.Save(ctx, url)
+		require.NoError(t, err)
+	}
+	require.NoError(t, repo.BatchDelete(ctx, []string{"b"}, "user1"))
+
+	var lister repository.Lister = repo
+	urls, err := lister.List(ctx)
+	require.NoError(t, err)
+	deleted := make(map[string]bool, len(urls))
+	for _, url := range urls {
+		deleted[url.Short] = url.IsDeleted
+	}
+	assert.Equal(t, map[string]bool{"a": false, "b": true, "c": false}, deleted, "soft-deleted URLs are listed as such")
+}
@@ -0,0 +1,150 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Aleksey170999/go-shortener/internal/model"
+	"github.com/google/uuid"
+)
+
+const (
+	// defaultAliasMinLength is the shortest custom alias accepted by default.
+	defaultAliasMinLength = 3
+	// defaultAliasMaxLength is the longest custom alias accepted by default.
+	defaultAliasMaxLength = 32
+)
+
+// defaultReservedAliases are path segments used by the service itself.
+var defaultReservedAliases = []string{"api", "ping", "admin", "debug", "metrics", "health", "static", "version"}
+
+// ErrInvalidAlias is the sentinel wrapped by every AliasValidationError.
+var ErrInvalidAlias = errors.New("invalid alias")
+
+// ErrAliasTaken is returned by ShortenAlias when the alias is already used.
+var ErrAliasTaken = errors.New("alias already taken")
+
+// AliasValidationError describes why a custom alias was rejected.
+// It matches ErrInvalidAlias with errors.Is.
+type AliasValidationError struct {
+	Alias  string // The rejected alias
+	Reason string // Human-readable rejection reason
+}
+
+// Error returns the string representation of the AliasValidationError.
+func (e *AliasValidationError) Error() string {
+	return fmt.Sprintf("invalid alias %q: %s", e.Alias, e.Reason)
+}
+
+// Unwrap returns ErrInvalidAlias so callers can use errors.Is.
+func (e *AliasValidationError) Unwrap() error {
+	return ErrInvalidAlias
+}
+
+// AliasPolicy holds the rules custom aliases must satisfy.
+type AliasPolicy struct {
+	MinLength int      // Minimum alias length
+	MaxLength int      // Maximum alias length
+	Reserved  []string // Aliases that may not be used, compared case-insensitively
+	Blocked   []string // Words that may not appear anywhere in an alias, compared case-insensitively
+}
+
+// DefaultAliasPolicy returns the policy used when none is configured.
+func DefaultAliasPolicy() AliasPolicy {
+	return AliasPolicy{
+		MinLength: defaultAliasMinLength,
+		MaxLength: defaultAliasMaxLength,
+		Reserved:  defaultReservedAliases,
+	}
+}
+
+// WithAliasPolicy sets the rules applied to custom aliases.
+func WithAliasPolicy(p AliasPolicy) Option {
+	return func(s *URLService) {
+		s.aliasPolicy = p
+	}
+}
+
+// Validate checks alias against the policy and returns an *AliasValidationError
+// describing the first violated rule.
+func (p AliasPolicy) Validate(alias string) error {
+	if len(alias) < p.MinLength || (p.MaxLength > 0 && len(alias) > p.MaxLength) {
+		return &AliasValidationError{
+			Alias:  alias,
+			Reason: fmt.Sprintf("length must be between %d and %d", p.MinLength, p.MaxLength),
+		}
+	}
+	for _, c := range alias {
+		if !isAliasChar(c) {
+			return &AliasValidationError{Alias: alias, Reason: "only letters, digits, '-' and '_' are allowed"}
+		}
+	}
+	lower := strings.ToLower(alias)
+	for _, word := range p.Reserved {
+		if lower == strings.ToLower(word) {
+			return &AliasValidationError{Alias: alias, Reason: "alias is reserved"}
+		}
+	}
+	for _, word := range p.Blocked {
+		if word != "" && strings.Contains(lower, strings.ToLower(word)) {
+			return &AliasValidationError{Alias: alias, Reason: "alias contains a blocked word"}
+		}
+	}
+	return nil
+}
+
+func isAliasChar(c rune) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' || c == '-' || c == '_'
+}
+
+// ShortenAlias creates a short URL using the caller-provided alias as the short code.
+// The alias is validated against the service's AliasPolicy first.
+//
+// Parameters:
+//   - ctx: Request context used for cancellation of repository calls
+//   - original: The original URL to be shortened
+//   - alias: The requested short code
+//   - userID: ID of the user creating the short URL
+//
+// Returns:
+//   - *model.URL: The created URL, or the existing one with model.ErrURLAlreadyExists
+//   - error: *AliasValidationError, ErrAliasTaken, ErrInvalidURL or a repository error
+func (s *URLService) ShortenAlias(ctx context.Context, original, alias, userID string) (*model.URL, error) {
+	start := time.Now()
+	url, err := s.shortenAlias(ctx, original, alias, userID)
+	s.hooks.OnShorten(ctx, time.Since(start), err)
+	return url, err
+}
+
+func (s *URLService) shortenAlias(ctx context.Context, original, alias, userID string) (*model.URL, error) {
+	if err := s.checkRateLimit(userID); err != nil {
+		return nil, err
+	}
+	if err := s.aliasPolicy.Validate(alias); err != nil {
+		return nil, err
+	}
+	original, err := CanonicalizeURL(original)
+	if err != nil {
+		return nil, err
+	}
+
+	url := &model.URL{
+		ID:        uuid.New().String(),
+		Original:  original,
+		Short:     alias,
+		UserID:    userID,
+		CreatedAt: time.Now(),
+	}
+	err = s.breaker.do(ctx, func() error {
+		var saveErr error
+		url, saveErr = s.repo.Save(ctx, url)
+		return saveErr
+	})
+	if errors.Is(err, model.ErrShortURLConflict) {
+		return nil, ErrAliasTaken
+	}
+	return url, err
+}
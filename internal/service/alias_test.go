@@ -0,0 +1,43 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Aleksey170999/go-shortener/internal/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAliasPolicy_Validate(t *testing.T) {
+	policy := DefaultAliasPolicy()
+	policy.Blocked = []string{"badword"}
+
+	valid := []string{"abc", "my-link", "Promo_2025"}
+	for _, alias := range valid {
+		assert.NoError(t, policy.Validate(alias), alias)
+	}
+
+	invalid := []string{"ab", "this-alias-is-way-too-long-to-be-accepted", "with space", "slash/es", "кириллица", "API", "ping", "version", "my-BadWord-link"}
+	for _, alias := range invalid {
+		err := policy.Validate(alias)
+		var aliasErr *AliasValidationError
+		assert.ErrorAs(t, err, &aliasErr, alias)
+		assert.ErrorIs(t, err, ErrInvalidAlias, alias)
+	}
+}
+
+func TestURLService_ShortenAlias(t *testing.T) {
+	s := NewURLService(repository.NewMemoryURLRepository())
+	ctx := context.Background()
+
+	url, err := s.ShortenAlias(ctx, "https://example.com/1", "promo", "user1")
+	require.NoError(t, err)
+	assert.Equal(t, "promo", url.Short)
+
+	_, err = s.ShortenAlias(ctx, "https://example.com/2", "promo", "user2")
+	assert.ErrorIs(t, err, ErrAliasTaken)
+
+	_, err = s.ShortenAlias(ctx, "https://example.com/3", "api", "user1")
+	assert.ErrorIs(t, err, ErrInvalidAlias)
+}
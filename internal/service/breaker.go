@@ -0,0 +1,119 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/Aleksey170999/go-shortener/internal/model"
+	"github.com/Aleksey170999/go-shortener/internal/repository"
+)
+
+// ErrCircuitOpen is returned instead of calling the repository while the
+// circuit breaker is open. Callers should report it as a temporary unavailability.
+var ErrCircuitOpen = errors.New("repository circuit breaker is open")
+
+type breakerState int
+
+const (
+	breakerClosed   breakerState = iota // Calls pass through, failures are counted
+	breakerOpen                         // Calls fail fast until the cooldown elapses
+	breakerHalfOpen                     // A single probe call decides whether to close again
+)
+
+// circuitBreaker guards repository calls. It opens after threshold consecutive
+// failures, rejects calls for cooldown, and then lets one probe call through:
+// a successful probe closes the breaker, a failed one opens it again.
+// A nil *circuitBreaker passes every call through.
+type circuitBreaker struct {
+	threshold int
+	cooldown  time.Duration
+	mu        sync.Mutex
+	state     breakerState
+	failures  int       // Consecutive failures while closed
+	openedAt  time.Time // When the breaker last opened
+	probing   bool      // Whether the half-open probe is in flight
+	now       func() time.Time
+}
+
+// newCircuitBreaker creates a closed circuit breaker.
+func newCircuitBreaker(threshold int, cooldown time.Duration) *circuitBreaker {
+	return &circuitBreaker{
+		threshold: threshold,
+		cooldown:  cooldown,
+		now:       time.Now,
+	}
+}
+
+// do runs fn if the breaker allows it and records the outcome. ctx is the
+// context of the repository call: a call failing after ctx was canceled or
+// expired is neutral, as the caller gave up rather than the repository
+// failing.
+func (b *circuitBreaker) do(ctx context.Context, fn func() error) error {
+	if b == nil {
+		return fn()
+	}
+	if err := b.allow(); err != nil {
+		return err
+	}
+	err := fn()
+	b.record(ctx, err)
+	return err
+}
+
+func (b *circuitBreaker) allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case breakerOpen:
+		if b.now().Sub(b.openedAt) < b.cooldown {
+			return ErrCircuitOpen
+		}
+		b.state = breakerHalfOpen
+		b.probing = true
+	case breakerHalfOpen:
+		if b.probing {
+			return ErrCircuitOpen
+		}
+		b.probing = true
+	}
+	return nil
+}
+
+func (b *circuitBreaker) record(ctx context.Context, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probing = false
+	if err != nil && ctx.Err() != nil {
+		// The call says nothing about the repository: the state is kept, and
+		// a half-open breaker lets the next call probe instead.
+		return
+	}
+	if !isBreakerFailure(err) {
+		b.state = breakerClosed
+		b.failures = 0
+		return
+	}
+	b.failures++
+	if b.state == breakerHalfOpen || b.failures >= b.threshold {
+		b.state = breakerOpen
+		b.openedAt = b.now()
+		b.failures = 0
+	}
+}
+
+// isBreakerFailure reports whether err indicates an unhealthy repository.
+// Domain outcomes do not count as failures.
+func isBreakerFailure(err error) bool {
+	switch {
+	case err == nil,
+		errors.Is(err, repository.ErrNotFound),
+		errors.Is(err, model.ErrURLAlreadyExists),
+		errors.Is(err, model.ErrShortURLConflict):
+		return false
+	}
+	return true
+}
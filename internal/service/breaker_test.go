@@ -0,0 +1,103 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Aleksey170999/go-shortener/internal/repository"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	now := time.Now()
+	b := newCircuitBreaker(2, time.Minute)
+	b.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	errDB := errors.New("connection refused")
+	fail := func() error { return errDB }
+	ok := func() error { return nil }
+
+	// Domain errors do not open the breaker.
+	for i := 0; i < 5; i++ {
+		assert.ErrorIs(t, b.do(ctx, func() error { return repository.ErrNotFound }), repository.ErrNotFound)
+	}
+
+	assert.ErrorIs(t, b.do(ctx, fail), errDB)
+	assert.ErrorIs(t, b.do(ctx, fail), errDB)
+
+	called := false
+	err := b.do(ctx, func() error { called = true; return nil })
+	assert.ErrorIs(t, err, ErrCircuitOpen)
+	assert.False(t, called)
+
+	// After the cooldown a failed probe reopens the breaker immediately.
+	now = now.Add(time.Minute)
+	assert.ErrorIs(t, b.do(ctx, fail), errDB)
+	assert.ErrorIs(t, b.do(ctx, ok), ErrCircuitOpen)
+
+	// A successful probe closes it again.
+	now = now.Add(time.Minute)
+	assert.NoError(t, b.do(ctx, ok))
+	assert.NoError(t, b.do(ctx, ok))
+}
+
+func TestCircuitBreaker_CanceledProbe(t *testing.T) {
+	now := time.Now()
+	b := newCircuitBreaker(1, time.Minute)
+	b.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	errDB := errors.New("connection refused")
+	assert.ErrorIs(t, b.do(ctx, func() error { return errDB }), errDB)
+	now = now.Add(time.Minute)
+
+	// Probes whose caller gave up neither close nor reopen the breaker; the
+	// next call probes again.
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	expired, cancel := context.WithDeadline(ctx, time.Time{})
+	defer cancel()
+	for _, probeCtx := range []context.Context{canceled, expired} {
+		err := probeCtx.Err()
+		assert.ErrorIs(t, b.do(probeCtx, func() error { return err }), err)
+		assert.Equal(t, breakerHalfOpen, b.state)
+	}
+	assert.ErrorIs(t, b.do(ctx, func() error { return errDB }), errDB)
+	assert.Equal(t, breakerOpen, b.state, "the next probe decides")
+}
+
+func TestCircuitBreaker_CanceledWhileClosed(t *testing.T) {
+	b := newCircuitBreaker(2, time.Minute)
+	ctx := context.Background()
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	errDB := errors.New("connection refused")
+
+	assert.ErrorIs(t, b.do(ctx, func() error { return errDB }), errDB)
+	assert.ErrorIs(t, b.do(canceled, func() error { return context.Canceled }), context.Canceled)
+	assert.Equal(t, 1, b.failures, "a cancellation does not reset the failure count")
+	assert.ErrorIs(t, b.do(ctx, func() error { return errDB }), errDB)
+	assert.Equal(t, breakerOpen, b.state)
+}
+
+func TestCircuitBreaker_DeadlineOfLiveCaller(t *testing.T) {
+	b := newCircuitBreaker(1, time.Minute)
+
+	// A deadline the repository call ran into while its caller still waits,
+	// such as the query timeout of a lookup, is a failure.
+	err := b.do(context.Background(), func() error { return context.DeadlineExceeded })
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, breakerOpen, b.state)
+}
+
+func TestCircuitBreaker_Nil(t *testing.T) {
+	var b *circuitBreaker
+	ctx := context.Background()
+	errDB := errors.New("connection refused")
+	for i := 0; i < 10; i++ {
+		assert.ErrorIs(t, b.do(ctx, func() error { return errDB }), errDB)
+	}
+}
@@ -0,0 +1,109 @@
+package service
+
+import (
+	"container/list"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Aleksey170999/go-shortener/internal/model"
+)
+
+// resolveCache is a fixed-size LRU cache of resolved URLs keyed by short code.
+// Entries older than ttl are treated as missing. It is safe for concurrent use.
+type resolveCache struct {
+	size    int
+	ttl     time.Duration
+	mu      sync.Mutex
+	order   *list.List               // Most recently used entries at the front
+	entries map[string]*list.Element // Short code to element in order
+	now     func() time.Time
+	hits    atomic.Uint64 // Lookups served from the cache
+	misses  atomic.Uint64 // Lookups of missing or expired entries
+}
+
+// resolveCacheEntry is the value stored in each list element.
+type resolveCacheEntry struct {
+	short     string
+	url       model.URL
+	expiresAt time.Time
+}
+
+// newResolveCache creates an LRU cache holding at most size entries.
+// A non-positive ttl means entries never expire.
+func newResolveCache(size int, ttl time.Duration) *resolveCache {
+	return &resolveCache{
+		size:    size,
+		ttl:     ttl,
+		order:   list.New(),
+		entries: make(map[string]*list.Element, size),
+		now:     time.Now,
+	}
+}
+
+// get returns a copy of the cached URL for short, if present and not expired.
+func (c *resolveCache) get(short string) (*model.URL, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.entries[short]
+	if !ok {
+		c.misses.Add(1)
+		return nil, false
+	}
+	entry := el.Value.(*resolveCacheEntry)
+	if c.ttl > 0 && c.now().After(entry.expiresAt) {
+		c.removeElement(el)
+		c.misses.Add(1)
+		return nil, false
+	}
+	c.hits.Add(1)
+	c.order.MoveToFront(el)
+	url := entry.url
+	return &url, true
+}
+
+// add stores a copy of url, evicting the least recently used entry if the cache is full.
+func (c *resolveCache) add(url *model.URL) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	expiresAt := c.now().Add(c.ttl)
+	if el, ok := c.entries[url.Short]; ok {
+		entry := el.Value.(*resolveCacheEntry)
+		entry.url = *url
+		entry.expiresAt = expiresAt
+		c.order.MoveToFront(el)
+		return
+	}
+
+	el := c.order.PushFront(&resolveCacheEntry{short: url.Short, url: *url, expiresAt: expiresAt})
+	c.entries[url.Short] = el
+	if c.order.Len() > c.size {
+		c.removeElement(c.order.Back())
+	}
+}
+
+// len returns the number of cached entries, including expired ones not yet evicted.
+func (c *resolveCache) len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+// remove drops the given short codes from the cache.
+func (c *resolveCache) remove(shorts ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, short := range shorts {
+		if el, ok := c.entries[short]; ok {
+			c.removeElement(el)
+		}
+	}
+}
+
+func (c *resolveCache) removeElement(el *list.Element) {
+	c.order.Remove(el)
+	delete(c.entries, el.Value.(*resolveCacheEntry).short)
+}
@@ -0,0 +1,116 @@
+package service
+
+import (
+	"errors"
+	"net"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/idna"
+)
+
+// ErrInvalidURL is returned when an original URL cannot be parsed or is not
+// an absolute URL with a host.
+var ErrInvalidURL = errors.New("invalid url")
+
+// hostProfile converts host names to their ASCII (punycode) form. Unlike
+// idna.Lookup it accepts underscores, which appear in real-world host names.
+var hostProfile = idna.New(idna.MapForLookup(), idna.StrictDomainName(false))
+
+// defaultPorts maps schemes to the port that is implied when none is given.
+var defaultPorts = map[string]string{
+	"http":  "80",
+	"https": "443",
+}
+
+// CanonicalizeURL returns a normalized form of raw so that equivalent URLs
+// compare equal. It lowercases the scheme and host, converts internationalized
+// host names to punycode, drops default ports, removes "." and ".." path
+// segments, and normalizes percent-encoding (uppercase hex digits, unreserved
+// characters decoded). An empty path is kept empty so the URL still redirects
+// to exactly what the user submitted.
+//
+// Returns ErrInvalidURL if raw is not an absolute URL with a host.
+func CanonicalizeURL(raw string) (string, error) {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil || u.Scheme == "" || u.Host == "" || u.Opaque != "" {
+		return "", ErrInvalidURL
+	}
+
+	u.Scheme = strings.ToLower(u.Scheme)
+
+	hostname := strings.ToLower(u.Hostname())
+	if net.ParseIP(hostname) == nil {
+		hostname, err = hostProfile.ToASCII(hostname)
+		if err != nil {
+			return "", ErrInvalidURL
+		}
+	}
+	port := u.Port()
+	if port == defaultPorts[u.Scheme] {
+		port = ""
+	}
+	switch {
+	case port != "":
+		u.Host = net.JoinHostPort(hostname, port)
+	case strings.Contains(hostname, ":"):
+		u.Host = "[" + hostname + "]"
+	default:
+		u.Host = hostname
+	}
+
+	if path := u.EscapedPath(); path != "" {
+		// The reference is built rather than parsed, as a path starting
+		// with "//" would be parsed as an authority.
+		ref := &url.URL{Path: u.Path, RawPath: normalizePercentEncoding(path)}
+		resolved := (&url.URL{Path: "/"}).ResolveReference(ref)
+		u.Path, u.RawPath = resolved.Path, resolved.RawPath
+	}
+	u.RawQuery = normalizePercentEncoding(u.RawQuery)
+	return u.String(), nil
+}
+
+// normalizePercentEncoding uppercases the hex digits of every percent-encoded
+// octet and decodes octets that represent unreserved characters (RFC 3986, 6.2.2.2).
+func normalizePercentEncoding(s string) string {
+	if !strings.Contains(s, "%") {
+		return s
+	}
+	var b strings.Builder
+	b.Grow(len(s))
+	for i := 0; i < len(s); i++ {
+		if s[i] != '%' || i+2 >= len(s) || !isHex(s[i+1]) || !isHex(s[i+2]) {
+			b.WriteByte(s[i])
+			continue
+		}
+		c := unhex(s[i+1])<<4 | unhex(s[i+2])
+		if isUnreserved(c) {
+			b.WriteByte(c)
+		} else {
+			b.WriteByte('%')
+			b.WriteString(strings.ToUpper(s[i+1 : i+3]))
+		}
+		i += 2
+	}
+	return b.String()
+}
+
+func isHex(c byte) bool {
+	return '0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F'
+}
+
+func unhex(c byte) byte {
+	switch {
+	case '0' <= c && c <= '9':
+		return c - '0'
+	case 'a' <= c && c <= 'f':
+		return c - 'a' + 10
+	default:
+		return c - 'A' + 10
+	}
+}
+
+func isUnreserved(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' ||
+		c == '-' || c == '.' || c == '_' || c == '~'
+}
@@ -0,0 +1,49 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCanonicalizeURL(t *testing.T) {
+	testCases := []struct {
+		name     string
+		raw      string
+		expected string
+	}{
+		{name: "unchanged", raw: "https://example.com", expected: "https://example.com"},
+		{name: "scheme and host case", raw: "HTTPS://Example.COM/Path", expected: "https://example.com/Path"},
+		{name: "default http port", raw: "http://example.com:80/a", expected: "http://example.com/a"},
+		{name: "default https port", raw: "https://example.com:443/a", expected: "https://example.com/a"},
+		{name: "custom port", raw: "https://example.com:8443/a", expected: "https://example.com:8443/a"},
+		{name: "dot segments", raw: "https://example.com/a/./b/../c", expected: "https://example.com/a/c"},
+		{name: "trailing dot segment", raw: "https://example.com/a/b/..", expected: "https://example.com/a/"},
+		{name: "unreserved percent-encoding", raw: "https://example.com/%7Euser/%61bc", expected: "https://example.com/~user/abc"},
+		{name: "hex case", raw: "https://example.com/a%2fb?q=%c3%a9", expected: "https://example.com/a%2Fb?q=%C3%A9"},
+		{name: "idn host", raw: "https://пример.рф/путь", expected: "https://xn--e1afmkfd.xn--p1ai/%D0%BF%D1%83%D1%82%D1%8C"},
+		{name: "idn host upper case", raw: "https://ПРИМЕР.РФ", expected: "https://xn--e1afmkfd.xn--p1ai"},
+		{name: "ipv6 host", raw: "http://[::1]:80/a", expected: "http://[::1]/a"},
+		{name: "surrounding spaces", raw: "  https://example.com/a \n", expected: "https://example.com/a"},
+		{name: "leading empty segment", raw: "https://example.com//foo/bar", expected: "https://example.com//foo/bar"},
+		{name: "inner empty segment", raw: "https://example.com/a//b", expected: "https://example.com/a//b"},
+		{name: "encoded slash segment", raw: "https://example.com/%2F/x", expected: "https://example.com/%2F/x"},
+		{name: "fragment kept", raw: "https://example.com/a#Frag", expected: "https://example.com/a#Frag"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := CanonicalizeURL(tc.raw)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, got)
+		})
+	}
+}
+
+func TestCanonicalizeURL_Invalid(t *testing.T) {
+	for _, raw := range []string{"", "example.com", "/relative/path", "mailto:user@example.com", "http://%zz"} {
+		_, err := CanonicalizeURL(raw)
+		assert.ErrorIs(t, err, ErrInvalidURL, raw)
+	}
+}
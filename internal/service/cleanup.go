@@ -0,0 +1,46 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+)
+
+// CleanupConfig configures the background job that purges expired links and
+// permanently removes soft-deleted links once their retention period is over.
+type CleanupConfig struct {
+	Interval  time.Duration // How often the job runs; non-positive disables it
+	Retention time.Duration // How long soft-deleted links are kept before removal
+	DryRun    bool          // Only log what would be removed
+}
+
+// WithCleanup enables the periodic cleanup job, returned by Jobs.
+func WithCleanup(cfg CleanupConfig) Option {
+	return func(s *URLService) {
+		if cfg.Interval > 0 {
+			s.cleanup = cfg
+		}
+	}
+}
+
+// runCleanup performs a single cleanup pass and logs what it removed.
+func (s *URLService) runCleanup(ctx context.Context) error {
+	now := time.Now()
+	stats, err := s.repo.Purge(ctx, now, now.Add(-s.cleanup.Retention), s.cleanup.DryRun)
+	if err != nil {
+		return fmt.Errorf("purge: %w", err)
+	}
+	if stats.Expired == 0 && stats.Deleted == 0 {
+		return nil
+	}
+	if s.cleanup.DryRun {
+		log.Printf("[cleanup] dry run: would purge %d expired and %d deleted urls", stats.Expired, stats.Deleted)
+		return nil
+	}
+	log.Printf("[cleanup] purged %d expired and %d deleted urls", stats.Expired, stats.Deleted)
+	if s.cache != nil {
+		s.cache.remove(stats.Shorts...)
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"math/big"
+
+	"github.com/Aleksey170999/go-shortener/internal/model"
+)
+
+// base62Alphabet is the digit set used for hash-based short codes.
+const base62Alphabet = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+// WithHashCodes makes the service derive short codes deterministically from
+// the SHA-256 of the canonical URL instead of generating random ones, so the
+// same URL maps to the same code on every instance. On a collision with a
+// different URL a longer prefix of the same hash is used.
+func WithHashCodes() Option {
+	return func(s *URLService) {
+		s.hashCodes = true
+	}
+}
+
+// WithPerUserDedup tells the service that the repository deduplicates
+// original URLs per user rather than globally. In hash mode the user ID is
+// then mixed into the hash, so different users get different codes for the
+// same URL. Repositories implementing repository.DedupReporter need not be
+// given it: the service follows the policy they report.
+func WithPerUserDedup() Option {
+	return func(s *URLService) {
+		s.perUserDedup = true
+	}
+}
+
+// newShortCode returns the short code to try for original on the given attempt.
+func (s *URLService) newShortCode(original, userID string, attempt int) (string, error) {
+	if s.hashCodes {
+		if s.perUserDedup {
+			original = userID + "\n" + original
+		}
+		return hashShortURL(original, shortURLLength+attempt), nil
+	}
+	return generateShortURL(shortURLLength + attempt)
+}
+
+// hashShortURL returns the first n base62 digits of the SHA-256 of original.
+func hashShortURL(original string, n int) string {
+	sum := sha256.Sum256([]byte(original))
+	num := new(big.Int).SetBytes(sum[:])
+	base := big.NewInt(int64(len(base62Alphabet)))
+	mod := new(big.Int)
+
+	digits := make([]byte, 0, 43)
+	for num.Sign() > 0 && len(digits) < n {
+		num.DivMod(num, base, mod)
+		digits = append(digits, base62Alphabet[mod.Int64()])
+	}
+	for len(digits) < n {
+		digits = append(digits, base62Alphabet[0])
+	}
+	return string(digits)
+}
+
+// existingHashed reports whether, in hash mode, a short code conflict was
+// caused by the same original URL being stored already (by the same user in
+// per-user mode). It returns the stored URL in that case so the caller can
+// treat it as a duplicate. A deleted URL does not count, as its link answers
+// 410 Gone; the caller then moves on to a longer code.
+func (s *URLService) existingHashed(ctx context.Context, short, original, userID string) (*model.URL, bool) {
+	if !s.hashCodes {
+		return nil, false
+	}
+	var existing *model.URL
+	err := s.breaker.do(ctx, func() error {
+		var getErr error
+		existing, getErr = s.repo.GetByShortURL(ctx, short)
+		return getErr
+	})
+	if err != nil || existing.Original != original || existing.IsDeleted {
+		return nil, false
+	}
+	if s.perUserDedup && existing.UserID != userID {
+		return nil, false
+	}
+	return existing, true
+}
@@ -0,0 +1,103 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Aleksey170999/go-shortener/internal/model"
+	"github.com/Aleksey170999/go-shortener/internal/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHashShortURL(t *testing.T) {
+	a := hashShortURL("https://example.com/a", 6)
+	assert.Len(t, a, 6)
+	assert.Equal(t, a, hashShortURL("https://example.com/a", 6))
+	assert.NotEqual(t, a, hashShortURL("https://example.com/b", 6))
+	assert.Equal(t, a, hashShortURL("https://example.com/a", 7)[:6])
+	for _, c := range a {
+		assert.Contains(t, base62Alphabet, string(c))
+	}
+}
+
+func TestURLService_Shorten_HashCodes(t *testing.T) {
+	ctx := context.Background()
+	first := NewURLService(repository.NewMemoryURLRepository(), WithHashCodes())
+	second := NewURLService(repository.NewMemoryURLRepository(), WithHashCodes())
+
+	url1, err := first.Shorten(ctx, "https://example.com/a", "", "user1")
+	require.NoError(t, err)
+	url2, err := second.Shorten(ctx, "HTTPS://EXAMPLE.COM/a", "", "user2")
+	require.NoError(t, err)
+	assert.Equal(t, url1.Short, url2.Short, "the same URL must get the same code on every instance")
+
+	again, err := first.Shorten(ctx, "https://example.com/a", "", "user1")
+	assert.ErrorIs(t, err, model.ErrURLAlreadyExists)
+	assert.Equal(t, url1.Short, again.Short)
+
+	results, err := first.ShortenBatch(ctx, []string{"https://example.com/a", "https://example.com/b"}, "user1")
+	require.NoError(t, err)
+	assert.ErrorIs(t, results[0].Err, model.ErrURLAlreadyExists)
+	assert.Equal(t, url1.Short, results[0].URL.Short)
+	assert.NoError(t, results[1].Err)
+	assert.Equal(t, hashShortURL("https://example.com/b", shortURLLength), results[1].URL.Short)
+}
+
+func TestURLService_Shorten_HashCodesPerUser(t *testing.T) {
+	ctx := context.Background()
+	s := NewURLService(repository.NewMemoryURLRepository(), WithHashCodes(), WithPerUserDedup())
+
+	url1, err := s.Shorten(ctx, "https://example.com/a", "", "user1")
+	require.NoError(t, err)
+	url2, err := s.Shorten(ctx, "https://example.com/a", "", "user2")
+	require.NoError(t, err)
+	assert.NotEqual(t, url1.Short, url2.Short)
+	assert.Equal(t, "user2", url2.UserID)
+
+	again, err := s.Shorten(ctx, "https://example.com/a", "", "user2")
+	assert.ErrorIs(t, err, model.ErrURLAlreadyExists)
+	assert.Equal(t, url2.Short, again.Short)
+}
+
+func TestURLService_Shorten_HashCodesDeleted(t *testing.T) {
+	ctx := context.Background()
+	repo := repository.NewMemoryURLRepository()
+	deleted := &model.URL{
+		ID:        "deleted",
+		Original:  "https://example.com/a",
+		Short:     hashShortURL("https://example.com/a", shortURLLength),
+		IsDeleted: true,
+	}
+	_, err := repo.Save(ctx, deleted)
+	require.NoError(t, err)
+	s := NewURLService(repo, WithHashCodes())
+
+	url, err := s.Shorten(ctx, "https://example.com/a", "", "user1")
+	require.NoError(t, err, "a deleted link is not returned as a duplicate")
+	assert.Equal(t, hashShortURL("https://example.com/a", shortURLLength+1), url.Short)
+	assert.False(t, url.IsDeleted)
+
+	results, err := s.ShortenBatch(ctx, []string{"https://example.com/a"}, "user1")
+	require.NoError(t, err)
+	assert.ErrorIs(t, results[0].Err, model.ErrURLAlreadyExists)
+	assert.Equal(t, url.Short, results[0].URL.Short)
+}
+
+// perUserRepo is a repository reporting per-user deduplication.
+type perUserRepo struct {
+	repository.URLRepository
+}
+
+func (perUserRepo) PerUserDedup() bool { return true }
+
+func TestURLService_Shorten_HashCodesRepositoryPolicy(t *testing.T) {
+	ctx := context.Background()
+	s := NewURLService(perUserRepo{repository.NewMemoryURLRepository()}, WithHashCodes())
+
+	url1, err := s.Shorten(ctx, "https://example.com/a", "", "user1")
+	require.NoError(t, err)
+	url2, err := s.Shorten(ctx, "https://example.com/a", "", "user2")
+	require.NoError(t, err)
+	assert.NotEqual(t, url1.Short, url2.Short)
+}
@@ -0,0 +1,45 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/Aleksey170999/go-shortener/internal/model"
+	"github.com/Aleksey170999/go-shortener/internal/repository"
+)
+
+// Service-level errors returned by URLService. Callers should match them with
+// errors.Is; errors translated from the repository layer still match the
+// original repository error as well.
+var (
+	// ErrNotFound is returned when no URL with the requested short code exists
+	// or a user has no URLs.
+	ErrNotFound = errors.New("url not found")
+
+	// ErrDeleted is returned by Resolve for URLs deleted by their owner.
+	ErrDeleted = errors.New("url has been deleted")
+
+	// ErrExpired is returned by Resolve for URLs past their expiry time.
+	ErrExpired = errors.New("url has expired")
+
+	// ErrQuotaExceeded is returned when a user has exceeded the number of
+	// operations allowed for them.
+	ErrQuotaExceeded = errors.New("quota exceeded")
+
+	// ErrDeleteJobNotFound is returned by GetDeleteJob for unknown or expired jobs.
+	ErrDeleteJobNotFound = errors.New("delete job not found")
+)
+
+// translateRepoError maps repository errors onto service-level errors. The
+// repository error stays in the chain so errors.Is matches both of them.
+func translateRepoError(err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, repository.ErrNotFound), errors.Is(err, model.ErrURLNotFound):
+		return fmt.Errorf("%w: %w", ErrNotFound, err)
+	case errors.Is(err, model.ErrURLDeleted):
+		return fmt.Errorf("%w: %w", ErrDeleted, err)
+	}
+	return err
+}
@@ -1,6 +1,7 @@
 package service_test
 
 import (
+	"context"
 	"fmt"
 	"sort"
 
@@ -20,6 +21,7 @@ func ExampleURLService_GetUserURLs() {
 
 	// Create a test user
 	userID := "user123"
+	ctx := context.Background()
 
 	// Create some test URLs
 	urls := []model.URL{
@@ -31,11 +33,11 @@ func ExampleURLService_GetUserURLs() {
 	// Save the URLs to the repository
 	for _, u := range urls {
 		url := u // Create a copy to avoid referencing the loop variable
-		_, _ = repo.Save(&url)
+		_, _ = repo.Save(ctx, &url)
 	}
 
 	// Retrieve and sort the user's URLs
-	userURLs, _ := urlService.GetUserURLs(userID)
+	userURLs, _ := urlService.GetUserURLs(ctx, userID)
 
 	// Sort the URLs by ID (which represents creation time)
 	sort.Slice(userURLs, func(i, j int) bool {
@@ -0,0 +1,63 @@
+package service
+
+import (
+	"context"
+	"time"
+)
+
+// Hooks receives notifications about completed URLService operations, so that
+// metrics and tracing can be attached without changing the service itself.
+// Each method gets the operation's duration and its error (nil on success;
+// domain errors such as model.ErrURLAlreadyExists are passed through as is).
+// Implementations must be safe for concurrent use and should return quickly.
+type Hooks interface {
+	// OnShorten is called after Shorten, ShortenAlias or ShortenBatch.
+	OnShorten(ctx context.Context, d time.Duration, err error)
+	// OnResolve is called after Resolve.
+	OnResolve(ctx context.Context, d time.Duration, err error)
+	// OnDelete is called after each repository deletion performed by the
+	// background delete workers. ctx is not tied to any request.
+	OnDelete(ctx context.Context, d time.Duration, err error)
+}
+
+// NopHooks implements Hooks with no-op methods. Embed it to implement only
+// the notifications you need.
+type NopHooks struct{}
+
+// OnShorten implements Hooks.
+func (NopHooks) OnShorten(context.Context, time.Duration, error) {}
+
+// OnResolve implements Hooks.
+func (NopHooks) OnResolve(context.Context, time.Duration, error) {}
+
+// OnDelete implements Hooks.
+func (NopHooks) OnDelete(context.Context, time.Duration, error) {}
+
+// WithHooks registers hooks that are notified about service operations.
+// It may be passed several times; all registered hooks are called in order.
+func WithHooks(hooks ...Hooks) Option {
+	return func(s *URLService) {
+		s.hooks = append(s.hooks, hooks...)
+	}
+}
+
+// hookList fans notifications out to every registered Hooks.
+type hookList []Hooks
+
+func (l hookList) OnShorten(ctx context.Context, d time.Duration, err error) {
+	for _, h := range l {
+		h.OnShorten(ctx, d, err)
+	}
+}
+
+func (l hookList) OnResolve(ctx context.Context, d time.Duration, err error) {
+	for _, h := range l {
+		h.OnResolve(ctx, d, err)
+	}
+}
+
+func (l hookList) OnDelete(ctx context.Context, d time.Duration, err error) {
+	for _, h := range l {
+		h.OnDelete(ctx, d, err)
+	}
+}
@@ -0,0 +1,71 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Aleksey170999/go-shortener/internal/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingHooks counts hook calls by operation and remembers the last error.
+type recordingHooks struct {
+	NopHooks
+	mu      sync.Mutex
+	calls   map[string]int
+	lastErr map[string]error
+}
+
+func newRecordingHooks() *recordingHooks {
+	return &recordingHooks{calls: make(map[string]int), lastErr: make(map[string]error)}
+}
+
+func (h *recordingHooks) record(op string, err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.calls[op]++
+	h.lastErr[op] = err
+}
+
+func (h *recordingHooks) OnShorten(_ context.Context, _ time.Duration, err error) {
+	h.record("shorten", err)
+}
+
+func (h *recordingHooks) OnResolve(_ context.Context, _ time.Duration, err error) {
+	h.record("resolve", err)
+}
+
+func (h *recordingHooks) OnDelete(_ context.Context, _ time.Duration, err error) {
+	h.record("delete", err)
+}
+
+func TestURLService_Hooks(t *testing.T) {
+	hooks := newRecordingHooks()
+	other := newRecordingHooks()
+	s := NewURLService(repository.NewMemoryURLRepository(), WithHooks(hooks), WithHooks(other))
+	ctx := context.Background()
+
+	url, err := s.Shorten(ctx, "https://example.com", "", "user1")
+	require.NoError(t, err)
+	_, err = s.ShortenBatch(ctx, []string{"https://example.com/1"}, "user1")
+	require.NoError(t, err)
+
+	_, err = s.Resolve(ctx, url.Short)
+	require.NoError(t, err)
+	_, err = s.Resolve(ctx, "missing")
+	require.Error(t, err)
+
+	require.NoError(t, enqueueDelete(ctx, s, []string{url.Short}, "user1"))
+	require.NoError(t, s.Shutdown(ctx))
+
+	for _, h := range []*recordingHooks{hooks, other} {
+		assert.Equal(t, 2, h.calls["shorten"])
+		assert.Equal(t, 2, h.calls["resolve"])
+		assert.ErrorIs(t, h.lastErr["resolve"], repository.ErrNotFound)
+		assert.Equal(t, 1, h.calls["delete"])
+		assert.NoError(t, h.lastErr["delete"])
+	}
+}
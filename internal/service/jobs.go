@@ -0,0 +1,165 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// deleteJobRetention is how long a finished delete job stays queryable.
+const deleteJobRetention = time.Hour
+
+// DeleteJobStatus is the overall state of a delete job.
+type DeleteJobStatus string
+
+const (
+	// DeleteJobPending means some of the job's URLs have not been processed yet.
+	DeleteJobPending DeleteJobStatus = "pending"
+	// DeleteJobCompleted means all of the job's URLs were processed successfully.
+	DeleteJobCompleted DeleteJobStatus = "completed"
+	// DeleteJobFailed means processing finished but the deletion of some URLs failed.
+	DeleteJobFailed DeleteJobStatus = "failed"
+)
+
+// DeleteJob describes the progress of a single BatchDelete call.
+// Counts refer to short URLs; a URL that does not belong to the user is
+// counted as completed because the repository silently ignores it.
+type DeleteJob struct {
+	ID         string    // Job identifier returned by BatchDelete
+	UserID     string    // Owner of the job
+	Total      int       // Number of short URLs in the request
+	Pending    int       // URLs still waiting in the delete queue
+	Completed  int       // URLs processed successfully
+	Failed     int       // URLs whose deletion failed
+	CreatedAt  time.Time // When the job was queued
+	FinishedAt time.Time // When the last URL was processed; zero while pending
+}
+
+// Status returns the overall state of the job.
+func (j DeleteJob) Status() DeleteJobStatus {
+	switch {
+	case j.Pending > 0:
+		return DeleteJobPending
+	case j.Failed > 0:
+		return DeleteJobFailed
+	}
+	return DeleteJobCompleted
+}
+
+// deleteJobs tracks delete jobs in memory. Finished jobs are dropped once
+// they are older than deleteJobRetention, whenever the delete worker finishes
+// another job.
+type deleteJobs struct {
+	mu       sync.Mutex
+	jobs     map[string]*DeleteJob
+	finished []string // IDs of the finished jobs, oldest first
+	now      func() time.Time
+}
+
+func newDeleteJobs() *deleteJobs {
+	return &deleteJobs{
+		jobs: make(map[string]*DeleteJob),
+		now:  time.Now,
+	}
+}
+
+// create registers a pending job for total URLs and returns its ID.
+func (t *deleteJobs) create(userID string, total int) string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	id := uuid.New().String()
+	t.jobs[id] = &DeleteJob{
+		ID:        id,
+		UserID:    userID,
+		Total:     total,
+		Pending:   total,
+		CreatedAt: now,
+	}
+	if total == 0 {
+		t.jobs[id].FinishedAt = now
+	}
+	return id
+}
+
+// finish records the outcome of processing n URLs of the job. It returns a
+// snapshot of the job and true if this completed the job.
+func (t *deleteJobs) finish(id string, n int, err error) (DeleteJob, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	job, ok := t.jobs[id]
+	if !ok {
+		return DeleteJob{}, false
+	}
+	job.Pending -= n
+	if err != nil {
+		job.Failed += n
+	} else {
+		job.Completed += n
+	}
+	if job.Pending <= 0 {
+		job.FinishedAt = t.now()
+		t.finished = append(t.finished, id)
+		t.evict(job.FinishedAt)
+		return *job, true
+	}
+	return DeleteJob{}, false
+}
+
+// evict drops the finished jobs older than deleteJobRetention. Jobs finish
+// in order, so only the expired ones at the front of t.finished are visited.
+func (t *deleteJobs) evict(now time.Time) {
+	for len(t.finished) > 0 {
+		id := t.finished[0]
+		if job, ok := t.jobs[id]; ok && now.Sub(job.FinishedAt) <= deleteJobRetention {
+			return
+		}
+		delete(t.jobs, id)
+		t.finished[0] = ""
+		t.finished = t.finished[1:]
+	}
+}
+
+// remove forgets a job that was never queued.
+func (t *deleteJobs) remove(id string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.jobs, id)
+}
+
+// get returns a snapshot of the job.
+func (t *deleteJobs) get(id string) (DeleteJob, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	job, ok := t.jobs[id]
+	if !ok {
+		return DeleteJob{}, false
+	}
+	return *job, true
+}
+
+// WithDeleteJobDone sets a function called once every delete job has been
+// processed, e.g. to write an audit event with the number of deleted links.
+// It runs on the delete worker, so it should not block.
+func WithDeleteJobDone(fn func(ctx context.Context, job DeleteJob)) Option {
+	return func(s *URLService) {
+		s.onDeleteJobDone = fn
+	}
+}
+
+// GetDeleteJob returns the progress of a delete job created by BatchDelete.
+// Jobs are only visible to the user who created them; ErrDeleteJobNotFound
+// is returned for unknown jobs, jobs of other users, and finished jobs older
+// than one hour.
+func (s *URLService) GetDeleteJob(_ context.Context, id, userID string) (DeleteJob, error) {
+	job, ok := s.deleteJobs.get(id)
+	if !ok || job.UserID != userID {
+		return DeleteJob{}, ErrDeleteJobNotFound
+	}
+	return job, nil
+}
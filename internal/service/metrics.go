@@ -0,0 +1,74 @@
+package service
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// RuntimeStats is a snapshot of the URLService's queues and caches.
+type RuntimeStats struct {
+	DeleteQueueLength   int    `json:"delete_queue_length"`   // Delete requests waiting for a worker
+	DeleteQueueCapacity int    `json:"delete_queue_capacity"` // Capacity of the delete queue
+	CacheEntries        int    `json:"cache_entries"`         // URLs in the resolve cache, 0 when the cache is disabled
+	CacheHits           uint64 `json:"cache_hits"`            // Resolves served from the cache
+	CacheMisses         uint64 `json:"cache_misses"`          // Resolves that had to query the repository despite the cache
+}
+
+// RuntimeStats returns the current state of the delete queue and the
+// resolve cache.
+func (s *URLService) RuntimeStats() RuntimeStats {
+	stats := RuntimeStats{
+		DeleteQueueLength:   len(s.deleteReqCh),
+		DeleteQueueCapacity: cap(s.deleteReqCh),
+	}
+	if s.cache != nil {
+		stats.CacheEntries = s.cache.len()
+		stats.CacheHits = s.cache.hits.Load()
+		stats.CacheMisses = s.cache.misses.Load()
+	}
+	return stats
+}
+
+// collector exports the RuntimeStats of a URLService as Prometheus metrics.
+type collector struct {
+	s                   *URLService
+	deleteQueueLength   *prometheus.Desc
+	deleteQueueCapacity *prometheus.Desc
+	cacheEntries        *prometheus.Desc
+	cacheHits           *prometheus.Desc
+	cacheMisses         *prometheus.Desc
+}
+
+// NewCollector returns a Prometheus collector reporting the RuntimeStats of s:
+//   - delete_queue_length and delete_queue_capacity: gauges of the delete queue
+//   - resolve_cache_entries: gauge of cached URLs
+//   - resolve_cache_hits_total and resolve_cache_misses_total: counters of
+//     cache lookups
+func NewCollector(s *URLService) prometheus.Collector {
+	return &collector{
+		s:                   s,
+		deleteQueueLength:   prometheus.NewDesc("delete_queue_length", "Number of delete requests waiting for a worker.", nil, nil),
+		deleteQueueCapacity: prometheus.NewDesc("delete_queue_capacity", "Capacity of the delete queue.", nil, nil),
+		cacheEntries:        prometheus.NewDesc("resolve_cache_entries", "Number of URLs in the resolve cache.", nil, nil),
+		cacheHits:           prometheus.NewDesc("resolve_cache_hits_total", "Number of resolves served from the cache.", nil, nil),
+		cacheMisses:         prometheus.NewDesc("resolve_cache_misses_total", "Number of resolves not found in the cache.", nil, nil),
+	}
+}
+
+// Describe implements prometheus.Collector.
+func (c *collector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.deleteQueueLength
+	ch <- c.deleteQueueCapacity
+	ch <- c.cacheEntries
+	ch <- c.cacheHits
+	ch <- c.cacheMisses
+}
+
+// Collect implements prometheus.Collector.
+func (c *collector) Collect(ch chan<- prometheus.Metric) {
+	stats := c.s.RuntimeStats()
+	ch <- prometheus.MustNewConstMetric(c.deleteQueueLength, prometheus.GaugeValue, float64(stats.DeleteQueueLength))
+	ch <- prometheus.MustNewConstMetric(c.deleteQueueCapacity, prometheus.GaugeValue, float64(stats.DeleteQueueCapacity))
+	ch <- prometheus.MustNewConstMetric(c.cacheEntries, prometheus.GaugeValue, float64(stats.CacheEntries))
+	ch <- prometheus.MustNewConstMetric(c.cacheHits, prometheus.CounterValue, float64(stats.CacheHits))
+	ch <- prometheus.MustNewConstMetric(c.cacheMisses, prometheus.CounterValue, float64(stats.CacheMisses))
+}
@@ -0,0 +1,49 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/Aleksey170999/go-shortener/internal/repository"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCollector(t *testing.T) {
+	s := NewURLService(repository.NewMemoryURLRepository(), WithResolveCache(10, 0), WithDeleteQueueSize(8))
+	ctx := context.Background()
+
+	url, err := s.Shorten(ctx, "https://example.com", "", "user1")
+	require.NoError(t, err)
+	for range 2 {
+		_, err = s.Resolve(ctx, url.Short)
+		require.NoError(t, err)
+	}
+	_, err = s.Resolve(ctx, "missing")
+	require.Error(t, err)
+	require.NoError(t, s.Shutdown(ctx))
+
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(NewCollector(s))
+	expected := `
+# HELP delete_queue_capacity Capacity of the delete queue.
+# TYPE delete_queue_capacity gauge
+delete_queue_capacity 8
+# HELP delete_queue_length Number of delete requests waiting for a worker.
+# TYPE delete_queue_length gauge
+delete_queue_length 0
+# HELP resolve_cache_entries Number of URLs in the resolve cache.
+# TYPE resolve_cache_entries gauge
+resolve_cache_entries 1
+# HELP resolve_cache_hits_total Number of resolves served from the cache.
+# TYPE resolve_cache_hits_total counter
+resolve_cache_hits_total 1
+# HELP resolve_cache_misses_total Number of resolves not found in the cache.
+# TYPE resolve_cache_misses_total counter
+resolve_cache_misses_total 2
+`
+	assert.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(expected)))
+}
@@ -0,0 +1,48 @@
+package service
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Aleksey170999/go-shortener/internal/ratelimit"
+)
+
+// WithRateLimit limits how often each user may call Shorten, ShortenAlias,
+// ShortenBatch and BatchDelete. Every call takes one token from the user's
+// bucket, which holds up to burst tokens and refills at rate tokens per
+// second; calls on an empty bucket fail with a *QuotaExceededError. Calls
+// without a user ID are not limited. A non-positive rate or burst leaves the
+// limit disabled.
+func WithRateLimit(rate float64, burst int) Option {
+	return func(s *URLService) {
+		s.limiter = ratelimit.New(rate, burst)
+	}
+}
+
+// QuotaExceededError is returned when a user's rate limit is exhausted.
+// It matches ErrQuotaExceeded with errors.Is.
+type QuotaExceededError struct {
+	RetryAfter time.Duration // How long until the user may call again
+}
+
+// Error returns the string representation of the QuotaExceededError.
+func (e *QuotaExceededError) Error() string {
+	return fmt.Sprintf("%v: retry after %v", ErrQuotaExceeded, e.RetryAfter)
+}
+
+// Unwrap returns ErrQuotaExceeded so callers can use errors.Is.
+func (e *QuotaExceededError) Unwrap() error {
+	return ErrQuotaExceeded
+}
+
+// checkRateLimit takes a token from userID's bucket and returns a
+// *QuotaExceededError if the bucket is empty.
+func (s *URLService) checkRateLimit(userID string) error {
+	if userID == "" {
+		return nil
+	}
+	if ok, wait := s.limiter.Allow(userID); !ok {
+		return &QuotaExceededError{RetryAfter: wait}
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Aleksey170999/go-shortener/internal/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestURLService_RateLimit(t *testing.T) {
+	ctx := context.Background()
+	s := NewURLService(repository.NewMemoryURLRepository(), WithRateLimit(0.001, 2))
+
+	_, err := s.Shorten(ctx, "https://example.com/1", "", "user1")
+	require.NoError(t, err)
+	_, err = s.BatchDelete(ctx, []string{"abc"}, "user1")
+	require.NoError(t, err)
+
+	_, err = s.Shorten(ctx, "https://example.com/2", "", "user1")
+	assert.ErrorIs(t, err, ErrQuotaExceeded)
+	var quotaErr *QuotaExceededError
+	require.ErrorAs(t, err, &quotaErr)
+	assert.Greater(t, quotaErr.RetryAfter, 900*time.Second, "a token takes 1000s at 0.001 calls per second")
+	_, err = s.ShortenBatch(ctx, []string{"https://example.com/3"}, "user1")
+	assert.ErrorIs(t, err, ErrQuotaExceeded)
+	_, err = s.BatchDelete(ctx, []string{"abc"}, "user1")
+	assert.ErrorIs(t, err, ErrQuotaExceeded)
+
+	_, err = s.Shorten(ctx, "https://example.com/2", "", "user2")
+	assert.NoError(t, err)
+	_, err = s.Shorten(ctx, "https://example.com/4", "", "")
+	assert.NoError(t, err, "anonymous calls are not limited")
+}
@@ -0,0 +1,89 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/Aleksey170999/go-shortener/internal/model"
+	"github.com/Aleksey170999/go-shortener/internal/repository"
+)
+
+// RetentionRule selects links that are removed permanently by the retention
+// runner, e.g. "links without an owner expire after 90 days" or "soft-deleted
+// links are purged after 30 days". A rule needs MaxAge, DeletedFor, or both;
+// all set conditions must hold.
+type RetentionRule struct {
+	Name       string        // Identifies the rule in logs and audit events
+	Anonymous  bool          // Only applies to links without an owner
+	MaxAge     time.Duration // Links created longer ago are removed
+	DeletedFor time.Duration // Links soft-deleted longer ago are removed
+}
+
+// filter returns the repository filter selecting the links the rule removes at now.
+func (r RetentionRule) filter(now time.Time) repository.PurgeFilter {
+	f := repository.PurgeFilter{Anonymous: r.Anonymous}
+	if r.MaxAge > 0 {
+		f.CreatedBefore = now.Add(-r.MaxAge)
+	}
+	if r.DeletedFor > 0 {
+		f.DeletedBefore = now.Add(-r.DeletedFor)
+	}
+	return f
+}
+
+// RetentionConfig configures the background retention runner.
+type RetentionConfig struct {
+	Interval time.Duration   // How often the rules are evaluated; non-positive disables the runner
+	Rules    []RetentionRule // Rules evaluated in order on every run
+	DryRun   bool            // Only log what would be removed
+
+	// OnRemove, if set, is called for every link removed by a rule, e.g. to
+	// write an audit event. It is not called in dry-run mode.
+	OnRemove func(ctx context.Context, rule RetentionRule, url model.URL)
+}
+
+// WithRetention enables the retention runner, returned by Jobs.
+func WithRetention(cfg RetentionConfig) Option {
+	return func(s *URLService) {
+		if cfg.Interval > 0 && len(cfg.Rules) > 0 {
+			s.retention = cfg
+		}
+	}
+}
+
+// runRetention evaluates every retention rule once. A failing rule does not
+// stop the others; their errors are joined.
+func (s *URLService) runRetention(ctx context.Context) error {
+	now := time.Now()
+	var errs []error
+	for _, rule := range s.retention.Rules {
+		urls, err := s.repo.PurgeMatching(ctx, rule.filter(now), s.retention.DryRun)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("rule %s: %w", rule.Name, err))
+			continue
+		}
+		if len(urls) == 0 {
+			continue
+		}
+		if s.retention.DryRun {
+			log.Printf("[retention] dry run: rule %s would remove %d urls", rule.Name, len(urls))
+			continue
+		}
+		log.Printf("[retention] rule %s removed %d urls", rule.Name, len(urls))
+
+		shorts := make([]string, len(urls))
+		for i, url := range urls {
+			shorts[i] = url.Short
+			if s.retention.OnRemove != nil {
+				s.retention.OnRemove(ctx, rule, url)
+			}
+		}
+		if s.cache != nil {
+			s.cache.remove(shorts...)
+		}
+	}
+	return errors.Join(errs...)
+}
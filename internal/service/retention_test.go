@@ -0,0 +1,56 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Aleksey170999/go-shortener/internal/model"
+	"github.com/Aleksey170999/go-shortener/internal/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestURLService_RunRetention(t *testing.T) {
+	ctx := context.Background()
+	repo := repository.NewMemoryURLRepository()
+	old := time.Now().Add(-48 * time.Hour)
+	deletedAt := time.Now().Add(-48 * time.Hour)
+	for _, url := range []*model.URL{
+		{ID: "1", Short: "anon", Original: "https://example.com/1", CreatedAt: old},
+		{ID: "2", Short: "owned", Original: "https://example.com/2", UserID: "user1", CreatedAt: old},
+		{ID: "3", Short: "deleted", Original: "https://example.com/3", UserID: "user1", CreatedAt: old, IsDeleted: true, DeletedAt: &deletedAt},
+	} {
+		_, err := repo.Save(ctx, url)
+		require.NoError(t, err)
+	}
+
+	removed := make(map[string]string)
+	cfg := RetentionConfig{
+		Interval: time.Hour,
+		Rules: []RetentionRule{
+			{Name: "anonymous", Anonymous: true, MaxAge: 24 * time.Hour},
+			{Name: "deleted", DeletedFor: 24 * time.Hour},
+		},
+		OnRemove: func(_ context.Context, rule RetentionRule, url model.URL) {
+			removed[url.Short] = rule.Name
+		},
+	}
+
+	dryRun := cfg
+	dryRun.DryRun = true
+	s := NewURLService(repo, WithRetention(dryRun))
+	require.NoError(t, s.runRetention(ctx))
+	require.NoError(t, s.Shutdown(ctx))
+	assert.Empty(t, removed, "dry run must not report removals")
+
+	s = NewURLService(repo, WithRetention(cfg))
+	require.NoError(t, s.runRetention(ctx))
+	require.NoError(t, s.Shutdown(ctx))
+	assert.Equal(t, map[string]string{"anon": "anonymous", "deleted": "deleted"}, removed)
+
+	_, err := repo.GetByShortURL(ctx, "owned")
+	assert.NoError(t, err)
+	_, err = repo.GetByShortURL(ctx, "anon")
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+}
@@ -0,0 +1,104 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Aleksey170999/go-shortener/internal/model"
+)
+
+// StatsConfig configures in-memory click aggregation.
+type StatsConfig struct {
+	Bucket        time.Duration // Width of the time bucket clicks are grouped into
+	FlushInterval time.Duration // How often aggregated clicks are written; non-positive disables stats
+}
+
+// WithStats enables click statistics. Clicks recorded with RecordClick are
+// counted in memory per short URL and time bucket and written to the
+// repository every FlushInterval by the stats job returned by Jobs, so
+// redirects never wait on a stats write.
+func WithStats(cfg StatsConfig) Option {
+	return func(s *URLService) {
+		if cfg.FlushInterval > 0 && cfg.Bucket > 0 {
+			s.stats = newStatsAggregator(cfg.Bucket)
+			s.statsFlushInterval = cfg.FlushInterval
+		}
+	}
+}
+
+// statsKey identifies a click counter.
+type statsKey struct {
+	short  string
+	bucket time.Time
+}
+
+// statsAggregator counts clicks per short URL and time bucket. It is safe for concurrent use.
+type statsAggregator struct {
+	bucket time.Duration
+	mu     sync.Mutex
+	counts map[statsKey]int64
+	now    func() time.Time
+}
+
+func newStatsAggregator(bucket time.Duration) *statsAggregator {
+	return &statsAggregator{
+		bucket: bucket,
+		counts: make(map[statsKey]int64),
+		now:    time.Now,
+	}
+}
+
+// record counts one click on short in the current bucket.
+func (a *statsAggregator) record(short string) {
+	key := statsKey{short: short, bucket: a.now().UTC().Truncate(a.bucket)}
+	a.mu.Lock()
+	a.counts[key]++
+	a.mu.Unlock()
+}
+
+// drain returns all accumulated counts and resets the aggregator.
+func (a *statsAggregator) drain() []model.ClickStat {
+	a.mu.Lock()
+	counts := a.counts
+	a.counts = make(map[statsKey]int64, len(counts))
+	a.mu.Unlock()
+
+	stats := make([]model.ClickStat, 0, len(counts))
+	for key, clicks := range counts {
+		stats = append(stats, model.ClickStat{Short: key.short, Bucket: key.bucket, Clicks: clicks})
+	}
+	return stats
+}
+
+// restore adds counts back after a failed flush so they are retried later.
+func (a *statsAggregator) restore(stats []model.ClickStat) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for _, stat := range stats {
+		a.counts[statsKey{short: stat.Short, bucket: stat.Bucket}] += stat.Clicks
+	}
+}
+
+// RecordClick registers a redirect through shortURL. It only updates an
+// in-memory counter and is a no-op when statistics are disabled.
+func (s *URLService) RecordClick(shortURL string) {
+	if s.stats != nil {
+		s.stats.record(shortURL)
+	}
+}
+
+// flushStats writes the aggregated clicks. Clicks that cannot be written
+// are kept for the next flush.
+func (s *URLService) flushStats(ctx context.Context) error {
+	stats := s.stats.drain()
+	if len(stats) == 0 {
+		return nil
+	}
+	if err := s.repo.AddClickStats(ctx, stats); err != nil {
+		s.stats.restore(stats)
+		return fmt.Errorf("click stats write: %w", err)
+	}
+	return nil
+}
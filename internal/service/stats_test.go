@@ -0,0 +1,71 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Aleksey170999/go-shortener/internal/jobs"
+	"github.com/Aleksey170999/go-shortener/internal/mocks"
+	"github.com/Aleksey170999/go-shortener/internal/model"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestStatsAggregator(t *testing.T) {
+	a := newStatsAggregator(time.Hour)
+	now := time.Date(2025, 1, 1, 10, 30, 0, 0, time.UTC)
+	a.now = func() time.Time { return now }
+
+	a.record("abc")
+	a.record("abc")
+	a.record("def")
+	now = now.Add(time.Hour)
+	a.record("abc")
+
+	stats := a.drain()
+	assert.ElementsMatch(t, []model.ClickStat{
+		{Short: "abc", Bucket: time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC), Clicks: 2},
+		{Short: "def", Bucket: time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC), Clicks: 1},
+		{Short: "abc", Bucket: time.Date(2025, 1, 1, 11, 0, 0, 0, time.UTC), Clicks: 1},
+	}, stats)
+	assert.Empty(t, a.drain())
+
+	a.restore(stats)
+	assert.Len(t, a.drain(), 3)
+}
+
+func TestURLService_RecordClick_FlushesOnStop(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	repo := mocks.NewMockURLRepository(ctrl)
+
+	var written []model.ClickStat
+	gomock.InOrder(
+		repo.EXPECT().AddClickStats(gomock.Any(), gomock.Any()).Return(errors.New("db down")),
+		repo.EXPECT().AddClickStats(gomock.Any(), gomock.Any()).DoAndReturn(
+			func(_ context.Context, stats []model.ClickStat) error {
+				written = stats
+				return nil
+			}),
+	)
+
+	ctx := context.Background()
+	s := NewURLService(repo, WithStats(StatsConfig{Bucket: time.Hour, FlushInterval: time.Hour}))
+	runner := jobs.NewRunner(zap.NewNop())
+	runner.Add(s.Jobs()...)
+	runner.Start(ctx)
+	s.RecordClick("abc")
+	s.RecordClick("abc")
+
+	// The first flush fails and the counts are kept for the final flush when
+	// the jobs stop.
+	require.Error(t, s.flushStats(ctx))
+	require.NoError(t, s.Shutdown(ctx))
+	require.NoError(t, runner.Stop(ctx))
+	require.Len(t, written, 1)
+	assert.Equal(t, "abc", written[0].Short)
+	assert.Equal(t, int64(2), written[0].Clicks)
+}
@@ -4,78 +4,285 @@ import (
 	"context"
 	"crypto/rand"
 	"encoding/base64"
+	"errors"
 	"fmt"
 	"log"
+	"sync"
 	"time"
 
+	"github.com/Aleksey170999/go-shortener/internal/jobs"
 	"github.com/Aleksey170999/go-shortener/internal/model"
+	"github.com/Aleksey170999/go-shortener/internal/ratelimit"
 	"github.com/Aleksey170999/go-shortener/internal/repository"
 	"github.com/google/uuid"
+	"golang.org/x/sync/singleflight"
 
 	"io"
 )
 
+const (
+	// shortURLLength is the length of a freshly generated short code.
+	shortURLLength = 6
+	// maxShortenAttempts bounds how many codes Shorten tries before giving up.
+	// Each retry uses a code one character longer than the previous one.
+	maxShortenAttempts = 5
+	// defaultQueryTimeout is the default of WithQueryTimeout.
+	defaultQueryTimeout = 5 * time.Second
+)
+
+// ErrShortURLExhausted is returned by Shorten when no free short code could be
+// generated within maxShortenAttempts attempts.
+var ErrShortURLExhausted = errors.New("failed to generate unique short url")
+
 type deleteRequest struct {
 	ShortURLs []string
 	UserID    string
+	JobID     string
 }
 
+const (
+	// defaultDeleteBatchSize is the number of queued delete requests that triggers an immediate flush.
+	defaultDeleteBatchSize = 50
+	// defaultDeleteFlushInterval is how often a partially filled delete batch is flushed.
+	defaultDeleteFlushInterval = 100 * time.Millisecond
+	// defaultDeleteQueueSize is the capacity of the pending delete request queue.
+	defaultDeleteQueueSize = 100
+	// defaultDeleteWorkers is the number of goroutines draining the delete queue.
+	defaultDeleteWorkers = 1
+)
+
+// ErrDeleteQueueFull is returned by BatchDelete when the delete queue has no
+// free capacity. Callers should report it as a temporary unavailability.
+var ErrDeleteQueueFull = errors.New("delete queue is full")
+
+// ErrServiceClosed is returned by BatchDelete after Shutdown has been called.
+var ErrServiceClosed = errors.New("url service is shut down")
+
 // URLService provides high-level operations for URL shortening and management.
 // It handles business logic and coordinates with the repository layer for data persistence.
 // URLService is safe for concurrent use by multiple goroutines.
 type URLService struct {
-	repo        repository.URLRepository // Underlying repository for data access
-	deleteReqCh chan deleteRequest       // Channel for asynchronous delete operations
+	repo                repository.URLRepository         // Underlying repository for data access
+	deleteReqCh         chan deleteRequest               // Channel for asynchronous delete operations
+	deleteBatchSize     int                              // Number of requests that triggers a flush
+	deleteFlushInterval time.Duration                    // Maximum time a request waits in a partial batch
+	deleteQueueSize     int                              // Capacity of deleteReqCh
+	deleteWorkers       int                              // Number of deleteWorker goroutines
+	deleteWG            sync.WaitGroup                   // Tracks running deleteWorker goroutines
+	closeMu             sync.RWMutex                     // Guards closed and closing deleteReqCh
+	closed              bool                             // Set once Shutdown has been called
+	cache               *resolveCache                    // Optional Resolve cache, nil when disabled
+	resolveGroup        singleflight.Group               // Collapses concurrent lookups of the same short code
+	breaker             *circuitBreaker                  // Optional repository circuit breaker, nil when disabled
+	cleanup             CleanupConfig                    // Cleanup job settings, zero Interval when disabled
+	aliasPolicy         AliasPolicy                      // Rules for custom aliases
+	stats               *statsAggregator                 // Optional click aggregator, nil when disabled
+	statsFlushInterval  time.Duration                    // How often aggregated clicks are written
+	hooks               hookList                         // Registered operation hooks
+	hashCodes           bool                             // Derive short codes from the URL hash instead of randomly
+	perUserDedup        bool                             // The repository deduplicates original URLs per user
+	deleteJobs          *deleteJobs                      // Progress of BatchDelete calls
+	limiter             *ratelimit.Limiter               // Optional per-user rate limiter, nil when disabled
+	retention           RetentionConfig                  // Retention runner settings, zero Interval when disabled
+	onDeleteJobDone     func(context.Context, DeleteJob) // Optional callback for processed delete jobs
+	queryTimeout        time.Duration                    // Bounds repository calls not tied to a caller's deadline
+}
+
+// Option configures optional URLService parameters.
+type Option func(*URLService)
+
+// WithDeleteBatchSize sets how many delete requests are accumulated before
+// they are flushed to the repository. Non-positive values are ignored.
+func WithDeleteBatchSize(n int) Option {
+	return func(s *URLService) {
+		if n > 0 {
+			s.deleteBatchSize = n
+		}
+	}
+}
+
+// WithDeleteFlushInterval sets how often a partially filled delete batch is
+// flushed to the repository. Non-positive values are ignored.
+func WithDeleteFlushInterval(d time.Duration) Option {
+	return func(s *URLService) {
+		if d > 0 {
+			s.deleteFlushInterval = d
+		}
+	}
+}
+
+// WithDeleteQueueSize sets the capacity of the delete request queue.
+// Once the queue is full BatchDelete fails fast with ErrDeleteQueueFull.
+// Non-positive values are ignored.
+func WithDeleteQueueSize(n int) Option {
+	return func(s *URLService) {
+		if n > 0 {
+			s.deleteQueueSize = n
+		}
+	}
+}
+
+// WithDeleteWorkers sets the number of goroutines that drain the delete queue.
+// Non-positive values are ignored.
+func WithDeleteWorkers(n int) Option {
+	return func(s *URLService) {
+		if n > 0 {
+			s.deleteWorkers = n
+		}
+	}
+}
+
+// WithResolveCache enables an in-process LRU cache for Resolve holding up to
+// size URLs, each for at most ttl (a non-positive ttl disables expiry).
+// Cached entries are invalidated when the URLs are deleted through this service.
+// A non-positive size leaves the cache disabled.
+func WithResolveCache(size int, ttl time.Duration) Option {
+	return func(s *URLService) {
+		if size > 0 {
+			s.cache = newResolveCache(size, ttl)
+		}
+	}
+}
+
+// WithQueryTimeout bounds the repository calls that are not bounded by a
+// single caller's deadline: lookups shared between concurrent Resolve calls,
+// which run detached from the callers' contexts, and PingDB. It defaults to
+// 5 seconds. Non-positive values are ignored.
+func WithQueryTimeout(d time.Duration) Option {
+	return func(s *URLService) {
+		if d > 0 {
+			s.queryTimeout = d
+		}
+	}
+}
+
+// WithCircuitBreaker guards request-path repository calls with a circuit breaker
+// that opens after threshold consecutive failures and rejects calls with
+// ErrCircuitOpen for cooldown before probing the repository again.
+// Background deletions bypass the breaker so queued deletes are never dropped.
+// A non-positive threshold leaves the breaker disabled.
+func WithCircuitBreaker(threshold int, cooldown time.Duration) Option {
+	return func(s *URLService) {
+		if threshold > 0 {
+			s.breaker = newCircuitBreaker(threshold, cooldown)
+		}
+	}
 }
 
 // NewURLService creates a new instance of URLService with the provided repository.
-// It initializes the background worker for processing batch delete operations.
+// It starts the background workers for processing batch delete operations.
 // The repository parameter must not be nil.
-func NewURLService(repo repository.URLRepository) *URLService {
+func NewURLService(repo repository.URLRepository, opts ...Option) *URLService {
 	s := &URLService{
-		repo:        repo,
-		deleteReqCh: make(chan deleteRequest, 100),
+		repo:                repo,
+		deleteBatchSize:     defaultDeleteBatchSize,
+		deleteFlushInterval: defaultDeleteFlushInterval,
+		deleteQueueSize:     defaultDeleteQueueSize,
+		deleteWorkers:       defaultDeleteWorkers,
+		aliasPolicy:         DefaultAliasPolicy(),
+		deleteJobs:          newDeleteJobs(),
+		queryTimeout:        defaultQueryTimeout,
+	}
+	if d, ok := repo.(repository.DedupReporter); ok {
+		s.perUserDedup = d.PerUserDedup()
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	s.deleteReqCh = make(chan deleteRequest, s.deleteQueueSize)
+	s.deleteWG.Add(s.deleteWorkers)
+	for i := 0; i < s.deleteWorkers; i++ {
+		go s.deleteWorker()
 	}
-	go s.deleteWorker()
 	return s
 }
 
+// Jobs returns the periodic jobs enabled by the options: the cleanup job
+// purging expired links, the retention runner, and the stats job flushing
+// aggregated clicks, which also flushes once more when the jobs are stopped.
+// The service does not run them itself; the caller adds them to a
+// jobs.Runner and stops it after Shutdown, so that no click recorded by a
+// request in flight is lost.
+func (s *URLService) Jobs() []jobs.Job {
+	var list []jobs.Job
+	if s.cleanup.Interval > 0 {
+		list = append(list, jobs.Job{Name: "cleanup", Schedule: jobs.Every(s.cleanup.Interval), Run: s.runCleanup})
+	}
+	if s.retention.Interval > 0 {
+		list = append(list, jobs.Job{Name: "retention", Schedule: jobs.Every(s.retention.Interval), Run: s.runRetention})
+	}
+	if s.stats != nil {
+		list = append(list, jobs.Job{Name: "stats", Schedule: jobs.Every(s.statsFlushInterval), Run: s.flushStats, RunOnStop: true})
+	}
+	return list
+}
+
+// deleteWorker accumulates delete requests and flushes them either when the
+// batch is full or when the flush interval elapses, whichever comes first.
+// It blocks in select while idle instead of polling. Once deleteReqCh is closed
+// the worker flushes whatever it still holds and exits.
 func (s *URLService) deleteWorker() {
-	batch := make([]deleteRequest, 0)
-	batchSize := 50
-	batchTimeout := 100
+	defer s.deleteWG.Done()
+	ticker := time.NewTicker(s.deleteFlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]deleteRequest, 0, s.deleteBatchSize)
 	for {
 		select {
-		case req := <-s.deleteReqCh:
+		case req, ok := <-s.deleteReqCh:
+			if !ok {
+				if len(batch) > 0 {
+					s.flushBatch(batch)
+				}
+				return
+			}
 			batch = append(batch, req)
-			if len(batch) >= batchSize {
+			if len(batch) >= s.deleteBatchSize {
 				s.flushBatch(batch)
 				batch = batch[:0]
+				ticker.Reset(s.deleteFlushInterval)
 			}
-		default:
+		case <-ticker.C:
 			if len(batch) > 0 {
 				s.flushBatch(batch)
 				batch = batch[:0]
 			}
-			time.Sleep(time.Millisecond * time.Duration(batchTimeout))
 		}
 	}
 }
 
 func (s *URLService) flushBatch(batch []deleteRequest) {
-	userURLs := make(map[string][]string)
+	userReqs := make(map[string][]deleteRequest)
 	for _, req := range batch {
-		userURLs[req.UserID] = append(userURLs[req.UserID], req.ShortURLs...)
+		userReqs[req.UserID] = append(userReqs[req.UserID], req)
 	}
-	for userID, urls := range userURLs {
-		if err := s.repo.BatchDelete(urls, userID); err != nil {
+	for userID, reqs := range userReqs {
+		var urls []string
+		for _, req := range reqs {
+			urls = append(urls, req.ShortURLs...)
+		}
+		ctx := context.Background()
+		start := time.Now()
+		err := s.repo.BatchDelete(ctx, urls, userID)
+		s.hooks.OnDelete(ctx, time.Since(start), err)
+		if err != nil {
 			log.Printf("[flushBatch] batch delete error: %v", err)
 		}
+		for _, req := range reqs {
+			job, done := s.deleteJobs.finish(req.JobID, len(req.ShortURLs), err)
+			if done && s.onDeleteJobDone != nil {
+				s.onDeleteJobDone(ctx, job)
+			}
+		}
+		if s.cache != nil {
+			s.cache.remove(urls...)
+		}
 	}
 }
 
-// Ping DataBase
-func (s *URLService) PingDB() error {
+// PingDB checks that the underlying database is reachable.
+// The ping is bounded by ctx and the query timeout (see WithQueryTimeout).
+func (s *URLService) PingDB(ctx context.Context) error {
 	// Check if the repository is a database repository
 	dbRepo, ok := s.repo.(*repository.DataBaseURLRepository)
 	if !ok {
@@ -83,7 +290,7 @@ func (s *URLService) PingDB() error {
 	}
 
 	// Create a context with timeout
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, s.queryTimeout)
 	defer cancel()
 
 	// Ping the database
@@ -92,7 +299,14 @@ func (s *URLService) PingDB() error {
 
 // Shorten creates a new shortened URL for the given original URL.
 // If the original URL already exists in the repository, the existing short URL is returned.
+// If a generated short code collides with an existing one, Shorten retries with a
+// longer code up to maxShortenAttempts times before returning ErrShortURLExhausted.
+// The original URL is canonicalized first (see CanonicalizeURL), so equivalent
+// spellings of the same URL share one short code; ErrInvalidURL is returned if
+// it cannot be canonicalized.
+// ErrQuotaExceeded is returned if the user's rate limit (see WithRateLimit) is exhausted.
 // Parameters:
+//   - ctx: Request context used for cancellation of repository calls
 //   - original: The original URL to be shortened
 //   - id: Optional custom ID for the short URL. If empty, a random string will be generated.
 //   - userID: ID of the user creating the short URL
@@ -100,8 +314,18 @@ func (s *URLService) PingDB() error {
 // Returns:
 //   - *model.URL: The created or existing URL object
 //   - error: Non-nil if an error occurs during the operation
-func (s *URLService) Shorten(original, id, userID string) (*model.URL, error) {
-	shortURL, err := generateShortURL(6)
+func (s *URLService) Shorten(ctx context.Context, original, id, userID string) (*model.URL, error) {
+	start := time.Now()
+	url, err := s.shorten(ctx, original, id, userID)
+	s.hooks.OnShorten(ctx, time.Since(start), err)
+	return url, err
+}
+
+func (s *URLService) shorten(ctx context.Context, original, id, userID string) (*model.URL, error) {
+	if err := s.checkRateLimit(userID); err != nil {
+		return nil, err
+	}
+	original, err := CanonicalizeURL(original)
 	if err != nil {
 		return nil, err
 	}
@@ -111,47 +335,223 @@ func (s *URLService) Shorten(original, id, userID string) (*model.URL, error) {
 	} else {
 		recID = id
 	}
-	url := &model.URL{
-		ID:       recID,
-		Original: original,
-		Short:    shortURL,
-		UserID:   userID,
-	}
-	url, err = s.repo.Save(url)
-	if err != nil {
+	for attempt := 0; attempt < maxShortenAttempts; attempt++ {
+		shortURL, err := s.newShortCode(original, userID, attempt)
+		if err != nil {
+			return nil, err
+		}
+		url := &model.URL{
+			ID:        recID,
+			Original:  original,
+			Short:     shortURL,
+			UserID:    userID,
+			CreatedAt: time.Now(),
+		}
+		err = s.breaker.do(ctx, func() error {
+			var saveErr error
+			url, saveErr = s.repo.Save(ctx, url)
+			return saveErr
+		})
+		if errors.Is(err, model.ErrShortURLConflict) {
+			if existing, ok := s.existingHashed(ctx, shortURL, original, userID); ok {
+				return existing, model.ErrURLAlreadyExists
+			}
+			continue
+		}
 		return url, err
 	}
-	return url, nil
+	return nil, ErrShortURLExhausted
+}
+
+// BatchResult is the outcome of shortening a single URL in ShortenBatch.
+type BatchResult struct {
+	// URL is the stored URL, or the already existing one when Err is model.ErrURLAlreadyExists.
+	URL *model.URL
+	// Err is nil on success, model.ErrURLAlreadyExists for known originals,
+	// or ErrShortURLExhausted if no free short code was found.
+	Err error
+}
+
+// ShortenBatch creates short URLs for all originals using a single repository
+// call per attempt. Items whose generated code collides are retried with a
+// longer code, like in Shorten. Originals are canonicalized like in Shorten
+// before anything is stored, so that a batch with an invalid one is
+// rejected as a whole.
+//
+// Parameters:
+//   - ctx: Request context used for cancellation of repository calls
+//   - originals: The original URLs to be shortened
+//   - userID: ID of the user creating the short URLs
+//
+// Returns:
+//   - []BatchResult: One result per original URL, in the same order
+//   - error: ErrInvalidURL if any original URL is invalid, or non-nil if
+//     the batch as a whole could not be stored
+func (s *URLService) ShortenBatch(ctx context.Context, originals []string, userID string) ([]BatchResult, error) {
+	start := time.Now()
+	results, err := s.shortenBatch(ctx, originals, userID)
+	s.hooks.OnShorten(ctx, time.Since(start), err)
+	return results, err
+}
+
+func (s *URLService) shortenBatch(ctx context.Context, originals []string, userID string) ([]BatchResult, error) {
+	if err := s.checkRateLimit(userID); err != nil {
+		return nil, err
+	}
+	results := make([]BatchResult, len(originals))
+	pending := make([]int, 0, len(originals))
+	for i, original := range originals {
+		canonical, err := CanonicalizeURL(original)
+		if err != nil {
+			return nil, fmt.Errorf("url %d: %w", i, err)
+		}
+		results[i].URL = &model.URL{
+			ID:        uuid.New().String(),
+			Original:  canonical,
+			UserID:    userID,
+			CreatedAt: time.Now(),
+		}
+		pending = append(pending, i)
+	}
+
+	for attempt := 0; attempt < maxShortenAttempts && len(pending) > 0; attempt++ {
+		urls := make([]*model.URL, len(pending))
+		for j, i := range pending {
+			shortURL, err := s.newShortCode(results[i].URL.Original, userID, attempt)
+			if err != nil {
+				return nil, err
+			}
+			results[i].URL.Short = shortURL
+			urls[j] = results[i].URL
+		}
+
+		var errs []error
+		err := s.breaker.do(ctx, func() error {
+			var saveErr error
+			errs, saveErr = s.repo.SaveBatch(ctx, urls)
+			return saveErr
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		retry := pending[:0]
+		for j, i := range pending {
+			if errors.Is(errs[j], model.ErrShortURLConflict) {
+				if existing, ok := s.existingHashed(ctx, results[i].URL.Short, results[i].URL.Original, userID); ok {
+					results[i] = BatchResult{URL: existing, Err: model.ErrURLAlreadyExists}
+					continue
+				}
+				retry = append(retry, i)
+				continue
+			}
+			results[i].Err = errs[j]
+		}
+		pending = retry
+	}
+
+	for _, i := range pending {
+		results[i] = BatchResult{Err: ErrShortURLExhausted}
+	}
+	return results, nil
 }
 
 // Resolve retrieves the original URL for a given short URL.
-// Returns model.ErrNotFound if no URL with the given short code exists.
+// Returns ErrNotFound if no URL with the given short code exists. Deleted and
+// expired URLs are returned together with ErrDeleted or ErrExpired respectively.
+// When the resolve cache is enabled, hits are served without a repository call.
+// Concurrent calls for the same short code share a single repository lookup;
+// each caller still stops waiting as soon as its own ctx is done.
 //
 // Parameters:
+//   - ctx: Request context used for cancellation of repository calls
 //   - shortURL: The short URL code to resolve
 //
 // Returns:
 //   - *model.URL: The URL object containing the original URL
-//   - error: Non-nil if the URL is not found or an error occurs
-func (s *URLService) Resolve(shortURL string) (*model.URL, error) {
-	url, err := s.repo.GetByShortURL(shortURL)
+//   - error: Non-nil if the URL is not found, deleted, expired or an error occurs
+func (s *URLService) Resolve(ctx context.Context, shortURL string) (*model.URL, error) {
+	start := time.Now()
+	url, err := s.resolve(ctx, shortURL)
+	s.hooks.OnResolve(ctx, time.Since(start), err)
+	return url, err
+}
+
+func (s *URLService) resolve(ctx context.Context, shortURL string) (*model.URL, error) {
+	url, err := s.lookup(ctx, shortURL)
 	if err != nil {
 		return nil, err
 	}
+	switch {
+	case url.IsDeleted:
+		return url, ErrDeleted
+	case url.IsExpired(time.Now()):
+		return url, ErrExpired
+	}
 	return url, nil
 }
 
+// lookup fetches a URL from the cache or, sharing the call between concurrent
+// callers, from the repository.
+func (s *URLService) lookup(ctx context.Context, shortURL string) (*model.URL, error) {
+	if s.cache != nil {
+		if url, ok := s.cache.get(shortURL); ok {
+			return url, nil
+		}
+	}
+
+	ch := s.resolveGroup.DoChan(shortURL, func() (interface{}, error) {
+		// The shared call outlives its callers, and the expiry of the query
+		// timeout is a failure of the repository rather than a cancellation.
+		sharedCtx := context.WithoutCancel(ctx)
+		lookupCtx, cancel := context.WithTimeout(sharedCtx, s.queryTimeout)
+		defer cancel()
+
+		var url *model.URL
+		err := s.breaker.do(sharedCtx, func() error {
+			var getErr error
+			url, getErr = s.repo.GetByShortURL(lookupCtx, shortURL)
+			return getErr
+		})
+		if err != nil {
+			return nil, translateRepoError(err)
+		}
+		if s.cache != nil {
+			s.cache.add(url)
+		}
+		return url, nil
+	})
+
+	select {
+	case res := <-ch:
+		if res.Err != nil {
+			return nil, res.Err
+		}
+		url := *res.Val.(*model.URL)
+		return &url, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
 // GetUserURLs retrieves all URLs created by a specific user.
-// Returns an empty slice if the user has no URLs.
+// Returns ErrNotFound or an empty slice if the user has no URLs.
 //
 // Parameters:
+//   - ctx: Request context used for cancellation of repository calls
 //   - userID: The ID of the user
 //
 // Returns:
 //   - []model.URL: A slice of URLs created by the user
 //   - error: Non-nil if an error occurs during the operation
-func (s *URLService) GetUserURLs(userID string) ([]model.URL, error) {
-	return s.repo.GetByUserID(userID)
+func (s *URLService) GetUserURLs(ctx context.Context, userID string) ([]model.URL, error) {
+	var urls []model.URL
+	err := s.breaker.do(ctx, func() error {
+		var getErr error
+		urls, getErr = s.repo.GetByUserID(ctx, userID)
+		return getErr
+	})
+	return urls, translateRepoError(err)
 }
 
 func generateShortURL(n int) (string, error) {
@@ -166,15 +566,67 @@ func generateShortURL(n int) (string, error) {
 // BatchDelete schedules URLs for deletion in a background worker.
 // This is an asynchronous operation that marks URLs as deleted without blocking.
 // Only URLs belonging to the specified user will be deleted.
+// The actual deletion runs detached from ctx. If the delete queue is full the
+// request is rejected with ErrDeleteQueueFull rather than blocking the caller.
+// The returned job ID can be passed to GetDeleteJob to follow the progress.
 //
 // Parameters:
+//   - ctx: Context checked before the request is queued
 //   - shortURLs: A slice of short URL codes to delete
 //   - userID: The ID of the user performing the deletion
 //
 // Returns:
-//   - error: Always returns nil as the operation is asynchronous
-//     (errors are logged but not returned to the caller)
-func (s *URLService) BatchDelete(shortURLs []string, userID string) error {
-	s.deleteReqCh <- deleteRequest{ShortURLs: shortURLs, UserID: userID}
-	return nil
+//   - string: ID of the delete job tracking the request
+//   - error: ctx.Err() if the context is already done, ErrDeleteQueueFull if
+//     the queue has no capacity, ErrServiceClosed after Shutdown,
+//     ErrQuotaExceeded if the user's rate limit is exhausted;
+//     deletion errors are reported through the job, not returned
+func (s *URLService) BatchDelete(ctx context.Context, shortURLs []string, userID string) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	s.closeMu.RLock()
+	defer s.closeMu.RUnlock()
+	if s.closed {
+		return "", ErrServiceClosed
+	}
+	if err := s.checkRateLimit(userID); err != nil {
+		return "", err
+	}
+	jobID := s.deleteJobs.create(userID, len(shortURLs))
+	select {
+	case s.deleteReqCh <- deleteRequest{ShortURLs: shortURLs, UserID: userID, JobID: jobID}:
+		return jobID, nil
+	default:
+		s.deleteJobs.remove(jobID)
+		return "", ErrDeleteQueueFull
+	}
+}
+
+// Shutdown stops accepting new delete requests, lets the delete workers drain
+// the queue and flush their pending batches, and waits for them to exit. The
+// periodic jobs returned by Jobs are stopped by their jobs.Runner.
+// It returns ctx.Err() if ctx is done before the workers finish; in that case
+// the workers keep draining in the background. Calling Shutdown more than once
+// is safe.
+func (s *URLService) Shutdown(ctx context.Context) error {
+	s.closeMu.Lock()
+	if !s.closed {
+		s.closed = true
+		close(s.deleteReqCh)
+	}
+	s.closeMu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		s.deleteWG.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
 }
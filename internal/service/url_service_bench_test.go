@@ -1,8 +1,12 @@
 package service
 
 import (
+	"context"
+	"errors"
+	"runtime"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/Aleksey170999/go-shortener/internal/model"
 	"github.com/Aleksey170999/go-shortener/internal/repository"
@@ -21,7 +25,7 @@ func newMemoryURLRepository() *memoryURLRepository {
 	}
 }
 
-func (r *memoryURLRepository) Save(url *model.URL) (*model.URL, error) {
+func (r *memoryURLRepository) Save(_ context.Context, url *model.URL) (*model.URL, error) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
@@ -29,7 +33,17 @@ func (r *memoryURLRepository) Save(url *model.URL) (*model.URL, error) {
 	return url, nil
 }
 
-func (r *memoryURLRepository) GetByShortURL(shortURL string) (*model.URL, error) {
+func (r *memoryURLRepository) SaveBatch(_ context.Context, urls []*model.URL) ([]error, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, url := range urls {
+		r.data[url.Short] = url
+	}
+	return make([]error, len(urls)), nil
+}
+
+func (r *memoryURLRepository) GetByShortURL(_ context.Context, shortURL string) (*model.URL, error) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
@@ -40,7 +54,7 @@ func (r *memoryURLRepository) GetByShortURL(shortURL string) (*model.URL, error)
 	return url, nil
 }
 
-func (r *memoryURLRepository) GetByUserID(userID string) ([]model.URL, error) {
+func (r *memoryURLRepository) GetByUserID(_ context.Context, userID string) ([]model.URL, error) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
@@ -54,7 +68,7 @@ func (r *memoryURLRepository) GetByUserID(userID string) ([]model.URL, error) {
 	return urls, nil
 }
 
-func (r *memoryURLRepository) BatchDelete(shortURLs []string, userID string) error {
+func (r *memoryURLRepository) BatchDelete(_ context.Context, shortURLs []string, userID string) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
@@ -68,14 +82,27 @@ func (r *memoryURLRepository) BatchDelete(shortURLs []string, userID string) err
 	return nil
 }
 
+func (r *memoryURLRepository) Purge(_ context.Context, _, _ time.Time, _ bool) (repository.PurgeStats, error) {
+	return repository.PurgeStats{}, nil
+}
+
+func (r *memoryURLRepository) PurgeMatching(_ context.Context, _ repository.PurgeFilter, _ bool) ([]model.URL, error) {
+	return nil, nil
+}
+
+func (r *memoryURLRepository) AddClickStats(_ context.Context, _ []model.ClickStat) error {
+	return nil
+}
+
 func BenchmarkURLService_Shorten(b *testing.B) {
 	repo := newMemoryURLRepository()
 	service := NewURLService(repo)
 	userID := "test-user"
+	ctx := context.Background()
 
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		_, err := service.Shorten("https://example.com", "", userID)
+		_, err := service.Shorten(ctx, "https://example.com", "", userID)
 		require.NoError(b, err)
 	}
 }
@@ -84,11 +111,12 @@ func BenchmarkURLService_Resolve(b *testing.B) {
 	repo := newMemoryURLRepository()
 	service := NewURLService(repo)
 	userID := "test-user"
+	ctx := context.Background()
 
 	// Pre-populate with test data
 	urls := make([]*model.URL, 1000)
 	for i := 0; i < 1000; i++ {
-		url, err := service.Shorten("https://example.com", "", userID)
+		url, err := service.Shorten(ctx, "https://example.com", "", userID)
 		if err != nil {
 			b.Fatal(err)
 		}
@@ -102,7 +130,7 @@ func BenchmarkURLService_Resolve(b *testing.B) {
 
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		_, err := service.Resolve(shortURLs[i%len(shortURLs)])
+		_, err := service.Resolve(ctx, shortURLs[i%len(shortURLs)])
 		require.NoError(b, err)
 	}
 }
@@ -111,10 +139,11 @@ func BenchmarkURLService_GetUserURLs(b *testing.B) {
 	repo := newMemoryURLRepository()
 	service := NewURLService(repo)
 	userID := "test-user"
+	ctx := context.Background()
 
 	// Pre-populate with test data
 	for i := 0; i < 1000; i++ {
-		_, err := service.Shorten("https://example.com", "", userID)
+		_, err := service.Shorten(ctx, "https://example.com", "", userID)
 		if err != nil {
 			b.Fatal(err)
 		}
@@ -122,7 +151,7 @@ func BenchmarkURLService_GetUserURLs(b *testing.B) {
 
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		_, err := service.GetUserURLs(userID)
+		_, err := service.GetUserURLs(ctx, userID)
 		require.NoError(b, err)
 	}
 }
@@ -131,12 +160,13 @@ func BenchmarkURLService_BatchDelete(b *testing.B) {
 	repo := newMemoryURLRepository()
 	service := NewURLService(repo)
 	userID := "test-user"
+	ctx := context.Background()
 
 	// Pre-populate with test data
 	urls := make([]*model.URL, 1000)
 	shortURLs := make([]string, 1000)
 	for i := 0; i < 1000; i++ {
-		url, err := service.Shorten("https://example.com", "", userID)
+		url, err := service.Shorten(ctx, "https://example.com", "", userID)
 		if err != nil {
 			b.Fatal(err)
 		}
@@ -155,7 +185,11 @@ func BenchmarkURLService_BatchDelete(b *testing.B) {
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
 		batch := batches[i%len(batches)]
-		err := service.BatchDelete(batch, userID)
+		_, err := service.BatchDelete(ctx, batch, userID)
+		for errors.Is(err, ErrDeleteQueueFull) {
+			runtime.Gosched()
+			_, err = service.BatchDelete(ctx, batch, userID)
+		}
 		require.NoError(b, err)
 	}
 }
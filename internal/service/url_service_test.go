@@ -0,0 +1,488 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Aleksey170999/go-shortener/internal/jobs"
+	"github.com/Aleksey170999/go-shortener/internal/mocks"
+	"github.com/Aleksey170999/go-shortener/internal/model"
+	"github.com/Aleksey170999/go-shortener/internal/repository"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestURLService_Shorten_RetriesOnCollision(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	repo := mocks.NewMockURLRepository(ctrl)
+
+	var lengths []int
+	gomock.InOrder(
+		repo.EXPECT().Save(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, url *model.URL) (*model.URL, error) {
+			lengths = append(lengths, len(url.Short))
+			return nil, model.ErrShortURLConflict
+		}),
+		repo.EXPECT().Save(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, url *model.URL) (*model.URL, error) {
+			lengths = append(lengths, len(url.Short))
+			return url, nil
+		}),
+	)
+
+	s := NewURLService(repo)
+	url, err := s.Shorten(context.Background(), "https://example.com", "", "user1")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com", url.Original)
+	assert.Equal(t, []int{shortURLLength, shortURLLength + 1}, lengths)
+}
+
+func TestURLService_Shorten_GivesUpAfterMaxAttempts(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	repo := mocks.NewMockURLRepository(ctrl)
+	repo.EXPECT().Save(gomock.Any(), gomock.Any()).Return(nil, model.ErrShortURLConflict).Times(maxShortenAttempts)
+
+	s := NewURLService(repo)
+	_, err := s.Shorten(context.Background(), "https://example.com", "", "user1")
+	assert.ErrorIs(t, err, ErrShortURLExhausted)
+}
+
+func TestURLService_BatchDelete_FlushesOnInterval(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	repo := mocks.NewMockURLRepository(ctrl)
+
+	flushed := make(chan []string, 1)
+	repo.EXPECT().BatchDelete(gomock.Any(), gomock.Any(), "user1").DoAndReturn(
+		func(_ context.Context, shortURLs []string, _ string) error {
+			flushed <- shortURLs
+			return nil
+		})
+
+	s := NewURLService(repo, WithDeleteBatchSize(10), WithDeleteFlushInterval(10*time.Millisecond))
+	require.NoError(t, enqueueDelete(context.Background(), s, []string{"a", "b"}, "user1"))
+
+	select {
+	case urls := <-flushed:
+		assert.ElementsMatch(t, []string{"a", "b"}, urls)
+	case <-time.After(time.Second):
+		t.Fatal("batch was not flushed within the flush interval")
+	}
+}
+
+func TestURLService_BatchDelete_FlushesWhenBatchFull(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	repo := mocks.NewMockURLRepository(ctrl)
+
+	flushed := make(chan []string, 1)
+	repo.EXPECT().BatchDelete(gomock.Any(), gomock.Any(), "user1").DoAndReturn(
+		func(_ context.Context, shortURLs []string, _ string) error {
+			flushed <- shortURLs
+			return nil
+		})
+
+	s := NewURLService(repo, WithDeleteBatchSize(2), WithDeleteFlushInterval(time.Hour))
+	require.NoError(t, enqueueDelete(context.Background(), s, []string{"a"}, "user1"))
+	require.NoError(t, enqueueDelete(context.Background(), s, []string{"b"}, "user1"))
+
+	select {
+	case urls := <-flushed:
+		assert.ElementsMatch(t, []string{"a", "b"}, urls)
+	case <-time.After(time.Second):
+		t.Fatal("full batch was not flushed")
+	}
+}
+
+func TestURLService_BatchDelete_QueueFull(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	repo := mocks.NewMockURLRepository(ctrl)
+
+	release := make(chan struct{})
+	defer close(release)
+	repo.EXPECT().BatchDelete(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ context.Context, _ []string, _ string) error {
+			<-release
+			return nil
+		}).AnyTimes()
+
+	s := NewURLService(repo, WithDeleteBatchSize(1), WithDeleteQueueSize(1), WithDeleteWorkers(1))
+	ctx := context.Background()
+
+	// The first request is picked up by the worker which then blocks in the repository,
+	// the second one fills the queue, so the third must be rejected.
+	require.NoError(t, enqueueDelete(ctx, s, []string{"a"}, "user1"))
+	require.Eventually(t, func() bool { return len(s.deleteReqCh) == 0 }, time.Second, time.Millisecond)
+	require.NoError(t, enqueueDelete(ctx, s, []string{"b"}, "user1"))
+	assert.ErrorIs(t, enqueueDelete(ctx, s, []string{"c"}, "user1"), ErrDeleteQueueFull)
+}
+
+func TestURLService_Shutdown_DrainsQueue(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	repo := mocks.NewMockURLRepository(ctrl)
+
+	var deleted []string
+	repo.EXPECT().BatchDelete(gomock.Any(), gomock.Any(), "user1").DoAndReturn(
+		func(_ context.Context, shortURLs []string, _ string) error {
+			deleted = append(deleted, shortURLs...)
+			return nil
+		}).AnyTimes()
+
+	s := NewURLService(repo, WithDeleteBatchSize(100), WithDeleteFlushInterval(time.Hour))
+	ctx := context.Background()
+	require.NoError(t, enqueueDelete(ctx, s, []string{"a"}, "user1"))
+	require.NoError(t, enqueueDelete(ctx, s, []string{"b"}, "user1"))
+
+	require.NoError(t, s.Shutdown(ctx))
+	assert.ElementsMatch(t, []string{"a", "b"}, deleted)
+
+	assert.ErrorIs(t, enqueueDelete(ctx, s, []string{"c"}, "user1"), ErrServiceClosed)
+	require.NoError(t, s.Shutdown(ctx))
+}
+
+func TestURLService_ShortenBatch(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	repo := mocks.NewMockURLRepository(ctrl)
+
+	gomock.InOrder(
+		repo.EXPECT().SaveBatch(gomock.Any(), gomock.Len(3)).DoAndReturn(
+			func(_ context.Context, urls []*model.URL) ([]error, error) {
+				urls[1].Short = "exists"
+				return []error{nil, model.ErrURLAlreadyExists, model.ErrShortURLConflict}, nil
+			}),
+		repo.EXPECT().SaveBatch(gomock.Any(), gomock.Len(1)).DoAndReturn(
+			func(_ context.Context, urls []*model.URL) ([]error, error) {
+				assert.Equal(t, "https://example.com/3", urls[0].Original)
+				assert.Len(t, urls[0].Short, shortURLLength+1)
+				return []error{nil}, nil
+			}),
+	)
+
+	s := NewURLService(repo)
+	results, err := s.ShortenBatch(context.Background(), []string{
+		"https://example.com/1",
+		"https://example.com/2",
+		"https://example.com/3",
+	}, "user1")
+	require.NoError(t, err)
+	require.Len(t, results, 3)
+
+	assert.NoError(t, results[0].Err)
+	assert.Equal(t, "https://example.com/1", results[0].URL.Original)
+	assert.ErrorIs(t, results[1].Err, model.ErrURLAlreadyExists)
+	assert.Equal(t, "exists", results[1].URL.Short)
+	assert.NoError(t, results[2].Err)
+	assert.Equal(t, "user1", results[2].URL.UserID)
+}
+
+func TestURLService_ShortenBatch_InvalidURL(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	repo := mocks.NewMockURLRepository(ctrl)
+
+	s := NewURLService(repo)
+	results, err := s.ShortenBatch(context.Background(), []string{
+		"https://example.com/1",
+		"mailto:user@example.com",
+	}, "user1")
+	assert.ErrorIs(t, err, ErrInvalidURL, "the batch is rejected before SaveBatch")
+	assert.Nil(t, results)
+}
+
+func TestURLService_Resolve_Cache(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	repo := mocks.NewMockURLRepository(ctrl)
+	ctx := context.Background()
+
+	stored := &model.URL{ID: "1", Short: "abc", Original: "https://example.com", UserID: "user1"}
+	repo.EXPECT().GetByShortURL(gomock.Any(), "abc").Return(stored, nil).Times(2)
+
+	flushed := make(chan struct{})
+	repo.EXPECT().BatchDelete(gomock.Any(), []string{"abc"}, "user1").DoAndReturn(
+		func(_ context.Context, _ []string, _ string) error {
+			close(flushed)
+			return nil
+		})
+
+	s := NewURLService(repo, WithResolveCache(10, time.Minute), WithDeleteFlushInterval(time.Millisecond))
+
+	for i := 0; i < 3; i++ {
+		url, err := s.Resolve(ctx, "abc")
+		require.NoError(t, err)
+		assert.Equal(t, "https://example.com", url.Original)
+	}
+
+	require.NoError(t, enqueueDelete(ctx, s, []string{"abc"}, "user1"))
+	<-flushed
+	require.NoError(t, s.Shutdown(ctx))
+
+	// The entry was invalidated by the delete, so the repository is queried again.
+	_, err := s.Resolve(ctx, "abc")
+	require.NoError(t, err)
+}
+
+func TestResolveCache_EvictionAndExpiry(t *testing.T) {
+	c := newResolveCache(2, time.Minute)
+	now := time.Now()
+	c.now = func() time.Time { return now }
+
+	c.add(&model.URL{Short: "a"})
+	c.add(&model.URL{Short: "b"})
+	_, ok := c.get("a")
+	require.True(t, ok)
+
+	// "b" is now the least recently used entry and gets evicted.
+	c.add(&model.URL{Short: "c"})
+	_, ok = c.get("b")
+	assert.False(t, ok)
+
+	now = now.Add(2 * time.Minute)
+	_, ok = c.get("a")
+	assert.False(t, ok)
+}
+
+func TestURLService_Resolve_CollapsesConcurrentLookups(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	repo := mocks.NewMockURLRepository(ctrl)
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	repo.EXPECT().GetByShortURL(gomock.Any(), "abc").DoAndReturn(
+		func(_ context.Context, _ string) (*model.URL, error) {
+			close(started)
+			<-release
+			return &model.URL{Short: "abc", Original: "https://example.com"}, nil
+		}).Times(1)
+
+	s := NewURLService(repo)
+	const callers = 20
+	var wg sync.WaitGroup
+	wg.Add(callers)
+	for i := 0; i < callers; i++ {
+		go func() {
+			defer wg.Done()
+			url, err := s.Resolve(context.Background(), "abc")
+			assert.NoError(t, err)
+			assert.Equal(t, "https://example.com", url.Original)
+		}()
+	}
+
+	<-started
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+}
+
+func TestURLService_Resolve_CallerCancellation(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	repo := mocks.NewMockURLRepository(ctrl)
+
+	release := make(chan struct{})
+	defer close(release)
+	repo.EXPECT().GetByShortURL(gomock.Any(), "abc").DoAndReturn(
+		func(_ context.Context, _ string) (*model.URL, error) {
+			<-release
+			return &model.URL{Short: "abc"}, nil
+		}).AnyTimes()
+
+	s := NewURLService(repo)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	_, err := s.Resolve(ctx, "abc")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestURLService_Resolve_QueryTimeout(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	repo := mocks.NewMockURLRepository(ctrl)
+
+	repo.EXPECT().GetByShortURL(gomock.Any(), "abc").DoAndReturn(
+		func(ctx context.Context, _ string) (*model.URL, error) {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		})
+
+	s := NewURLService(repo, WithQueryTimeout(10*time.Millisecond))
+	_, err := s.Resolve(context.Background(), "abc")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestURLService_Cleanup(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	repo := mocks.NewMockURLRepository(ctrl)
+
+	purged := make(chan struct{}, 1)
+	repo.EXPECT().Purge(gomock.Any(), gomock.Any(), gomock.Any(), true).DoAndReturn(
+		func(_ context.Context, expiredBefore, deletedBefore time.Time, _ bool) (repository.PurgeStats, error) {
+			assert.WithinDuration(t, expiredBefore.Add(-time.Hour), deletedBefore, time.Second)
+			select {
+			case purged <- struct{}{}:
+			default:
+			}
+			return repository.PurgeStats{Expired: 1}, nil
+		}).MinTimes(1)
+
+	s := NewURLService(repo, WithCleanup(CleanupConfig{
+		Interval:  5 * time.Millisecond,
+		Retention: time.Hour,
+		DryRun:    true,
+	}))
+	runner := jobs.NewRunner(zap.NewNop())
+	runner.Add(s.Jobs()...)
+	runner.Start(context.Background())
+
+	select {
+	case <-purged:
+	case <-time.After(time.Second):
+		t.Fatal("cleanup job did not run")
+	}
+	require.NoError(t, s.Shutdown(context.Background()))
+	require.NoError(t, runner.Stop(context.Background()))
+}
+
+func TestURLService_RunCleanup_InvalidatesCache(t *testing.T) {
+	ctx := context.Background()
+	repo := repository.NewMemoryURLRepository()
+	deletedAt := time.Now().Add(-48 * time.Hour)
+	_, err := repo.Save(ctx, &model.URL{
+		ID: "1", Short: "gone", Original: "https://example.com/1", UserID: "user1",
+		IsDeleted: true, DeletedAt: &deletedAt,
+	})
+	require.NoError(t, err)
+
+	s := NewURLService(repo, WithResolveCache(10, time.Hour), WithCleanup(CleanupConfig{
+		Interval:  time.Hour,
+		Retention: 24 * time.Hour,
+	}))
+	defer s.Shutdown(ctx)
+
+	_, err = s.Resolve(ctx, "gone")
+	require.ErrorIs(t, err, ErrDeleted, "the deleted link is cached")
+
+	require.NoError(t, s.runCleanup(ctx))
+	_, err = s.Resolve(ctx, "gone")
+	assert.ErrorIs(t, err, ErrNotFound, "the purged link must not be served from the cache")
+}
+
+func TestURLService_Resolve_TypedErrors(t *testing.T) {
+	ctx := context.Background()
+	repo := repository.NewMemoryURLRepository()
+	past := time.Now().Add(-time.Hour)
+	_, err := repo.Save(ctx, &model.URL{ID: "1", Original: "https://example.com/1", Short: "deleted", UserID: "u", IsDeleted: true})
+	require.NoError(t, err)
+	_, err = repo.Save(ctx, &model.URL{ID: "2", Original: "https://example.com/2", Short: "expired", UserID: "u", ExpiresAt: &past})
+	require.NoError(t, err)
+	s := NewURLService(repo)
+
+	_, err = s.Resolve(ctx, "missing")
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+
+	url, err := s.Resolve(ctx, "deleted")
+	assert.ErrorIs(t, err, ErrDeleted)
+	assert.Equal(t, "https://example.com/1", url.Original)
+
+	_, err = s.Resolve(ctx, "expired")
+	assert.ErrorIs(t, err, ErrExpired)
+
+	_, err = s.GetUserURLs(ctx, "nobody")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+// enqueueDelete calls BatchDelete and discards the job ID.
+func enqueueDelete(ctx context.Context, s *URLService, shortURLs []string, userID string) error {
+	_, err := s.BatchDelete(ctx, shortURLs, userID)
+	return err
+}
+
+func TestURLService_DeleteJobs(t *testing.T) {
+	ctx := context.Background()
+	ctrl := gomock.NewController(t)
+	repo := mocks.NewMockURLRepository(ctrl)
+	release := make(chan struct{})
+	gomock.InOrder(
+		repo.EXPECT().BatchDelete(gomock.Any(), []string{"a", "b"}, "user1").DoAndReturn(
+			func(context.Context, []string, string) error {
+				<-release
+				return nil
+			}),
+		repo.EXPECT().BatchDelete(gomock.Any(), []string{"c"}, "user1").Return(errors.New("db down")),
+	)
+	s := NewURLService(repo, WithDeleteBatchSize(1))
+
+	okID, err := s.BatchDelete(ctx, []string{"a", "b"}, "user1")
+	require.NoError(t, err)
+	job, err := s.GetDeleteJob(ctx, okID, "user1")
+	require.NoError(t, err)
+	assert.Equal(t, DeleteJobPending, job.Status())
+	assert.Equal(t, 2, job.Total)
+	assert.Equal(t, 2, job.Pending)
+
+	_, err = s.GetDeleteJob(ctx, okID, "user2")
+	assert.ErrorIs(t, err, ErrDeleteJobNotFound)
+
+	failID, err := s.BatchDelete(ctx, []string{"c"}, "user1")
+	require.NoError(t, err)
+	close(release)
+	require.NoError(t, s.Shutdown(ctx))
+
+	job, err = s.GetDeleteJob(ctx, okID, "user1")
+	require.NoError(t, err)
+	assert.Equal(t, DeleteJobCompleted, job.Status())
+	assert.Equal(t, 2, job.Completed)
+
+	job, err = s.GetDeleteJob(ctx, failID, "user1")
+	require.NoError(t, err)
+	assert.Equal(t, DeleteJobFailed, job.Status())
+	assert.Equal(t, 1, job.Failed)
+}
+
+func TestURLService_DeleteJobDone(t *testing.T) {
+	ctx := context.Background()
+	ctrl := gomock.NewController(t)
+	repo := mocks.NewMockURLRepository(ctrl)
+	repo.EXPECT().BatchDelete(gomock.Any(), []string{"a", "b"}, "user1").Return(nil)
+	repo.EXPECT().BatchDelete(gomock.Any(), []string{"c"}, "user2").Return(errors.New("db down"))
+
+	done := make(chan DeleteJob, 2)
+	s := NewURLService(repo, WithDeleteBatchSize(1), WithDeleteJobDone(func(_ context.Context, job DeleteJob) {
+		done <- job
+	}))
+	_, err := s.BatchDelete(ctx, []string{"a", "b"}, "user1")
+	require.NoError(t, err)
+	_, err = s.BatchDelete(ctx, []string{"c"}, "user2")
+	require.NoError(t, err)
+	require.NoError(t, s.Shutdown(ctx))
+	close(done)
+
+	jobs := make(map[string]DeleteJob)
+	for job := range done {
+		jobs[job.UserID] = job
+	}
+	require.Len(t, jobs, 2)
+	assert.Equal(t, DeleteJobCompleted, jobs["user1"].Status())
+	assert.Equal(t, 2, jobs["user1"].Completed)
+	assert.Equal(t, DeleteJobFailed, jobs["user2"].Status())
+	assert.Equal(t, 1, jobs["user2"].Failed)
+}
+
+func TestDeleteJobs_PrunesFinishedJobs(t *testing.T) {
+	jobs := newDeleteJobs()
+	now := time.Now()
+	jobs.now = func() time.Time { return now }
+
+	id := jobs.create("user1", 1)
+	jobs.finish(id, 1, nil)
+	now = now.Add(deleteJobRetention + time.Second)
+	next := jobs.create("user1", 1)
+	_, ok := jobs.get(id)
+	assert.True(t, ok, "creating a job does not scan for expired ones")
+
+	jobs.finish(next, 1, nil)
+	_, ok = jobs.get(id)
+	assert.False(t, ok, "finishing a job drops the expired ones")
+	_, ok = jobs.get(next)
+	assert.True(t, ok)
+	assert.Equal(t, []string{next}, jobs.finished)
+}
@@ -0,0 +1,128 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"os"
+	"time"
+
+	"github.com/Aleksey170999/go-shortener/internal/model"
+	"github.com/Aleksey170999/go-shortener/internal/repository"
+)
+
+// CompactOptions selects what Compact removes besides duplicates.
+type CompactOptions struct {
+	Now time.Time // Entries that expired before Now are removed; zero keeps them
+	// DeletedBefore removes the entries of URLs soft-deleted before it, as
+	// the cleanup of a server does after DELETED_RETENTION; zero keeps them.
+	DeletedBefore time.Time
+	// Keep, if not nil, decides whether an entry stays, e.g. whether the
+	// repository still holds it; see InRepository.
+	Keep   func(ctx context.Context, url model.URL) (bool, error)
+	DryRun bool // Only count what would be removed
+}
+
+// CompactStats reports what Compact removed (or would remove in dry-run mode).
+type CompactStats struct {
+	Kept       int // Entries left in the file
+	Duplicates int // Entries superseded by a later record of the URL, or conflicting with an earlier URL
+	Expired    int // Entries that expired before CompactOptions.Now
+	Removed    int // Entries deleted before CompactOptions.DeletedBefore or rejected by CompactOptions.Keep
+}
+
+// Compact rewrites the storage file without duplicate, expired, long-deleted
+// and, with opts.Keep, otherwise stale entries. The file is only ever appended to,
+// so it keeps every record of a URL, e.g. its creation and its deletion,
+// and links that were purged since they were added, which reappear when
+// the file is loaded on the next start. Of the records of a URL the last
+// one is kept, in place of the first, as it is the one a load ends up
+// with; a record with the short code of an earlier URL but another ID
+// conflicts with it and is dropped. The file is not rewritten if nothing
+// is removed.
+func (s *Storage) Compact(ctx context.Context, opts CompactOptions) (CompactStats, error) {
+	var stats CompactStats
+	if s.FilePath == "" {
+		return stats, nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data, err := os.ReadFile(s.FilePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return stats, nil
+		}
+		return stats, err
+	}
+	urls, err := s.decode(data)
+	if err != nil {
+		return stats, err
+	}
+
+	latest := make([]model.URL, 0, len(urls))
+	index := make(map[string]int, len(urls))
+	for _, url := range urls {
+		i, seen := index[url.Short]
+		if !seen {
+			index[url.Short] = len(latest)
+			latest = append(latest, url)
+			continue
+		}
+		stats.Duplicates++
+		if latest[i].ID == url.ID {
+			latest[i] = url
+		}
+	}
+
+	kept := make([]model.URL, 0, len(latest))
+	for _, url := range latest {
+		if !opts.Now.IsZero() && url.ExpiresAt != nil && url.ExpiresAt.Before(opts.Now) {
+			stats.Expired++
+			continue
+		}
+		if !opts.DeletedBefore.IsZero() && url.IsDeleted && url.DeletedAt != nil && url.DeletedAt.Before(opts.DeletedBefore) {
+			stats.Removed++
+			continue
+		}
+		if opts.Keep != nil {
+			keep, err := opts.Keep(ctx, url)
+			if err != nil {
+				return stats, err
+			}
+			if !keep {
+				stats.Removed++
+				continue
+			}
+		}
+		kept = append(kept, url)
+	}
+	stats.Kept = len(kept)
+
+	if opts.DryRun || len(kept) == len(urls) {
+		return stats, nil
+	}
+	newData, err := s.encode(kept)
+	if err != nil {
+		return stats, err
+	}
+	return stats, writeFileAtomic(s.FilePath, newData, 0644)
+}
+
+// InRepository returns a CompactOptions.Keep for the storage file of a
+// running server: an entry stays if repo still holds a URL with its short
+// code and ID, unless that URL was soft-deleted before deletedBefore.
+func InRepository(repo repository.URLRepository, deletedBefore time.Time) func(ctx context.Context, url model.URL) (bool, error) {
+	return func(ctx context.Context, url model.URL) (bool, error) {
+		stored, err := repo.GetByShortURL(ctx, url.Short)
+		if errors.Is(err, repository.ErrNotFound) {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		if stored.ID != url.ID {
+			return false, nil
+		}
+		return !(stored.IsDeleted && stored.DeletedAt != nil && stored.DeletedAt.Before(deletedBefore)), nil
+	}
+}
@@ -0,0 +1,91 @@
+package storage
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Aleksey170999/go-shortener/internal/model"
+	"github.com/Aleksey170999/go-shortener/internal/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStorage_Compact(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	past, future := now.Add(-time.Hour), now.Add(time.Hour)
+	s := NewStorage(filepath.Join(t.TempDir(), "storage.json"))
+	require.NoError(t, s.Append(
+		model.URL{ID: "1", Short: "a", Original: "https://a.example"},
+		model.URL{ID: "2", Short: "b", Original: "https://b.example", ExpiresAt: &past},
+		model.URL{ID: "3", Short: "c", Original: "https://c.example", ExpiresAt: &future},
+		model.URL{ID: "1", Short: "a", Original: "https://a.example", IsDeleted: true, DeletedAt: &past},
+		model.URL{ID: "4", Short: "a", Original: "https://d.example"},
+		model.URL{ID: "5", Short: "e", Original: "https://e.example"},
+	))
+	before, err := os.ReadFile(s.FilePath)
+	require.NoError(t, err)
+
+	opts := CompactOptions{
+		Now: now,
+		Keep: func(_ context.Context, url model.URL) (bool, error) {
+			return url.Short != "e", nil
+		},
+		DryRun: true,
+	}
+	stats, err := s.Compact(ctx, opts)
+	require.NoError(t, err)
+	assert.Equal(t, CompactStats{Kept: 2, Duplicates: 2, Expired: 1, Removed: 1}, stats)
+	after, err := os.ReadFile(s.FilePath)
+	require.NoError(t, err)
+	assert.Equal(t, before, after, "a dry run leaves the file alone")
+
+	opts.DryRun = false
+	_, err = s.Compact(ctx, opts)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"c"}, load(t, s))
+	repo := repository.NewMemoryURLRepository()
+	require.NoError(t, s.LoadFromStorage(ctx, repo))
+	url, err := repo.GetByShortURL(ctx, "a")
+	require.NoError(t, err)
+	assert.Equal(t, "1", url.ID, "the conflicting URL is dropped")
+	assert.True(t, url.IsDeleted, "the last record of the URL is kept")
+
+	stats, err = s.Compact(ctx, opts)
+	require.NoError(t, err)
+	assert.Equal(t, CompactStats{Kept: 2}, stats)
+
+	opts.DeletedBefore = now
+	stats, err = s.Compact(ctx, opts)
+	require.NoError(t, err)
+	assert.Equal(t, CompactStats{Kept: 1, Removed: 1}, stats)
+	assert.Equal(t, []string{"c"}, load(t, s), "the URL deleted before the cutoff is removed")
+}
+
+func TestInRepository(t *testing.T) {
+	ctx := context.Background()
+	repo := repository.NewMemoryURLRepository()
+	for _, url := range []*model.URL{
+		{ID: "1", Short: "a", Original: "https://a.example", UserID: "user"},
+		{ID: "2", Short: "b", Original: "https://b.example", UserID: "user"},
+	} {
+		_, err := repo.Save(ctx, url)
+		require.NoError(t, err)
+	}
+	require.NoError(t, repo.BatchDelete(ctx, []string{"b"}, "user"))
+
+	keep := func(deletedBefore time.Time, url model.URL) bool {
+		t.Helper()
+		ok, err := InRepository(repo, deletedBefore)(ctx, url)
+		require.NoError(t, err)
+		return ok
+	}
+	assert.True(t, keep(time.Now(), model.URL{ID: "1", Short: "a"}))
+	assert.False(t, keep(time.Now(), model.URL{ID: "9", Short: "a"}), "short code reused by another URL")
+	assert.False(t, keep(time.Now(), model.URL{ID: "3", Short: "c"}), "purged")
+	assert.True(t, keep(time.Now().Add(-time.Hour), model.URL{ID: "2", Short: "b"}), "deleted within the retention")
+	assert.False(t, keep(time.Now().Add(time.Hour), model.URL{ID: "2", Short: "b"}), "deleted past the retention")
+}
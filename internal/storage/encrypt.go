@@ -0,0 +1,93 @@
+package storage
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// KeySize is the size of a storage encryption key, which selects AES-256.
+const KeySize = 32
+
+// encryptedMagic starts an encrypted storage file, followed by the nonce
+// and the sealed contents. Neither JSON nor gzip can start with it.
+var encryptedMagic = []byte("GSENC1")
+
+// ErrEncrypted is returned when an encrypted storage file is read without
+// a key.
+var ErrEncrypted = errors.New("storage file is encrypted, but no key is configured")
+
+// LoadKey returns the storage encryption key, given base64-encoded either
+// directly or in the file keyFile, or nil if neither is set. The key must
+// decode to KeySize bytes, e.g. one generated with
+//
+//	$ openssl rand -base64 32
+func LoadKey(key, keyFile string) ([]byte, error) {
+	if keyFile != "" {
+		data, err := os.ReadFile(keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read storage key file: %w", err)
+		}
+		key = strings.TrimSpace(string(data))
+	}
+	if key == "" {
+		return nil, nil
+	}
+	decoded, err := base64.StdEncoding.DecodeString(key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid storage key: %w", err)
+	}
+	if len(decoded) != KeySize {
+		return nil, fmt.Errorf("invalid storage key: %d bytes instead of %d", len(decoded), KeySize)
+	}
+	return decoded, nil
+}
+
+// NewCipher returns the AES-GCM cipher encrypting storage files with key.
+func NewCipher(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// Encrypt seals data, the contents of a storage file, with aead under a
+// random nonce. The header is authenticated along with the contents.
+func Encrypt(data []byte, aead cipher.AEAD) ([]byte, error) {
+	out := make([]byte, len(encryptedMagic)+aead.NonceSize(), len(encryptedMagic)+aead.NonceSize()+len(data)+aead.Overhead())
+	copy(out, encryptedMagic)
+	nonce := out[len(encryptedMagic):]
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(out, nonce, data, encryptedMagic), nil
+}
+
+// Decrypt returns the contents of a storage file encrypted with Encrypt,
+// or data itself if it is not encrypted, so that a plain file is read as
+// before and encrypted on its next write. aead may be nil if no key is
+// configured, which only fails for encrypted data.
+func Decrypt(data []byte, aead cipher.AEAD) ([]byte, error) {
+	if !bytes.HasPrefix(data, encryptedMagic) {
+		return data, nil
+	}
+	if aead == nil {
+		return nil, ErrEncrypted
+	}
+	data = data[len(encryptedMagic):]
+	if len(data) < aead.NonceSize() {
+		return nil, errors.New("encrypted storage file is truncated")
+	}
+	plain, err := aead.Open(nil, data[:aead.NonceSize()], data[aead.NonceSize():], encryptedMagic)
+	if err != nil {
+		return nil, errors.New("failed to decrypt storage file: wrong key or corrupted file")
+	}
+	return plain, nil
+}
@@ -0,0 +1,66 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Aleksey170999/go-shortener/internal/model"
+	"github.com/Aleksey170999/go-shortener/internal/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadKey(t *testing.T) {
+	key := bytes.Repeat([]byte{7}, KeySize)
+	encoded := base64.StdEncoding.EncodeToString(key)
+
+	loaded, err := LoadKey("", "")
+	require.NoError(t, err)
+	assert.Nil(t, loaded, "no key configured")
+
+	loaded, err = LoadKey(encoded, "")
+	require.NoError(t, err)
+	assert.Equal(t, key, loaded)
+
+	keyFile := filepath.Join(t.TempDir(), "storage_key")
+	require.NoError(t, os.WriteFile(keyFile, []byte(encoded+"\n"), 0600))
+	loaded, err = LoadKey("", keyFile)
+	require.NoError(t, err)
+	assert.Equal(t, key, loaded)
+
+	_, err = LoadKey("c2VjcmV0", "")
+	assert.ErrorContains(t, err, "6 bytes instead of 32")
+	_, err = LoadKey("not base64!", "")
+	assert.Error(t, err)
+}
+
+func TestStorage_SetKey(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "storage.json")
+	plain := NewStorage(path)
+	require.NoError(t, plain.Append(model.URL{ID: "1", Short: "a", Original: "https://a.example"}))
+
+	s := NewStorage(path)
+	require.NoError(t, s.SetKey(bytes.Repeat([]byte{1}, KeySize)))
+	assert.ElementsMatch(t, []string{"a"}, load(t, s), "a plain file is still read")
+	require.NoError(t, s.Append(model.URL{ID: "2", Short: "b", Original: "https://secret.example"}))
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "https://", "the file is encrypted on the next write")
+	assert.ElementsMatch(t, []string{"a", "b"}, load(t, s))
+
+	err = plain.LoadFromStorage(ctx, repository.NewMemoryURLRepository())
+	assert.ErrorIs(t, err, ErrEncrypted)
+	wrong := NewStorage(path)
+	require.NoError(t, wrong.SetKey(bytes.Repeat([]byte{2}, KeySize)))
+	err = wrong.LoadFromStorage(ctx, repository.NewMemoryURLRepository())
+	assert.ErrorContains(t, err, "wrong key or corrupted file")
+
+	data[len(data)-1] ^= 1
+	_, err = Decrypt(data, s.aead)
+	assert.Error(t, err, "tampering is detected")
+}
@@ -0,0 +1,74 @@
+package storage
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/Aleksey170999/go-shortener/internal/model"
+)
+
+// gzipMagic starts every gzip stream. JSON cannot start with it, so a
+// compressed storage file is told from a plain one by its contents.
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// Decode parses the contents of a storage file, a JSON array of URLs that
+// may be gzip-compressed. Empty data holds no URLs.
+func Decode(data []byte) ([]model.URL, error) {
+	if bytes.HasPrefix(data, gzipMagic) {
+		zr, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		if data, err = io.ReadAll(zr); err != nil {
+			return nil, fmt.Errorf("failed to decompress: %w", err)
+		}
+	}
+	if len(data) == 0 {
+		return nil, nil
+	}
+	var urls []model.URL
+	if err := json.Unmarshal(data, &urls); err != nil {
+		return nil, err
+	}
+	return urls, nil
+}
+
+// Encode returns the contents of a storage file holding urls, compressed
+// with gzip if compress is set.
+func Encode(urls []model.URL, compress bool) ([]byte, error) {
+	data, err := json.MarshalIndent(urls, "", "  ")
+	if err != nil || !compress {
+		return data, err
+	}
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(data); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Compressed reports whether a storage file at path is written with gzip:
+// if its name ends with ".gz", or if the existing file is compressed, so
+// that a file compressed by hand stays so.
+func Compressed(path string) bool {
+	if strings.HasSuffix(path, ".gz") {
+		return true
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	head := make([]byte, len(gzipMagic))
+	_, err = io.ReadFull(f, head)
+	return err == nil && bytes.Equal(head, gzipMagic)
+}
@@ -0,0 +1,63 @@
+package storage
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Aleksey170999/go-shortener/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncodeDecode(t *testing.T) {
+	urls := []model.URL{
+		{ID: "1", Short: "a", Original: "https://a.example"},
+		{ID: "2", Short: "b", Original: "https://b.example", UserID: "user"},
+	}
+	for _, compress := range []bool{false, true} {
+		data, err := Encode(urls, compress)
+		require.NoError(t, err)
+		assert.Equal(t, compress, bytes.HasPrefix(data, gzipMagic))
+
+		decoded, err := Decode(data)
+		require.NoError(t, err)
+		assert.Equal(t, urls, decoded)
+	}
+
+	decoded, err := Decode(nil)
+	require.NoError(t, err)
+	assert.Empty(t, decoded)
+
+	_, err = Decode([]byte{0x1f, 0x8b, 0x00})
+	assert.Error(t, err, "truncated gzip stream")
+}
+
+func TestStorage_Compressed(t *testing.T) {
+	dir := t.TempDir()
+	url := &model.URL{ID: "1", Short: "a", Original: "https://a.example"}
+	read := func(path string) []byte {
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		return data
+	}
+
+	gz := NewStorage(filepath.Join(dir, "storage.json.gz"))
+	require.NoError(t, gz.LoadToStorage(url))
+	assert.True(t, bytes.HasPrefix(read(gz.FilePath), gzipMagic), "compressed by extension")
+	assert.Equal(t, []string{"a"}, load(t, gz))
+
+	plain := NewStorage(filepath.Join(dir, "storage.json"))
+	require.NoError(t, plain.LoadToStorage(url))
+	assert.False(t, Compressed(plain.FilePath))
+	assert.Equal(t, byte('['), read(plain.FilePath)[0], "plain JSON stays plain")
+
+	// A file compressed by hand keeps being written compressed.
+	data, err := Encode([]model.URL{*url}, true)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(plain.FilePath, data, 0644))
+	require.NoError(t, plain.Append(model.URL{ID: "2", Short: "b", Original: "https://b.example"}))
+	assert.True(t, bytes.HasPrefix(read(plain.FilePath), gzipMagic))
+	assert.ElementsMatch(t, []string{"a", "b"}, load(t, plain))
+}
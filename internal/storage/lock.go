@@ -0,0 +1,45 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"os"
+)
+
+// ErrLocked is returned by LockFile if another process holds the lock.
+var ErrLocked = errors.New("storage file is in use by another process")
+
+// FileLock is an exclusive lock on a storage file, held by the process
+// until it is closed or the process exits.
+type FileLock struct {
+	f *os.File
+}
+
+// LockFile takes an exclusive lock on a lock file next to the storage file
+// at path, path with ".lock" appended, and returns ErrLocked right away if
+// another process holds it. Every process reading and rewriting the storage
+// file takes the lock, so that two servers, or a server and a maintenance
+// command, do not overwrite each other's changes. The storage file itself
+// is not locked, as it is replaced on every write. The lock file is left in
+// place; an unlocked one is not stale. The lock is a flock on Unix and a
+// LockFileEx lock on Windows; other platforms do not lock.
+func LockFile(path string) (*FileLock, error) {
+	lockPath := path + ".lock"
+	f, err := os.OpenFile(lockPath, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open lock file: %w", err)
+	}
+	if err := tryLock(f); err != nil {
+		f.Close()
+		if errors.Is(err, ErrLocked) {
+			return nil, fmt.Errorf("%w: %s is locked", ErrLocked, lockPath)
+		}
+		return nil, fmt.Errorf("failed to lock %s: %w", lockPath, err)
+	}
+	return &FileLock{f: f}, nil
+}
+
+// Close releases the lock.
+func (l *FileLock) Close() error {
+	return l.f.Close()
+}
@@ -0,0 +1,27 @@
+//go:build unix || windows
+
+package storage
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLockFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "storage.json")
+	lock, err := LockFile(path)
+	require.NoError(t, err)
+
+	// The locks belong to the open file, so a second open conflicts within
+	// a process as well.
+	_, err = LockFile(path)
+	assert.ErrorIs(t, err, ErrLocked)
+
+	require.NoError(t, lock.Close())
+	lock, err = LockFile(path)
+	require.NoError(t, err, "the lock is free again once closed")
+	require.NoError(t, lock.Close())
+}
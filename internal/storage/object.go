@@ -0,0 +1,235 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Aleksey170999/go-shortener/internal/model"
+	"github.com/Aleksey170999/go-shortener/internal/repository"
+)
+
+// objectTimeout bounds a single request to the object storage.
+const objectTimeout = time.Minute
+
+// ObjectConfig describes the object an ObjectStorage keeps the URLs in.
+type ObjectConfig struct {
+	URL       string // Path-style object URL, e.g. "https://s3.eu-central-1.amazonaws.com/bucket/storage.json.gz"
+	Region    string // Region the requests are signed for, e.g. "us-east-1", or "auto" for GCS
+	AccessKey string // Access key ID, empty sends unsigned requests
+	SecretKey string // Secret access key
+	Key       []byte // Encrypts the object like Storage.SetKey, nil stores it plain
+}
+
+// ObjectStorage keeps the URLs in a single object of an S3-compatible
+// object storage, such as Amazon S3, MinIO or Google Cloud Storage with an
+// HMAC key, so that a server without a persistent volume can restore its
+// in-memory repository after a restart. Requests are signed with AWS
+// Signature Version 4. The object has the format of the storage file and
+// is gzip-compressed if its key ends with ".gz". It is only ever written
+// whole, see WriteAll; Snapshotter does that periodically.
+type ObjectStorage struct {
+	cfg        ObjectConfig
+	compress   bool
+	aead       cipher.AEAD // Nil unless ObjectConfig.Key is set
+	httpClient *http.Client
+	now        func() time.Time
+}
+
+// NewObjectStorage creates an ObjectStorage for the object of cfg.
+func NewObjectStorage(cfg ObjectConfig) (*ObjectStorage, error) {
+	u, err := url.Parse(cfg.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid object URL: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" || u.Host == "" {
+		return nil, fmt.Errorf("invalid object URL %q", cfg.URL)
+	}
+	var aead cipher.AEAD
+	if cfg.Key != nil {
+		if aead, err = NewCipher(cfg.Key); err != nil {
+			return nil, err
+		}
+	}
+	return &ObjectStorage{
+		cfg:        cfg,
+		compress:   strings.HasSuffix(u.Path, ".gz"),
+		aead:       aead,
+		httpClient: &http.Client{Timeout: objectTimeout},
+		now:        time.Now,
+	}, nil
+}
+
+// LoadFromStorage reads the URLs of the object and loads them into repo,
+// like Storage.LoadFromStorage. A missing object is not an error, so that
+// a new deployment starts empty.
+//
+// Implements SnapshotStore interface.
+func (o *ObjectStorage) LoadFromStorage(ctx context.Context, repo repository.URLRepository) error {
+	resp, err := o.do(ctx, http.MethodGet, nil, "")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil
+	}
+	if err := objectError(resp); err != nil {
+		return err
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read object: %w", err)
+	}
+	if data, err = Decrypt(data, o.aead); err != nil {
+		return err
+	}
+	urls, err := Decode(data)
+	if err != nil {
+		return err
+	}
+	for i := range urls {
+		if _, err := repo.Save(ctx, &urls[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteAll replaces the object with urls. The upload is a single PUT, which
+// object storages apply atomically: readers see the old or the new object.
+//
+// Implements SnapshotStore interface.
+func (o *ObjectStorage) WriteAll(ctx context.Context, urls []model.URL) error {
+	data, err := Encode(urls, o.compress)
+	if err != nil {
+		return err
+	}
+	contentType := "application/json"
+	switch {
+	case o.aead != nil:
+		if data, err = Encrypt(data, o.aead); err != nil {
+			return err
+		}
+		contentType = "application/octet-stream"
+	case o.compress:
+		contentType = "application/gzip"
+	}
+	resp, err := o.do(ctx, http.MethodPut, data, contentType)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return objectError(resp)
+}
+
+// do sends a signed request for the object with body.
+func (o *ObjectStorage) do(ctx context.Context, method string, body []byte, contentType string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, o.cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if o.cfg.AccessKey != "" {
+		signV4(req, body, o.cfg.Region, o.cfg.AccessKey, o.cfg.SecretKey, o.now())
+	}
+	resp, err := o.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("object storage request failed: %w", err)
+	}
+	return resp, nil
+}
+
+// objectError returns an error if resp reports a failure, including the
+// start of the error document the storage sent.
+func objectError(resp *http.Response) error {
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	return fmt.Errorf("object storage responded with %s: %s", resp.Status, bytes.TrimSpace(msg))
+}
+
+// signV4 signs req for the s3 service with AWS Signature Version 4, see
+// https://docs.aws.amazon.com/AmazonS3/latest/API/sig-v4-header-based-auth.html.
+// The host and all headers already set are signed; body is the payload,
+// whose hash is signed as well.
+func signV4(req *http.Request, body []byte, region, accessKey, secretKey string, now time.Time) {
+	const algorithm = "AWS4-HMAC-SHA256"
+	now = now.UTC()
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	payloadHash := sha256.Sum256(body)
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", hex.EncodeToString(payloadHash[:]))
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(strings.Join(values, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		escapePath(req.URL.Path),
+		req.URL.Query().Encode(),
+		canonicalHeaders.String(),
+		signedHeaders,
+		hex.EncodeToString(payloadHash[:]),
+	}, "\n")
+	scope := date + "/" + region + "/s3/aws4_request"
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := algorithm + "\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(requestHash[:])
+
+	key := hmacSHA256([]byte("AWS4"+secretKey), date)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		algorithm, accessKey, scope, signedHeaders, signature))
+}
+
+// escapePath percent-encodes path as Signature Version 4 expects: every
+// byte but the unreserved characters of RFC 3986 and the slashes.
+func escapePath(path string) string {
+	var b strings.Builder
+	for i := 0; i < len(path); i++ {
+		c := path[i]
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' || strings.IndexByte("-._~/", c) >= 0 {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+// hmacSHA256 returns the HMAC-SHA256 of data with key.
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,124 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/Aleksey170999/go-shortener/internal/model"
+	"github.com/Aleksey170999/go-shortener/internal/repository"
+)
+
+// Persister records the current state of URLs, e.g. in a storage file, so
+// that an in-memory repository can be restored from it after a restart.
+// Storage implements it, writing through to the file or, while its writer
+// runs, behind it.
+type Persister interface {
+	// Store records urls, which are new or changed, e.g. soft-deleted.
+	Store(urls ...model.URL) error
+}
+
+var _ Persister = (*Storage)(nil)
+
+// Persist returns repo, recording the links it saves and soft-deletes with
+// p, so that the repository owns its persistence and every caller of Save,
+// SaveBatch and BatchDelete gets it alike. A write fails if the URLs
+// cannot be recorded, although repo keeps them. Purged links are not
+// recorded; Compact removes them from a storage file.
+func Persist(repo repository.URLRepository, p Persister) repository.URLRepository {
+	return &persistedRepository{URLRepository: repo, persister: p}
+}
+
+// persistedRepository records the writes of a URLRepository with a Persister.
+type persistedRepository struct {
+	repository.URLRepository
+	persister Persister
+}
+
+// Save implements repository.URLRepository.
+func (r *persistedRepository) Save(ctx context.Context, url *model.URL) (*model.URL, error) {
+	saved, err := r.URLRepository.Save(ctx, url)
+	if err != nil {
+		return saved, err
+	}
+	return saved, r.store(*saved)
+}
+
+// SaveBatch implements repository.URLRepository.
+func (r *persistedRepository) SaveBatch(ctx context.Context, urls []*model.URL) ([]error, error) {
+	errs, err := r.URLRepository.SaveBatch(ctx, urls)
+	if err != nil {
+		return errs, err
+	}
+	saved := make([]model.URL, 0, len(urls))
+	for i, url := range urls {
+		if errs[i] == nil {
+			saved = append(saved, *url)
+		}
+	}
+	return errs, r.store(saved...)
+}
+
+// BatchDelete implements repository.URLRepository. Only the links that the
+// call deletes are recorded: those of userID that were not deleted yet.
+func (r *persistedRepository) BatchDelete(ctx context.Context, shortURLs []string, userID string) error {
+	var deletable []string
+	for _, short := range shortURLs {
+		url, err := r.URLRepository.GetByShortURL(ctx, short)
+		if errors.Is(err, repository.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if url.UserID == userID && !url.IsDeleted {
+			deletable = append(deletable, short)
+		}
+	}
+
+	if err := r.URLRepository.BatchDelete(ctx, shortURLs, userID); err != nil {
+		return err
+	}
+
+	deleted := make([]model.URL, 0, len(deletable))
+	for _, short := range deletable {
+		url, err := r.URLRepository.GetByShortURL(ctx, short)
+		if errors.Is(err, repository.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if url.IsDeleted {
+			deleted = append(deleted, *url)
+		}
+	}
+	return r.store(deleted...)
+}
+
+// List implements repository.Lister if the wrapped repository does, as
+// the in-memory one.
+func (r *persistedRepository) List(ctx context.Context) ([]model.URL, error) {
+	return list(ctx, r.URLRepository)
+}
+
+// list lists the URLs of repo for the wrappers of this package, which
+// implement repository.Lister on behalf of the repository they wrap.
+func list(ctx context.Context, repo repository.URLRepository) ([]model.URL, error) {
+	lister, ok := repo.(repository.Lister)
+	if !ok {
+		return nil, errors.New("repository cannot list its URLs")
+	}
+	return lister.List(ctx)
+}
+
+// store records urls with the Persister.
+func (r *persistedRepository) store(urls ...model.URL) error {
+	if len(urls) == 0 {
+		return nil
+	}
+	if err := r.persister.Store(urls...); err != nil {
+		return fmt.Errorf("failed to persist urls: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Aleksey170999/go-shortener/internal/model"
+	"github.com/Aleksey170999/go-shortener/internal/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPersist(t *testing.T) {
+	ctx := context.Background()
+	st := NewStorage(filepath.Join(t.TempDir(), "storage.json"))
+	repo := Persist(repository.NewMemoryURLRepository(), st)
+
+	_, err := repo.Save(ctx, &model.URL{ID: "1", Short: "a", Original: "https://a.example", UserID: "user"})
+	require.NoError(t, err)
+	errs, err := repo.SaveBatch(ctx, []*model.URL{
+		{ID: "2", Short: "b", Original: "https://b.example", UserID: "user"},
+		{ID: "3", Short: "c", Original: "https://c.example", UserID: "other"},
+		{ID: "4", Short: "a", Original: "https://d.example"},
+	})
+	require.NoError(t, err)
+	assert.ErrorIs(t, errs[2], model.ErrShortURLConflict)
+	assert.ElementsMatch(t, []string{"a", "b", "c"}, load(t, st), "saved links are recorded, conflicting ones are not")
+
+	require.NoError(t, repo.BatchDelete(ctx, []string{"a", "c", "missing"}, "user"))
+	require.NoError(t, repo.BatchDelete(ctx, []string{"a"}, "user"))
+	assert.ElementsMatch(t, []string{"b", "c"}, load(t, st), "deleted links stay deleted after a restart")
+	listed, err := repo.(repository.Lister).List(ctx)
+	require.NoError(t, err)
+	assert.Len(t, listed, 3)
+
+	data, err := os.ReadFile(st.FilePath)
+	require.NoError(t, err)
+	urls, err := Decode(data)
+	require.NoError(t, err)
+	require.Len(t, urls, 4, "only the deletion of a is recorded, once")
+	assert.Equal(t, "a", urls[3].Short)
+	assert.True(t, urls[3].IsDeleted)
+	assert.NotNil(t, urls[3].DeletedAt)
+}
+
+// failingPersister is a Persister that cannot record anything.
+type failingPersister struct{}
+
+func (failingPersister) Store(...model.URL) error { return errors.New("disk full") }
+
+func TestPersist_StoreError(t *testing.T) {
+	ctx := context.Background()
+	mem := repository.NewMemoryURLRepository()
+	repo := Persist(mem, failingPersister{})
+
+	_, err := repo.Save(ctx, &model.URL{ID: "1", Short: "a", Original: "https://a.example"})
+	assert.ErrorContains(t, err, "disk full")
+	_, err = mem.GetByShortURL(ctx, "a")
+	assert.NoError(t, err, "the repository keeps the link")
+}
@@ -0,0 +1,169 @@
+package storage
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Aleksey170999/go-shortener/internal/jobs"
+	"github.com/Aleksey170999/go-shortener/internal/model"
+	"github.com/Aleksey170999/go-shortener/internal/repository"
+	"go.uber.org/zap"
+)
+
+// SnapshotStore holds the snapshots of a Snapshotter: the storage file, see
+// Storage, or an object in a bucket, see ObjectStorage.
+type SnapshotStore interface {
+	// LoadFromStorage loads the last snapshot into repo; there may be none.
+	LoadFromStorage(ctx context.Context, repo repository.URLRepository) error
+	// WriteAll replaces the last snapshot with urls.
+	WriteAll(ctx context.Context, urls []model.URL) error
+}
+
+// Snapshotter keeps a SnapshotStore in sync with an in-memory repository
+// off the request path. Writes through the repository returned by Track
+// only count as changes; the Job rewrites the store with the full state
+// when there were any, every interval and whenever the configured number of
+// changes has piled up. Links created since the last snapshot are lost if
+// the process crashes, the price of not writing on every request.
+type Snapshotter struct {
+	storage  SnapshotStore
+	repo     repository.Lister
+	interval time.Duration
+	changes  int64 // Changes that trigger an early snapshot, 0 disables it
+	logger   *zap.Logger
+
+	mu      sync.Mutex   // Serializes snapshots
+	dirty   atomic.Int64 // Changes since the last snapshot
+	running atomic.Bool  // An early snapshot is being written
+}
+
+// NewSnapshotter creates a Snapshotter writing the URLs of repo to storage
+// every interval, or once changes writes happened if changes is positive.
+func NewSnapshotter(storage SnapshotStore, repo repository.Lister, interval time.Duration, changes int, logger *zap.Logger) *Snapshotter {
+	return &Snapshotter{
+		storage:  storage,
+		repo:     repo,
+		interval: interval,
+		changes:  int64(changes),
+		logger:   logger,
+	}
+}
+
+// Job returns the job writing the snapshots. It writes a final one when the
+// runner stops, so nothing is lost on a graceful shutdown.
+func (s *Snapshotter) Job() jobs.Job {
+	return jobs.Job{Name: "snapshot", Schedule: jobs.Every(s.interval), Run: s.Snapshot, RunOnStop: true}
+}
+
+// Snapshot writes all URLs of the repository to the SnapshotStore, sorted
+// by short code, if anything changed since the previous snapshot.
+func (s *Snapshotter) Snapshot(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Changes made while the URLs are listed may or may not be included,
+	// so they are only cleared from the count after the write.
+	dirty := s.dirty.Load()
+	if dirty == 0 {
+		return nil
+	}
+	urls, err := s.repo.List(ctx)
+	if err != nil {
+		return err
+	}
+	slices.SortFunc(urls, func(a, b model.URL) int { return strings.Compare(a.Short, b.Short) })
+	if err := s.storage.WriteAll(ctx, urls); err != nil {
+		return err
+	}
+	s.dirty.Add(-dirty)
+	return nil
+}
+
+// markDirty records n changes, and starts an early snapshot if they add up
+// to the configured number and none is being written already.
+func (s *Snapshotter) markDirty(n int) {
+	if n <= 0 {
+		return
+	}
+	if s.dirty.Add(int64(n)) < s.changes || s.changes == 0 || !s.running.CompareAndSwap(false, true) {
+		return
+	}
+	go func() {
+		defer s.running.Store(false)
+		if err := s.Snapshot(context.Background()); err != nil {
+			s.logger.Error("snapshot failed", zap.Error(err))
+		}
+	}()
+}
+
+// Track returns repo with its writes counted as changes. repo must be the
+// repository the Snapshotter lists, or share its state.
+func (s *Snapshotter) Track(repo repository.URLRepository) repository.URLRepository {
+	return &trackedRepository{URLRepository: repo, snapshotter: s}
+}
+
+// trackedRepository reports the writes of a URLRepository to a Snapshotter.
+type trackedRepository struct {
+	repository.URLRepository
+	snapshotter *Snapshotter
+}
+
+// Save implements repository.URLRepository.
+func (r *trackedRepository) Save(ctx context.Context, url *model.URL) (*model.URL, error) {
+	saved, err := r.URLRepository.Save(ctx, url)
+	if err == nil {
+		r.snapshotter.markDirty(1)
+	}
+	return saved, err
+}
+
+// SaveBatch implements repository.URLRepository.
+func (r *trackedRepository) SaveBatch(ctx context.Context, urls []*model.URL) ([]error, error) {
+	errs, err := r.URLRepository.SaveBatch(ctx, urls)
+	if err == nil {
+		saved := 0
+		for _, err := range errs {
+			if err == nil {
+				saved++
+			}
+		}
+		r.snapshotter.markDirty(saved)
+	}
+	return errs, err
+}
+
+// BatchDelete implements repository.URLRepository.
+func (r *trackedRepository) BatchDelete(ctx context.Context, shortURLs []string, userID string) error {
+	err := r.URLRepository.BatchDelete(ctx, shortURLs, userID)
+	if err == nil {
+		r.snapshotter.markDirty(len(shortURLs))
+	}
+	return err
+}
+
+// List implements repository.Lister if the wrapped repository does.
+func (r *trackedRepository) List(ctx context.Context) ([]model.URL, error) {
+	return list(ctx, r.URLRepository)
+}
+
+// Purge implements repository.URLRepository.
+func (r *trackedRepository) Purge(ctx context.Context, expiredBefore, deletedBefore time.Time, dryRun bool) (repository.PurgeStats, error) {
+	stats, err := r.URLRepository.Purge(ctx, expiredBefore, deletedBefore, dryRun)
+	if err == nil && !dryRun {
+		r.snapshotter.markDirty(int(stats.Expired + stats.Deleted))
+	}
+	return stats, err
+}
+
+// PurgeMatching implements repository.URLRepository.
+func (r *trackedRepository) PurgeMatching(ctx context.Context, filter repository.PurgeFilter, dryRun bool) ([]model.URL, error) {
+	removed, err := r.URLRepository.PurgeMatching(ctx, filter, dryRun)
+	if err == nil && !dryRun {
+		r.snapshotter.markDirty(len(removed))
+	}
+	return removed, err
+}
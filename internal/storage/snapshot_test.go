@@ -0,0 +1,95 @@
+package storage
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Aleksey170999/go-shortener/internal/model"
+	"github.com/Aleksey170999/go-shortener/internal/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// load returns the short codes of the links stored in the file of s that
+// are not deleted.
+func load(t *testing.T, s *Storage) []string {
+	t.Helper()
+	repo := repository.NewMemoryURLRepository()
+	require.NoError(t, s.LoadFromStorage(context.Background(), repo))
+	urls, err := repo.List(context.Background())
+	require.NoError(t, err)
+	shorts := make([]string, 0, len(urls))
+	for _, url := range urls {
+		if !url.IsDeleted {
+			shorts = append(shorts, url.Short)
+		}
+	}
+	return shorts
+}
+
+func TestSnapshotter(t *testing.T) {
+	ctx := context.Background()
+	st := NewStorage(filepath.Join(t.TempDir(), "storage.json"))
+	mem := repository.NewMemoryURLRepository()
+	snap := NewSnapshotter(st, mem, time.Minute, 0, zap.NewNop())
+	repo := snap.Track(mem)
+
+	require.NoError(t, snap.Snapshot(ctx))
+	_, err := os.Stat(st.FilePath)
+	assert.ErrorIs(t, err, os.ErrNotExist, "nothing changed, nothing written")
+
+	_, err = repo.Save(ctx, &model.URL{ID: "1", Short: "a", Original: "https://a.example", UserID: "user"})
+	require.NoError(t, err)
+	_, err = repo.SaveBatch(ctx, []*model.URL{
+		{ID: "2", Short: "b", Original: "https://b.example", UserID: "user"},
+		{ID: "3", Short: "c", Original: "https://c.example"},
+	})
+	require.NoError(t, err)
+	assert.Empty(t, load(t, st), "writes do not touch the file")
+
+	require.NoError(t, snap.Snapshot(ctx))
+	assert.ElementsMatch(t, []string{"a", "b", "c"}, load(t, st))
+
+	require.NoError(t, repo.BatchDelete(ctx, []string{"b"}, "user"))
+	require.NoError(t, snap.Snapshot(ctx))
+	assert.ElementsMatch(t, []string{"a", "c"}, load(t, st), "deleted links stay deleted")
+	loaded := repository.NewMemoryURLRepository()
+	require.NoError(t, st.LoadFromStorage(ctx, loaded))
+	url, err := loaded.GetByShortURL(ctx, "b")
+	require.NoError(t, err)
+	assert.True(t, url.IsDeleted)
+	assert.NotNil(t, url.DeletedAt)
+
+	job := snap.Job()
+	assert.Equal(t, "snapshot", job.Name)
+	assert.True(t, job.RunOnStop)
+}
+
+func TestSnapshotter_Changes(t *testing.T) {
+	ctx := context.Background()
+	st := NewStorage(filepath.Join(t.TempDir(), "storage.json"))
+	mem := repository.NewMemoryURLRepository()
+	snap := NewSnapshotter(st, mem, time.Hour, 2, zap.NewNop())
+	repo := snap.Track(mem)
+
+	_, err := repo.Save(ctx, &model.URL{ID: "1", Short: "a", Original: "https://a.example"})
+	require.NoError(t, err)
+	_, err = repo.Purge(ctx, time.Now(), time.Now(), true)
+	require.NoError(t, err)
+	time.Sleep(10 * time.Millisecond)
+	_, err = os.Stat(st.FilePath)
+	assert.ErrorIs(t, err, os.ErrNotExist, "a single change and a dry run do not trigger a snapshot")
+
+	_, err = repo.Save(ctx, &model.URL{ID: "2", Short: "b", Original: "https://b.example"})
+	require.NoError(t, err)
+	assert.Eventually(t, func() bool {
+		_, err := os.Stat(st.FilePath)
+		return err == nil
+	}, time.Second, 5*time.Millisecond)
+	assert.Eventually(t, func() bool { return !snap.running.Load() }, time.Second, 5*time.Millisecond)
+	assert.ElementsMatch(t, []string{"a", "b"}, load(t, st))
+}
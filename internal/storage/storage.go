@@ -1,8 +1,11 @@
 package storage
 
 import (
-	"encoding/json"
+	"context"
+	"crypto/cipher"
+	"fmt"
 	"os"
+	"path/filepath"
 	"sync"
 
 	"github.com/Aleksey170999/go-shortener/internal/model"
@@ -11,20 +14,35 @@ import (
 
 // Storage provides file-based persistence for URLs.
 // It handles reading from and writing to a JSON file in a thread-safe manner.
+// The file is gzip-compressed if its name ends with ".gz" or it already is,
+// see Compressed; plain and compressed files are both read. With a key,
+// see SetKey, it is encrypted as well.
 type Storage struct {
 	FilePath string
 	mu       sync.Mutex
+	aead     cipher.AEAD // Encrypts the file, nil if it is written plain
+
+	// The asynchronous writer, see StartWriter.
+	qmu     sync.RWMutex    // Guards queue against Close
+	queue   chan model.URL  // Nil unless the writer is running
+	done    chan struct{}   // Closed when the writer has exited
+	onError func(err error) // Called with failed writes of the writer
 }
 
 // LoadFromStorage reads URLs from the storage file and loads them into the provided repository.
-// If the storage file doesn't exist, it returns without an error.
+// If the storage file doesn't exist or FilePath is empty, it returns without an error.
+// Later records of a URL, e.g. its deletion, replace the earlier ones.
 //
 // Parameters:
+//   - ctx: Context passed to the repository when saving loaded URLs
 //   - repo: The URLRepository where the loaded URLs will be stored
 //
 // Returns:
 //   - error: If there's an error reading or parsing the storage file
-func (s *Storage) LoadFromStorage(repo repository.URLRepository) error {
+func (s *Storage) LoadFromStorage(ctx context.Context, repo repository.URLRepository) error {
+	if s.FilePath == "" {
+		return nil
+	}
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -36,17 +54,13 @@ func (s *Storage) LoadFromStorage(repo repository.URLRepository) error {
 		return err
 	}
 
-	if len(data) == 0 {
-		return nil
-	}
-
-	var urls []model.URL
-	if err := json.Unmarshal(data, &urls); err != nil {
+	urls, err := s.decode(data)
+	if err != nil {
 		return err
 	}
 
 	for i := range urls {
-		_, err := repo.Save(&urls[i])
+		_, err := repo.Save(ctx, &urls[i])
 		if err != nil {
 			return err
 		}
@@ -56,8 +70,12 @@ func (s *Storage) LoadFromStorage(repo repository.URLRepository) error {
 }
 
 // LoadToStorage adds a URL to the storage file.
+// The file is rewritten atomically, see Append.
+// While the writer started with StartWriter runs, the URL is queued for it
+// instead, and LoadToStorage only blocks while the queue is full.
 // If the file doesn't exist, it will be created.
 // The URLs are stored as a JSON array with pretty-printed formatting.
+// It does nothing if FilePath is empty.
 //
 // Parameters:
 //   - url: The URL to be stored
@@ -65,37 +83,211 @@ func (s *Storage) LoadFromStorage(repo repository.URLRepository) error {
 // Returns:
 //   - error: If there's an error reading, writing, or parsing the storage file
 func (s *Storage) LoadToStorage(url *model.URL) error {
+	return s.Store(*url)
+}
+
+// Store records the current state of urls in the storage file, whether
+// they are new or changed, e.g. soft-deleted. Like LoadToStorage it queues
+// them while the writer runs. The file keeps the earlier records; a load
+// ends up with the last record of a URL, see LoadFromStorage.
+func (s *Storage) Store(urls ...model.URL) error {
+	s.qmu.RLock()
+	defer s.qmu.RUnlock()
+	if s.queue != nil {
+		for _, url := range urls {
+			s.queue <- url
+		}
+		return nil
+	}
+	return s.Append(urls...)
+}
+
+// Append adds URLs to the storage file, rewriting it once for all of them.
+// It is LoadToStorage for many URLs, e.g. imported ones. The new contents
+// are written to a temporary file that is renamed over the storage file, so
+// a crash mid-write cannot corrupt it.
+func (s *Storage) Append(added ...model.URL) error {
+	if s.FilePath == "" || len(added) == 0 {
+		return nil
+	}
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	var urls []model.URL
-
 	data, err := os.ReadFile(s.FilePath)
 	if err != nil && !os.IsNotExist(err) {
 		return err
 	}
 
-	if len(data) > 0 {
-		if err := json.Unmarshal(data, &urls); err != nil {
-			return err
+	urls, err := s.decode(data)
+	if err != nil {
+		return err
+	}
+
+	urls = append(urls, added...)
+
+	newData, err := s.encode(urls)
+	if err != nil {
+		return err
+	}
+
+	return writeFileAtomic(s.FilePath, newData, 0644)
+}
+
+// StartWriter makes LoadToStorage queue URLs for a writer goroutine
+// instead of rewriting the file itself, so that concurrent requests do not
+// wait for each other's writes. The writer appends everything queued at a
+// time in one rewrite. At most size URLs are queued; write errors are passed
+// to onError, if not nil. Close stops the writer. StartWriter must be called
+// before the Storage is used, and at most once.
+func (s *Storage) StartWriter(size int, onError func(err error)) {
+	s.queue = make(chan model.URL, size)
+	s.done = make(chan struct{})
+	s.onError = onError
+	go s.write(s.queue)
+}
+
+// write appends the URLs of queue to the file until queue is closed.
+func (s *Storage) write(queue <-chan model.URL) {
+	defer close(s.done)
+	for url := range queue {
+		batch := []model.URL{url}
+	drain:
+		for len(batch) < cap(queue) {
+			select {
+			case url, ok := <-queue:
+				if !ok {
+					break drain
+				}
+				batch = append(batch, url)
+			default:
+				break drain
+			}
+		}
+		if err := s.Append(batch...); err != nil && s.onError != nil {
+			s.onError(err)
 		}
 	}
+}
+
+// Close stops the writer started with StartWriter after it has written
+// all queued URLs, waiting for it until ctx is done. Later calls of
+// LoadToStorage write synchronously. Close does nothing if the writer is
+// not running.
+func (s *Storage) Close(ctx context.Context) error {
+	s.qmu.Lock()
+	queue := s.queue
+	s.queue = nil
+	s.qmu.Unlock()
+	if queue == nil {
+		return nil
+	}
+	close(queue)
+	select {
+	case <-s.done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("storage queue not drained: %w", ctx.Err())
+	}
+}
+
+// WriteAll replaces the contents of the storage file with urls, e.g. with
+// a snapshot of the whole repository. Like Append it writes atomically, and
+// it does nothing if FilePath is empty.
+//
+// Implements SnapshotStore interface.
+func (s *Storage) WriteAll(_ context.Context, urls []model.URL) error {
+	if s.FilePath == "" {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
 
-	urls = append(urls, *url)
+	data, err := s.encode(urls)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(s.FilePath, data, 0644)
+}
 
-	newData, err := json.MarshalIndent(urls, "", "  ")
+// SetKey makes the Storage encrypt the file with AES-256-GCM under key, see
+// LoadKey, for deployments where the links themselves are sensitive and the
+// disk is not encrypted. A plain file is still read, and encrypted on the
+// next write. SetKey must be called before the Storage is used.
+func (s *Storage) SetKey(key []byte) error {
+	aead, err := NewCipher(key)
 	if err != nil {
 		return err
 	}
+	s.aead = aead
+	return nil
+}
+
+// decode parses the contents of the storage file, decrypting them first.
+func (s *Storage) decode(data []byte) ([]model.URL, error) {
+	data, err := Decrypt(data, s.aead)
+	if err != nil {
+		return nil, err
+	}
+	return Decode(data)
+}
 
-	return os.WriteFile(s.FilePath, newData, 0644)
+// encode returns the contents of the storage file holding urls, compressed
+// as the file is, see Compressed, and encrypted if a key is set.
+func (s *Storage) encode(urls []model.URL) ([]byte, error) {
+	data, err := Encode(urls, Compressed(s.FilePath))
+	if err != nil || s.aead == nil {
+		return data, err
+	}
+	return Encrypt(data, s.aead)
+}
+
+// writeFileAtomic replaces the file at path with data. The data is written
+// to a temporary file in the same directory, synced and renamed over path,
+// so that a crash leaves either the old or the new file, never a truncated
+// one. The directory is synced as well, so that the rename itself survives
+// a power loss.
+func writeFileAtomic(path string, data []byte, perm os.FileMode) (err error) {
+	dir := filepath.Dir(path)
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+	}()
+
+	if _, err := tmp.Write(data); err != nil {
+		return err
+	}
+	if err := tmp.Chmod(perm); err != nil {
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
 }
 
 // NewStorage creates a new Storage instance with the specified file path.
 // The file will be created if it doesn't exist when LoadToStorage is first called.
 //
 // Parameters:
-//   - filePath: Path to the JSON file where URLs will be stored
+//   - filePath: Path to the JSON file where URLs will be stored, empty to disable the file
 //
 // Returns:
 //   - *Storage: A new Storage instance
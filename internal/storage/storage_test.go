@@ -0,0 +1,92 @@
+package storage
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/Aleksey170999/go-shortener/internal/model"
+	"github.com/Aleksey170999/go-shortener/internal/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStorage_Append(t *testing.T) {
+	dir := t.TempDir()
+	s := NewStorage(filepath.Join(dir, "storage.json"))
+
+	require.NoError(t, s.LoadToStorage(&model.URL{ID: "1", Short: "a", Original: "https://a.example"}))
+	require.NoError(t, s.Append(
+		model.URL{ID: "2", Short: "b", Original: "https://b.example"},
+		model.URL{ID: "3", Short: "c", Original: "https://c.example"},
+	))
+
+	repo := repository.NewMemoryURLRepository()
+	require.NoError(t, s.LoadFromStorage(context.Background(), repo))
+	urls, err := repo.List(context.Background())
+	require.NoError(t, err)
+	assert.Len(t, urls, 3)
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, entries, 1, "no temporary files are left behind")
+	info, err := entries[0].Info()
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0644), info.Mode().Perm())
+}
+
+func TestStorage_Writer(t *testing.T) {
+	s := NewStorage(filepath.Join(t.TempDir(), "storage.json"))
+	require.NoError(t, s.Close(context.Background()), "no writer to stop")
+
+	var failed atomic.Bool
+	s.StartWriter(4, func(error) { failed.Store(true) })
+	var wg sync.WaitGroup
+	for i := range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			short := strconv.Itoa(i)
+			assert.NoError(t, s.LoadToStorage(&model.URL{ID: short, Short: short, Original: "https://example.com/" + short}))
+		}()
+	}
+	wg.Wait()
+	require.NoError(t, s.Close(context.Background()))
+	assert.False(t, failed.Load())
+	assert.Len(t, load(t, s), 50, "Close drains the queue")
+
+	require.NoError(t, s.LoadToStorage(&model.URL{ID: "sync", Short: "sync", Original: "https://example.com/sync"}))
+	assert.Len(t, load(t, s), 51, "written synchronously after Close")
+}
+
+func TestStorage_WriterErrors(t *testing.T) {
+	s := NewStorage(filepath.Join(t.TempDir(), "missing", "storage.json"))
+	errs := make(chan error, 1)
+	s.StartWriter(1, func(err error) { errs <- err })
+	require.NoError(t, s.LoadToStorage(&model.URL{ID: "1", Short: "a", Original: "https://a.example"}))
+	require.NoError(t, s.Close(context.Background()))
+	select {
+	case err := <-errs:
+		assert.ErrorIs(t, err, os.ErrNotExist)
+	default:
+		t.Fatal("the write error was not reported")
+	}
+}
+
+func TestWriteFileAtomic_RemovesTempFileOnFailure(t *testing.T) {
+	dir := t.TempDir()
+	// Renaming a file over a non-empty directory fails after the data has
+	// been written to the temporary file.
+	target := filepath.Join(dir, "storage.json")
+	require.NoError(t, os.MkdirAll(filepath.Join(target, "child"), 0755))
+	require.Error(t, writeFileAtomic(target, []byte("[]"), 0644))
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "storage.json", entries[0].Name())
+}
@@ -0,0 +1,109 @@
+// Package tracing sets up OpenTelemetry tracing for the application.
+//
+// Spans are exported over OTLP/HTTP and the W3C traceparent/tracestate and
+// baggage headers are used for propagation, so a redirect can be followed
+// from the gateway through the HTTP handler and URLService down to the
+// repository.
+package tracing
+
+import (
+	"context"
+	"time"
+
+	"github.com/Aleksey170999/go-shortener/internal/service"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.34.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentationName identifies the tracer used by this application.
+const instrumentationName = "github.com/Aleksey170999/go-shortener"
+
+// Config describes where and how spans are exported.
+type Config struct {
+	Endpoint    string  // OTLP/HTTP collector endpoint, e.g. "localhost:4318"
+	Insecure    bool    // Use plain HTTP instead of HTTPS
+	ServiceName string  // Value of the service.name resource attribute
+	SampleRatio float64 // Fraction of new traces that are sampled, 1 samples all
+}
+
+// Setup installs a global tracer provider exporting to cfg.Endpoint and the
+// W3C trace context propagator. The returned function flushes pending spans
+// and shuts the provider down; it must be called before the process exits.
+func Setup(ctx context.Context, cfg Config) (func(context.Context) error, error) {
+	opts := []otlptracehttp.Option{otlptracehttp.WithEndpoint(cfg.Endpoint)}
+	if cfg.Insecure {
+		opts = append(opts, otlptracehttp.WithInsecure())
+	}
+	exporter, err := otlptracehttp.New(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(
+		semconv.SchemaURL,
+		semconv.ServiceName(cfg.ServiceName),
+	))
+	if err != nil {
+		return nil, err
+	}
+
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+	)
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+	return tp.Shutdown, nil
+}
+
+// Tracer returns the application tracer of the global tracer provider.
+func Tracer() trace.Tracer {
+	return otel.Tracer(instrumentationName)
+}
+
+// Hooks implements service.Hooks by recording a span for every completed
+// URLService operation. Hooks are notified after the fact, so each span is
+// backdated by the reported duration; it becomes a child of the span in the
+// operation's context, usually the HTTP server span.
+type Hooks struct{}
+
+var _ service.Hooks = Hooks{}
+
+// OnShorten implements service.Hooks.
+func (Hooks) OnShorten(ctx context.Context, d time.Duration, err error) {
+	recordSpan(ctx, "URLService.Shorten", d, err)
+}
+
+// OnResolve implements service.Hooks.
+func (Hooks) OnResolve(ctx context.Context, d time.Duration, err error) {
+	recordSpan(ctx, "URLService.Resolve", d, err)
+}
+
+// OnDelete implements service.Hooks.
+func (Hooks) OnDelete(ctx context.Context, d time.Duration, err error) {
+	recordSpan(ctx, "URLRepository.BatchDelete", d, err)
+}
+
+// recordSpan records a span named name that ended now and lasted d.
+func recordSpan(ctx context.Context, name string, d time.Duration, err error) {
+	end := time.Now()
+	_, span := Tracer().Start(ctx, name,
+		trace.WithTimestamp(end.Add(-d)),
+		trace.WithSpanKind(trace.SpanKindInternal),
+	)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End(trace.WithTimestamp(end))
+}
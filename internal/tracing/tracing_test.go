@@ -0,0 +1,39 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestHooks(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	prev := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(prev) })
+
+	ctx, parent := Tracer().Start(context.Background(), "parent")
+	Hooks{}.OnResolve(ctx, 50*time.Millisecond, nil)
+	Hooks{}.OnShorten(ctx, time.Millisecond, errors.New("boom"))
+	parent.End()
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 3)
+
+	resolve := spans[0]
+	assert.Equal(t, "URLService.Resolve", resolve.Name())
+	assert.Equal(t, parent.SpanContext().SpanID(), resolve.Parent().SpanID())
+	assert.InDelta(t, float64(50*time.Millisecond), float64(resolve.EndTime().Sub(resolve.StartTime())), float64(time.Millisecond))
+
+	shorten := spans[1]
+	assert.Equal(t, "URLService.Shorten", shorten.Name())
+	assert.Equal(t, codes.Error, shorten.Status().Code)
+}
@@ -0,0 +1,12 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE urls ADD COLUMN expires_at TIMESTAMPTZ;
+ALTER TABLE urls ADD COLUMN deleted_at TIMESTAMPTZ;
+UPDATE urls SET deleted_at = NOW() WHERE is_deleted;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE urls DROP COLUMN IF EXISTS deleted_at;
+ALTER TABLE urls DROP COLUMN IF EXISTS expires_at;
+-- +goose StatementEnd
@@ -0,0 +1,40 @@
+package shortener_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	"github.com/Aleksey170999/go-shortener/pkg/shortener"
+	"github.com/go-chi/chi/v5"
+)
+
+// ExampleServer_Handler mounts the shortener under /s of another router.
+func ExampleServer_Handler() {
+	cfg := shortener.NewConfig()
+	cfg.ReturnPrefix = "https://example.com/s"
+	cfg.StorageFilePath = ""
+	srv, err := shortener.New(cfg)
+	if err != nil {
+		panic(err)
+	}
+	defer srv.Close(context.Background())
+
+	r := chi.NewRouter()
+	r.Get("/", func(w http.ResponseWriter, r *http.Request) { fmt.Fprint(w, "home") })
+	r.Mount("/s", srv.Handler())
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/s/", strings.NewReader("https://go.dev")))
+	short := strings.TrimPrefix(w.Body.String(), "https://example.com")
+	fmt.Println(w.Code, strings.HasPrefix(short, "/s/"))
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, short, nil))
+	fmt.Println(w.Code, w.Header().Get("Location"))
+	// Output:
+	// 201 true
+	// 307 https://go.dev
+}
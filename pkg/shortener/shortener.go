@@ -0,0 +1,137 @@
+// Package shortener embeds the URL shortener into other Go services. A
+// Server has the same routes as the shortener binary, and its Handler can be
+// mounted into another router instead of running a separate process:
+//
+//	cfg := shortener.NewConfig()
+//	cfg.ReturnPrefix = "https://example.com/s"
+//	cfg.StorageFilePath = "" // keep links in memory
+//	srv, err := shortener.New(cfg)
+//	if err != nil {
+//		return err
+//	}
+//	defer srv.Close(context.Background())
+//	r.Mount("/s", srv.Handler())
+//
+// Links are stored as configured, or in a URLRepository of the embedding
+// service given with WithRepository. NewConfig keeps the defaults of the
+// binary, so the storage file ./storage.json is used unless StorageFilePath
+// is cleared or changed.
+package shortener
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/Aleksey170999/go-shortener/internal/app"
+	"github.com/Aleksey170999/go-shortener/internal/config"
+	"github.com/Aleksey170999/go-shortener/internal/model"
+	"github.com/Aleksey170999/go-shortener/internal/repository"
+	"go.uber.org/zap"
+)
+
+// Config is the configuration of a Server. Its fields correspond to the
+// environment variables and flags of the shortener binary; BASE_URL, for
+// instance, is ReturnPrefix, which must include the mount path, and an
+// empty StorageFilePath keeps the links in memory only. The address and
+// server settings are not used, as a Server does not listen itself.
+type Config = config.Config
+
+// NewConfig returns a Config with the default settings of the shortener
+// binary. Unlike the binary, it reads neither flags nor the environment.
+func NewConfig() *Config {
+	return config.NewConfig()
+}
+
+// URLRepository stores the links of a Server. Implementations must be safe
+// for concurrent use and report the errors documented on its methods, such
+// as ErrNotFound and ErrShortURLConflict.
+type URLRepository = repository.URLRepository
+
+// Types used by URLRepository.
+type (
+	URL         = model.URL
+	ClickStat   = model.ClickStat
+	PurgeStats  = repository.PurgeStats
+	PurgeFilter = repository.PurgeFilter
+)
+
+// Errors returned by URLRepository implementations.
+var (
+	ErrNotFound         = repository.ErrNotFound
+	ErrURLAlreadyExists = model.ErrURLAlreadyExists
+	ErrShortURLConflict = model.ErrShortURLConflict
+	ErrEmptyPurgeFilter = repository.ErrEmptyPurgeFilter
+)
+
+// Option configures a Server.
+type Option func(*options)
+
+type options struct {
+	repo   URLRepository
+	logger *zap.Logger
+}
+
+// WithRepository makes the Server store links in repo instead of the
+// repository selected by the Config. The Server closes repo in Close if it
+// implements io.Closer.
+func WithRepository(repo URLRepository) Option {
+	return func(o *options) {
+		o.repo = repo
+	}
+}
+
+// WithLogger sets the logger of the Server. Without it nothing is logged.
+// Changing the log level through /api/internal/log-level does not affect
+// logger.
+func WithLogger(logger *zap.Logger) Option {
+	return func(o *options) {
+		o.logger = logger
+	}
+}
+
+// Server is an embedded URL shortener.
+type Server struct {
+	app *app.App
+}
+
+// New validates cfg and builds a Server from it. Its background work, such
+// as the delete workers and the periodic cleanup, starts right away and runs
+// until Close.
+func New(cfg *Config, opts ...Option) (*Server, error) {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+
+	var appOpts []app.Option
+	if o.repo != nil {
+		appOpts = append(appOpts, app.WithRepository(o.repo))
+	}
+	if o.logger != nil {
+		appOpts = append(appOpts, app.WithLogger(o.logger, zap.NewAtomicLevelAt(o.logger.Level())))
+	}
+	a, err := app.New(cfg, appOpts...)
+	if err != nil {
+		return nil, err
+	}
+	a.Jobs.Start(context.Background())
+	return &Server{app: a}, nil
+}
+
+// Handler returns the handler serving all routes of the shortener, e.g. to
+// mount it into a chi router with Mount.
+func (s *Server) Handler() http.Handler {
+	return s.app.Handlers
+}
+
+// Close flushes queued deletions and audit events, stops the background
+// work and closes the repository. The Handler must not be served any more
+// once Close is called. It returns ctx.Err() among the failures if ctx is
+// done first.
+func (s *Server) Close(ctx context.Context) error {
+	return s.app.Close(ctx)
+}
@@ -0,0 +1,55 @@
+package shortener
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/Aleksey170999/go-shortener/internal/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// closingRepository counts the links saved through it and records Close.
+type closingRepository struct {
+	URLRepository
+	saved  atomic.Int32
+	closed bool
+}
+
+func (r *closingRepository) Save(ctx context.Context, url *URL) (*URL, error) {
+	r.saved.Add(1)
+	return r.URLRepository.Save(ctx, url)
+}
+
+func (r *closingRepository) Close() error {
+	r.closed = true
+	return nil
+}
+
+func TestNew_WithRepository(t *testing.T) {
+	repo := &closingRepository{URLRepository: repository.NewMemoryURLRepository()}
+	cfg := NewConfig()
+	cfg.StorageFilePath = t.TempDir() + "/storage.json"
+	srv, err := New(cfg, WithRepository(repo))
+	require.NoError(t, err)
+
+	w := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/", strings.NewReader("https://example.com")))
+	require.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, int32(1), repo.saved.Load())
+	assert.NoFileExists(t, cfg.StorageFilePath, "links of a custom repository are not copied to the storage file")
+
+	require.NoError(t, srv.Close(context.Background()))
+	assert.True(t, repo.closed)
+}
+
+func TestNew_InvalidConfig(t *testing.T) {
+	cfg := NewConfig()
+	cfg.LogLevel = "loud"
+	_, err := New(cfg)
+	assert.ErrorContains(t, err, "invalid configuration")
+}